@@ -0,0 +1,156 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// AccountNames maps SID strings to account names in DOMAIN\user form.
+type AccountNames map[string]string
+
+// Account names of well-known SIDs. These are the same on every Windows system so they are always resolved.
+// See here for the full list: https://docs.microsoft.com/en-us/windows/win32/secauthz/well-known-sids
+var wellKnownAccountNames = AccountNames{
+	"S-1-0-0":      "NULL SID",
+	"S-1-1-0":      "Everyone",
+	"S-1-2-0":      "LOCAL",
+	"S-1-2-1":      "CONSOLE LOGON",
+	"S-1-3-0":      "CREATOR OWNER",
+	"S-1-3-1":      "CREATOR GROUP",
+	"S-1-3-4":      "OWNER RIGHTS",
+	"S-1-5-1":      "NT AUTHORITY\\DIALUP",
+	"S-1-5-2":      "NT AUTHORITY\\NETWORK",
+	"S-1-5-3":      "NT AUTHORITY\\BATCH",
+	"S-1-5-4":      "NT AUTHORITY\\INTERACTIVE",
+	"S-1-5-6":      "NT AUTHORITY\\SERVICE",
+	"S-1-5-7":      "NT AUTHORITY\\ANONYMOUS LOGON",
+	"S-1-5-9":      "NT AUTHORITY\\ENTERPRISE DOMAIN CONTROLLERS",
+	"S-1-5-10":     "NT AUTHORITY\\SELF",
+	"S-1-5-11":     "NT AUTHORITY\\Authenticated Users",
+	"S-1-5-12":     "NT AUTHORITY\\RESTRICTED",
+	"S-1-5-13":     "NT AUTHORITY\\TERMINAL SERVER USER",
+	"S-1-5-14":     "NT AUTHORITY\\REMOTE INTERACTIVE LOGON",
+	"S-1-5-15":     "NT AUTHORITY\\This Organization",
+	"S-1-5-17":     "NT AUTHORITY\\IUSR",
+	"S-1-5-18":     "NT AUTHORITY\\SYSTEM",
+	"S-1-5-19":     "NT AUTHORITY\\LOCAL SERVICE",
+	"S-1-5-20":     "NT AUTHORITY\\NETWORK SERVICE",
+	"S-1-5-32-544": "BUILTIN\\Administrators",
+	"S-1-5-32-545": "BUILTIN\\Users",
+	"S-1-5-32-546": "BUILTIN\\Guests",
+	"S-1-5-32-547": "BUILTIN\\Power Users",
+	"S-1-5-32-548": "BUILTIN\\Account Operators",
+	"S-1-5-32-549": "BUILTIN\\Server Operators",
+	"S-1-5-32-550": "BUILTIN\\Print Operators",
+	"S-1-5-32-551": "BUILTIN\\Backup Operators",
+	"S-1-5-32-552": "BUILTIN\\Replicator",
+	"S-1-5-32-555": "BUILTIN\\Remote Desktop Users",
+	"S-1-5-32-568": "BUILTIN\\IIS_IUSRS",
+	"S-1-5-80-0":   "NT SERVICE\\ALL SERVICES",
+	"S-1-5-80-956008885-3418522649-1831038044-1853292631-2271478464": "NT SERVICE\\TrustedInstaller",
+	"S-1-15-2-1":   "APPLICATION PACKAGE AUTHORITY\\ALL APPLICATION PACKAGES",
+	"S-1-15-2-2":   "APPLICATION PACKAGE AUTHORITY\\ALL RESTRICTED APPLICATION PACKAGES",
+	"S-1-16-4096":  "Mandatory Label\\Low Mandatory Level",
+	"S-1-16-8192":  "Mandatory Label\\Medium Mandatory Level",
+	"S-1-16-12288": "Mandatory Label\\High Mandatory Level",
+	"S-1-16-16384": "Mandatory Label\\System Mandatory Level",
+	"S-1-5-64-10":  "NT AUTHORITY\\NTLM Authentication",
+	"S-1-5-113":    "NT AUTHORITY\\Local account",
+	"S-1-5-114":    "NT AUTHORITY\\Local account and member of Administrators group",
+	"S-1-5-32-573": "BUILTIN\\Event Log Readers",
+	"S-1-5-32-578": "BUILTIN\\Hyper-V Administrators",
+	"S-1-5-32-580": "BUILTIN\\Remote Management Users",
+	"S-1-5-80-3139157870-2983391045-3678747466-658725712-1809340420": "NT SERVICE\\WdiServiceHost",
+}
+
+// LoadAccountNames reads a SID to account name mapping file. The file is either json, as an object of SID keys and account name values or as an array of objects with SID and Name fields, or csv with the SID in the first column and the account name in the second. A csv header row is skipped.
+func LoadAccountNames(reader io.Reader) (accountNames AccountNames, err error) {
+	rawMapping, err := ioutil.ReadAll(reader)
+	if err != nil {
+		err = fmt.Errorf("failed to read account name mapping: %w", err)
+		return
+	}
+	rawMapping = bytes.TrimSpace(bytes.TrimPrefix(rawMapping, []byte("\xef\xbb\xbf")))
+	if len(rawMapping) == 0 {
+		err = errors.New("account name mapping is empty")
+		return
+	}
+
+	switch rawMapping[0] {
+	case '{':
+		var entries map[string]string
+		err = json.Unmarshal(rawMapping, &entries)
+		if err != nil {
+			err = fmt.Errorf("failed to parse json account name mapping: %w", err)
+			return
+		}
+		accountNames = make(AccountNames)
+		for sid, name := range entries {
+			accountNames[normalizeSID(sid)] = name
+		}
+	case '[':
+		var entries []struct {
+			SID  string
+			Name string
+		}
+		err = json.Unmarshal(rawMapping, &entries)
+		if err != nil {
+			err = fmt.Errorf("failed to parse json account name mapping: %w", err)
+			return
+		}
+		accountNames = make(AccountNames)
+		for _, entry := range entries {
+			accountNames[normalizeSID(entry.SID)] = entry.Name
+		}
+	default:
+		accountNames, err = parseAccountNamesCSV(rawMapping)
+	}
+	return
+}
+
+// Parses a csv SID to account name mapping.
+func parseAccountNamesCSV(rawMapping []byte) (accountNames AccountNames, err error) {
+	csvReader := csv.NewReader(bytes.NewReader(rawMapping))
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+	rows, err := csvReader.ReadAll()
+	if err != nil {
+		err = fmt.Errorf("failed to parse csv account name mapping: %w", err)
+		return
+	}
+
+	accountNames = make(AccountNames)
+	for _, row := range rows {
+		if len(row) < 2 || !strings.HasPrefix(normalizeSID(row[0]), "S-") {
+			continue
+		}
+		accountNames[normalizeSID(row[0])] = row[1]
+	}
+	return
+}
+
+// Mapping files written by hand or exported from the registry don't always agree on case or whitespace.
+func normalizeSID(sid string) string {
+	return strings.ToUpper(strings.TrimSpace(sid))
+}
+
+// Lookup returns the account name of a SID. The account names receiver is checked first, followed by the well-known SIDs.
+func (accountNames AccountNames) Lookup(sid string) (accountName string, ok bool) {
+	if sid == "" {
+		return
+	}
+	sid = normalizeSID(sid)
+	if accountName, ok = accountNames[sid]; ok {
+		return
+	}
+	accountName, ok = wellKnownAccountNames[sid]
+	return
+}
@@ -0,0 +1,109 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLoadAccountNames(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    AccountNames
+		wantErr bool
+	}{
+		{
+			name:  "json object",
+			input: `{"S-1-5-21-1-2-3-1001": "CORP\\alice", "s-1-5-21-1-2-3-1002": "CORP\\bob"}`,
+			want: AccountNames{
+				"S-1-5-21-1-2-3-1001": "CORP\\alice",
+				"S-1-5-21-1-2-3-1002": "CORP\\bob",
+			},
+		},
+		{
+			name:  "json array",
+			input: `[{"SID": "S-1-5-21-1-2-3-1001", "Name": "CORP\\alice"}]`,
+			want:  AccountNames{"S-1-5-21-1-2-3-1001": "CORP\\alice"},
+		},
+		{
+			name:  "csv with header and byte order mark",
+			input: "\xef\xbb\xbfSID,Name\nS-1-5-21-1-2-3-1001,CORP\\alice\n s-1-5-21-1-2-3-1002, CORP\\bob\n",
+			want: AccountNames{
+				"S-1-5-21-1-2-3-1001": "CORP\\alice",
+				"S-1-5-21-1-2-3-1002": "CORP\\bob",
+			},
+		},
+		{
+			name:    "empty input",
+			input:   "  \n",
+			wantErr: true,
+		},
+		{
+			name:    "malformed json",
+			input:   `{"S-1-5-21-1-2-3-1001": `,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := LoadAccountNames(strings.NewReader(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("LoadAccountNames() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("LoadAccountNames() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAccountNames_Lookup(t *testing.T) {
+	tests := []struct {
+		name            string
+		accountNames    AccountNames
+		sid             string
+		wantAccountName string
+		wantOk          bool
+	}{
+		{
+			name:            "from mapping",
+			accountNames:    AccountNames{"S-1-5-21-1-2-3-1001": "CORP\\alice"},
+			sid:             "S-1-5-21-1-2-3-1001",
+			wantAccountName: "CORP\\alice",
+			wantOk:          true,
+		},
+		{
+			name:            "mapping overrides well-known",
+			accountNames:    AccountNames{"S-1-5-32-544": "BUILTIN\\Administratoren"},
+			sid:             "S-1-5-32-544",
+			wantAccountName: "BUILTIN\\Administratoren",
+			wantOk:          true,
+		},
+		{
+			name:            "well-known with nil mapping",
+			sid:             "S-1-5-18",
+			wantAccountName: "NT AUTHORITY\\SYSTEM",
+			wantOk:          true,
+		},
+		{
+			name:         "unknown",
+			accountNames: AccountNames{"S-1-5-21-1-2-3-1001": "CORP\\alice"},
+			sid:          "S-1-5-21-1-2-3-1002",
+		},
+		{
+			name: "empty sid",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAccountName, gotOk := tt.accountNames.Lookup(tt.sid)
+			if gotAccountName != tt.wantAccountName || gotOk != tt.wantOk {
+				t.Errorf("Lookup() got = %v, %v, want %v, %v", gotAccountName, gotOk, tt.wantAccountName, tt.wantOk)
+			}
+		})
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	bin "github.com/AlecRandazzo/BinaryTransforms"
+)
+
+// RawAttributeDefinitions is a []byte alias for the raw contents of the $AttrDef file. Used with the Parse() method.
+// See here for the layout: https://flatcap.org/linux-ntfs/ntfs/files/attrdef.html
+type RawAttributeDefinitions []byte
+
+// AttributeDefinitions maps attribute type codes to their $AttrDef entries. A nil AttributeDefinitions falls back to DefaultAttributeDefinitions.
+type AttributeDefinitions map[uint32]AttributeDefinition
+
+// AttributeDefinitionFlags contains the flags of an $AttrDef entry.
+type AttributeDefinitionFlags uint32
+
+// AttributeDefinition contains a parsed $AttrDef entry.
+type AttributeDefinition struct {
+	Name          string
+	Type          uint32
+	DisplayRule   uint32
+	CollationRule uint32
+	Flags         AttributeDefinitionFlags
+	MinimumSize   int64
+	MaximumSize   int64
+}
+
+// AttributeInfo contains the header fields of an attribute in an MFT record. Every attribute in a record gets one, even those of types this package doesn't parse.
+type AttributeInfo struct {
+	Type     uint32
+	Name     string
+	Resident bool
+	Size     uint32
+	ID       uint16
+}
+
+// $AttrDef entry flags.
+const (
+	AttributeDefinitionFlagIndexable      AttributeDefinitionFlags = 0x02
+	AttributeDefinitionFlagMultiple       AttributeDefinitionFlags = 0x04
+	AttributeDefinitionFlagNotZero        AttributeDefinitionFlags = 0x08
+	AttributeDefinitionFlagIndexedUnique  AttributeDefinitionFlags = 0x10
+	AttributeDefinitionFlagNamedUnique    AttributeDefinitionFlags = 0x20
+	AttributeDefinitionFlagResident       AttributeDefinitionFlags = 0x40
+	AttributeDefinitionFlagLogNonResident AttributeDefinitionFlags = 0x80
+	lengthAttributeDefinition                                      = 0xA0
+	attributeDefinitionsRecordNumber                               = 4
+	attributeTypeEnd                                               = 0xFFFFFFFF
+)
+
+// DefaultAttributeDefinitions is the $AttrDef table of an NTFS 3.1 volume. It is used when a volume's own $AttrDef isn't available. $PROPERTY_SET is only defined on older volumes but is kept so their records can be named.
+var DefaultAttributeDefinitions = AttributeDefinitions{
+	0x10:  {Name: "$STANDARD_INFORMATION", Type: 0x10, Flags: AttributeDefinitionFlagResident, MinimumSize: 0x30, MaximumSize: 0x48},
+	0x20:  {Name: "$ATTRIBUTE_LIST", Type: 0x20, Flags: AttributeDefinitionFlagLogNonResident, MaximumSize: -1},
+	0x30:  {Name: "$FILE_NAME", Type: 0x30, CollationRule: 1, Flags: AttributeDefinitionFlagIndexable | AttributeDefinitionFlagResident, MinimumSize: 0x44, MaximumSize: 0x242},
+	0x40:  {Name: "$OBJECT_ID", Type: 0x40, Flags: AttributeDefinitionFlagResident, MaximumSize: 0x100},
+	0x50:  {Name: "$SECURITY_DESCRIPTOR", Type: 0x50, Flags: AttributeDefinitionFlagLogNonResident, MaximumSize: -1},
+	0x60:  {Name: "$VOLUME_NAME", Type: 0x60, Flags: AttributeDefinitionFlagResident, MinimumSize: 0x02, MaximumSize: 0x100},
+	0x70:  {Name: "$VOLUME_INFORMATION", Type: 0x70, Flags: AttributeDefinitionFlagResident, MinimumSize: 0x0C, MaximumSize: 0x0C},
+	0x80:  {Name: "$DATA", Type: 0x80, MaximumSize: -1},
+	0x90:  {Name: "$INDEX_ROOT", Type: 0x90, Flags: AttributeDefinitionFlagResident, MaximumSize: -1},
+	0xA0:  {Name: "$INDEX_ALLOCATION", Type: 0xA0, Flags: AttributeDefinitionFlagLogNonResident, MaximumSize: -1},
+	0xB0:  {Name: "$BITMAP", Type: 0xB0, Flags: AttributeDefinitionFlagLogNonResident, MaximumSize: -1},
+	0xC0:  {Name: "$REPARSE_POINT", Type: 0xC0, Flags: AttributeDefinitionFlagLogNonResident, MaximumSize: 0x4000},
+	0xD0:  {Name: "$EA_INFORMATION", Type: 0xD0, Flags: AttributeDefinitionFlagResident, MinimumSize: 0x08, MaximumSize: 0x08},
+	0xE0:  {Name: "$EA", Type: 0xE0, MaximumSize: 0x10000},
+	0xF0:  {Name: "$PROPERTY_SET", Type: 0xF0, MaximumSize: -1},
+	0x100: {Name: "$LOGGED_UTILITY_STREAM", Type: 0x100, Flags: AttributeDefinitionFlagLogNonResident, MaximumSize: 0x10000},
+}
+
+// Parse parses the raw $AttrDef receiver. Parsing stops at the first entry with a type of 0 which marks the end of the table.
+func (rawAttributeDefinitions RawAttributeDefinitions) Parse() (attributeDefinitions AttributeDefinitions, err error) {
+	const offsetName = 0x00
+	const lengthName = 0x80
+	const offsetType = 0x80
+	const offsetDisplayRule = 0x84
+	const offsetCollationRule = 0x88
+	const offsetFlags = 0x8C
+	const offsetMinimumSize = 0x90
+	const offsetMaximumSize = 0x98
+
+	// Sanity checks
+	sizeOfRawAttributeDefinitions := len(rawAttributeDefinitions)
+	if sizeOfRawAttributeDefinitions < lengthAttributeDefinition {
+		err = fmt.Errorf("RawAttributeDefinitions.Parse() expected at least %d bytes, instead received %d: %w", lengthAttributeDefinition, sizeOfRawAttributeDefinitions, ErrTruncated)
+		return
+	}
+
+	attributeDefinitions = make(AttributeDefinitions)
+	for offset := 0; offset+lengthAttributeDefinition <= sizeOfRawAttributeDefinitions; offset += lengthAttributeDefinition {
+		rawEntry := rawAttributeDefinitions[offset : offset+lengthAttributeDefinition]
+		var attributeDefinition AttributeDefinition
+		attributeDefinition.Type = binary.LittleEndian.Uint32(rawEntry[offsetType : offsetType+0x04])
+		if attributeDefinition.Type == 0 {
+			break
+		}
+		attributeDefinition.Name, _ = bin.UnicodeBytesToASCII(rawEntry[offsetName : offsetName+lengthName])
+		attributeDefinition.DisplayRule = binary.LittleEndian.Uint32(rawEntry[offsetDisplayRule : offsetDisplayRule+0x04])
+		attributeDefinition.CollationRule = binary.LittleEndian.Uint32(rawEntry[offsetCollationRule : offsetCollationRule+0x04])
+		attributeDefinition.Flags = AttributeDefinitionFlags(binary.LittleEndian.Uint32(rawEntry[offsetFlags : offsetFlags+0x04]))
+		attributeDefinition.MinimumSize = int64(binary.LittleEndian.Uint64(rawEntry[offsetMinimumSize : offsetMinimumSize+0x08]))
+		attributeDefinition.MaximumSize = int64(binary.LittleEndian.Uint64(rawEntry[offsetMaximumSize : offsetMaximumSize+0x08]))
+		attributeDefinitions[attributeDefinition.Type] = attributeDefinition
+	}
+	if len(attributeDefinitions) == 0 {
+		err = errors.New("RawAttributeDefinitions.Parse() did not find any attribute definitions")
+	}
+	return
+}
+
+// BuildAttributeDefinitions reads an extracted $AttrDef file and returns its attribute definitions.
+func BuildAttributeDefinitions(reader io.Reader) (attributeDefinitions AttributeDefinitions, err error) {
+	rawAttributeDefinitions, err := ioutil.ReadAll(reader)
+	if err != nil {
+		err = fmt.Errorf("failed to read $AttrDef: %w", err)
+		return
+	}
+	attributeDefinitions, err = RawAttributeDefinitions(rawAttributeDefinitions).Parse()
+	return
+}
+
+// ReadAttributeDefinitions reads the $AttrDef file of a volume. The volume must start at the beginning of the NTFS partition since the boot sector is used to find the MFT.
+func ReadAttributeDefinitions(volume io.ReaderAt) (attributeDefinitions AttributeDefinitions, err error) {
+	const codeData = 0x80
+
+	bootSector, err := readBootSector(volume)
+	if err != nil {
+		return
+	}
+	bytesPerCluster := int64(bootSector.BytesPerCluster())
+	mftOffset := int64(bootSector.MftCluster) * bytesPerCluster
+	rawMftRecord, err := readRawMftRecord(volume, mftOffset, int64(bootSector.MftRecordSize), int(bootSector.BytesPerSector), attributeDefinitionsRecordNumber)
+	if err != nil {
+		err = fmt.Errorf("failed to read the $AttrDef record: %w", err)
+		return
+	}
+	rawRecordHeader, err := rawMftRecord.GetRawRecordHeader()
+	if err != nil {
+		err = fmt.Errorf("failed to parse the $AttrDef record header: %w", err)
+		return
+	}
+	recordHeader, _ := rawRecordHeader.Parse()
+	rawAttributes, err := rawMftRecord.GetRawAttributes(recordHeader)
+	if err != nil {
+		err = fmt.Errorf("failed to get the attributes of the $AttrDef record: %w", err)
+		return
+	}
+
+	for _, rawAttribute := range rawAttributes {
+		attributeInfo := rawAttribute.info()
+		if attributeInfo.Type != codeData || attributeInfo.Name != "" {
+			continue
+		}
+		var rawAttributeDefinitions []byte
+		if attributeInfo.Resident {
+			rawAttributeDefinitions, err = residentContent(rawAttribute, codeData, "RawAttributeDefinitions")
+		} else {
+			rawAttributeDefinitions, err = readNonResidentAttribute(volume, rawAttribute, bytesPerCluster)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to read $AttrDef from the volume: %w", err)
+			return
+		}
+		attributeDefinitions, err = RawAttributeDefinitions(rawAttributeDefinitions).Parse()
+		return
+	}
+	err = errors.New("the $AttrDef record does not have a data attribute")
+	return
+}
+
+// Returns the attribute definitions receiver, or the default attribute definitions when the receiver is nil.
+func (attributeDefinitions AttributeDefinitions) orDefault() AttributeDefinitions {
+	if attributeDefinitions == nil {
+		return DefaultAttributeDefinitions
+	}
+	return attributeDefinitions
+}
+
+// IsDefined checks if the attribute type is in the attribute definitions receiver.
+func (attributeDefinitions AttributeDefinitions) IsDefined(attributeType uint32) bool {
+	_, ok := attributeDefinitions.orDefault()[attributeType]
+	return ok
+}
+
+// TypeName returns the name of the attribute type, e.g. $DATA. Types that aren't in the attribute definitions receiver are returned in hex, e.g. 0x1000.
+func (attributeDefinitions AttributeDefinitions) TypeName(attributeType uint32) string {
+	if attributeDefinition, ok := attributeDefinitions.orDefault()[attributeType]; ok {
+		return attributeDefinition.Name
+	}
+	return fmt.Sprintf("0x%X", attributeType)
+}
+
+// Describe returns the type name of the attribute info receiver followed by the attribute name if it has one, e.g. $DATA:Zone.Identifier.
+func (attributeInfo AttributeInfo) Describe(attributeDefinitions AttributeDefinitions) string {
+	typeName := attributeDefinitions.TypeName(attributeInfo.Type)
+	if attributeInfo.Name == "" {
+		return typeName
+	}
+	return typeName + ":" + attributeInfo.Name
+}
+
+// DescribeAttributes returns the descriptions of every attribute in a record joined with a semicolon.
+func DescribeAttributes(attributes []AttributeInfo, attributeDefinitions AttributeDefinitions) string {
+	descriptions := make([]string, 0, len(attributes))
+	for _, attributeInfo := range attributes {
+		descriptions = append(descriptions, attributeInfo.Describe(attributeDefinitions))
+	}
+	return strings.Join(descriptions, ";")
+}
+
+// Returns the header fields of the raw attribute receiver.
+func (rawAttribute rawAttribute) info() (attributeInfo AttributeInfo) {
+	const offsetSize = 0x04
+	const offsetResidentFlag = 0x08
+	const offsetAttributeID = 0x0E
+	const lengthHeader = 0x10
+
+	if len(rawAttribute) < lengthHeader {
+		return
+	}
+	attributeInfo.Type = rawAttribute.attributeType()
+	attributeInfo.Size = binary.LittleEndian.Uint32(rawAttribute[offsetSize : offsetSize+0x04])
+	attributeInfo.Resident = RawResidencyFlag(rawAttribute[offsetResidentFlag]).Parse()
+	attributeInfo.ID = binary.LittleEndian.Uint16(rawAttribute[offsetAttributeID : offsetAttributeID+0x02])
+	attributeInfo.Name = rawAttribute.name()
+	return
+}
+
+// Returns the 32 bit type code of the raw attribute receiver, or 0 if it is too short to have one.
+func (rawAttribute rawAttribute) attributeType() uint32 {
+	if len(rawAttribute) < 0x04 {
+		return 0
+	}
+	return binary.LittleEndian.Uint32(rawAttribute[0x00:0x04])
+}
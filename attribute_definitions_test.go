@@ -0,0 +1,272 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds a raw $AttrDef table out of the provided attribute definitions. The table ends with a zeroed out entry.
+func buildTestAttributeDefinitions(attributeDefinitions ...AttributeDefinition) []byte {
+	rawAttributeDefinitions := make([]byte, (len(attributeDefinitions)+1)*lengthAttributeDefinition)
+	for index, attributeDefinition := range attributeDefinitions {
+		rawEntry := rawAttributeDefinitions[index*lengthAttributeDefinition:]
+		copy(rawEntry, buildTestUnicode(attributeDefinition.Name))
+		binary.LittleEndian.PutUint32(rawEntry[0x80:], attributeDefinition.Type)
+		binary.LittleEndian.PutUint32(rawEntry[0x84:], attributeDefinition.DisplayRule)
+		binary.LittleEndian.PutUint32(rawEntry[0x88:], attributeDefinition.CollationRule)
+		binary.LittleEndian.PutUint32(rawEntry[0x8C:], uint32(attributeDefinition.Flags))
+		binary.LittleEndian.PutUint64(rawEntry[0x90:], uint64(attributeDefinition.MinimumSize))
+		binary.LittleEndian.PutUint64(rawEntry[0x98:], uint64(attributeDefinition.MaximumSize))
+	}
+	return rawAttributeDefinitions
+}
+
+var testCustomAttributeDefinition = AttributeDefinition{Name: "$CUSTOM", Type: 0x1000, Flags: AttributeDefinitionFlagResident, MaximumSize: 0x100}
+
+func TestRawAttributeDefinitions_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     RawAttributeDefinitions
+		want    AttributeDefinitions
+		wantErr bool
+	}{
+		{
+			name: "default and custom types",
+			raw:  buildTestAttributeDefinitions(DefaultAttributeDefinitions[0x10], DefaultAttributeDefinitions[0x30], testCustomAttributeDefinition),
+			want: AttributeDefinitions{
+				0x10:   DefaultAttributeDefinitions[0x10],
+				0x30:   DefaultAttributeDefinitions[0x30],
+				0x1000: testCustomAttributeDefinition,
+			},
+		},
+		{
+			name: "entries after the end of the table are ignored",
+			raw:  append(buildTestAttributeDefinitions(DefaultAttributeDefinitions[0x80]), buildTestAttributeDefinitions(testCustomAttributeDefinition)...),
+			want: AttributeDefinitions{0x80: DefaultAttributeDefinitions[0x80]},
+		},
+		{
+			name:    "empty table",
+			raw:     buildTestAttributeDefinitions(),
+			want:    AttributeDefinitions{},
+			wantErr: true,
+		},
+		{
+			name:    "too small",
+			raw:     make([]byte, lengthAttributeDefinition-1),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestBuildAttributeDefinitions(t *testing.T) {
+	got, err := BuildAttributeDefinitions(bytes.NewReader(buildTestAttributeDefinitions(testCustomAttributeDefinition)))
+	if err != nil {
+		t.Fatalf("BuildAttributeDefinitions() error = %v", err)
+	}
+	if want := (AttributeDefinitions{0x1000: testCustomAttributeDefinition}); !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
+
+func TestReadAttributeDefinitions(t *testing.T) {
+	rawMft, err := ioutil.ReadFile(filepath.FromSlash("./test/testdata/mft-lite"))
+	if err != nil {
+		t.Fatalf("failed to read test mft: %v", err)
+	}
+
+	// The mft starts at cluster 2 of the test volume and the $AttrDef data of the test mft is at cluster 0x23.
+	rawAttributeDefinitions := buildTestAttributeDefinitions(DefaultAttributeDefinitions[0x10], testCustomAttributeDefinition)
+	volume := make([]byte, 0x24*4096)
+	copy(volume, buildTestBootSector(2, 0x1234567890abcdef))
+	copy(volume[8192:], rawMft)
+	copy(volume[0x23*4096:], rawAttributeDefinitions)
+
+	tests := []struct {
+		name    string
+		volume  []byte
+		want    AttributeDefinitions
+		wantErr bool
+	}{
+		{
+			name:   "volume image",
+			volume: volume,
+			want:   AttributeDefinitions{0x10: DefaultAttributeDefinitions[0x10], 0x1000: testCustomAttributeDefinition},
+		},
+		{
+			name:    "volume without a boot sector",
+			volume:  rawMft,
+			wantErr: true,
+		},
+		{
+			name:    "volume without an $AttrDef record",
+			volume:  volume[:8192+4*1024],
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadAttributeDefinitions(bytes.NewReader(tt.volume))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadAttributeDefinitions() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestAttributeDefinitions_TypeName(t *testing.T) {
+	tests := []struct {
+		name                 string
+		attributeDefinitions AttributeDefinitions
+		attributeType        uint32
+		want                 string
+		wantDefined          bool
+	}{
+		{
+			name:          "nil falls back to the defaults",
+			attributeType: 0x100,
+			want:          "$LOGGED_UTILITY_STREAM",
+			wantDefined:   true,
+		},
+		{
+			name:                 "volume defined type",
+			attributeDefinitions: AttributeDefinitions{0x1000: testCustomAttributeDefinition},
+			attributeType:        0x1000,
+			want:                 "$CUSTOM",
+			wantDefined:          true,
+		},
+		{
+			name:                 "type missing from the volume's $AttrDef",
+			attributeDefinitions: AttributeDefinitions{0x1000: testCustomAttributeDefinition},
+			attributeType:        0x10,
+			want:                 "0x10",
+		},
+		{
+			name:          "unknown type",
+			attributeType: 0x2000,
+			want:          "0x2000",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.attributeDefinitions.TypeName(tt.attributeType); got != tt.want {
+				t.Errorf("TypeName() = %v, want %v", got, tt.want)
+			}
+			if got := tt.attributeDefinitions.IsDefined(tt.attributeType); got != tt.wantDefined {
+				t.Errorf("IsDefined() = %v, want %v", got, tt.wantDefined)
+			}
+		})
+	}
+}
+
+func TestDescribeAttributes(t *testing.T) {
+	attributes := []AttributeInfo{
+		{Type: 0x10, Resident: true},
+		{Type: 0x80, Name: "Zone.Identifier", Resident: true},
+		{Type: 0x1000, Name: "payload"},
+	}
+	tests := []struct {
+		name                 string
+		attributeDefinitions AttributeDefinitions
+		want                 string
+	}{
+		{
+			name: "default attribute definitions",
+			want: "$STANDARD_INFORMATION;$DATA:Zone.Identifier;0x1000:payload",
+		},
+		{
+			name:                 "volume attribute definitions",
+			attributeDefinitions: AttributeDefinitions{0x10: DefaultAttributeDefinitions[0x10], 0x80: DefaultAttributeDefinitions[0x80], 0x1000: testCustomAttributeDefinition},
+			want:                 "$STANDARD_INFORMATION;$DATA:Zone.Identifier;$CUSTOM:payload",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := DescribeAttributes(attributes, tt.attributeDefinitions); got != tt.want {
+				t.Errorf("DescribeAttributes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawMasterFileTableRecord_Parse_unknownAttribute(t *testing.T) {
+	// An attribute type that isn't in the default $AttrDef sits between two attributes that are.
+	rawUnknownAttribute := buildTestResidentAttribute(0x00, []byte{0x01, 0x02, 0x03, 0x04})
+	binary.LittleEndian.PutUint32(rawUnknownAttribute[0x00:], 0x1000)
+	rawMftRecord := buildTestMftRecord(70, 0, buildTestFileNameAttribute("hidden.txt", 5), rawUnknownAttribute, buildTestResidentAttribute(0x80, []byte("data")))
+
+	mftRecord, err := rawMftRecord.Parse(4096)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if got, want := DescribeAttributes(mftRecord.Attributes, nil), "$FILE_NAME;0x1000;$DATA"; got != want {
+		t.Errorf("DescribeAttributes() = %v, want %v", got, want)
+	}
+	if got, want := string(mftRecord.DataAttribute.ResidentDataAttribute), "data"; got != want {
+		t.Errorf("resident data = %v, want %v", got, want)
+	}
+}
+
+func TestRawMasterFileTableRecord_GetRawAttributesWithDefinitions(t *testing.T) {
+	rawUnknownAttribute := buildTestResidentAttribute(0x00, []byte{0x01, 0x02, 0x03, 0x04})
+	binary.LittleEndian.PutUint32(rawUnknownAttribute[0x00:], 0x1000)
+	rawMftRecord := buildTestMftRecord(70, 0, buildTestFileNameAttribute("hidden.txt", 5), rawUnknownAttribute, buildTestResidentAttribute(0x80, []byte("data")))
+	tests := []struct {
+		name                 string
+		attributeDefinitions AttributeDefinitions
+		want                 []uint32
+	}{
+		{
+			name: "no attribute definitions",
+			want: []uint32{0x30, 0x1000, 0x80},
+		},
+		{
+			name:                 "type defined by $AttrDef",
+			attributeDefinitions: AttributeDefinitions{0x30: DefaultAttributeDefinitions[0x30], 0x80: DefaultAttributeDefinitions[0x80], 0x1000: testCustomAttributeDefinition},
+			want:                 []uint32{0x30, 0x1000, 0x80},
+		},
+		{
+			name:                 "type missing from $AttrDef",
+			attributeDefinitions: DefaultAttributeDefinitions,
+			want:                 []uint32{0x30},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawAttributes, err := rawMftRecord.GetRawAttributesWithDefinitions(RecordHeader{AttributesOffset: 0x38}, tt.attributeDefinitions)
+			if err != nil {
+				t.Fatalf("GetRawAttributesWithDefinitions() error = %v", err)
+			}
+			var got []uint32
+			for _, rawAttribute := range rawAttributes {
+				got = append(got, rawAttribute.attributeType())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetRawAttributesWithDefinitions() got attribute types %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
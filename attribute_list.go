@@ -3,9 +3,10 @@
 package mft
 
 import (
-	"errors"
+	"encoding/binary"
 	"fmt"
 	bin "github.com/AlecRandazzo/BinaryTransforms"
+	"io"
 )
 
 // RawAttributeListAttribute is a []byte alias for raw attribute list attributes. Used with the Parse() method
@@ -13,8 +14,12 @@ type RawAttributeListAttribute []byte
 
 // AttributeListAttribute contains information about a attribute list attribute
 type AttributeListAttribute struct {
-	Type                     byte
-	MFTReferenceRecordNumber uint32
+	Type                       uint32
+	Name                       string
+	StartingVCN                uint64
+	MFTReferenceRecordNumber   uint64
+	MFTReferenceSequenceNumber uint16
+	AttributeID                uint16
 }
 
 // AttributeListAttributes is a slice of AttributeListAttribute
@@ -22,20 +27,29 @@ type AttributeListAttributes []AttributeListAttribute
 
 // Parse a raw attribute list attribute
 func (rawAttributeListAttribute RawAttributeListAttribute) Parse() (attributeListAttributes AttributeListAttributes, err error) {
+	attributeListAttributes, err = rawAttributeListAttribute.parse(nil)
+	return
+}
+
+// Parses the raw attribute list attribute receiver, ending the entries at the first one whose type isn't in the attribute definitions.
+func (rawAttributeListAttribute RawAttributeListAttribute) parse(attributeDefinitions AttributeDefinitions) (attributeListAttributes AttributeListAttributes, err error) {
 	const offsetAttributeType = 0x00
 
 	const offsetRecordLength = 0x04
 	const lengthRecordLength = 0x02
 
-	const offsetFirstSubAttribute = 0x18
+	const offsetContentLength = 0x10
+	const lengthContentLength = 0x04
 
-	const offsetMFTReferenceRecordNumber = 0x10
-	const lengthMFTReferenceRecordNumber = 0x04
+	const offsetContentOffset = 0x14
+	const lengthContentOffset = 0x02
+
+	const offsetFirstSubAttribute = 0x18
 
 	// Sanity checking
 	sizeOfRawAttribute := len(rawAttributeListAttribute)
 	if sizeOfRawAttribute == 0 {
-		err = errors.New("RawAttributeListAttribute.Parse() received nil bytes")
+		err = fmt.Errorf("RawAttributeListAttribute.Parse(): %w", ErrNilBytes)
 		attributeListAttributes = AttributeListAttributes{}
 		return
 	} else if rawAttributeListAttribute[offsetAttributeType] != 0x20 {
@@ -49,18 +63,129 @@ func (rawAttributeListAttribute RawAttributeListAttribute) Parse() (attributeLis
 		err = fmt.Errorf("RawAttributeListAttribute.Parse() received a byte slice thats not equal to the expected attribute length. Size received was %d but expected %d", sizeOfRawAttribute, recordLength)
 		attributeListAttributes = AttributeListAttributes{}
 		return
+	} else if sizeOfRawAttribute < offsetContentOffset+lengthContentOffset {
+		err = fmt.Errorf("RawAttributeListAttribute.Parse() expected at least %d bytes, instead received %d: %w", offsetContentOffset+lengthContentOffset, sizeOfRawAttribute, ErrTruncated)
+		attributeListAttributes = AttributeListAttributes{}
+		return
+	}
+
+	// Use the content offset and length from the resident attribute header, falling back to the rest of the attribute if they don't make sense.
+	contentLength, _ := bin.LittleEndianBinaryToUInt32(rawAttributeListAttribute[offsetContentLength : offsetContentLength+lengthContentLength])
+	contentOffset, _ := bin.LittleEndianBinaryToUInt16(rawAttributeListAttribute[offsetContentOffset : offsetContentOffset+lengthContentOffset])
+	if contentOffset == 0 || int(contentOffset) > sizeOfRawAttribute {
+		contentOffset = offsetFirstSubAttribute
+	}
+	contentEnd := sizeOfRawAttribute
+	if contentLength != 0 && int(contentOffset)+int(contentLength) < contentEnd {
+		contentEnd = int(contentOffset) + int(contentLength)
+	}
+
+	attributeListAttributes, err = parseAttributeListEntries(rawAttributeListAttribute[contentOffset:contentEnd], attributeDefinitions)
+	if err != nil {
+		err = fmt.Errorf("RawAttributeListAttribute.Parse() failed to parse the attribute list entries: %w", err)
+		attributeListAttributes = AttributeListAttributes{}
+		return
+	}
+	return
+}
+
+// ParseNonResident parses a non-resident raw attribute list attribute. The attribute list entries live outside of the MFT record so they are read from the volume using the attribute's data runs.
+func (rawAttributeListAttribute RawAttributeListAttribute) ParseNonResident(volume io.ReaderAt, bytesPerCluster int64) (attributeListAttributes AttributeListAttributes, err error) {
+	const offsetAttributeType = 0x00
+
+	// Sanity checking
+	if len(rawAttributeListAttribute) == 0 {
+		err = fmt.Errorf("RawAttributeListAttribute.ParseNonResident(): %w", ErrNilBytes)
+		return
+	} else if rawAttributeListAttribute[offsetAttributeType] != 0x20 {
+		err = fmt.Errorf("RawAttributeListAttribute.ParseNonResident() receive an attribute thats not an attribute list. Attribute magic number is %x", rawAttributeListAttribute[offsetAttributeType])
+		return
+	}
+
+	rawEntries, err := readNonResidentAttribute(volume, rawAttributeListAttribute, bytesPerCluster)
+	if err != nil {
+		err = fmt.Errorf("failed to read the attribute list from the volume: %w", err)
+		return
+	}
+
+	attributeListAttributes, err = parseAttributeListEntries(rawEntries, nil)
+	if err != nil {
+		err = fmt.Errorf("RawAttributeListAttribute.ParseNonResident() failed to parse the attribute list entries: %w", err)
+		attributeListAttributes = nil
+		return
 	}
+	return
+}
+
+// Walks a byte slice of attribute list entries and returns the parsed entries. Parsing stops at the first entry that does not look like an attribute, see isThisAnAttribute() for how the attribute definitions are used. An entry that claims to be shorter than the fixed part of an entry is an error.
+// See here for the layout of an entry: https://flatcap.org/linux-ntfs/ntfs/attributes/attribute_list.html
+func parseAttributeListEntries(rawEntries []byte, attributeDefinitions AttributeDefinitions) (attributeListAttributes AttributeListAttributes, err error) {
+	const offsetRecordLength = 0x04
+	const lengthRecordLength = 0x02
+
+	const offsetNameLength = 0x06
+	const offsetNameOffset = 0x07
+
+	const offsetStartingVCN = 0x08
+	const lengthStartingVCN = 0x08
+
+	const offsetMFTReferenceRecordNumber = 0x10
+	const lengthMFTReference = 0x08
+
+	const offsetMFTReferenceSequenceNumber = 0x16
+	const lengthMFTReferenceSequenceNumber = 0x02
 
-	pointerToSubAttribute := offsetFirstSubAttribute
-	for pointerToSubAttribute < sizeOfRawAttribute {
-		result := isThisAnAttribute(rawAttributeListAttribute[pointerToSubAttribute])
-		if result == false {
+	const offsetAttributeID = 0x18
+	const lengthAttributeID = 0x02
+
+	sizeOfRawEntries := len(rawEntries)
+	pointerToSubAttribute := 0
+	for pointerToSubAttribute+offsetAttributeID+lengthAttributeID <= sizeOfRawEntries {
+		rawEntry := rawEntries[pointerToSubAttribute:]
+		attributeType := binary.LittleEndian.Uint32(rawEntry[0x00:0x04])
+		if isThisAnAttribute(attributeType, attributeDefinitions) == false {
+			return
+		}
+		sizeOfSubAttribute, _ := bin.LittleEndianBinaryToUInt16(rawEntry[offsetRecordLength : offsetRecordLength+lengthRecordLength])
+		if sizeOfSubAttribute == 0 || int(sizeOfSubAttribute) > len(rawEntry) {
+			return
+		} else if int(sizeOfSubAttribute) < offsetAttributeID+lengthAttributeID {
+			err = fmt.Errorf("entry at offset %d has a length of %d which is too short to be an attribute list entry: %w", pointerToSubAttribute, sizeOfSubAttribute, ErrTruncated)
 			return
 		}
+		rawEntry = rawEntry[:sizeOfSubAttribute]
+
 		attributeListAttribute := AttributeListAttribute{}
-		attributeListAttribute.Type = rawAttributeListAttribute[pointerToSubAttribute]
-		sizeOfSubAttribute, _ := bin.LittleEndianBinaryToUInt16(rawAttributeListAttribute[pointerToSubAttribute+offsetRecordLength : pointerToSubAttribute+offsetRecordLength+lengthRecordLength])
-		attributeListAttribute.MFTReferenceRecordNumber, _ = bin.LittleEndianBinaryToUInt32(rawAttributeListAttribute[pointerToSubAttribute+offsetMFTReferenceRecordNumber : pointerToSubAttribute+offsetMFTReferenceRecordNumber+lengthMFTReferenceRecordNumber])
+		attributeListAttribute.Type = attributeType
+		attributeListAttribute.StartingVCN, err = bin.LittleEndianBinaryToUInt64(rawEntry[offsetStartingVCN : offsetStartingVCN+lengthStartingVCN])
+		if err != nil {
+			err = fmt.Errorf("failed to get the starting vcn of the entry at offset %d: %w", pointerToSubAttribute, err)
+			return
+		}
+		// The record number of an MFT reference is 48 bits, the upper 16 bits are the sequence number.
+		attributeListAttribute.MFTReferenceRecordNumber = binary.LittleEndian.Uint64(rawEntry[offsetMFTReferenceRecordNumber:offsetMFTReferenceRecordNumber+lengthMFTReference]) & 0x0000ffffffffffff
+		attributeListAttribute.MFTReferenceSequenceNumber, err = bin.LittleEndianBinaryToUInt16(rawEntry[offsetMFTReferenceSequenceNumber : offsetMFTReferenceSequenceNumber+lengthMFTReferenceSequenceNumber])
+		if err != nil {
+			err = fmt.Errorf("failed to get the mft reference sequence number of the entry at offset %d: %w", pointerToSubAttribute, err)
+			return
+		}
+		attributeListAttribute.AttributeID, err = bin.LittleEndianBinaryToUInt16(rawEntry[offsetAttributeID : offsetAttributeID+lengthAttributeID])
+		if err != nil {
+			err = fmt.Errorf("failed to get the attribute id of the entry at offset %d: %w", pointerToSubAttribute, err)
+			return
+		}
+
+		// Names are stored as utf16 so the name length is doubled to get the byte count.
+		nameLength := int(rawEntry[offsetNameLength]) * 2
+		nameOffset := int(rawEntry[offsetNameOffset])
+		if nameLength != 0 && nameOffset+nameLength <= len(rawEntry) {
+			attributeListAttribute.Name, err = bin.UnicodeBytesToASCII(rawEntry[nameOffset : nameOffset+nameLength])
+			if err != nil {
+				err = fmt.Errorf("failed to decode the name of the entry at offset %d: %w", pointerToSubAttribute, err)
+				return
+			}
+		}
+
 		attributeListAttributes = append(attributeListAttributes, attributeListAttribute)
 		pointerToSubAttribute += int(sizeOfSubAttribute)
 	}
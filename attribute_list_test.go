@@ -3,6 +3,7 @@
 package mft
 
 import (
+	"bytes"
 	"reflect"
 	"testing"
 )
@@ -20,20 +21,26 @@ func TestRawAttributeListAttribute_Parse(t *testing.T) {
 			wantErr:                   false,
 			wantAttributeListAttributes: AttributeListAttributes{
 				AttributeListAttribute{
-					Type:                     0x10,
-					MFTReferenceRecordNumber: 493401,
+					Type:                       0x10,
+					MFTReferenceRecordNumber:   493401,
+					MFTReferenceSequenceNumber: 8,
 				},
 				AttributeListAttribute{
-					Type:                     0x30,
-					MFTReferenceRecordNumber: 493401,
+					Type:                       0x30,
+					MFTReferenceRecordNumber:   493401,
+					MFTReferenceSequenceNumber: 8,
+					AttributeID:                5,
 				},
 				AttributeListAttribute{
-					Type:                     0x80,
-					MFTReferenceRecordNumber: 1423172,
+					Type:                       0x80,
+					MFTReferenceRecordNumber:   1423172,
+					MFTReferenceSequenceNumber: 3,
 				},
 				AttributeListAttribute{
-					Type:                     0x80,
-					MFTReferenceRecordNumber: 1423173,
+					Type:                       0x80,
+					StartingVCN:                10240,
+					MFTReferenceRecordNumber:   1423173,
+					MFTReferenceSequenceNumber: 3,
 				},
 			},
 		},
@@ -61,16 +68,20 @@ func TestRawAttributeListAttribute_Parse(t *testing.T) {
 			wantErr:                   false,
 			wantAttributeListAttributes: AttributeListAttributes{
 				AttributeListAttribute{
-					Type:                     0x10,
-					MFTReferenceRecordNumber: 493401,
+					Type:                       0x10,
+					MFTReferenceRecordNumber:   493401,
+					MFTReferenceSequenceNumber: 8,
 				},
 				AttributeListAttribute{
-					Type:                     0x30,
-					MFTReferenceRecordNumber: 493401,
+					Type:                       0x30,
+					MFTReferenceRecordNumber:   493401,
+					MFTReferenceSequenceNumber: 8,
+					AttributeID:                5,
 				},
 				AttributeListAttribute{
-					Type:                     0x80,
-					MFTReferenceRecordNumber: 1423172,
+					Type:                       0x80,
+					MFTReferenceRecordNumber:   1423172,
+					MFTReferenceSequenceNumber: 3,
 				},
 			},
 		},
@@ -88,3 +99,124 @@ func TestRawAttributeListAttribute_Parse(t *testing.T) {
 		})
 	}
 }
+
+func Test_parseAttributeListEntries(t *testing.T) {
+	tests := []struct {
+		name                        string
+		rawEntries                  []byte
+		attributeDefinitions        AttributeDefinitions
+		wantAttributeListAttributes AttributeListAttributes
+		wantErr                     bool
+	}{
+		{
+			name: "named entry with a 48 bit record reference",
+			rawEntries: buildTestAttributeListEntries(AttributeListAttribute{
+				Type:                       0x80,
+				Name:                       "Zone.Identifier",
+				StartingVCN:                16,
+				MFTReferenceRecordNumber:   0x0001000000a0,
+				MFTReferenceSequenceNumber: 2,
+				AttributeID:                7,
+			}),
+			wantAttributeListAttributes: AttributeListAttributes{
+				AttributeListAttribute{
+					Type:                       0x80,
+					Name:                       "Zone.Identifier",
+					StartingVCN:                16,
+					MFTReferenceRecordNumber:   0x0001000000a0,
+					MFTReferenceSequenceNumber: 2,
+					AttributeID:                7,
+				},
+			},
+		},
+		{
+			name:                        "entry type missing from the attribute definitions",
+			rawEntries:                  buildTestAttributeListEntries(AttributeListAttribute{Type: 0x30, MFTReferenceRecordNumber: 40}, AttributeListAttribute{Type: 0x1000, MFTReferenceRecordNumber: 41}),
+			attributeDefinitions:        AttributeDefinitions{0x30: DefaultAttributeDefinitions[0x30]},
+			wantAttributeListAttributes: AttributeListAttributes{{Type: 0x30, MFTReferenceRecordNumber: 40}},
+		},
+		{
+			name:                        "zero length entry",
+			rawEntries:                  make([]byte, 0x20),
+			wantAttributeListAttributes: nil,
+		},
+		{
+			name:                        "short entry",
+			rawEntries:                  []byte{0x80, 0x00, 0x00, 0x00, 0x08, 0x00, 0x00, 0x1a, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			wantAttributeListAttributes: nil,
+			wantErr:                     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAttributeListAttributes, err := parseAttributeListEntries(tt.rawEntries, tt.attributeDefinitions)
+			if !reflect.DeepEqual(gotAttributeListAttributes, tt.wantAttributeListAttributes) || (err != nil) != tt.wantErr {
+				t.Errorf("parseAttributeListEntries() gotAttributeListAttributes = %v, want %v", gotAttributeListAttributes, tt.wantAttributeListAttributes)
+			}
+		})
+	}
+}
+
+func TestRawAttributeListAttribute_ParseNonResident(t *testing.T) {
+	rawEntries := buildTestAttributeListEntries(
+		AttributeListAttribute{Type: 0x10, MFTReferenceRecordNumber: 40},
+		AttributeListAttribute{Type: 0x80, MFTReferenceRecordNumber: 41, MFTReferenceSequenceNumber: 3},
+	)
+	volume := make([]byte, 8192)
+	copy(volume[4096:], rawEntries)
+
+	tests := []struct {
+		name                        string
+		rawAttributeListAttribute   RawAttributeListAttribute
+		volume                      *bytes.Reader
+		wantAttributeListAttributes AttributeListAttributes
+		wantErr                     bool
+	}{
+		{
+			name:                      "non-resident attribute list",
+			rawAttributeListAttribute: buildTestNonResidentAttribute(0x20, uint64(len(rawEntries)), []byte{0x11, 0x01, 0x01}),
+			volume:                    bytes.NewReader(volume),
+			wantAttributeListAttributes: AttributeListAttributes{
+				AttributeListAttribute{Type: 0x10, MFTReferenceRecordNumber: 40},
+				AttributeListAttribute{Type: 0x80, MFTReferenceRecordNumber: 41, MFTReferenceSequenceNumber: 3},
+			},
+			wantErr: false,
+		},
+		{
+			name:                      "resident attribute list",
+			rawAttributeListAttribute: buildTestAttributeListAttribute(rawEntries),
+			volume:                    bytes.NewReader(volume),
+			wantErr:                   true,
+		},
+		{
+			name:                      "not an attribute list",
+			rawAttributeListAttribute: buildTestNonResidentAttribute(0x80, uint64(len(rawEntries)), []byte{0x11, 0x01, 0x01}),
+			volume:                    bytes.NewReader(volume),
+			wantErr:                   true,
+		},
+		{
+			name:                      "data runs beyond the volume",
+			rawAttributeListAttribute: buildTestNonResidentAttribute(0x20, uint64(len(rawEntries)), []byte{0x11, 0x01, 0x08}),
+			volume:                    bytes.NewReader(volume),
+			wantErr:                   true,
+		},
+		{
+			name:                      "nil bytes",
+			rawAttributeListAttribute: nil,
+			volume:                    bytes.NewReader(volume),
+			wantErr:                   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotAttributeListAttributes, err := tt.rawAttributeListAttribute.ParseNonResident(tt.volume, 4096)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseNonResident() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotAttributeListAttributes, tt.wantAttributeListAttributes) {
+				t.Errorf("ParseNonResident() gotAttributeListAttributes = %v, want %v", gotAttributeListAttributes, tt.wantAttributeListAttributes)
+			}
+		})
+	}
+}
@@ -0,0 +1,117 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// AttributeParser parses a raw attribute of the type it was registered for. The raw attribute includes the attribute header and is a copy, so the parser is free to keep it. The bytes per cluster is the same value that was passed to RawMasterFileTableRecord.Parse().
+type AttributeParser func(rawAttribute []byte, bytesPerCluster int64) (value interface{}, err error)
+
+// AttributeParsers is a registry of attribute parsers keyed by attribute type. It is safe to use from multiple goroutines.
+type AttributeParsers struct {
+	mutex   sync.RWMutex
+	parsers map[uint32]AttributeParser
+}
+
+// ParsedAttribute contains the result of a registered attribute parser along with the header fields of the attribute it parsed.
+type ParsedAttribute struct {
+	AttributeInfo
+	Value interface{}
+	Err   error
+}
+
+// ParsedAttributes maps attribute types to the results of their registered parser. A record has one entry per attribute of that type.
+type ParsedAttributes map[uint32][]ParsedAttribute
+
+// DefaultAttributeParsers is the registry used by RawMasterFileTableRecord.Parse(), and by ParseMftRecordsWithOptions() when ParseOptions.AttributeParsers is nil.
+var DefaultAttributeParsers = &AttributeParsers{}
+
+// RegisterAttributeParser registers a parser for the attribute type with DefaultAttributeParsers. Registering a parser for a type this package already parses adds to the built-in parsing rather than replacing it.
+func RegisterAttributeParser(attributeType uint32, parser AttributeParser) (err error) {
+	err = DefaultAttributeParsers.Register(attributeType, parser)
+	return
+}
+
+// UnregisterAttributeParser removes the parser for the attribute type from DefaultAttributeParsers.
+func UnregisterAttributeParser(attributeType uint32) {
+	DefaultAttributeParsers.Unregister(attributeType)
+	return
+}
+
+// Register registers a parser for the attribute type, replacing any parser already registered for it.
+func (attributeParsers *AttributeParsers) Register(attributeType uint32, parser AttributeParser) (err error) {
+	// Sanity checks
+	if parser == nil {
+		err = errors.New("AttributeParsers.Register() received a nil parser")
+		return
+	} else if isThisAnAttribute(attributeType, nil) == false {
+		err = fmt.Errorf("AttributeParsers.Register() received 0x%X which is not a valid attribute type", attributeType)
+		return
+	}
+
+	attributeParsers.mutex.Lock()
+	defer attributeParsers.mutex.Unlock()
+	if attributeParsers.parsers == nil {
+		attributeParsers.parsers = make(map[uint32]AttributeParser)
+	}
+	attributeParsers.parsers[attributeType] = parser
+	return
+}
+
+// Unregister removes the parser for the attribute type.
+func (attributeParsers *AttributeParsers) Unregister(attributeType uint32) {
+	attributeParsers.mutex.Lock()
+	defer attributeParsers.mutex.Unlock()
+	delete(attributeParsers.parsers, attributeType)
+	return
+}
+
+// Parse runs the registered parsers over the raw attributes. Attributes without a registered parser are skipped. A parser error is kept with its result rather than failing the rest of the attributes.
+func (attributeParsers *AttributeParsers) Parse(rawAttributes RawAttributes, bytesPerCluster int64) (parsedAttributes ParsedAttributes) {
+	attributeParsers.mutex.RLock()
+	defer attributeParsers.mutex.RUnlock()
+	if len(attributeParsers.parsers) == 0 {
+		return
+	}
+
+	for _, rawAttribute := range rawAttributes {
+		parser, ok := attributeParsers.parsers[rawAttribute.attributeType()]
+		if !ok {
+			continue
+		}
+		parsedAttribute := ParsedAttribute{AttributeInfo: rawAttribute.info()}
+		rawAttributeCopy := make([]byte, len(rawAttribute))
+		copy(rawAttributeCopy, rawAttribute)
+		parsedAttribute.Value, parsedAttribute.Err = parser(rawAttributeCopy, bytesPerCluster)
+		if parsedAttributes == nil {
+			parsedAttributes = make(ParsedAttributes)
+		}
+		parsedAttributes[parsedAttribute.Type] = append(parsedAttributes[parsedAttribute.Type], parsedAttribute)
+	}
+	return
+}
+
+// Values returns the values of every successfully parsed attribute of the attribute type.
+func (parsedAttributes ParsedAttributes) Values(attributeType uint32) (values []interface{}) {
+	for _, parsedAttribute := range parsedAttributes[attributeType] {
+		if parsedAttribute.Err == nil {
+			values = append(values, parsedAttribute.Value)
+		}
+	}
+	return
+}
+
+// Merges the parsed attributes of an extension record into the parsed attributes receiver.
+func (parsedAttributes *ParsedAttributes) merge(extensionParsedAttributes ParsedAttributes) {
+	for attributeType, extensionParsedAttribute := range extensionParsedAttributes {
+		if *parsedAttributes == nil {
+			*parsedAttributes = make(ParsedAttributes)
+		}
+		(*parsedAttributes)[attributeType] = append((*parsedAttributes)[attributeType], extensionParsedAttribute...)
+	}
+	return
+}
@@ -0,0 +1,206 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Returns the content length of a resident attribute.
+func testContentLengthParser(rawAttribute []byte, bytesPerCluster int64) (value interface{}, err error) {
+	if len(rawAttribute) < 0x14 {
+		err = errors.New("too short")
+		return
+	}
+	value = binary.LittleEndian.Uint32(rawAttribute[0x10:0x14])
+	return
+}
+
+// Builds a resident attribute with a 32 bit attribute type.
+func buildTestCustomAttribute(attributeType uint32, content []byte) []byte {
+	rawAttribute := buildTestResidentAttribute(0x00, content)
+	binary.LittleEndian.PutUint32(rawAttribute[0x00:], attributeType)
+	return rawAttribute
+}
+
+func TestAttributeParsers_Register(t *testing.T) {
+	tests := []struct {
+		name          string
+		attributeType uint32
+		parser        AttributeParser
+		wantErr       bool
+	}{
+		{
+			name:          "type missing from $AttrDef",
+			attributeType: 0x1000,
+			parser:        testContentLengthParser,
+		},
+		{
+			name:          "built-in type",
+			attributeType: 0x80,
+			parser:        testContentLengthParser,
+		},
+		{
+			name:          "nil parser",
+			attributeType: 0x1000,
+			wantErr:       true,
+		},
+		{
+			name:          "end marker",
+			attributeType: 0xFFFFFFFF,
+			parser:        testContentLengthParser,
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attributeParsers AttributeParsers
+			if err := attributeParsers.Register(tt.attributeType, tt.parser); (err != nil) != tt.wantErr {
+				t.Errorf("Register() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAttributeParsers_Parse(t *testing.T) {
+	parserErr := errors.New("bad attribute")
+	failingParser := func(rawAttribute []byte, bytesPerCluster int64) (interface{}, error) { return nil, parserErr }
+	rawAttributes := RawAttributes{
+		buildTestResidentAttribute(0x10, make([]byte, 0x48)),
+		buildTestCustomAttribute(0x1000, []byte{0x01, 0x02}),
+		buildTestCustomAttribute(0x1000, []byte{0x01, 0x02, 0x03}),
+		buildTestResidentAttribute(0x80, []byte("data")),
+	}
+
+	tests := []struct {
+		name    string
+		parsers map[uint32]AttributeParser
+		want    ParsedAttributes
+	}{
+		{
+			name:    "every attribute of a registered type",
+			parsers: map[uint32]AttributeParser{0x1000: testContentLengthParser},
+			want: ParsedAttributes{0x1000: {
+				{AttributeInfo: AttributeInfo{Type: 0x1000, Resident: true, Size: 0x1A}, Value: uint32(2)},
+				{AttributeInfo: AttributeInfo{Type: 0x1000, Resident: true, Size: 0x1B}, Value: uint32(3)},
+			}},
+		},
+		{
+			name:    "parser errors are kept",
+			parsers: map[uint32]AttributeParser{0x80: failingParser},
+			want: ParsedAttributes{0x80: {
+				{AttributeInfo: AttributeInfo{Type: 0x80, Resident: true, Size: 0x1C}, Err: parserErr},
+			}},
+		},
+		{
+			name: "no registered parsers",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var attributeParsers AttributeParsers
+			for attributeType, parser := range tt.parsers {
+				_ = attributeParsers.Register(attributeType, parser)
+			}
+			got := attributeParsers.Parse(rawAttributes, 4096)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsedAttributes_Values(t *testing.T) {
+	parsedAttributes := ParsedAttributes{0x1000: {
+		{Value: uint32(2)},
+		{Err: errors.New("bad attribute")},
+		{Value: uint32(3)},
+	}}
+	tests := []struct {
+		name          string
+		attributeType uint32
+		want          []interface{}
+	}{
+		{
+			name:          "failed results are skipped",
+			attributeType: 0x1000,
+			want:          []interface{}{uint32(2), uint32(3)},
+		},
+		{
+			name:          "type without results",
+			attributeType: 0x80,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parsedAttributes.Values(tt.attributeType); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Values() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsedAttributes_merge(t *testing.T) {
+	var got ParsedAttributes
+	got.merge(ParsedAttributes{0x1000: {{Value: 1}}})
+	got.merge(ParsedAttributes{0x1000: {{Value: 2}}, 0x2000: {{Value: 3}}})
+	want := ParsedAttributes{0x1000: {{Value: 1}, {Value: 2}}, 0x2000: {{Value: 3}}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
+
+func TestRegisterAttributeParser(t *testing.T) {
+	err := RegisterAttributeParser(0x1000, testContentLengthParser)
+	if err != nil {
+		t.Fatalf("RegisterAttributeParser() error = %v", err)
+	}
+	defer UnregisterAttributeParser(0x1000)
+
+	rawMftRecord := buildTestMftRecord(70, 0, buildTestFileNameAttribute("custom.txt", 5), buildTestCustomAttribute(0x1000, []byte{0x01, 0x02}), buildTestResidentAttribute(0x80, []byte("data")))
+	mftRecord, err := rawMftRecord.Parse(4096)
+	if err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+
+	// The registered results come back alongside the built-in ones.
+	if got, want := mftRecord.ParsedAttributes.Values(0x1000), []interface{}{uint32(2)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsedAttributes.Values() = %v, want %v", got, want)
+	}
+	if got, want := string(mftRecord.DataAttribute.ResidentDataAttribute), "data"; got != want {
+		t.Errorf("resident data = %v, want %v", got, want)
+	}
+
+	UnregisterAttributeParser(0x1000)
+	mftRecord, _ = rawMftRecord.Parse(4096)
+	if mftRecord.ParsedAttributes != nil {
+		t.Errorf("ParsedAttributes = %v after unregistering, want nil", mftRecord.ParsedAttributes)
+	}
+}
+
+func TestParseOptions_AttributeParsers(t *testing.T) {
+	var attributeParsers AttributeParsers
+	if err := attributeParsers.Register(0x1000, testContentLengthParser); err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	rawMftRecord := buildTestMftRecord(70, 0, buildTestFileNameAttribute("custom.txt", 5), buildTestCustomAttribute(0x1000, []byte{0x01, 0x02}))
+
+	mftRecord, err := rawMftRecord.parse(4096, ParseOptions{AttributeParsers: &attributeParsers})
+	if err != nil {
+		t.Fatalf("parse() error = %v", err)
+	}
+	if got, want := mftRecord.ParsedAttributes.Values(0x1000), []interface{}{uint32(2)}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ParsedAttributes.Values() = %v, want %v", got, want)
+	}
+
+	// Without the options Parse() falls back to DefaultAttributeParsers, which has nothing registered for the type.
+	mftRecord, _ = rawMftRecord.Parse(4096)
+	if mftRecord.ParsedAttributes != nil {
+		t.Errorf("Parse() returned ParsedAttributes = %v, want nil", mftRecord.ParsedAttributes)
+	}
+}
@@ -6,6 +6,8 @@ import (
 	"encoding/binary"
 	"errors"
 	"fmt"
+
+	bin "github.com/AlecRandazzo/BinaryTransforms"
 )
 
 // []byte alias containing bytes of a raw MFT record attribute.
@@ -15,8 +17,31 @@ type rawAttribute []byte
 // See here for a handy list of attributes: https://flatcap.org/linux-ntfs/ntfs/attributes/index.html
 type RawAttributes []rawAttribute
 
+// RecordAttributes contains the attributes that RawAttributes.ParseLenient() parsed, along with an error for each attribute that didn't.
+type RecordAttributes struct {
+	FileNameAttributes           FileNameAttributes
+	StandardInformationAttribute StandardInformationAttribute
+	DataAttribute                DataAttribute
+	AttributeList                AttributeListAttributes
+	AttributeErrors              []*AttributeError
+}
+
 // Parse parses a slice of raw attributes and returns its filename, standard information, and dat attributes. It takes an argument for bytes per cluster (typically 4096) which is used for computing data run information in a data attributes.
+// The first attribute that fails to parse is returned as an *AttributeError and the filename, standard information, and data results are left at their zero value. See ParseLenient() to keep the attributes that did parse.
 func (rawAttributes RawAttributes) Parse(bytesPerCluster int64) (fileNameAttributes FileNameAttributes, standardInformationAttribute StandardInformationAttribute, dataAttribute DataAttribute, attributeListAttributes AttributeListAttributes, err error) {
+	recordAttributes, err := rawAttributes.parse(bytesPerCluster, ParseOptions{})
+	fileNameAttributes, standardInformationAttribute, dataAttribute, attributeListAttributes = recordAttributes.FileNameAttributes, recordAttributes.StandardInformationAttribute, recordAttributes.DataAttribute, recordAttributes.AttributeList
+	return
+}
+
+// ParseLenient works like Parse() but carries on past attributes that fail to parse. Every attribute that did parse is returned along with an error for each one that didn't. The error return is only used when the raw attributes can't be parsed at all.
+func (rawAttributes RawAttributes) ParseLenient(bytesPerCluster int64) (recordAttributes RecordAttributes, err error) {
+	recordAttributes, err = rawAttributes.parse(bytesPerCluster, ParseOptions{Lenient: true})
+	return
+}
+
+// Parses the raw attributes receiver. Lenient parsing collects the attribute errors and moves on to the next attribute, otherwise the first attribute error is returned. The attribute definitions of the options are used to validate the entries of an attribute list.
+func (rawAttributes RawAttributes) parse(bytesPerCluster int64, options ParseOptions) (recordAttributes RecordAttributes, err error) {
 	// Sanity check to make sure that the method received valid data
 	sizeOfRawAttributesSlice := len(rawAttributes)
 	if sizeOfRawAttributesSlice == 0 {
@@ -27,81 +52,141 @@ func (rawAttributes RawAttributes) Parse(bytesPerCluster int64) (fileNameAttribu
 		return
 	}
 
-	// These constants are the "magic number" aka first byte for each type of attribute.
+	// These constants are the type codes for each type of attribute.
 	const codeStandardInformation = 0x10
 	const codeattributeList = 0x20
 	const codeFileName = 0x30
 	const codeData = 0x80
 
-	// Determine what each raw attribute is and parse it accordingly.
+	// Determine what each raw attribute is and parse it accordingly. The offset of each attribute is tracked for the errors.
+	attributeOffset := 0
 	for _, rawAttribute := range rawAttributes {
+		offset := attributeOffset
+		attributeOffset += len(rawAttribute)
 
 		// Sanity check to make sure the attribute actually has bytes in it.
 		sizeOfRawAttribute := len(rawAttribute)
-		if sizeOfRawAttribute == 0 {
-			err = errors.New("came across a rawAttribute with a nil size")
-			fileNameAttributes = nil
-			standardInformationAttribute = StandardInformationAttribute{}
-			dataAttribute = DataAttribute{}
+		if sizeOfRawAttribute < 0x04 {
+			attributeError := &AttributeError{Offset: offset, Err: fmt.Errorf("came across a rawAttribute with a size of %d which is too small to have a type: %w", sizeOfRawAttribute, ErrTruncated)}
+			if options.Lenient {
+				recordAttributes.AttributeErrors = append(recordAttributes.AttributeErrors, attributeError)
+				continue
+			}
+			err = attributeError
+			recordAttributes.FileNameAttributes = nil
+			recordAttributes.StandardInformationAttribute = StandardInformationAttribute{}
+			recordAttributes.DataAttribute = DataAttribute{}
 			return
 		}
 
-		// Check the first byte to see if it is one of the "magic number" bytes we care about. If it is, we parse those raw attributes accordingly.
-		switch rawAttribute[0x00] {
+		// Check the type code to see if it is one of the attribute types we care about. If it is, we parse those raw attributes accordingly. The results of an attribute are only kept if it parses.
+		var attributeErr error
+		switch rawAttribute.attributeType() {
 		case codeFileName:
 			rawFileNameAttribute := RawFileNameAttribute(make([]byte, len(rawAttribute)))
 			copy(rawFileNameAttribute, rawAttribute)
 			var fileNameAttribute FileNameAttribute
-			fileNameAttribute, err = rawFileNameAttribute.Parse()
-			if err != nil {
-				err = fmt.Errorf("failed to get filename Attribute %w", err)
-				fileNameAttributes = nil
-				standardInformationAttribute = StandardInformationAttribute{}
-				dataAttribute = DataAttribute{}
-				return
+			fileNameAttribute, attributeErr = rawFileNameAttribute.Parse()
+			if attributeErr == nil {
+				recordAttributes.FileNameAttributes = append(recordAttributes.FileNameAttributes, fileNameAttribute)
 			}
-			fileNameAttributes = append(fileNameAttributes, fileNameAttribute)
 		case codeStandardInformation:
 			rawStandardInformationAttribute := RawStandardInformationAttribute(make([]byte, len(rawAttribute)))
 			copy(rawStandardInformationAttribute, rawAttribute)
-			standardInformationAttribute, err = rawStandardInformationAttribute.Parse()
-			if err != nil {
-				err = fmt.Errorf("failed to get standard info Attribute %w", err)
-				fileNameAttributes = nil
-				standardInformationAttribute = StandardInformationAttribute{}
-				dataAttribute = DataAttribute{}
-				return
+			var parsedStandardInformationAttribute StandardInformationAttribute
+			parsedStandardInformationAttribute, attributeErr = rawStandardInformationAttribute.Parse()
+			if attributeErr == nil {
+				recordAttributes.StandardInformationAttribute = parsedStandardInformationAttribute
 			}
 		case codeData:
 			rawDataAttribute := RawDataAttribute(make([]byte, len(rawAttribute)))
 			copy(rawDataAttribute, rawAttribute)
-			dataAttribute.NonResidentDataAttribute, dataAttribute.ResidentDataAttribute, err = rawDataAttribute.Parse(bytesPerCluster)
-			if err != nil {
-				err = fmt.Errorf("failed to get data Attribute %w", err)
-				fileNameAttributes = nil
-				standardInformationAttribute = StandardInformationAttribute{}
-				dataAttribute = DataAttribute{}
-				return
+			var parsedDataAttribute DataAttribute
+			parsedDataAttribute.NonResidentDataAttribute, parsedDataAttribute.ResidentDataAttribute, attributeErr = rawDataAttribute.Parse(bytesPerCluster)
+			if attributeErr == nil {
+				recordAttributes.DataAttribute.NonResidentDataAttribute, recordAttributes.DataAttribute.ResidentDataAttribute = parsedDataAttribute.NonResidentDataAttribute, parsedDataAttribute.ResidentDataAttribute
 			}
 		case codeattributeList:
+			// Non-resident attribute lists live outside of the MFT record. Those are read by the RecordAssembler.
+			const offsetResidentFlag = 0x08
+			if sizeOfRawAttribute > offsetResidentFlag && RawResidencyFlag(rawAttribute[offsetResidentFlag]).Parse() == false {
+				break
+			}
 			rawAttributeListAttribute := RawAttributeListAttribute(make([]byte, len(rawAttribute)))
 			copy(rawAttributeListAttribute, rawAttribute)
-			attributeListAttributes, err = rawAttributeListAttribute.Parse()
-			if err != nil {
-				err = fmt.Errorf("failed to get attribute list Attribute %w", err)
-				attributeListAttributes = AttributeListAttributes{}
-				return
+			var parsedAttributeListAttributes AttributeListAttributes
+			parsedAttributeListAttributes, attributeErr = rawAttributeListAttribute.parse(options.AttributeDefinitions)
+			if attributeErr == nil {
+				recordAttributes.AttributeList = parsedAttributeListAttributes
 			}
 		}
+		if attributeErr == nil {
+			continue
+		}
+
+		attributeError := &AttributeError{AttributeType: rawAttribute.attributeType(), Offset: offset, Err: attributeErr}
+		if options.Lenient {
+			recordAttributes.AttributeErrors = append(recordAttributes.AttributeErrors, attributeError)
+			continue
+		}
+		err = attributeError
+		if attributeError.AttributeType == codeattributeList {
+			recordAttributes.AttributeList = AttributeListAttributes{}
+			return
+		}
+		recordAttributes.FileNameAttributes = nil
+		recordAttributes.StandardInformationAttribute = StandardInformationAttribute{}
+		recordAttributes.DataAttribute = DataAttribute{}
+		return
+	}
+	return
+}
+
+// Returns the name of the raw attribute receiver. Attribute names are stored as utf16 after the attribute header.
+func (rawAttribute rawAttribute) name() (name string) {
+	const offsetNameLength = 0x09
+	const offsetNameOffset = 0x0A
+	const lengthNameOffset = 0x02
+
+	if len(rawAttribute) < offsetNameOffset+lengthNameOffset {
+		return
+	}
+	nameLength := int(rawAttribute[offsetNameLength]) * 2
+	nameOffset := int(binary.LittleEndian.Uint16(rawAttribute[offsetNameOffset : offsetNameOffset+lengthNameOffset]))
+	if nameLength == 0 || nameOffset+nameLength > len(rawAttribute) {
+		return
+	}
+	name, _ = bin.UnicodeBytesToASCII(rawAttribute[nameOffset : nameOffset+nameLength])
+	return
+}
+
+// Parses the record header of the raw mft record receiver and returns its raw attributes.
+func (rawMftRecord RawMasterFileTableRecord) getRawAttributes() (rawAttributes RawAttributes, err error) {
+	rawRecordHeader, err := rawMftRecord.GetRawRecordHeader()
+	if err != nil {
+		err = fmt.Errorf("failed to get record header: %w", err)
+		return
 	}
+	recordHeader, err := rawRecordHeader.Parse()
+	if err != nil {
+		err = fmt.Errorf("failed to parse record header: %w", err)
+		return
+	}
+	rawAttributes, err = rawMftRecord.GetRawAttributes(recordHeader)
 	return
 }
 
 // GetRawAttributes returns the attribute bytes from an unparsed mft record which is the method receiver. It takes recordHeader as an argument since the record header contains the offset for the start of the attributes.
 func (rawMftRecord RawMasterFileTableRecord) GetRawAttributes(recordHeader RecordHeader) (rawAttributes RawAttributes, err error) {
+	rawAttributes, err = rawMftRecord.GetRawAttributesWithDefinitions(recordHeader, nil)
+	return
+}
+
+// GetRawAttributesWithDefinitions works like GetRawAttributes() but stops at the first attribute whose type isn't in the attribute definitions, typically the volume's own $AttrDef. Nil attribute definitions accept any attribute type.
+func (rawMftRecord RawMasterFileTableRecord) GetRawAttributesWithDefinitions(recordHeader RecordHeader, attributeDefinitions AttributeDefinitions) (rawAttributes RawAttributes, err error) {
 	// Doing some sanity checks
 	if len(rawMftRecord) == 0 {
-		err = errors.New("received nil bytes")
+		err = ErrNilBytes
 		return
 	}
 	if recordHeader.AttributesOffset == 0 {
@@ -111,90 +196,43 @@ func (rawMftRecord RawMasterFileTableRecord) GetRawAttributes(recordHeader Recor
 
 	const offsetAttributeSize = 0x04
 	const lengthAttributeSize = 0x04
+	const lengthAttributeHeader = 0x10
 
-	// Init variable that tracks how far to the next Attribute
-	var distanceToNextAttribute uint16
-	offset := recordHeader.AttributesOffset
+	// Attributes are walked by their size rather than their type so that attribute types this package doesn't parse are still returned.
+	offset := int(recordHeader.AttributesOffset)
 	sizeOfRawMftRecord := len(rawMftRecord)
-
-	for {
-		// Calculate offset to next Attribute
-		offset = offset + distanceToNextAttribute
-
-		// Break if the offset is beyond the byte slice
-		if offset > uint16(sizeOfRawMftRecord) || offset+0x04 > uint16(sizeOfRawMftRecord) {
+	for offset+offsetAttributeSize+lengthAttributeSize <= sizeOfRawMftRecord {
+		// Stop at the end marker or at anything else that can't be an attribute type.
+		if isThisAnAttribute(binary.LittleEndian.Uint32(rawMftRecord[offset:offset+0x04]), attributeDefinitions) == false {
 			break
 		}
 
-		// Verify if the byte slice is actually an MFT Attribute
-		shouldWeContinue := isThisAnAttribute(rawMftRecord[offset])
-		if shouldWeContinue == false {
+		// Stop if the attribute size would run past the record or never advance the walk.
+		attributeSize := int(binary.LittleEndian.Uint32(rawMftRecord[offset+offsetAttributeSize : offset+offsetAttributeSize+lengthAttributeSize]))
+		if attributeSize < lengthAttributeHeader || offset+attributeSize > sizeOfRawMftRecord {
 			break
 		}
 
-		attributeSize := binary.LittleEndian.Uint16(rawMftRecord[offset+offsetAttributeSize : offset+offsetAttributeSize+lengthAttributeSize])
-		end := offset + attributeSize
-
 		rawAttribute := rawAttribute(make([]byte, attributeSize))
-		copy(rawAttribute, rawMftRecord[offset:end])
+		copy(rawAttribute, rawMftRecord[offset:offset+attributeSize])
 
 		// Append the rawAttributes to the RawAttributes struct
 		rawAttributes = append(rawAttributes, rawAttribute)
-
-		// Track the distance to the next Attribute based on the size of the current Attribute
-		distanceToNextAttribute = binary.LittleEndian.Uint16(rawMftRecord[offset+offsetAttributeSize : offset+offsetAttributeSize+lengthAttributeSize])
+		offset += attributeSize
 	}
 
 	return
 }
 
-// Checks if the byte value equals a valid attribute type. We only do things with a few of these.
-func isThisAnAttribute(attributeHeaderToCheck byte) (result bool) {
-	// Init a byte slice that tracks all possible valid MFT Attribute types.
-	// We'll be used this to verify if what we are looking at is actually an MFT Attribute.
-	const codeStandardInformation = 0x10
-	const codeAttributeList = 0x20
-	const codeFileName = 0x30
-	const codeVolumeVersion = 0x40
-	const codeSecurityDescriptor = 0x50
-	const codeVolumeName = 0x60
-	const codeVolumeInformation = 0x70
-	const codeData = 0x80
-	const codeIndexRoot = 0x90
-	const codeIndexAllocation = 0xA0
-	const codeBitmap = 0xB0
-	const codeSymbolicLink = 0xC0
-	const codeReparsePoint = 0xD0
-	const codeEaInformation = 0xE0
-	const codePropertySet = 0xF0
-
-	validAttributeTypes := []byte{
-		codeStandardInformation,
-		codeAttributeList,
-		codeFileName,
-		codeVolumeVersion,
-		codeSecurityDescriptor,
-		codeVolumeName,
-		codeVolumeInformation,
-		codeData,
-		codeIndexRoot,
-		codeIndexAllocation,
-		codeBitmap,
-		codeSymbolicLink,
-		codeReparsePoint,
-		codeEaInformation,
-		codePropertySet,
+// Checks if the value is an attribute type. Attribute types are multiples of 0x10 and when the volume's attribute definitions are provided the type also has to be one of them, since $AttrDef lists every type the volume's records can use. Nil attribute definitions don't fall back to DefaultAttributeDefinitions here, a volume can define types the built-in table has never heard of and those should show up in the attribute listing rather than end the walk.
+func isThisAnAttribute(attributeHeaderToCheck uint32, attributeDefinitions AttributeDefinitions) (result bool) {
+	if attributeHeaderToCheck == 0 || attributeHeaderToCheck == attributeTypeEnd || attributeHeaderToCheck&0x0F != 0 {
+		return
 	}
-
-	// Verify if the byte slice is actually an MFT Attribute
-	for _, validType := range validAttributeTypes {
-		if attributeHeaderToCheck == validType {
-			result = true
-			break
-		} else {
-			result = false
-		}
+	if attributeDefinitions != nil {
+		result = attributeDefinitions.IsDefined(attributeHeaderToCheck)
+		return
 	}
-
+	result = true
 	return
 }
@@ -3,6 +3,7 @@
 package mft
 
 import (
+	"github.com/google/go-cmp/cmp"
 	"reflect"
 	"testing"
 	"time"
@@ -10,7 +11,8 @@ import (
 
 func Test_isThisAnAttribute(t *testing.T) {
 	type args struct {
-		attributeHeaderToCheck byte
+		attributeHeaderToCheck uint32
+		attributeDefinitions   AttributeDefinitions
 	}
 	tests := []struct {
 		name string
@@ -32,10 +34,54 @@ func Test_isThisAnAttribute(t *testing.T) {
 			},
 			want: false,
 		},
+		{
+			name: "Header 0x100",
+			args: args{
+				attributeHeaderToCheck: 0x100,
+			},
+			want: true,
+		},
+		{
+			name: "Header 0x1000 without attribute definitions",
+			args: args{
+				attributeHeaderToCheck: 0x1000,
+			},
+			want: true,
+		},
+		{
+			name: "Header 0x1000 missing from $AttrDef",
+			args: args{
+				attributeHeaderToCheck: 0x1000,
+				attributeDefinitions:   DefaultAttributeDefinitions,
+			},
+			want: false,
+		},
+		{
+			name: "Header 0x1000 defined by $AttrDef",
+			args: args{
+				attributeHeaderToCheck: 0x1000,
+				attributeDefinitions:   AttributeDefinitions{0x1000: testCustomAttributeDefinition},
+			},
+			want: true,
+		},
+		{
+			name: "End marker",
+			args: args{
+				attributeHeaderToCheck: 0xFFFFFFFF,
+			},
+			want: false,
+		},
+		{
+			name: "Zeroed out space",
+			args: args{
+				attributeHeaderToCheck: 0x00,
+			},
+			want: false,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			if tt.got = isThisAnAttribute(tt.args.attributeHeaderToCheck); tt.got != tt.want {
+			if tt.got = isThisAnAttribute(tt.args.attributeHeaderToCheck, tt.args.attributeDefinitions); tt.got != tt.want {
 				t.Errorf("Test %v failed \ngot = %v, \nwant = %v", tt.name, tt.got, tt.want)
 			}
 		})
@@ -72,6 +118,7 @@ func TestRawAttributes_Parse(t *testing.T) {
 				SiAccessed:   time.Date(2016, 7, 2, 15, 13, 30, 670820200, time.UTC),
 				SiChanged:    time.Date(2016, 7, 2, 15, 13, 30, 670820200, time.UTC),
 				FlagResident: true,
+				SecurityID:   256,
 			},
 			wantFileNameAttributes: FileNameAttributes{
 				FileNameAttribute{
@@ -210,20 +257,26 @@ func TestRawAttributes_Parse(t *testing.T) {
 			},
 			wantAttributeListAttribute: AttributeListAttributes{
 				AttributeListAttribute{
-					Type:                     0x10,
-					MFTReferenceRecordNumber: 493401,
+					Type:                       0x10,
+					MFTReferenceRecordNumber:   493401,
+					MFTReferenceSequenceNumber: 8,
 				},
 				AttributeListAttribute{
-					Type:                     0x30,
-					MFTReferenceRecordNumber: 493401,
+					Type:                       0x30,
+					MFTReferenceRecordNumber:   493401,
+					MFTReferenceSequenceNumber: 8,
+					AttributeID:                5,
 				},
 				AttributeListAttribute{
-					Type:                     0x80,
-					MFTReferenceRecordNumber: 1423172,
+					Type:                       0x80,
+					MFTReferenceRecordNumber:   1423172,
+					MFTReferenceSequenceNumber: 3,
 				},
 				AttributeListAttribute{
-					Type:                     0x80,
-					MFTReferenceRecordNumber: 1423173,
+					Type:                       0x80,
+					StartingVCN:                10240,
+					MFTReferenceRecordNumber:   1423173,
+					MFTReferenceSequenceNumber: 3,
 				},
 			},
 		},
@@ -326,3 +379,76 @@ func TestRawMasterFileTableRecord_GetRawAttributes(t *testing.T) {
 		})
 	}
 }
+
+func Test_rawAttribute_name(t *testing.T) {
+	tests := []struct {
+		name         string
+		rawAttribute rawAttribute
+		want         string
+	}{
+		{
+			name:         "named",
+			rawAttribute: buildTestLoggedUtilityStreamAttribute("$EFS", []byte{0x00}),
+			want:         "$EFS",
+		},
+		{
+			name:         "unnamed",
+			rawAttribute: buildTestResidentAttribute(0x80, []byte{0x00}),
+			want:         "",
+		},
+		{
+			name:         "name beyond the attribute",
+			rawAttribute: buildTestLoggedUtilityStreamAttribute("$TXF_DATA", nil)[:0x1C],
+			want:         "",
+		},
+		{
+			name:         "nil bytes",
+			rawAttribute: nil,
+			want:         "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.rawAttribute.name(); got != tt.want {
+				t.Errorf("name() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawAttributes_ParseLenient(t *testing.T) {
+	brokenFileName := buildTestFileNameAttribute("broken.txt", 5)
+	brokenFileName[0x08] = 0x01
+	badDataRuns := buildTestNonResidentAttribute(0x80, 4096, []byte{0x11, 0x01, 0x10, 0x2F, 0x01})
+	rawAttributes := RawAttributes{
+		rawAttribute(brokenFileName),
+		rawAttribute(buildTestFileNameAttribute("notes.txt", 5)),
+		rawAttribute(badDataRuns),
+	}
+
+	recordAttributes, err := rawAttributes.ParseLenient(4096)
+	if err != nil {
+		t.Fatalf("ParseLenient() returned %v", err)
+	}
+	if len(recordAttributes.FileNameAttributes) != 1 || recordAttributes.FileNameAttributes[0].FileName != "notes.txt" {
+		t.Errorf("ParseLenient() returned the filename attributes %+v, want notes.txt", recordAttributes.FileNameAttributes)
+	}
+	if !reflect.DeepEqual(recordAttributes.DataAttribute, DataAttribute{}) {
+		t.Errorf("ParseLenient() kept the data attribute %+v that failed to parse", recordAttributes.DataAttribute)
+	}
+	type testAttributeError struct {
+		AttributeType uint32
+		Offset        int
+	}
+	var got []testAttributeError
+	for _, attributeError := range recordAttributes.AttributeErrors {
+		got = append(got, testAttributeError{AttributeType: attributeError.AttributeType, Offset: attributeError.Offset})
+	}
+	want := []testAttributeError{
+		{AttributeType: 0x30, Offset: 0},
+		{AttributeType: 0x80, Offset: len(brokenFileName) + len(rawAttributes[1])},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
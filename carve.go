@@ -0,0 +1,192 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// CarveOptions contains the settings used to carve records out of arbitrary data.
+type CarveOptions struct {
+	// Unaligned checks every byte offset for a signature instead of only the offsets that are a multiple of the bytes per sector. This finds records that were copied to unaligned offsets, e.g. inside other files, at the cost of speed.
+	Unaligned bool
+
+	// RecordSize is the size of a record. When left at 0 it's 1024 for MFT records and 4096 for INDX records.
+	RecordSize int
+
+	// BytesPerSector is used to apply fixups and to align the scan, 512 when left at 0.
+	BytesPerSector int
+}
+
+// Returns the record size and bytes per sector of the carve options receiver with the defaults filled in.
+func (carveOptions CarveOptions) sizes(defaultRecordSize int) (recordSize int, bytesPerSector int) {
+	recordSize = carveOptions.RecordSize
+	if recordSize == 0 {
+		recordSize = defaultRecordSize
+	}
+	bytesPerSector = carveOptions.BytesPerSector
+	if bytesPerSector == 0 {
+		bytesPerSector = 512
+	}
+	return
+}
+
+// CarveMftRecords scans arbitrary data, such as a memory dump, unallocated clusters, or a pagefile, for records starting with a FILE or BAAD signature and sends the ones that pass validation to an output channel. Records from this output channel are popped off by a ResultWriter, and the channel is closed once the reader is exhausted.
+// A FILE record has to pass the header checks and its fixups. A BAAD record, which chkdsk marks when a record fails its fixups, only has to pass the header checks. Paths are resolved against the directory tree where the parent directory is in it, and every result carries the offset in the data it was carved from.
+func CarveMftRecords(reader io.Reader, bytesPerCluster int64, directoryTree DirectoryTree, outputChannel *chan UsefulMftFields, options CarveOptions) (err error) {
+	defer close(*outputChannel)
+
+	recordSize, bytesPerSector := options.sizes(defaultMftRecordSize)
+
+	// Sanity checks
+	if bytesPerCluster <= 0 {
+		err = fmt.Errorf("invalid bytes per cluster value of %d", bytesPerCluster)
+		return
+	}
+	err = checkCarveSizes(recordSize, bytesPerSector)
+	if err != nil {
+		return
+	}
+
+	err = scanForRecords(reader, recordSize, options.step(bytesPerSector), func(candidate []byte, sourceOffset int64) bool {
+		if !hasRecordSignature(candidate) {
+			return false
+		}
+		mftRecord, baad, carveErr := carveMftRecord(candidate, bytesPerSector, bytesPerCluster)
+		if carveErr != nil {
+			return false
+		}
+		usefulMftFields := GetUsefulMftFields(mftRecord, directoryTree)
+		usefulMftFields.CarvedOffset = sourceOffset
+		usefulMftFields.CarvedSignature = "FILE"
+		if baad {
+			usefulMftFields.CarvedSignature = "BAAD"
+		}
+		*outputChannel <- usefulMftFields
+		return true
+	})
+	return
+}
+
+// Returns how far the scan moves between candidate offsets.
+func (carveOptions CarveOptions) step(bytesPerSector int) int {
+	if carveOptions.Unaligned {
+		return 1
+	}
+	return bytesPerSector
+}
+
+// Checks the sizes used to carve records.
+func checkCarveSizes(recordSize int, bytesPerSector int) (err error) {
+	if bytesPerSector < 0x200 {
+		err = fmt.Errorf("invalid bytes per sector value of %d", bytesPerSector)
+		return
+	} else if recordSize < bytesPerSector || recordSize%bytesPerSector != 0 {
+		err = fmt.Errorf("a record size of %d is not a multiple of the bytes per sector value of %d", recordSize, bytesPerSector)
+		return
+	}
+	return
+}
+
+// Reads the data from the reader and calls carve with every record sized candidate that starts at a multiple of the step from the start of the data. Carve returns true when it found a record in the candidate, in which case the scan moves past the whole record.
+func scanForRecords(reader io.Reader, recordSize int, step int, carve func(candidate []byte, sourceOffset int64) bool) (err error) {
+	// The buffer always keeps less than a record's worth of unscanned bytes from the previous read so that records spanning two reads are found. Every offset the scan stops at is a multiple of the step from the start of the data, so shifting by it keeps the scan aligned.
+	const chunkSize = 1024 * 1024
+	buffer := make([]byte, 0, chunkSize+recordSize)
+	bufferOffset := int64(0)
+	for {
+		read, readErr := io.ReadFull(reader, buffer[len(buffer):cap(buffer)])
+		buffer = buffer[:len(buffer)+read]
+		if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+			err = fmt.Errorf("failed to read data at offset %d: %w", bufferOffset+int64(len(buffer)), readErr)
+			return
+		}
+
+		position := 0
+		for position+recordSize <= len(buffer) {
+			if carve(buffer[position:position+recordSize], bufferOffset+int64(position)) {
+				position += recordSize
+			} else {
+				position += step
+			}
+		}
+
+		if readErr != nil {
+			break
+		}
+		bufferOffset += int64(position)
+		buffer = buffer[:copy(buffer, buffer[position:])]
+	}
+	return
+}
+
+// Returns true when the data starts with the FILE or BAAD signature.
+func hasRecordSignature(data []byte) bool {
+	if len(data) < 4 {
+		return false
+	}
+	signature := string(data[0:4])
+	return signature == "FILE" || signature == "BAAD"
+}
+
+// Validates a candidate record and parses a copy of it. The copy of a BAAD record gets the FILE signature back so that it can be parsed like any other record, and its fixups are only applied when they check out.
+func carveMftRecord(candidate []byte, bytesPerSector int, bytesPerCluster int64) (mftRecord MasterFileTableRecord, baad bool, err error) {
+	err = checkCarvedRecordHeader(candidate, bytesPerSector)
+	if err != nil {
+		return
+	}
+	rawMftRecord := make(RawMasterFileTableRecord, len(candidate))
+	copy(rawMftRecord, candidate)
+	baad = string(rawMftRecord[0:4]) == "BAAD"
+	fixupErr := applyFixups(rawMftRecord, bytesPerSector)
+	if fixupErr != nil && !baad {
+		err = fmt.Errorf("failed to apply fixups: %w", fixupErr)
+		return
+	}
+	copy(rawMftRecord[0:4], "FILE")
+	mftRecord, err = rawMftRecord.Parse(bytesPerCluster)
+	return
+}
+
+// Checks that the header of a candidate record is consistent with a record of its size. Random data that happens to start with a signature rarely gets past these checks.
+func checkCarvedRecordHeader(candidate []byte, bytesPerSector int) (err error) {
+	const offsetUpdateSequenceOffset = 0x04
+	const offsetUpdateSequenceCount = 0x06
+	const offsetAttributesOffset = 0x14
+	const offsetFlags = 0x16
+	const offsetUsedSize = 0x18
+	const offsetAllocatedSize = 0x1C
+	const lengthRecordHeader = 0x30
+
+	recordSize := len(candidate)
+	updateSequenceOffset := int(binary.LittleEndian.Uint16(candidate[offsetUpdateSequenceOffset : offsetUpdateSequenceOffset+0x02]))
+	updateSequenceCount := int(binary.LittleEndian.Uint16(candidate[offsetUpdateSequenceCount : offsetUpdateSequenceCount+0x02]))
+	attributesOffset := int(binary.LittleEndian.Uint16(candidate[offsetAttributesOffset : offsetAttributesOffset+0x02]))
+	flags := binary.LittleEndian.Uint16(candidate[offsetFlags : offsetFlags+0x02])
+	usedSize := int(binary.LittleEndian.Uint32(candidate[offsetUsedSize : offsetUsedSize+0x04]))
+	allocatedSize := int(binary.LittleEndian.Uint32(candidate[offsetAllocatedSize : offsetAllocatedSize+0x04]))
+
+	if allocatedSize != recordSize {
+		err = fmt.Errorf("allocated size of %d does not match the record size of %d", allocatedSize, recordSize)
+		return
+	} else if usedSize > allocatedSize || usedSize%8 != 0 {
+		err = fmt.Errorf("invalid used size of %d", usedSize)
+		return
+	} else if updateSequenceCount != recordSize/bytesPerSector+1 {
+		err = fmt.Errorf("update sequence count of %d does not cover a %d byte record", updateSequenceCount, recordSize)
+		return
+	} else if updateSequenceOffset < 0x28 || updateSequenceOffset%2 != 0 || updateSequenceOffset+updateSequenceCount*2 > attributesOffset {
+		err = fmt.Errorf("invalid update sequence offset of %d", updateSequenceOffset)
+		return
+	} else if attributesOffset < lengthRecordHeader || attributesOffset%8 != 0 || attributesOffset >= usedSize {
+		err = fmt.Errorf("invalid attributes offset of %d", attributesOffset)
+		return
+	} else if flags&^0x0F != 0 {
+		err = errors.New("unknown record header flags are set")
+		return
+	}
+	return
+}
@@ -0,0 +1,127 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds a record with fixups and the used and allocated sizes filled in, which is what the carver expects to find.
+func buildTestCarvableMftRecord(recordNumber uint32, fileName string) []byte {
+	rawMftRecord := buildTestVolumeMftRecord(recordNumber, 1, buildTestFileNameAttribute(fileName, 5))
+	binary.LittleEndian.PutUint32(rawMftRecord[0x18:], 0x200)
+	binary.LittleEndian.PutUint32(rawMftRecord[0x1C:], 0x400)
+	return rawMftRecord
+}
+
+// The parts of a carved result that are checked by the tests.
+type testCarvedRecord struct {
+	CarvedOffset    int64
+	CarvedSignature string
+	RecordNumber    uint32
+	FullPath        string
+}
+
+func TestCarveMftRecords(t *testing.T) {
+	baadRecord := buildTestCarvableMftRecord(41, "chkdsk.txt")
+	copy(baadRecord, "BAAD")
+	baadRecord[510] = 0xFF
+	tornRecord := buildTestCarvableMftRecord(42, "torn.txt")
+	tornRecord[510] = 0xFF
+	wrongSizeRecord := buildTestCarvableMftRecord(43, "wrong.txt")
+	binary.LittleEndian.PutUint32(wrongSizeRecord[0x1C:], 0x1000)
+
+	joinData := func(parts ...[]byte) []byte {
+		return bytes.Join(parts, nil)
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		options CarveOptions
+		want    []testCarvedRecord
+		wantErr bool
+	}{
+		{
+			name: "sector aligned FILE and BAAD records",
+			data: joinData(make([]byte, 512), buildTestCarvableMftRecord(40, "notes.txt"), make([]byte, 1024), baadRecord, make([]byte, 100)),
+			want: []testCarvedRecord{
+				{CarvedOffset: 512, CarvedSignature: "FILE", RecordNumber: 40, FullPath: "C:\\notes.txt"},
+				{CarvedOffset: 2560, CarvedSignature: "BAAD", RecordNumber: 41, FullPath: "C:\\chkdsk.txt"},
+			},
+		},
+		{
+			name: "unaligned record skipped by an aligned scan",
+			data: joinData(make([]byte, 3), buildTestCarvableMftRecord(40, "notes.txt")),
+		},
+		{
+			name:    "unaligned record",
+			data:    joinData(make([]byte, 3), buildTestCarvableMftRecord(40, "notes.txt")),
+			options: CarveOptions{Unaligned: true},
+			want: []testCarvedRecord{
+				{CarvedOffset: 3, CarvedSignature: "FILE", RecordNumber: 40, FullPath: "C:\\notes.txt"},
+			},
+		},
+		{
+			name: "record spanning two reads",
+			data: joinData(make([]byte, 1024*1024-512), buildTestCarvableMftRecord(40, "notes.txt")),
+			want: []testCarvedRecord{
+				{CarvedOffset: 1024*1024 - 512, CarvedSignature: "FILE", RecordNumber: 40, FullPath: "C:\\notes.txt"},
+			},
+		},
+		{
+			name: "FILE record with torn fixups",
+			data: tornRecord,
+		},
+		{
+			name: "allocated size does not match the record size",
+			data: wrongSizeRecord,
+		},
+		{
+			name: "signature without a record",
+			data: joinData([]byte("FILE0"), make([]byte, 2048)),
+		},
+		{
+			name: "record cut off at the end of the data",
+			data: buildTestCarvableMftRecord(40, "notes.txt")[:1000],
+		},
+		{
+			name:    "record size not a multiple of the sector size",
+			data:    buildTestCarvableMftRecord(40, "notes.txt"),
+			options: CarveOptions{RecordSize: 1000},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			outputChannel := make(chan UsefulMftFields, 10)
+			var got []testCarvedRecord
+			done := make(chan struct{})
+			go func() {
+				for usefulMftFields := range outputChannel {
+					got = append(got, testCarvedRecord{
+						CarvedOffset:    usefulMftFields.CarvedOffset,
+						CarvedSignature: usefulMftFields.CarvedSignature,
+						RecordNumber:    usefulMftFields.RecordNumber,
+						FullPath:        usefulMftFields.FullPath,
+					})
+				}
+				close(done)
+			}()
+			err := CarveMftRecords(bytes.NewReader(tt.data), 4096, DirectoryTree{5: "C:\\"}, &outputChannel, tt.options)
+			<-done
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CarveMftRecords() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// ClusterIndex is an interval index over the clusters used by the non-resident attributes of every record of an MFT. It answers which file, and which stream of that file, owns a cluster of the volume.
+type ClusterIndex struct {
+	BytesPerCluster int64
+	extents         []ClusterExtent
+	maxEnds         []int64
+	files           map[uint32]clusterIndexFile
+}
+
+// ClusterExtent contains a run of clusters used by a non-resident attribute. The record number is always the base record, even when the attribute lives in an extension record.
+type ClusterExtent struct {
+	FirstCluster int64
+	ClusterCount int64
+	RecordNumber uint32
+	Stream       string
+	StreamOffset int64
+}
+
+// ClusterOwner contains a file that owns a cluster. The stream offset is the offset within the stream of the looked up byte, and deleted files are included since their clusters may not have been reused yet.
+type ClusterOwner struct {
+	RecordNumber   uint32
+	SequenceNumber uint16
+	FullPath       string
+	Stream         string
+	StreamOffset   int64
+	Deleted        bool
+}
+
+// ClusterLookup contains the files that own a cluster. Allocated is only meaningful when BitmapChecked is set, which happens when a volume bitmap was provided for the lookup.
+type ClusterLookup struct {
+	Cluster       int64
+	VolumeOffset  int64
+	Allocated     bool
+	BitmapChecked bool
+	Owners        []ClusterOwner
+}
+
+// The details of a base record needed to describe the owner of a cluster.
+type clusterIndexFile struct {
+	sequenceNumber uint16
+	fullPath       string
+	deleted        bool
+}
+
+// BuildClusterIndex reads every record of an MFT and indexes the clusters used by their non-resident attributes, including the attribute segments kept in extension records. Records that can't be parsed are skipped.
+func BuildClusterIndex(reader io.Reader, bytesPerCluster int64, directoryTree DirectoryTree) (clusterIndex *ClusterIndex, err error) {
+	// Sanity checks
+	if bytesPerCluster <= 0 {
+		err = fmt.Errorf("invalid bytes per cluster value of %d", bytesPerCluster)
+		return
+	}
+
+	clusterIndex = &ClusterIndex{
+		BytesPerCluster: bytesPerCluster,
+		files:           make(map[uint32]clusterIndexFile),
+	}
+	for {
+		buffer := make(RawMasterFileTableRecord, defaultMftRecordSize)
+		_, readErr := io.ReadFull(reader, buffer)
+		if readErr != nil {
+			break
+		}
+		mftRecord, parseErr := buffer.Parse(bytesPerCluster)
+		if parseErr != nil {
+			continue
+		}
+		recordHeader := mftRecord.RecordHeader
+		baseRecordNumber := recordHeader.RecordNumber
+		if recordHeader.IsExtensionRecord() {
+			baseRecordNumber = recordHeader.BaseRecordNumber
+		} else {
+			clusterIndex.files[recordHeader.RecordNumber] = clusterIndexFile{
+				sequenceNumber: recordHeader.SequenceNumber,
+				fullPath:       GetUsefulMftFields(mftRecord, directoryTree).FullPath,
+				deleted:        recordHeader.Flags.FlagDeleted,
+			}
+		}
+
+		rawAttributes, err := buffer.GetRawAttributes(recordHeader)
+		if err != nil {
+			continue
+		}
+		for _, rawAttribute := range rawAttributes {
+			clusterIndex.addAttribute(baseRecordNumber, rawAttribute)
+		}
+	}
+	clusterIndex.sort()
+	return
+}
+
+// Adds the clusters used by a raw attribute to the cluster index receiver. Resident attributes don't use any clusters and sparse data runs have no clusters on the volume, so both are skipped.
+func (clusterIndex *ClusterIndex) addAttribute(recordNumber uint32, rawAttribute rawAttribute) {
+	const offsetStartingVcn = 0x10
+	const lengthStartingVcn = 0x08
+
+	attributeInfo := rawAttribute.info()
+	if attributeInfo.Resident || len(rawAttribute) < offsetStartingVcn+lengthStartingVcn {
+		return
+	}
+	nonResidentDataAttribute, err := RawNonResidentDataAttribute(rawAttribute).Parse(clusterIndex.BytesPerCluster)
+	if err != nil {
+		return
+	}
+
+	// Each segment of an attribute starts its data runs over, so the stream offset starts at the segment's first vcn.
+	stream := attributeInfo.Describe(nil)
+	streamOffset := int64(binary.LittleEndian.Uint64(rawAttribute[offsetStartingVcn:offsetStartingVcn+lengthStartingVcn])) * clusterIndex.BytesPerCluster
+	for i := 0; i < len(nonResidentDataAttribute.DataRuns); i++ {
+		dataRun := nonResidentDataAttribute.DataRuns[i]
+		if !dataRun.Sparse && dataRun.Length > 0 {
+			clusterIndex.extents = append(clusterIndex.extents, ClusterExtent{
+				FirstCluster: dataRun.AbsoluteOffset / clusterIndex.BytesPerCluster,
+				ClusterCount: dataRun.Length / clusterIndex.BytesPerCluster,
+				RecordNumber: recordNumber,
+				Stream:       stream,
+				StreamOffset: streamOffset,
+			})
+		}
+		streamOffset += dataRun.Length
+	}
+	return
+}
+
+// Sorts the extents of the cluster index receiver by their first cluster and records the furthest end of every prefix of them, so that a lookup can stop walking back once no earlier extent reaches the cluster.
+func (clusterIndex *ClusterIndex) sort() {
+	sort.SliceStable(clusterIndex.extents, func(i, j int) bool {
+		return clusterIndex.extents[i].FirstCluster < clusterIndex.extents[j].FirstCluster
+	})
+	clusterIndex.maxEnds = make([]int64, len(clusterIndex.extents))
+	maxEnd := int64(0)
+	for i, clusterExtent := range clusterIndex.extents {
+		if end := clusterExtent.FirstCluster + clusterExtent.ClusterCount; end > maxEnd {
+			maxEnd = end
+		}
+		clusterIndex.maxEnds[i] = maxEnd
+	}
+	return
+}
+
+// Extents returns the extents of the cluster index receiver ordered by their first cluster.
+func (clusterIndex *ClusterIndex) Extents() []ClusterExtent {
+	return clusterIndex.extents
+}
+
+// Owners returns every file that has the cluster in one of its non-resident attributes, ordered by record number. More than one owner means a deleted file's clusters were reused or the volume has cross-linked clusters.
+func (clusterIndex *ClusterIndex) Owners(cluster int64) (clusterOwners []ClusterOwner) {
+	clusterOwners = clusterIndex.owners(cluster, 0)
+	return
+}
+
+// Returns the owners of a cluster with the stream offset pointing at the byte that is offsetInCluster bytes into the cluster.
+func (clusterIndex *ClusterIndex) owners(cluster int64, offsetInCluster int64) (clusterOwners []ClusterOwner) {
+	last := sort.Search(len(clusterIndex.extents), func(i int) bool {
+		return clusterIndex.extents[i].FirstCluster > cluster
+	}) - 1
+	for i := last; i >= 0 && clusterIndex.maxEnds[i] > cluster; i-- {
+		clusterExtent := clusterIndex.extents[i]
+		if cluster >= clusterExtent.FirstCluster+clusterExtent.ClusterCount {
+			continue
+		}
+		file := clusterIndex.files[clusterExtent.RecordNumber]
+		clusterOwners = append(clusterOwners, ClusterOwner{
+			RecordNumber:   clusterExtent.RecordNumber,
+			SequenceNumber: file.sequenceNumber,
+			FullPath:       file.fullPath,
+			Stream:         clusterExtent.Stream,
+			StreamOffset:   clusterExtent.StreamOffset + (cluster-clusterExtent.FirstCluster)*clusterIndex.BytesPerCluster + offsetInCluster,
+			Deleted:        file.deleted,
+		})
+	}
+	sort.SliceStable(clusterOwners, func(i, j int) bool {
+		if clusterOwners[i].RecordNumber != clusterOwners[j].RecordNumber {
+			return clusterOwners[i].RecordNumber < clusterOwners[j].RecordNumber
+		}
+		return clusterOwners[i].StreamOffset < clusterOwners[j].StreamOffset
+	})
+	return
+}
+
+// Lookup returns the owners of a cluster. The volume bitmap is optional, when provided it's used to report whether the cluster is allocated.
+func (clusterIndex *ClusterIndex) Lookup(cluster int64, volumeBitmap VolumeBitmap) (clusterLookup ClusterLookup) {
+	clusterLookup = clusterIndex.lookup(cluster*clusterIndex.BytesPerCluster, volumeBitmap)
+	return
+}
+
+// LookupOffset returns the owners of the cluster that holds a byte offset of the volume. The offset is relative to the start of the NTFS partition, not the start of a disk image.
+func (clusterIndex *ClusterIndex) LookupOffset(volumeOffset int64, volumeBitmap VolumeBitmap) (clusterLookup ClusterLookup) {
+	clusterLookup = clusterIndex.lookup(volumeOffset, volumeBitmap)
+	return
+}
+
+func (clusterIndex *ClusterIndex) lookup(volumeOffset int64, volumeBitmap VolumeBitmap) (clusterLookup ClusterLookup) {
+	cluster := volumeOffset / clusterIndex.BytesPerCluster
+	clusterLookup = ClusterLookup{
+		Cluster:      cluster,
+		VolumeOffset: volumeOffset,
+		Owners:       clusterIndex.owners(cluster, volumeOffset%clusterIndex.BytesPerCluster),
+	}
+	if volumeBitmap != nil {
+		clusterLookup.BitmapChecked = true
+		clusterLookup.Allocated = volumeBitmap.IsAllocated(cluster)
+	}
+	return
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds a named non-resident attribute whose data runs start at the provided vcn.
+func buildTestNamedNonResidentAttribute(attributeType byte, name string, startingVcn uint64, rawDataRuns []byte) []byte {
+	const offsetName = 0x40
+	offsetDataRuns := (offsetName + len(name)*2 + 7) &^ 7
+	rawAttribute := buildTestNonResidentAttribute(attributeType, 0, append(make([]byte, offsetDataRuns-0x40), rawDataRuns...))
+	rawAttribute[0x09] = byte(len(name))
+	binary.LittleEndian.PutUint16(rawAttribute[0x0A:], offsetName)
+	binary.LittleEndian.PutUint64(rawAttribute[0x10:], startingVcn)
+	rawAttribute[0x20] = byte(offsetDataRuns)
+	copy(rawAttribute[offsetName:], buildTestUnicode(name))
+	return rawAttribute
+}
+
+// Builds an MFT where record 30 is a file with a fragmented $DATA attribute that continues in extension record 31 and an alternate data stream, and record 32 is a deleted file whose clusters overlap record 30.
+//
+// Record 30's $DATA attribute covers clusters 0x100 to 0x10F, then 4 sparse clusters, then clusters 0x110 to 0x113. Extension record 31 picks it up at vcn 0x18 with clusters 0x20 to 0x27.
+func buildTestClusterIndexMft() []byte {
+	deletedRecord := buildTestMftRecord(32, 0, buildTestFileNameAttribute("old.txt", 5), buildTestNonResidentAttribute(0x80, 0x2000, []byte{0x21, 0x02, 0x04, 0x01}))
+	deletedRecord[0x16] = 0x00
+	var rawMft []byte
+	for _, rawMftRecord := range [][]byte{
+		buildTestMftRecord(30, 0,
+			buildTestFileNameAttribute("report.docx", 5),
+			buildTestNonResidentAttribute(0x80, 0x1C000, []byte{0x21, 0x10, 0x00, 0x01, 0x01, 0x04, 0x11, 0x04, 0x10}),
+			buildTestNamedNonResidentAttribute(0x80, "Zone.Identifier", 0, []byte{0x11, 0x01, 0x40}),
+		),
+		buildTestMftRecord(31, 30, buildTestNamedNonResidentAttribute(0x80, "", 0x18, []byte{0x11, 0x08, 0x20})),
+		deletedRecord,
+	} {
+		rawMft = append(rawMft, rawMftRecord...)
+	}
+	return rawMft
+}
+
+func TestBuildClusterIndex(t *testing.T) {
+	clusterIndex, err := BuildClusterIndex(bytes.NewReader(buildTestClusterIndexMft()), 4096, DirectoryTree{5: "C:\\"})
+	if err != nil {
+		t.Fatalf("BuildClusterIndex() returned %v", err)
+	}
+	want := []ClusterExtent{
+		{FirstCluster: 0x20, ClusterCount: 8, RecordNumber: 30, Stream: "$DATA", StreamOffset: 0x18 * 4096},
+		{FirstCluster: 0x40, ClusterCount: 1, RecordNumber: 30, Stream: "$DATA:Zone.Identifier"},
+		{FirstCluster: 0x100, ClusterCount: 0x10, RecordNumber: 30, Stream: "$DATA"},
+		{FirstCluster: 0x104, ClusterCount: 2, RecordNumber: 32, Stream: "$DATA"},
+		{FirstCluster: 0x110, ClusterCount: 4, RecordNumber: 30, Stream: "$DATA", StreamOffset: 0x14 * 4096},
+	}
+	if got := clusterIndex.Extents(); !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
+
+func TestBuildClusterIndex_BadBytesPerCluster(t *testing.T) {
+	if _, err := BuildClusterIndex(bytes.NewReader(buildTestClusterIndexMft()), 0, DirectoryTree{5: "C:\\"}); err == nil {
+		t.Errorf("BuildClusterIndex() didn't return an error for 0 bytes per cluster")
+	}
+}
+
+func TestClusterIndex_Lookup(t *testing.T) {
+	clusterIndex, err := BuildClusterIndex(bytes.NewReader(buildTestClusterIndexMft()), 4096, DirectoryTree{5: "C:\\"})
+	if err != nil {
+		t.Fatalf("BuildClusterIndex() returned %v", err)
+	}
+	reportOwner := func(stream string, streamOffset int64) ClusterOwner {
+		return ClusterOwner{RecordNumber: 30, FullPath: "C:\\report.docx", Stream: stream, StreamOffset: streamOffset}
+	}
+	volumeBitmap := make(VolumeBitmap, 0x40)
+	volumeBitmap[0x100/8] = 0xFF
+
+	tests := []struct {
+		name         string
+		cluster      int64
+		volumeBitmap VolumeBitmap
+		want         ClusterLookup
+	}{
+		{
+			name:    "first cluster of a data run",
+			cluster: 0x100,
+			want:    ClusterLookup{Cluster: 0x100, VolumeOffset: 0x100 * 4096, Owners: []ClusterOwner{reportOwner("$DATA", 0)}},
+		},
+		{
+			name:    "data run after a sparse run",
+			cluster: 0x111,
+			want:    ClusterLookup{Cluster: 0x111, VolumeOffset: 0x111 * 4096, Owners: []ClusterOwner{reportOwner("$DATA", 0x15*4096)}},
+		},
+		{
+			name:    "segment in an extension record",
+			cluster: 0x27,
+			want:    ClusterLookup{Cluster: 0x27, VolumeOffset: 0x27 * 4096, Owners: []ClusterOwner{reportOwner("$DATA", 0x1F*4096)}},
+		},
+		{
+			name:    "alternate data stream",
+			cluster: 0x40,
+			want:    ClusterLookup{Cluster: 0x40, VolumeOffset: 0x40 * 4096, Owners: []ClusterOwner{reportOwner("$DATA:Zone.Identifier", 0)}},
+		},
+		{
+			name:         "cluster reused from a deleted file",
+			cluster:      0x105,
+			volumeBitmap: volumeBitmap,
+			want: ClusterLookup{Cluster: 0x105, VolumeOffset: 0x105 * 4096, Allocated: true, BitmapChecked: true, Owners: []ClusterOwner{
+				reportOwner("$DATA", 5*4096),
+				{RecordNumber: 32, FullPath: "C:\\old.txt", Stream: "$DATA", StreamOffset: 4096, Deleted: true},
+			}},
+		},
+		{
+			name:    "cluster past a data run that is overlapped by a longer one",
+			cluster: 0x108,
+			want:    ClusterLookup{Cluster: 0x108, VolumeOffset: 0x108 * 4096, Owners: []ClusterOwner{reportOwner("$DATA", 8*4096)}},
+		},
+		{
+			name:         "unowned and unallocated cluster",
+			cluster:      0x10,
+			volumeBitmap: volumeBitmap,
+			want:         ClusterLookup{Cluster: 0x10, VolumeOffset: 0x10 * 4096, BitmapChecked: true},
+		},
+		{
+			name:    "cluster past every data run",
+			cluster: 0x212,
+			want:    ClusterLookup{Cluster: 0x212, VolumeOffset: 0x212 * 4096},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := clusterIndex.Lookup(tt.cluster, tt.volumeBitmap)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestClusterIndex_LookupOffset(t *testing.T) {
+	clusterIndex, err := BuildClusterIndex(bytes.NewReader(buildTestClusterIndexMft()), 4096, DirectoryTree{5: "C:\\"})
+	if err != nil {
+		t.Fatalf("BuildClusterIndex() returned %v", err)
+	}
+	got := clusterIndex.LookupOffset(0x101*4096+0x123, nil)
+	want := ClusterLookup{
+		Cluster:      0x101,
+		VolumeOffset: 0x101*4096 + 0x123,
+		Owners:       []ClusterOwner{{RecordNumber: 30, FullPath: "C:\\report.docx", Stream: "$DATA", StreamOffset: 4096 + 0x123}},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
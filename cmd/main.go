@@ -7,7 +7,11 @@ import (
 	"fmt"
 	mft "github.com/AlecRandazzo/MFT-Parser"
 	log "github.com/sirupsen/logrus"
+	"io"
 	"os"
+	"strconv"
+	"strings"
+	"sync"
 )
 
 func init() {
@@ -19,27 +23,514 @@ func init() {
 }
 
 func main() {
+	err := run()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// Runs the modes selected by the command line flags and returns the first error.
+func run() error {
 	inFileName := flag.String("mft", "", "Input MFT file to parse.")
 	outFileName := flag.String("output", "parsed_mft.csv", "Output file.")
 	bytesPerCluster := flag.Int64("c", 4096, "Bytes per cluster. This is typically 4096.")
 	volumeLetter := flag.String("volume", "", "Volume letter. This will prepend the volume letter to all directory paths.")
+	attributeListVolumeFileName := flag.String("attrlistvolume", "", "Optional volume image used to read non-resident attribute lists, so the extension records of heavily fragmented files are merged into their base records.")
+	includeSecurity := flag.Bool("security", false, "Include owner SID, owner account name, and SDDL columns.")
+	sdsFileName := flag.String("sds", "", "Optional extracted $Secure:$SDS stream used to resolve security ids. Implies -security.")
+	sidMapFileName := flag.String("sidmap", "", "Optional json or csv SID to account name mapping file used to resolve owner account names. Implies -security.")
+	includeReparse := flag.Bool("reparse", false, "Include reparse tag and reparse target columns.")
+	includeObjectID := flag.Bool("objectid", false, "Include object id, birth id, and domain id columns.")
+	includeEA := flag.Bool("ea", false, "Include extended attribute and WSL metadata columns.")
+	includeVolume := flag.Bool("volumeinfo", false, "Include volume label and volume serial columns. The label is read from the $Volume record of the MFT.")
+	bootFileName := flag.String("boot", "", "Optional extracted $Boot file or volume image used to get the volume serial. Implies -volumeinfo.")
+	includeEFS := flag.Bool("efs", false, "Include EFS encryption, EFS user, EFS recovery agent, and TxF columns.")
+	includeAttributes := flag.Bool("attributes", false, "Include a column listing every attribute of a record by type name and attribute name.")
+	attrDefFileName := flag.String("attrdef", "", "Optional extracted $AttrDef file used to name attribute types. Implies -attributes.")
+	usnFileName := flag.String("usn", "", "Optional extracted $UsnJrnl:$J stream to parse. Paths are resolved against the MFT when a volume letter is provided.")
+	usnOutFileName := flag.String("usnoutput", "parsed_usn.csv", "Output file for the parsed $UsnJrnl:$J stream.")
+	usnAnomaliesFileName := flag.String("usnanomalies", "usn_anomalies.csv", "Output file for MFT records whose $STANDARD_INFORMATION usn disagrees with the $UsnJrnl:$J stream.")
+	mftBitmapFileName := flag.String("mftbitmap", "", "Optional extracted $MFT:$BITMAP stream or volume image used to check the in use flag of every record against the MFT bitmap.")
+	mftBitmapAnomaliesFileName := flag.String("mftbitmapanomalies", "mft_bitmap_anomalies.csv", "Output file for MFT records whose in use flag disagrees with the MFT bitmap.")
+	includeSlack := flag.Bool("recordslack", false, "Include the size of the slack after each record's end marker and the attributes left behind in it.")
+	fileSlackFileName := flag.String("fileslack", "", "Optional volume image to extract the file slack of every non-resident $DATA stream from.")
+	fileSlackOutFileName := flag.String("fileslackoutput", "file_slack.bin", "Output file the file slack is copied to, one stream after the other.")
+	fileSlackIndexFileName := flag.String("fileslackindex", "file_slack.csv", "Output file listing where each piece of file slack came from.")
+	clusterList := flag.String("cluster", "", "Optional comma separated list of clusters to find the owning record, path, and stream of. Values can be decimal or hex with a 0x prefix.")
+	offsetList := flag.String("offset", "", "Optional comma separated list of byte offsets, relative to the start of the NTFS partition, to find the owning record, path, and stream of. Values can be decimal or hex with a 0x prefix.")
+	volumeBitmapFileName := flag.String("volumebitmap", "", "Optional extracted $Bitmap file or volume image used to report whether the looked up clusters are allocated.")
+	clusterOutFileName := flag.String("clusteroutput", "cluster_owners.csv", "Output file for the owners of the clusters and offsets looked up with -cluster and -offset.")
+	unallocatedFileName := flag.String("unallocated", "", "Optional volume image whose unallocated cluster ranges are read from its $Bitmap file. Can be used without -mft.")
+	unallocatedOutFileName := flag.String("unallocatedoutput", "unallocated_clusters.csv", "Output file for the list of unallocated cluster ranges.")
+	unallocatedRawFileName := flag.String("unallocatedraw", "", "Optional output file the unallocated clusters are copied to, one range after the other.")
+	mftMirrFileName := flag.String("mftmirr", "", "Optional volume image whose $MFT system records are compared against $MFTMirr. Can be used without -mft.")
+	mftMirrOutFileName := flag.String("mftmirroutput", "mft_mirror_differences.csv", "Output file for the fields that differ between the $MFT and $MFTMirr.")
+	carveFileName := flag.String("carve", "", "Optional memory dump, unallocated clusters, pagefile, or other data to carve FILE and BAAD records from. Paths are resolved against the MFT when -mft is provided. Can be used without -mft.")
+	carveOutFileName := flag.String("carveoutput", "carved_records.csv", "Output file for the carved records.")
+	carveIndexFileName := flag.String("carveindx", "", "Optional memory dump, unallocated clusters, or other data to carve INDX records from. Their live and slack $FILE_NAME entries are written out. Can be used without -mft.")
+	carveIndexOutFileName := flag.String("carveindxoutput", "carved_index_entries.csv", "Output file for the carved INDX entries.")
+	carveUnaligned := flag.Bool("unaligned", false, "Look for records at every byte offset of the -carve and -carveindx inputs instead of only at sector aligned offsets.")
+	lenient := flag.Bool("lenient", false, "Keep every attribute of a record that parsed when another one of its attributes fails, and flag the record as partial along with the attributes that failed.")
+	diagnosticsFileName := flag.String("diagnostics", "", "Optional output file listing every record that was skipped or only partially parsed. A summary of the run is written to stderr.")
+	logFileName := flag.String("logfile", "", "Optional extracted $LogFile to parse into a timeline of operations. Paths are resolved against the MFT when a volume letter is provided.")
+	logFileOutFileName := flag.String("logfileoutput", "parsed_logfile.csv", "Output file for the $LogFile timeline.")
 	flag.Parse()
 
+	if *unallocatedFileName != "" {
+		unallocatedFile, err := os.Open(*unallocatedFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *unallocatedFileName, err)
+		}
+		defer unallocatedFile.Close()
+		clusterRanges, volumeBytesPerCluster, err := mft.ReadUnallocatedRanges(unallocatedFile)
+		if err != nil {
+			return fmt.Errorf("failed to read the unallocated clusters of %s: %w", *unallocatedFileName, err)
+		}
+		unallocatedOutFile, err := os.Create(*unallocatedOutFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *unallocatedOutFileName, err)
+		}
+		err = mft.WriteClusterRangesCsv(unallocatedOutFile, clusterRanges, volumeBytesPerCluster)
+		_ = unallocatedOutFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write the unallocated cluster ranges: %w", err)
+		}
+		if *unallocatedRawFileName != "" {
+			unallocatedRawFile, err := os.Create(*unallocatedRawFileName)
+			if err != nil {
+				return fmt.Errorf("failed to create output file %s: %w", *unallocatedRawFileName, err)
+			}
+			_, err = mft.ExtractClusterRanges(unallocatedRawFile, unallocatedFile, clusterRanges, volumeBytesPerCluster)
+			_ = unallocatedRawFile.Close()
+			if err != nil {
+				return fmt.Errorf("failed to extract the unallocated clusters of %s: %w", *unallocatedFileName, err)
+			}
+		}
+	}
+
+	if *mftMirrFileName != "" {
+		mftMirrFile, err := os.Open(*mftMirrFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *mftMirrFileName, err)
+		}
+		mftMirrorDifferences, err := mft.CompareMftMirror(mftMirrFile)
+		_ = mftMirrFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to compare the $MFT against the $MFTMirr of %s: %w", *mftMirrFileName, err)
+		}
+		mftMirrOutFile, err := os.Create(*mftMirrOutFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *mftMirrOutFileName, err)
+		}
+		err = mft.WriteMftMirrorCsv(mftMirrOutFile, mftMirrorDifferences)
+		_ = mftMirrOutFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write the $MFTMirr differences: %w", err)
+		}
+	}
+
+	if *carveIndexFileName != "" {
+		carveIndexFile, err := os.Open(*carveIndexFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *carveIndexFileName, err)
+		}
+		carvedIndexEntries, err := mft.CarveIndexRecords(carveIndexFile, mft.CarveOptions{Unaligned: *carveUnaligned})
+		_ = carveIndexFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to carve index records from %s: %w", *carveIndexFileName, err)
+		}
+		carveIndexOutFile, err := os.Create(*carveIndexOutFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *carveIndexOutFileName, err)
+		}
+		err = mft.WriteCarvedIndexEntriesCsv(carveIndexOutFile, carvedIndexEntries)
+		_ = carveIndexOutFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write the carved index entries: %w", err)
+		}
+	}
+
+	if *carveFileName != "" {
+		carveFile, err := os.Open(*carveFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *carveFileName, err)
+		}
+		defer carveFile.Close()
+		carveOutFile, err := os.Create(*carveOutFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *carveOutFileName, err)
+		}
+		defer carveOutFile.Close()
+
+		directoryTree := mft.DirectoryTree{}
+		if *inFileName != "" {
+			mftFile, err := os.Open(*inFileName)
+			if err != nil {
+				return fmt.Errorf("failed to open file %s: %w", *inFileName, err)
+			}
+			directoryTree, _ = mft.BuildDirectoryTree(mftFile, *volumeLetter)
+			_ = mftFile.Close()
+		}
+		carveWriter := mft.CsvResultWriter{IncludeCarved: true}
+		outputChannel := make(chan mft.UsefulMftFields, 100)
+		var waitGroup sync.WaitGroup
+		waitGroup.Add(1)
+		go carveWriter.ResultWriter(carveOutFile, &outputChannel, &waitGroup)
+		err = mft.CarveMftRecords(carveFile, *bytesPerCluster, directoryTree, &outputChannel, mft.CarveOptions{Unaligned: *carveUnaligned})
+		waitGroup.Wait()
+		if err != nil {
+			return fmt.Errorf("failed to carve records from %s: %w", *carveFileName, err)
+		}
+	}
+
+	// The modes above can be used without -mft, everything below parses it.
+	if *inFileName == "" {
+		return nil
+	}
+
 	outFile, err := os.Create(*outFileName)
 	if err != nil {
-		err = fmt.Errorf("failed to create output file %s: %w", *outFileName, err)
-		return
+		return fmt.Errorf("failed to create output file %s: %w", *outFileName, err)
 	}
 	defer outFile.Close()
 
 	inFile, err := os.Open(*inFileName)
 	if err != nil {
-		err = fmt.Errorf("failed to open file %s: %w", *inFileName, err)
-		return
+		return fmt.Errorf("failed to open file %s: %w", *inFileName, err)
 	}
 	defer inFile.Close()
 
-	writer := mft.CsvResultWriter{}
-	mft.ParseMFT(*volumeLetter, inFile, &writer, outFile, *bytesPerCluster)
+	var options mft.ParseOptions
+	if *attributeListVolumeFileName != "" {
+		attributeListVolumeFile, err := os.Open(*attributeListVolumeFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *attributeListVolumeFileName, err)
+		}
+		defer attributeListVolumeFile.Close()
+		options.VolumeImage = attributeListVolumeFile
+	}
 
+	if *sdsFileName != "" {
+		sdsFile, err := os.Open(*sdsFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *sdsFileName, err)
+		}
+		options.SecurityDescriptors, err = mft.BuildSecurityDescriptors(sdsFile)
+		_ = sdsFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse $SDS stream %s: %w", *sdsFileName, err)
+		}
+		*includeSecurity = true
+	}
+	if *sidMapFileName != "" {
+		sidMapFile, err := os.Open(*sidMapFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *sidMapFileName, err)
+		}
+		options.AccountNames, err = mft.LoadAccountNames(sidMapFile)
+		_ = sidMapFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to load account name mapping %s: %w", *sidMapFileName, err)
+		}
+		*includeSecurity = true
+	}
+
+	if *bootFileName != "" {
+		*includeVolume = true
+	}
+	if *includeVolume {
+		var volumeMetadata mft.VolumeMetadata
+		volumeMetadata, err = mft.ReadVolumeMetadataFromMFT(inFile, *bytesPerCluster)
+		if err != nil {
+			return fmt.Errorf("failed to read the $Volume record from %s: %w", *inFileName, err)
+		}
+		if *bootFileName != "" {
+			bootFile, err := os.Open(*bootFileName)
+			if err != nil {
+				return fmt.Errorf("failed to open file %s: %w", *bootFileName, err)
+			}
+			rawBootSector := make(mft.RawBootSector, 512)
+			_, err = io.ReadFull(bootFile, rawBootSector)
+			_ = bootFile.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read the boot sector from %s: %w", *bootFileName, err)
+			}
+			volumeMetadata.BootSector, err = rawBootSector.Parse()
+			if err != nil {
+				return fmt.Errorf("failed to parse the boot sector from %s: %w", *bootFileName, err)
+			}
+		}
+		options.Volume = &volumeMetadata
+	}
+
+	if *attrDefFileName != "" {
+		attrDefFile, err := os.Open(*attrDefFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *attrDefFileName, err)
+		}
+		options.AttributeDefinitions, err = mft.BuildAttributeDefinitions(attrDefFile)
+		_ = attrDefFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to parse $AttrDef %s: %w", *attrDefFileName, err)
+		}
+		*includeAttributes = true
+	}
+
+	if *mftBitmapFileName != "" {
+		mftBitmapFile, err := os.Open(*mftBitmapFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *mftBitmapFileName, err)
+		}
+
+		// A volume image is told apart from an extracted bitmap by the OEM id of its boot sector.
+		oemID := make([]byte, 11)
+		_, _ = mftBitmapFile.ReadAt(oemID, 0)
+		if string(oemID[3:]) == "NTFS    " {
+			options.MftBitmap, err = mft.ReadMftBitmap(mftBitmapFile)
+		} else {
+			options.MftBitmap, err = mft.LoadMftBitmap(mftBitmapFile)
+		}
+		_ = mftBitmapFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to read the MFT bitmap from %s: %w", *mftBitmapFileName, err)
+		}
+	}
+
+	var usnFile *os.File
+	var usnFileSize int64
+	if *usnFileName != "" {
+		usnFile, err = os.Open(*usnFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *usnFileName, err)
+		}
+		defer usnFile.Close()
+		usnFileInfo, err := usnFile.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to get the size of %s: %w", *usnFileName, err)
+		}
+		usnFileSize = usnFileInfo.Size()
+		options.UsnJournal, err = mft.BuildUsnJournalIndex(mft.NewUsnJournalReaderAt(usnFile, usnFileSize))
+		if err != nil {
+			return fmt.Errorf("failed to index the usn journal %s: %w", *usnFileName, err)
+		}
+	}
+
+	options.RecordSlack = *includeSlack
+	options.Lenient = *lenient
+	writer := mft.CsvResultWriter{
+		IncludeSecurity:   *includeSecurity,
+		IncludeReparse:    *includeReparse,
+		IncludeObjectID:   *includeObjectID,
+		IncludeEA:         *includeEA,
+		IncludeVolume:     *includeVolume,
+		IncludeEFS:        *includeEFS,
+		IncludeAttributes: *includeAttributes,
+		IncludeUsn:        usnFile != nil,
+		IncludeMftBitmap:  options.MftBitmap != nil,
+		IncludeSlack:      *includeSlack,
+		IncludePartial:    *lenient,
+	}
+	var diagnosticLog mft.DiagnosticLog
+	if *diagnosticsFileName != "" {
+		options.Diagnostics = &diagnosticLog
+	}
+	mft.ParseMFTWithOptions(*volumeLetter, inFile, &writer, outFile, *bytesPerCluster, options)
+	if *diagnosticsFileName != "" {
+		fmt.Fprintln(os.Stderr, diagnosticLog.Summary)
+		diagnosticsFile, err := os.Create(*diagnosticsFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *diagnosticsFileName, err)
+		}
+		err = mft.WriteRecordErrorsCsv(diagnosticsFile, diagnosticLog.RecordErrors)
+		_ = diagnosticsFile.Close()
+		if err != nil {
+			return fmt.Errorf("failed to write the diagnostics: %w", err)
+		}
+	}
+
+	if usnFile != nil {
+		var usnPathResolver *mft.UsnPathResolver
+		if *volumeLetter != "" {
+			_, _ = inFile.Seek(0, 0)
+			usnPathResolver, err = mft.BuildUsnPathResolver(inFile, *volumeLetter)
+			if err != nil {
+				return fmt.Errorf("failed to build the directory tree for the usn journal: %w", err)
+			}
+		}
+
+		usnOutFile, err := os.Create(*usnOutFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *usnOutFileName, err)
+		}
+		defer usnOutFile.Close()
+		err = mft.WriteUsnJournalCsv(usnOutFile, mft.NewUsnJournalReaderAt(usnFile, usnFileSize), usnPathResolver)
+		if err != nil {
+			return fmt.Errorf("failed to parse the usn journal %s: %w", *usnFileName, err)
+		}
+
+		_, _ = inFile.Seek(0, 0)
+		directoryTree, _ := mft.BuildDirectoryTree(inFile, *volumeLetter)
+		_, _ = inFile.Seek(0, 0)
+		usnAnomalies := mft.FindUsnAnomalies(inFile, *bytesPerCluster, directoryTree, options.UsnJournal, false)
+		usnAnomaliesFile, err := os.Create(*usnAnomaliesFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *usnAnomaliesFileName, err)
+		}
+		defer usnAnomaliesFile.Close()
+		err = mft.WriteUsnAnomalyCsv(usnAnomaliesFile, usnAnomalies)
+		if err != nil {
+			return fmt.Errorf("failed to write the usn anomalies: %w", err)
+		}
+	}
+
+	if options.MftBitmap != nil {
+		_, _ = inFile.Seek(0, 0)
+		directoryTree, _ := mft.BuildDirectoryTree(inFile, *volumeLetter)
+		_, _ = inFile.Seek(0, 0)
+		mftBitmapAnomalies := mft.FindMftBitmapAnomalies(inFile, *bytesPerCluster, directoryTree, options.MftBitmap)
+		mftBitmapAnomaliesFile, err := os.Create(*mftBitmapAnomaliesFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *mftBitmapAnomaliesFileName, err)
+		}
+		defer mftBitmapAnomaliesFile.Close()
+		err = mft.WriteMftBitmapAnomalyCsv(mftBitmapAnomaliesFile, mftBitmapAnomalies)
+		if err != nil {
+			return fmt.Errorf("failed to write the MFT bitmap anomalies: %w", err)
+		}
+	}
+
+	if *clusterList != "" || *offsetList != "" {
+		var volumeBitmap mft.VolumeBitmap
+		if *volumeBitmapFileName != "" {
+			volumeBitmapFile, err := os.Open(*volumeBitmapFileName)
+			if err != nil {
+				return fmt.Errorf("failed to open file %s: %w", *volumeBitmapFileName, err)
+			}
+
+			// A volume image is told apart from an extracted bitmap by the OEM id of its boot sector.
+			oemID := make([]byte, 11)
+			_, _ = volumeBitmapFile.ReadAt(oemID, 0)
+			if string(oemID[3:]) == "NTFS    " {
+				volumeBitmap, err = mft.ReadVolumeBitmap(volumeBitmapFile)
+			} else {
+				volumeBitmap, err = mft.LoadVolumeBitmap(volumeBitmapFile)
+			}
+			_ = volumeBitmapFile.Close()
+			if err != nil {
+				return fmt.Errorf("failed to read the volume bitmap from %s: %w", *volumeBitmapFileName, err)
+			}
+		}
+
+		_, _ = inFile.Seek(0, 0)
+		directoryTree, _ := mft.BuildDirectoryTree(inFile, *volumeLetter)
+		_, _ = inFile.Seek(0, 0)
+		clusterIndex, err := mft.BuildClusterIndex(inFile, *bytesPerCluster, directoryTree)
+		if err != nil {
+			return fmt.Errorf("failed to index the clusters of %s: %w", *inFileName, err)
+		}
+
+		var clusterLookups []mft.ClusterLookup
+		for _, value := range strings.Split(*clusterList, ",") {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			cluster, err := strconv.ParseInt(value, 0, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse cluster %s: %w", value, err)
+			}
+			clusterLookups = append(clusterLookups, clusterIndex.Lookup(cluster, volumeBitmap))
+		}
+		for _, value := range strings.Split(*offsetList, ",") {
+			if value = strings.TrimSpace(value); value == "" {
+				continue
+			}
+			volumeOffset, err := strconv.ParseInt(value, 0, 64)
+			if err != nil {
+				return fmt.Errorf("failed to parse offset %s: %w", value, err)
+			}
+			clusterLookups = append(clusterLookups, clusterIndex.LookupOffset(volumeOffset, volumeBitmap))
+		}
+
+		clusterOutFile, err := os.Create(*clusterOutFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *clusterOutFileName, err)
+		}
+		defer clusterOutFile.Close()
+		err = mft.WriteClusterLookupCsv(clusterOutFile, clusterLookups)
+		if err != nil {
+			return fmt.Errorf("failed to write the cluster owners: %w", err)
+		}
+	}
+
+	if *fileSlackFileName != "" {
+		fileSlackFile, err := os.Open(*fileSlackFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *fileSlackFileName, err)
+		}
+		defer fileSlackFile.Close()
+		fileSlackOutFile, err := os.Create(*fileSlackOutFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *fileSlackOutFileName, err)
+		}
+		defer fileSlackOutFile.Close()
+
+		_, _ = inFile.Seek(0, 0)
+		directoryTree, _ := mft.BuildDirectoryTree(inFile, *volumeLetter)
+		_, _ = inFile.Seek(0, 0)
+		fileSlacks, err := mft.ExtractFileSlack(fileSlackOutFile, inFile, fileSlackFile, *bytesPerCluster, directoryTree)
+		if err != nil {
+			return fmt.Errorf("failed to extract the file slack from %s: %w", *fileSlackFileName, err)
+		}
+		fileSlackIndexFile, err := os.Create(*fileSlackIndexFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *fileSlackIndexFileName, err)
+		}
+		defer fileSlackIndexFile.Close()
+		err = mft.WriteFileSlackCsv(fileSlackIndexFile, fileSlacks)
+		if err != nil {
+			return fmt.Errorf("failed to write the file slack index: %w", err)
+		}
+	}
+
+	if *logFileName != "" {
+		logFile, err := os.Open(*logFileName)
+		if err != nil {
+			return fmt.Errorf("failed to open file %s: %w", *logFileName, err)
+		}
+		defer logFile.Close()
+		logFileInfo, err := logFile.Stat()
+		if err != nil {
+			return fmt.Errorf("failed to get the size of %s: %w", *logFileName, err)
+		}
+		parsedLogFile, err := mft.ReadLogFile(logFile, logFileInfo.Size())
+		if err != nil {
+			return fmt.Errorf("failed to parse the $LogFile %s: %w", *logFileName, err)
+		}
+
+		timelineOptions := mft.LogFileTimelineOptions{BytesPerCluster: *bytesPerCluster}
+		if *volumeLetter != "" {
+			_, _ = inFile.Seek(0, 0)
+			timelineOptions.DirectoryTree, err = mft.BuildDirectoryTree(inFile, *volumeLetter)
+			if err != nil {
+				return fmt.Errorf("failed to build the directory tree for the $LogFile: %w", err)
+			}
+			_, _ = inFile.Seek(0, 0)
+			timelineOptions.FilePaths, err = mft.BuildFilePaths(inFile, *volumeLetter, *bytesPerCluster)
+			if err != nil {
+				return fmt.Errorf("failed to build the file paths for the $LogFile: %w", err)
+			}
+		}
+
+		logFileOutFile, err := os.Create(*logFileOutFileName)
+		if err != nil {
+			return fmt.Errorf("failed to create output file %s: %w", *logFileOutFileName, err)
+		}
+		defer logFileOutFile.Close()
+		err = mft.WriteLogFileTimelineCsv(logFileOutFile, parsedLogFile.Timeline(timelineOptions))
+		if err != nil {
+			return fmt.Errorf("failed to write the $LogFile timeline: %w", err)
+		}
+	}
+	return nil
 }
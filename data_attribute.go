@@ -3,12 +3,17 @@
 package mft
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	bin "github.com/AlecRandazzo/BinaryTransforms"
-	"strconv"
+	"io"
+	"os"
 )
 
+// MaxReadDataSize is the most that DataRuns.ReadData() will read into memory. The attributes read this way, such as attribute lists, $EA and the $MFT bitmap, are far smaller on any real volume, so a larger size means the record is corrupt.
+const MaxReadDataSize = 64 << 20
+
 // RawDataAttribute is an alias for a raw data attribute. Used as a receiver to the parse() method.
 type RawDataAttribute []byte
 
@@ -35,6 +40,7 @@ type unresolvedDataRun struct {
 	numberOrder      int
 	clusterOffset    int64
 	numberOfClusters int64
+	sparse           bool
 }
 
 type unresolvedDataRuns map[int]unresolvedDataRun
@@ -42,10 +48,11 @@ type unresolvedDataRuns map[int]unresolvedDataRun
 // DataRuns contains an ordered slice of parsed data runs
 type DataRuns map[int]DataRun
 
-// DataRun contains a parsed data run which contains the absolute offset of where the data run resides in the volume and the length of the data run.
+// DataRun contains a parsed data run which contains the absolute offset of where the data run resides in the volume and the length of the data run. Sparse data runs have no clusters on the volume and read back as zeros, their absolute offset is 0.
 type DataRun struct {
 	AbsoluteOffset int64
 	Length         int64
+	Sparse         bool
 }
 
 type dataRunSplit struct {
@@ -68,7 +75,7 @@ func (rawDataAttribute RawDataAttribute) Parse(bytesPerCluster int64) (nonReside
 	const offsetResidentFlag = 0x08
 	sizeOfRawDataAttribute := len(rawDataAttribute)
 	if sizeOfRawDataAttribute == 0 {
-		err = errors.New("received nil bytes")
+		err = ErrNilBytes
 		return
 	} else if sizeOfRawDataAttribute <= offsetResidentFlag {
 		err = errors.New("received bytes less than 8")
@@ -108,7 +115,7 @@ func (rawResidentDataAttribute RawResidentDataAttribute) Parse() (residentDataAt
 	const offsetResidentData = 0x18
 	sizeOfRawResidentDataAttribute := len(rawResidentDataAttribute)
 	if sizeOfRawResidentDataAttribute == 0 {
-		err = errors.New("received nil bytes")
+		err = ErrNilBytes
 		return
 	} else if sizeOfRawResidentDataAttribute < offsetResidentData {
 		err = fmt.Errorf("expected to receive at least 18 bytes, but received %d", sizeOfRawResidentDataAttribute)
@@ -126,7 +133,7 @@ func (rawNonResidentDataAttribute RawNonResidentDataAttribute) Parse(bytesPerClu
 	const offsetDataRunOffset = 0x20
 	sizeOfRawNonResidentDataAttribute := len(rawNonResidentDataAttribute)
 	if sizeOfRawNonResidentDataAttribute == 0 {
-		err = errors.New("received nil bytes")
+		err = ErrNilBytes
 		return
 	} else if sizeOfRawNonResidentDataAttribute <= offsetDataRunOffset {
 		err = fmt.Errorf("expected to receive at least 18 bytes, but received %d", sizeOfRawNonResidentDataAttribute)
@@ -147,7 +154,11 @@ func (rawNonResidentDataAttribute RawNonResidentDataAttribute) Parse(bytesPerClu
 	copy(rawDataRuns, rawNonResidentDataAttribute[dataRunOffset:])
 
 	// Send the bytes to be parsed
-	nonResidentDataAttributes.DataRuns, _ = rawDataRuns.Parse(bytesPerCluster)
+	nonResidentDataAttributes.DataRuns, err = rawDataRuns.Parse(bytesPerCluster)
+	if err != nil {
+		err = fmt.Errorf("failed to parse data runs: %w", err)
+		return
+	}
 
 	return
 }
@@ -156,7 +167,7 @@ func (rawNonResidentDataAttribute RawNonResidentDataAttribute) Parse(bytesPerClu
 func (rawDataRuns RawDataRuns) Parse(bytesPerCluster int64) (dataRuns DataRuns, err error) {
 	// Sanity check that the method received good data
 	if rawDataRuns == nil {
-		err = errors.New("received null bytes")
+		err = ErrNilBytes
 		return
 	}
 
@@ -164,47 +175,75 @@ func (rawDataRuns RawDataRuns) Parse(bytesPerCluster int64) (dataRuns DataRuns,
 	UnresolvedDataRun := unresolvedDataRun{}
 	UnresolvedDataRuns := make(unresolvedDataRuns)
 	sizeOfRawDataRuns := len(rawDataRuns)
-	dataRuns = make(DataRuns)
 	offset := 0
 	runCounter := 0
 
 	for {
 		// Checks to see if we reached the end of the data runs. If so, break out of the loop.
-		if rawDataRuns[offset] == 0x00 || sizeOfRawDataRuns < offset {
+		if offset >= sizeOfRawDataRuns || rawDataRuns[offset] == 0x00 {
 			break
-		} else {
-			// Take the first byte of a data run and send it to get split so we know how many bytes account for the
-			// data run's offset and how many account for the data run's length.
-			byteToBeSplit := rawDataRunSplitByte(rawDataRuns[offset])
-			dataRunSplit := byteToBeSplit.parse()
-			offset++
-
-			// Pull out the the bytes that account for the data runs offset2 and length
-			var lengthBytes, offsetBytes []byte
+		}
 
-			lengthBytes = make([]byte, len(rawDataRuns[offset:(offset+dataRunSplit.lengthByteCount)]))
-			copy(lengthBytes, rawDataRuns[offset:(offset+dataRunSplit.lengthByteCount)])
-			offsetBytes = make([]byte, len(rawDataRuns[(offset+dataRunSplit.lengthByteCount):(offset+dataRunSplit.lengthByteCount+dataRunSplit.offsetByteCount)]))
-			copy(offsetBytes, rawDataRuns[(offset+dataRunSplit.lengthByteCount):(offset+dataRunSplit.lengthByteCount+dataRunSplit.offsetByteCount)])
+		// Take the first byte of a data run and send it to get split so we know how many bytes account for the
+		// data run's offset and how many account for the data run's length.
+		dataRunStart := offset
+		byteToBeSplit := rawDataRunSplitByte(rawDataRuns[offset])
+		dataRunSplit, splitErr := byteToBeSplit.parse()
+		if splitErr != nil {
+			err = &DataRunError{Run: runCounter, Offset: dataRunStart, Err: splitErr}
+			return
+		}
+		offset++
+		if offset+dataRunSplit.lengthByteCount+dataRunSplit.offsetByteCount > sizeOfRawDataRuns {
+			err = &DataRunError{Run: runCounter, Offset: dataRunStart, Err: fmt.Errorf("runs past the end of the data runs: %w", ErrBadDataRun)}
+			return
+		}
 
-			// Convert the bytes for the data run offset and length to little endian int64
-			UnresolvedDataRun.clusterOffset, _ = bin.LittleEndianBinaryToInt64(offsetBytes)
-			UnresolvedDataRun.numberOfClusters, _ = bin.LittleEndianBinaryToInt64(lengthBytes)
+		// Pull out the the bytes that account for the data runs offset2 and length
+		var lengthBytes, offsetBytes []byte
+
+		lengthBytes = make([]byte, dataRunSplit.lengthByteCount)
+		copy(lengthBytes, rawDataRuns[offset:(offset+dataRunSplit.lengthByteCount)])
+		offsetBytes = make([]byte, dataRunSplit.offsetByteCount)
+		copy(offsetBytes, rawDataRuns[(offset+dataRunSplit.lengthByteCount):(offset+dataRunSplit.lengthByteCount+dataRunSplit.offsetByteCount)])
+
+		// Convert the bytes for the data run offset and length to little endian int64. Sparse data runs don't have an offset.
+		UnresolvedDataRun.sparse = dataRunSplit.offsetByteCount == 0
+		UnresolvedDataRun.clusterOffset = 0
+		if !UnresolvedDataRun.sparse {
+			UnresolvedDataRun.clusterOffset, err = bin.LittleEndianBinaryToInt64(offsetBytes)
+			if err != nil {
+				err = &DataRunError{Run: runCounter, Offset: dataRunStart, Err: fmt.Errorf("failed to parse the cluster offset: %w", err)}
+				return
+			}
+		}
+		UnresolvedDataRun.numberOfClusters, err = bin.LittleEndianBinaryToInt64(lengthBytes)
+		if err != nil {
+			err = &DataRunError{Run: runCounter, Offset: dataRunStart, Err: fmt.Errorf("failed to parse the cluster count: %w", err)}
+			return
+		}
 
-			// Append the data run to our data run struct
-			UnresolvedDataRuns[runCounter] = UnresolvedDataRun
+		// Append the data run to our data run struct
+		UnresolvedDataRuns[runCounter] = UnresolvedDataRun
 
-			// Increment the number order in preparation for the next data run.
-			runCounter++
+		// Increment the number order in preparation for the next data run.
+		runCounter++
 
-			// Set the offset tracker to the position of the next data run
-			offset = offset + dataRunSplit.lengthByteCount + dataRunSplit.offsetByteCount
-		}
+		// Set the offset tracker to the position of the next data run
+		offset = offset + dataRunSplit.lengthByteCount + dataRunSplit.offsetByteCount
 	}
 
 	// Resolve Data Runs
+	dataRuns = make(DataRuns)
 	dataRunOffset := int64(0)
 	for i := 0; i < len(UnresolvedDataRuns); i++ {
+		if UnresolvedDataRuns[i].sparse {
+			dataRuns[i] = DataRun{
+				Length: UnresolvedDataRuns[i].numberOfClusters * bytesPerCluster,
+				Sparse: true,
+			}
+			continue
+		}
 		dataRunOffset = dataRunOffset + (UnresolvedDataRuns[i].clusterOffset * bytesPerCluster)
 		dataRuns[i] = DataRun{
 			AbsoluteOffset: dataRunOffset,
@@ -214,19 +253,121 @@ func (rawDataRuns RawDataRuns) Parse(bytesPerCluster int64) (dataRuns DataRuns,
 	return
 }
 
-// This function will split the first byte of a data run.
+// ReadData reads the clusters referenced by the data runs receiver from the volume and returns the first size bytes. Data runs are read in order and sparse data runs are filled with zeros. The size and the data runs usually come straight from the record, so they're checked before anything is allocated: a size larger than MaxReadDataSize or than the data runs cover, and a data run past the end of the volume, return ErrBadDataRun. The end of the volume is only known for readers with a Size() method, such as bytes.Reader, and for regular files.
+func (dataRuns DataRuns) ReadData(volume io.ReaderAt, size int64) (data []byte, err error) {
+	// Sanity checks
+	if volume == nil {
+		err = errors.New("did not receive a volume to read from")
+		return
+	} else if size < 0 {
+		err = fmt.Errorf("received a negative size of %d", size)
+		return
+	} else if size > MaxReadDataSize {
+		err = fmt.Errorf("size of %d is larger than the maximum of %d: %w", size, MaxReadDataSize, ErrBadDataRun)
+		return
+	}
+	volumeSize, volumeSizeKnown := readerAtSize(volume)
+	if volumeSizeKnown && size > volumeSize {
+		err = fmt.Errorf("size of %d is larger than the volume: %w", size, ErrBadDataRun)
+		return
+	}
+	var totalLength int64
+	for i := 0; i < len(dataRuns) && totalLength < size; i++ {
+		dataRun, ok := dataRuns[i]
+		if !ok {
+			err = fmt.Errorf("data run %d is missing", i)
+			return
+		} else if dataRun.Length < 0 || dataRun.AbsoluteOffset < 0 {
+			err = fmt.Errorf("data run %d has a negative offset or length: %w", i, ErrBadDataRun)
+			return
+		} else if volumeSizeKnown && !dataRun.Sparse && (dataRun.AbsoluteOffset > volumeSize || dataRun.Length > volumeSize-dataRun.AbsoluteOffset) {
+			err = fmt.Errorf("data run %d at offset %d with a length of %d runs past the end of the volume: %w", i, dataRun.AbsoluteOffset, dataRun.Length, ErrBadDataRun)
+			return
+		}
+		if dataRun.Length > size-totalLength {
+			totalLength = size
+		} else {
+			totalLength += dataRun.Length
+		}
+	}
+	if totalLength < size {
+		err = fmt.Errorf("data runs only cover %d of %d bytes: %w", totalLength, size, ErrBadDataRun)
+		return
+	}
+
+	data = make([]byte, 0, size)
+	for i := 0; int64(len(data)) < size; i++ {
+		dataRun := dataRuns[i]
+		lengthToRead := dataRun.Length
+		if remaining := size - int64(len(data)); lengthToRead > remaining {
+			lengthToRead = remaining
+		}
+		buffer := make([]byte, lengthToRead)
+		if dataRun.Sparse {
+			data = append(data, buffer...)
+			continue
+		}
+		var bytesRead int
+		bytesRead, err = volume.ReadAt(buffer, dataRun.AbsoluteOffset)
+		if err == io.EOF && bytesRead == len(buffer) {
+			err = nil
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to read data run %d at offset %d: %w", i, dataRun.AbsoluteOffset, err)
+			return
+		}
+		data = append(data, buffer...)
+	}
+	return
+}
+
+// Returns the size of a reader at when it can be found out without reading it. Devices are reported as unknown since their size isn't in their file info.
+func readerAtSize(readerAt io.ReaderAt) (size int64, ok bool) {
+	switch reader := readerAt.(type) {
+	case interface{ Size() int64 }:
+		size, ok = reader.Size(), true
+	case interface{ Stat() (os.FileInfo, error) }:
+		fileInfo, err := reader.Stat()
+		if err == nil && fileInfo.Mode().IsRegular() {
+			size, ok = fileInfo.Size(), true
+		}
+	}
+	return
+}
+
+// Reads the content of a raw non-resident attribute from the volume using its data runs and actual size.
+func readNonResidentAttribute(volume io.ReaderAt, rawAttribute []byte, bytesPerCluster int64) (content []byte, err error) {
+	const offsetResidentFlag = 0x08
+	const offsetActualSize = 0x30
+	const lengthActualSize = 0x08
+
+	// Sanity checks
+	if len(rawAttribute) < offsetActualSize+lengthActualSize {
+		err = fmt.Errorf("expected a non-resident attribute of at least %d bytes, instead received %d", offsetActualSize+lengthActualSize, len(rawAttribute))
+		return
+	} else if RawResidencyFlag(rawAttribute[offsetResidentFlag]).Parse() == true {
+		err = errors.New("received a resident attribute")
+		return
+	}
+
+	nonResidentDataAttribute, err := RawNonResidentDataAttribute(rawAttribute).Parse(bytesPerCluster)
+	if err != nil {
+		err = fmt.Errorf("failed to parse the data runs of the attribute: %w", err)
+		return
+	}
+	actualSize := binary.LittleEndian.Uint64(rawAttribute[offsetActualSize : offsetActualSize+lengthActualSize])
+	content, err = nonResidentDataAttribute.DataRuns.ReadData(volume, int64(actualSize))
+	return
+}
+
+// This function will split the first byte of a data run. The high nibble is the number of bytes holding the data run's offset and the low nibble is the number of bytes holding its length.
 // See the following for a good write up on data runs: https://homepage.cs.uri.edu/~thenry/csc487/video/66_NTFS_Data_Runs.pdf
-func (rawDataRunSplitByte rawDataRunSplitByte) parse() (dataRunSplit dataRunSplit) {
-	// Convert the byte to a hex string
-	hexToSplit := fmt.Sprintf("%x", rawDataRunSplitByte)
-
-	if len(hexToSplit) == 1 {
-		dataRunSplit.offsetByteCount = 0
-		dataRunSplit.lengthByteCount, _ = strconv.Atoi(string(hexToSplit[0]))
-	} else {
-		// Split the hex string in half and return each half as an int
-		dataRunSplit.offsetByteCount, _ = strconv.Atoi(string(hexToSplit[0]))
-		dataRunSplit.lengthByteCount, _ = strconv.Atoi(string(hexToSplit[1]))
+func (rawDataRunSplitByte rawDataRunSplitByte) parse() (dataRunSplit dataRunSplit, err error) {
+	dataRunSplit.offsetByteCount = int(rawDataRunSplitByte >> 4)
+	dataRunSplit.lengthByteCount = int(rawDataRunSplitByte & 0x0F)
+	if dataRunSplit.lengthByteCount == 0 || dataRunSplit.lengthByteCount > 8 || dataRunSplit.offsetByteCount > 8 {
+		err = fmt.Errorf("invalid header byte 0x%02X: %w", byte(rawDataRunSplitByte), ErrBadDataRun)
+		return
 	}
 	return
 }
@@ -3,6 +3,8 @@
 package mft
 
 import (
+	"bytes"
+	"io"
 	"reflect"
 	"testing"
 )
@@ -173,6 +175,35 @@ func TestRawDataRuns_Parse(t *testing.T) {
 			},
 			wantErr: false,
 		},
+		{
+			name: "sparse data run",
+			args: args{
+				bytesPerCluster: 4096,
+			},
+			rawDataRuns: []byte{0x11, 0x02, 0x10, 0x01, 0x04, 0x11, 0x01, 0x02, 0x00},
+			want: DataRuns{
+				0: {AbsoluteOffset: 0x10 * 4096, Length: 2 * 4096},
+				1: {Length: 4 * 4096, Sparse: true},
+				2: {AbsoluteOffset: 0x12 * 4096, Length: 4096},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid header byte",
+			args: args{
+				bytesPerCluster: 4096,
+			},
+			rawDataRuns: []byte{0x11, 0x02, 0x10, 0x1A, 0x01, 0x00},
+			wantErr:     true,
+		},
+		{
+			name: "data run past the end",
+			args: args{
+				bytesPerCluster: 4096,
+			},
+			rawDataRuns: []byte{0x11, 0x02, 0x10, 0x33, 0x01, 0x02},
+			wantErr:     true,
+		},
 		{
 			name:        "null bytes",
 			wantErr:     true,
@@ -317,6 +348,7 @@ func Test_RawDataRunSplitByte_Parse(t *testing.T) {
 		name                string
 		got                 dataRunSplit
 		want                dataRunSplit
+		wantErr             bool
 		rawDataRunSplitByte rawDataRunSplitByte
 	}{
 		{
@@ -335,13 +367,145 @@ func Test_RawDataRunSplitByte_Parse(t *testing.T) {
 				lengthByteCount: 4,
 			},
 		},
+		{
+			name:                "Split 0x1A",
+			rawDataRunSplitByte: rawDataRunSplitByte(byte(0x1A)),
+			want: dataRunSplit{
+				offsetByteCount: 1,
+				lengthByteCount: 10,
+			},
+			wantErr: true,
+		},
+		{
+			name:                "Split 0x30",
+			rawDataRunSplitByte: rawDataRunSplitByte(byte(0x30)),
+			want: dataRunSplit{
+				offsetByteCount: 3,
+				lengthByteCount: 0,
+			},
+			wantErr: true,
+		},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			tt.got = tt.rawDataRunSplitByte.parse()
-			if !reflect.DeepEqual(tt.got, tt.want) {
+			var err error
+			tt.got, err = tt.rawDataRunSplitByte.parse()
+			if !reflect.DeepEqual(tt.got, tt.want) || (err != nil) != tt.wantErr {
 				t.Errorf("Test %v failed \ngot = %v, \nwant = %v", tt.name, tt.got, tt.want)
 			}
 		})
 	}
 }
+
+func TestDataRuns_ReadData(t *testing.T) {
+	volume := make([]byte, 16)
+	for i := range volume {
+		volume[i] = byte(i)
+	}
+	type args struct {
+		volume io.ReaderAt
+		size   int64
+	}
+	tests := []struct {
+		name     string
+		dataRuns DataRuns
+		args     args
+		wantData []byte
+		wantErr  bool
+	}{
+		{
+			name: "two data runs",
+			dataRuns: DataRuns{
+				0: DataRun{AbsoluteOffset: 8, Length: 4},
+				1: DataRun{AbsoluteOffset: 0, Length: 4},
+			},
+			args:     args{volume: bytes.NewReader(volume), size: 6},
+			wantData: []byte{8, 9, 10, 11, 0, 1},
+			wantErr:  false,
+		},
+		{
+			name: "sparse data run",
+			dataRuns: DataRuns{
+				0: DataRun{Length: 2, Sparse: true},
+				1: DataRun{AbsoluteOffset: 8, Length: 4},
+			},
+			args:     args{volume: bytes.NewReader(volume), size: 5},
+			wantData: []byte{0, 0, 8, 9, 10},
+			wantErr:  false,
+		},
+		{
+			name: "data runs smaller than size",
+			dataRuns: DataRuns{
+				0: DataRun{AbsoluteOffset: 8, Length: 4},
+			},
+			args:    args{volume: bytes.NewReader(volume), size: 6},
+			wantErr: true,
+		},
+		{
+			name: "size far beyond the data runs",
+			dataRuns: DataRuns{
+				0: DataRun{AbsoluteOffset: 8, Length: 4},
+			},
+			args:    args{volume: bytes.NewReader(volume), size: 1 << 62},
+			wantErr: true,
+		},
+		{
+			name: "huge sparse data run",
+			dataRuns: DataRuns{
+				0: DataRun{Length: 1 << 40, Sparse: true},
+			},
+			args:    args{volume: bytes.NewReader(volume), size: 1 << 39},
+			wantErr: true,
+		},
+		{
+			name: "huge data run",
+			dataRuns: DataRuns{
+				0: DataRun{AbsoluteOffset: 8, Length: 1 << 40},
+			},
+			args:    args{volume: bytes.NewReader(volume), size: 4},
+			wantErr: true,
+		},
+		{
+			name: "size above the maximum",
+			dataRuns: DataRuns{
+				0: DataRun{Length: MaxReadDataSize + 1, Sparse: true},
+			},
+			args:    args{volume: bytes.NewReader(make([]byte, 1)), size: MaxReadDataSize + 1},
+			wantErr: true,
+		},
+		{
+			name: "missing data run",
+			dataRuns: DataRuns{
+				1: DataRun{AbsoluteOffset: 8, Length: 4},
+			},
+			args:    args{volume: bytes.NewReader(volume), size: 4},
+			wantErr: true,
+		},
+		{
+			name: "data run beyond the volume",
+			dataRuns: DataRuns{
+				0: DataRun{AbsoluteOffset: 32, Length: 4},
+			},
+			args:    args{volume: bytes.NewReader(volume), size: 4},
+			wantErr: true,
+		},
+		{
+			name:     "nil volume",
+			dataRuns: DataRuns{},
+			args:     args{volume: nil, size: 4},
+			wantErr:  true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotData, err := tt.dataRuns.ReadData(tt.args.volume, tt.args.size)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadData() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(gotData, tt.wantData) {
+				t.Errorf("ReadData() gotData = %v, want %v", gotData, tt.wantData)
+			}
+		})
+	}
+}
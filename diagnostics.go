@@ -0,0 +1,204 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// RecordErrorReason is a uint8 for why a record was skipped or only partially parsed.
+type RecordErrorReason uint8
+
+// Record error reasons.
+const (
+	// RecordErrorNone means the record parsed.
+	RecordErrorNone RecordErrorReason = iota
+
+	// RecordErrorShortRead means the input ended part way through the record.
+	RecordErrorShortRead
+
+	// RecordErrorBadSignature means the record doesn't start with the FILE signature.
+	RecordErrorBadSignature
+
+	// RecordErrorBaad means chkdsk marked the record with the BAAD signature because it failed its fixups.
+	RecordErrorBaad
+
+	// RecordErrorBadHeader means the record has the FILE signature but its header or attributes couldn't be read.
+	RecordErrorBadHeader
+
+	// RecordErrorBadAttribute means an attribute of the record failed to parse. The rest of the record was kept.
+	RecordErrorBadAttribute
+)
+
+var recordErrorReasonNames = map[RecordErrorReason]string{
+	RecordErrorNone:         "",
+	RecordErrorShortRead:    "SHORT_READ",
+	RecordErrorBadSignature: "BAD_SIGNATURE",
+	RecordErrorBaad:         "BAAD",
+	RecordErrorBadHeader:    "BAD_HEADER",
+	RecordErrorBadAttribute: "BAD_ATTRIBUTE",
+}
+
+// String returns the name of the record error reason receiver. No reason is an empty string.
+func (recordErrorReason RecordErrorReason) String() string {
+	if name, ok := recordErrorReasonNames[recordErrorReason]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%X", uint8(recordErrorReason))
+}
+
+// RecordError describes a record that was skipped or only partially parsed. The offset is where the record starts in the input and the record number is its position in the MFT, since the record number in the header can't be trusted for a record that didn't parse. The attribute type is only set when a single attribute failed, in which case the record is partial rather than skipped.
+type RecordError struct {
+	Offset        int64
+	RecordNumber  uint32
+	AttributeType uint32
+	Reason        RecordErrorReason
+	Partial       bool
+	Err           error
+}
+
+// Error returns a message describing the record error receiver.
+func (recordError *RecordError) Error() string {
+	message := fmt.Sprintf("record %d at offset %d", recordError.RecordNumber, recordError.Offset)
+	// An attribute error already names its attribute.
+	var attributeError *AttributeError
+	if recordError.AttributeType != 0 && !errors.As(recordError.Err, &attributeError) {
+		message += fmt.Sprintf(", attribute %s", DefaultAttributeDefinitions.TypeName(recordError.AttributeType))
+	}
+	message += ": " + recordError.Reason.String()
+	if recordError.Err != nil {
+		message += ": " + recordError.Err.Error()
+	}
+	return message
+}
+
+// Unwrap returns the error that caused the record error receiver.
+func (recordError *RecordError) Unwrap() error {
+	return recordError.Err
+}
+
+// ParseSummary counts what happened to the records of a parsing run. Records that were skipped or partially parsed are also counted by reason.
+type ParseSummary struct {
+	RecordsRead    int
+	RecordsParsed  int
+	EmptyRecords   int
+	SkippedRecords int
+	PartialRecords int
+	Reasons        map[RecordErrorReason]int
+}
+
+// String returns a one line description of the parse summary receiver, e.g. "read 10 records: 7 parsed, 1 partial, 1 empty, 1 skipped (BAAD: 1)".
+func (parseSummary ParseSummary) String() string {
+	summary := fmt.Sprintf("read %d records: %d parsed, %d partial, %d empty, %d skipped", parseSummary.RecordsRead, parseSummary.RecordsParsed, parseSummary.PartialRecords, parseSummary.EmptyRecords, parseSummary.SkippedRecords)
+	var reasons []RecordErrorReason
+	for reason := range parseSummary.Reasons {
+		reasons = append(reasons, reason)
+	}
+	if len(reasons) == 0 {
+		return summary
+	}
+	sort.Slice(reasons, func(i, j int) bool {
+		return reasons[i] < reasons[j]
+	})
+	var counts []string
+	for _, reason := range reasons {
+		counts = append(counts, fmt.Sprintf("%s: %d", reason, parseSummary.Reasons[reason]))
+	}
+	return summary + " (" + strings.Join(counts, ", ") + ")"
+}
+
+// Counts a record error in the parse summary receiver.
+func (parseSummary *ParseSummary) add(recordError *RecordError) {
+	if parseSummary.Reasons == nil {
+		parseSummary.Reasons = make(map[RecordErrorReason]int)
+	}
+	parseSummary.Reasons[recordError.Reason]++
+	return
+}
+
+// DiagnosticHandler receives the records that a parsing run skipped or only partially parsed, followed by a summary of the run once it's over.
+type DiagnosticHandler interface {
+	HandleRecordError(recordError *RecordError)
+	HandleSummary(parseSummary ParseSummary)
+}
+
+// DiagnosticLog is a DiagnosticHandler that keeps every record error along with the summary.
+type DiagnosticLog struct {
+	RecordErrors []*RecordError
+	Summary      ParseSummary
+}
+
+// HandleRecordError adds the record error to the diagnostic log receiver.
+func (diagnosticLog *DiagnosticLog) HandleRecordError(recordError *RecordError) {
+	diagnosticLog.RecordErrors = append(diagnosticLog.RecordErrors, recordError)
+	return
+}
+
+// HandleSummary keeps the summary in the diagnostic log receiver.
+func (diagnosticLog *DiagnosticLog) HandleSummary(parseSummary ParseSummary) {
+	diagnosticLog.Summary = parseSummary
+	return
+}
+
+// Returns the record error for a record that couldn't be parsed. The signature is checked first since a BAAD record or a record of some other kind fails to parse long before its header is looked at.
+func newSkippedRecordError(rawMftRecord RawMasterFileTableRecord, offset int64, err error) (recordError *RecordError) {
+	recordError = &RecordError{
+		Offset:       offset,
+		RecordNumber: uint32(offset / defaultMftRecordSize),
+		Reason:       RecordErrorBadHeader,
+		Err:          err,
+	}
+	if len(rawMftRecord) < defaultMftRecordSize {
+		recordError.Reason = RecordErrorShortRead
+	} else if string(rawMftRecord[0x00:0x04]) == "BAAD" {
+		recordError.Reason = RecordErrorBaad
+	} else if string(rawMftRecord[0x00:0x04]) != "FILE" {
+		recordError.Reason = RecordErrorBadSignature
+	}
+	return
+}
+
+// Returns the record error for an attribute that failed to parse. The offset and record number of the record are left for the caller to fill in.
+func newAttributeRecordError(attributeError *AttributeError) (recordError *RecordError) {
+	recordError = &RecordError{
+		RecordNumber:  attributeError.RecordNumber,
+		AttributeType: attributeError.AttributeType,
+		Reason:        RecordErrorBadAttribute,
+		Partial:       true,
+		Err:           attributeError,
+	}
+	return
+}
+
+// IsPartial returns true when an attribute of the mft record receiver failed to parse.
+func (mftRecord MasterFileTableRecord) IsPartial() bool {
+	return len(mftRecord.AttributeErrors) != 0
+}
+
+// Fills in the partial record fields. Each attribute that failed is listed by type and offset along with the reason it failed.
+func (usefulMftFields *UsefulMftFields) setAttributeErrorFields(mftRecord MasterFileTableRecord) {
+	usefulMftFields.Partial = mftRecord.IsPartial()
+	var attributeErrors []string
+	for _, attributeError := range mftRecord.AttributeErrors {
+		location := fmt.Sprintf("%s@0x%X", DefaultAttributeDefinitions.TypeName(attributeError.AttributeType), attributeError.Offset)
+		if attributeError.RecordNumber != mftRecord.RecordHeader.RecordNumber {
+			location = fmt.Sprintf("%s in record %d", location, attributeError.RecordNumber)
+		}
+		attributeErrors = append(attributeErrors, fmt.Sprintf("%s: %v", location, attributeError.Err))
+	}
+	usefulMftFields.AttributeErrors = strings.Join(attributeErrors, ";")
+	return
+}
+
+// Returns true when every byte of the raw mft record receiver is 0, which is what a record that has never been used looks like.
+func (rawMftRecord RawMasterFileTableRecord) isEmpty() bool {
+	for _, value := range rawMftRecord {
+		if value != 0 {
+			return false
+		}
+	}
+	return true
+}
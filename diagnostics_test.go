@@ -0,0 +1,188 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRecordErrorReason_String(t *testing.T) {
+	tests := []struct {
+		name              string
+		recordErrorReason RecordErrorReason
+		want              string
+	}{
+		{
+			name:              "no reason",
+			recordErrorReason: RecordErrorNone,
+			want:              "",
+		},
+		{
+			name:              "BAAD record",
+			recordErrorReason: RecordErrorBaad,
+			want:              "BAAD",
+		},
+		{
+			name:              "unknown reason",
+			recordErrorReason: 0x10,
+			want:              "0x10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.recordErrorReason.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordError_Error(t *testing.T) {
+	tests := []struct {
+		name        string
+		recordError *RecordError
+		want        string
+	}{
+		{
+			name:        "skipped record",
+			recordError: &RecordError{Offset: 2048, RecordNumber: 2, Reason: RecordErrorBaad, Err: errors.New("this is not an mft record")},
+			want:        "record 2 at offset 2048: BAAD: this is not an mft record",
+		},
+		{
+			name:        "partial record",
+			recordError: &RecordError{Offset: 4096, RecordNumber: 40, AttributeType: 0x30, Reason: RecordErrorBadAttribute, Partial: true},
+			want:        "record 40 at offset 4096, attribute $FILE_NAME: BAD_ATTRIBUTE",
+		},
+		{
+			name:        "attribute error",
+			recordError: newAttributeRecordError(&AttributeError{RecordNumber: 40, AttributeType: 0x30, Offset: 0x98, Err: ErrNonResident}),
+			want:        "record 40 at offset 0: BAD_ATTRIBUTE: $FILE_NAME attribute at offset 152: attribute is non-resident",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.recordError.Error(); got != tt.want {
+				t.Errorf("Error() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecordError_Unwrap(t *testing.T) {
+	var err error = &RecordError{Offset: 5120, RecordNumber: 5, Reason: RecordErrorShortRead, Err: io.ErrUnexpectedEOF}
+	if !errors.Is(err, io.ErrUnexpectedEOF) {
+		t.Errorf("errors.Is() did not find io.ErrUnexpectedEOF in %v", err)
+	}
+	var recordError *RecordError
+	if !errors.As(err, &recordError) || recordError.Reason != RecordErrorShortRead {
+		t.Errorf("errors.As() did not find the record error in %v", err)
+	}
+}
+
+func TestParseSummary_String(t *testing.T) {
+	tests := []struct {
+		name         string
+		parseSummary ParseSummary
+		want         string
+	}{
+		{
+			name:         "clean run",
+			parseSummary: ParseSummary{RecordsRead: 3, RecordsParsed: 2, EmptyRecords: 1},
+			want:         "read 3 records: 2 parsed, 0 partial, 1 empty, 0 skipped",
+		},
+		{
+			name: "reasons in order",
+			parseSummary: ParseSummary{
+				RecordsRead:    6,
+				RecordsParsed:  2,
+				EmptyRecords:   1,
+				SkippedRecords: 3,
+				PartialRecords: 1,
+				Reasons:        map[RecordErrorReason]int{RecordErrorBadAttribute: 1, RecordErrorBaad: 2, RecordErrorShortRead: 1},
+			},
+			want: "read 6 records: 2 parsed, 1 partial, 1 empty, 3 skipped (SHORT_READ: 1, BAAD: 2, BAD_ATTRIBUTE: 1)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.parseSummary.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseMftRecordsWithOptions_diagnostics(t *testing.T) {
+	baadRecord := buildTestMftRecord(2, 0, buildTestFileNameAttribute("chkdsk.txt", 5))
+	copy(baadRecord, "BAAD")
+	garbageRecord := bytes.Repeat([]byte("junk"), 256)
+	brokenFileName := buildTestFileNameAttribute("broken.txt", 5)
+	brokenFileName[0x08] = 0x01
+	rawMft := bytes.Join([][]byte{
+		buildTestMftRecord(0, 0, buildTestFileNameAttribute("notes.txt", 5)),
+		make([]byte, 1024),
+		baadRecord,
+		garbageRecord,
+		buildTestMftRecord(40, 0, brokenFileName),
+		make([]byte, 100),
+	}, nil)
+
+	var diagnosticLog DiagnosticLog
+	outputChannel := make(chan UsefulMftFields, 10)
+	ParseMftRecordsWithOptions(bytes.NewReader(rawMft), 4096, DirectoryTree{5: "C:\\"}, &outputChannel, ParseOptions{Diagnostics: &diagnosticLog})
+	var parsed int
+	for range outputChannel {
+		parsed++
+	}
+	if parsed != 2 {
+		t.Errorf("ParseMftRecordsWithOptions() sent %d records, want 2", parsed)
+	}
+
+	type testRecordError struct {
+		Offset        int64
+		RecordNumber  uint32
+		AttributeType uint32
+		Reason        RecordErrorReason
+		Partial       bool
+	}
+	var got []testRecordError
+	for _, recordError := range diagnosticLog.RecordErrors {
+		if recordError.Err == nil {
+			t.Errorf("record error %v does not have a cause", recordError)
+		}
+		got = append(got, testRecordError{
+			Offset:        recordError.Offset,
+			RecordNumber:  recordError.RecordNumber,
+			AttributeType: recordError.AttributeType,
+			Reason:        recordError.Reason,
+			Partial:       recordError.Partial,
+		})
+	}
+	want := []testRecordError{
+		{Offset: 2048, RecordNumber: 2, Reason: RecordErrorBaad},
+		{Offset: 3072, RecordNumber: 3, Reason: RecordErrorBadSignature},
+		{Offset: 4096, RecordNumber: 40, AttributeType: 0x30, Reason: RecordErrorBadAttribute, Partial: true},
+		{Offset: 5120, RecordNumber: 5, Reason: RecordErrorShortRead},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+
+	wantSummary := ParseSummary{
+		RecordsRead:    6,
+		RecordsParsed:  2,
+		EmptyRecords:   1,
+		SkippedRecords: 3,
+		PartialRecords: 1,
+		Reasons:        map[RecordErrorReason]int{RecordErrorBaad: 1, RecordErrorBadSignature: 1, RecordErrorBadAttribute: 1, RecordErrorShortRead: 1},
+	}
+	if !reflect.DeepEqual(diagnosticLog.Summary, wantSummary) {
+		t.Errorf(cmp.Diff(diagnosticLog.Summary, wantSummary))
+	}
+}
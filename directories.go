@@ -13,6 +13,7 @@ import (
 // UnResolvedDirectory type is used for creating a directory tree.
 type UnResolvedDirectory struct {
 	RecordNumber       uint32
+	SequenceNumber     uint16
 	DirectoryName      string
 	ParentRecordNumber uint32
 }
@@ -23,6 +24,9 @@ type UnresolvedDirectoryTree map[uint32]UnResolvedDirectory
 // DirectoryTree contains a directory tree.
 type DirectoryTree map[uint32]string
 
+// DirectorySequenceNumbers maps the record number of a directory to the sequence number of its MFT record. A reference to a directory with a different sequence number was made to a directory that has since been deleted.
+type DirectorySequenceNumbers map[uint32]uint16
+
 // IsThisADirectory will quickly check the bytes of an MFT record to determine if it is a directory or not.
 func (rawMftRecord RawMasterFileTableRecord) IsThisADirectory() (result bool, err error) {
 	// Sanity checks that the method received good data
@@ -31,7 +35,7 @@ func (rawMftRecord RawMasterFileTableRecord) IsThisADirectory() (result bool, er
 	sizeOfRawMFTRecord := len(rawMftRecord)
 	if sizeOfRawMFTRecord == 0 {
 		result = false
-		err = errors.New("RawMasterFileTableRecord.IsThisADirectory() received nil bytes ")
+		err = fmt.Errorf("RawMasterFileTableRecord.IsThisADirectory(): %w", ErrNilBytes)
 		return
 	}
 	if sizeOfRawMFTRecord <= offsetRecordFlag {
@@ -54,7 +58,9 @@ func (rawMftRecord RawMasterFileTableRecord) IsThisADirectory() (result bool, er
 func ConvertRawMFTRecordToDirectory(rawMftRecord RawMasterFileTableRecord) (directory UnResolvedDirectory, err error) {
 	// Sanity checks that the raw mft record is a directory or not
 	result, err := rawMftRecord.IsThisADirectory()
-	if result == false {
+	if err != nil {
+		return
+	} else if result == false {
 		err = errors.New("this is not a directory")
 		return
 	}
@@ -67,7 +73,11 @@ func ConvertRawMFTRecordToDirectory(rawMftRecord RawMasterFileTableRecord) (dire
 	}
 
 	// Parse the raw record header
-	recordHeader, _ := rawRecordHeader.Parse()
+	recordHeader, err := rawRecordHeader.Parse()
+	if err != nil {
+		err = fmt.Errorf("failed to parse record header: %w", err)
+		return
+	}
 
 	// Get the raw mft attributes
 	rawAttributes, err := rawMftRecord.GetRawAttributes(recordHeader)
@@ -81,6 +91,7 @@ func ConvertRawMFTRecordToDirectory(rawMftRecord RawMasterFileTableRecord) (dire
 	for _, fileNameAttribute := range fileNameAttributes {
 		if strings.Contains(fileNameAttribute.FileNamespace, "WIN32") == true || strings.Contains(fileNameAttribute.FileNamespace, "POSIX") {
 			directory.RecordNumber = recordHeader.RecordNumber
+			directory.SequenceNumber = recordHeader.SequenceNumber
 			directory.DirectoryName = fileNameAttribute.FileName
 			directory.ParentRecordNumber = fileNameAttribute.ParentDirRecordNumber
 			break
@@ -149,6 +160,18 @@ func (unresolvedDirectoryTree UnresolvedDirectoryTree) Resolve(volumeLetter stri
 	return
 }
 
+// SequenceNumbers returns the sequence number of every directory in the unresolved directory tree receiver.
+func (unresolvedDirectoryTree UnresolvedDirectoryTree) SequenceNumbers() (sequenceNumbers DirectorySequenceNumbers) {
+	sequenceNumbers = make(DirectorySequenceNumbers)
+	for recordNumber, directory := range unresolvedDirectoryTree {
+		if directory.DirectoryName == "" && directory.ParentRecordNumber == 0 && directory.RecordNumber == 0 {
+			continue
+		}
+		sequenceNumbers[recordNumber] = directory.SequenceNumber
+	}
+	return
+}
+
 // BuildDirectoryTree takes an MFT and creates a directory tree where the slice keys are the mft record number of the UnResolvedDirectory. This record number is importable because files will reference it as its parent mft record number.
 func BuildDirectoryTree(reader io.Reader, volumeLetter string) (directoryTree DirectoryTree, err error) {
 	err = volumeLetterCheck(volumeLetter)
@@ -74,6 +74,7 @@ func Test_convertRawMFTRecordToDirectory(t *testing.T) {
 			wantErr: false,
 			wantDirectory: UnResolvedDirectory{
 				RecordNumber:       5,
+				SequenceNumber:     5,
 				DirectoryName:      ".",
 				ParentRecordNumber: 5,
 			},
@@ -125,11 +126,13 @@ func TestBuildUnresolvedDirectoryTree(t *testing.T) {
 			wantUnresolvedDirectoryTree: UnresolvedDirectoryTree{
 				5: UnResolvedDirectory{
 					RecordNumber:       5,
+					SequenceNumber:     5,
 					DirectoryName:      ".",
 					ParentRecordNumber: 5,
 				},
 				11: UnResolvedDirectory{
 					RecordNumber:       11,
+					SequenceNumber:     11,
 					DirectoryName:      "$Extend",
 					ParentRecordNumber: 5,
 				},
@@ -327,3 +330,15 @@ func Test_volumeLetterCheck(t *testing.T) {
 		})
 	}
 }
+
+func TestUnresolvedDirectoryTree_SequenceNumbers(t *testing.T) {
+	unresolvedDirectoryTree := UnresolvedDirectoryTree{
+		5:  UnResolvedDirectory{RecordNumber: 5, SequenceNumber: 5, DirectoryName: ".", ParentRecordNumber: 5},
+		40: UnResolvedDirectory{RecordNumber: 40, SequenceNumber: 3, DirectoryName: "Users", ParentRecordNumber: 5},
+		0:  UnResolvedDirectory{},
+	}
+	want := DirectorySequenceNumbers{5: 5, 40: 3}
+	if got := unresolvedDirectoryTree.SequenceNumbers(); !reflect.DeepEqual(got, want) {
+		t.Errorf("SequenceNumbers() = %v, want %v", got, want)
+	}
+}
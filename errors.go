@@ -0,0 +1,88 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Sentinel errors returned by the parsers of this package. They are always wrapped with more context, so check for them with errors.Is().
+var (
+	// ErrNilBytes is returned when a parser receives no bytes at all.
+	ErrNilBytes = errors.New("received nil bytes")
+
+	// ErrTruncated is returned when there are fewer bytes than the structure being parsed needs.
+	ErrTruncated = errors.New("truncated data")
+
+	// ErrNotMftRecord is returned for a record that doesn't start with the FILE signature.
+	ErrNotMftRecord = errors.New("not an mft record")
+
+	// ErrNotIndexRecord is returned for a record that doesn't start with the INDX signature.
+	ErrNotIndexRecord = errors.New("not an INDX record")
+
+	// ErrNonResident is returned when an attribute that is always resident, such as $STANDARD_INFORMATION or $FILE_NAME, is flagged as non-resident.
+	ErrNonResident = errors.New("attribute is non-resident")
+
+	// ErrFixupMismatch is returned when a sector of a multi-sector record doesn't end with the update sequence number, which happens when a write to the record was torn. See FixupError.
+	ErrFixupMismatch = errors.New("sector does not end with the update sequence number")
+
+	// ErrBadDataRun is returned for a data run with an invalid header byte or one that runs past the end of its attribute. See DataRunError.
+	ErrBadDataRun = errors.New("bad data run")
+
+	// ErrBadTimestamp is returned when a timestamp can't be parsed.
+	ErrBadTimestamp = errors.New("bad timestamp")
+
+	// ErrRecordOutOfRange is returned for a record number past the last record of an MFT.
+	ErrRecordOutOfRange = errors.New("record number out of range")
+)
+
+// AttributeError describes an attribute of an MFT record that failed to parse. The record number is the record the attribute is in, which is an extension record for the errors merged in by RecordAssembler, and the offset is where the attribute starts in that record. RawAttributes.Parse() doesn't know which record it's parsing or where the first attribute starts, so its record numbers are 0 and its offsets are relative to the first attribute until RawMasterFileTableRecord.Parse() fills them in.
+type AttributeError struct {
+	RecordNumber  uint32
+	AttributeType uint32
+	Offset        int
+	Err           error
+}
+
+// Error returns a message describing the attribute error receiver.
+func (attributeError *AttributeError) Error() string {
+	return fmt.Sprintf("%s attribute at offset %d: %v", DefaultAttributeDefinitions.TypeName(attributeError.AttributeType), attributeError.Offset, attributeError.Err)
+}
+
+// Unwrap returns the error that caused the attribute error receiver.
+func (attributeError *AttributeError) Unwrap() error {
+	return attributeError.Err
+}
+
+// DataRunError describes a data run that couldn't be decoded. The offset is where the data run starts in the data run list and the run is its position in the list.
+type DataRunError struct {
+	Run    int
+	Offset int
+	Err    error
+}
+
+// Error returns a message describing the data run error receiver.
+func (dataRunError *DataRunError) Error() string {
+	return fmt.Sprintf("data run %d at offset %d: %v", dataRunError.Run, dataRunError.Offset, dataRunError.Err)
+}
+
+// Unwrap returns the error that caused the data run error receiver.
+func (dataRunError *DataRunError) Unwrap() error {
+	return dataRunError.Err
+}
+
+// FixupError describes a sector of a multi-sector record whose last two bytes don't match the update sequence number. It matches ErrFixupMismatch with errors.Is().
+type FixupError struct {
+	Sector int
+}
+
+// Error returns a message describing the fixup error receiver.
+func (fixupError *FixupError) Error() string {
+	return fmt.Sprintf("sector %d does not end with the update sequence number", fixupError.Sector)
+}
+
+// Is returns true for ErrFixupMismatch.
+func (fixupError *FixupError) Is(target error) bool {
+	return target == ErrFixupMismatch
+}
@@ -0,0 +1,158 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestSentinelErrors(t *testing.T) {
+	baadRecord := buildTestMftRecord(2, 0, buildTestFileNameAttribute("chkdsk.txt", 5))
+	copy(baadRecord, "BAAD")
+	tornRecord := buildTestMultiSectorRecord(make([]byte, 1024), 512)
+	tornRecord[0x1FE] = 0x00
+	nonResidentFileName := buildTestFileNameAttribute("broken.txt", 5)
+	nonResidentFileName[0x08] = 0x01
+	tests := []struct {
+		name   string
+		parse  func() error
+		target error
+	}{
+		{
+			name: "nil record",
+			parse: func() error {
+				_, err := RawMasterFileTableRecord(nil).Parse(4096)
+				return err
+			},
+			target: ErrNilBytes,
+		},
+		{
+			name: "BAAD record",
+			parse: func() error {
+				_, err := baadRecord.Parse(4096)
+				return err
+			},
+			target: ErrNotMftRecord,
+		},
+		{
+			name: "short record header",
+			parse: func() error {
+				_, err := RawRecordHeader(make([]byte, 0x20)).Parse()
+				return err
+			},
+			target: ErrTruncated,
+		},
+		{
+			name: "not an index record",
+			parse: func() error {
+				_, err := RawIndexRecord(make([]byte, 4096)).Parse(512)
+				return err
+			},
+			target: ErrNotIndexRecord,
+		},
+		{
+			name: "torn write",
+			parse: func() error {
+				return applyFixups(tornRecord, 512)
+			},
+			target: ErrFixupMismatch,
+		},
+		{
+			name: "bad data run",
+			parse: func() error {
+				_, _, err := RawDataAttribute(buildTestNonResidentAttribute(0x80, 4096, []byte{0x11, 0x01, 0x10, 0x2F, 0x01})).Parse(4096)
+				return err
+			},
+			target: ErrBadDataRun,
+		},
+		{
+			name: "data run past the end of the volume",
+			parse: func() error {
+				_, err := DataRuns{0: DataRun{AbsoluteOffset: 4096, Length: 1 << 40}}.ReadData(bytes.NewReader(make([]byte, 8192)), 4096)
+				return err
+			},
+			target: ErrBadDataRun,
+		},
+		{
+			name: "non-resident filename",
+			parse: func() error {
+				_, _, _, _, err := RawAttributes{rawAttribute(nonResidentFileName)}.Parse(4096)
+				return err
+			},
+			target: ErrNonResident,
+		},
+		{
+			name: "short standard information",
+			parse: func() error {
+				_, err := RawStandardInformationAttribute(make([]byte, 0x30)).Parse()
+				return err
+			},
+			target: ErrTruncated,
+		},
+		{
+			name: "nil security descriptor",
+			parse: func() error {
+				_, err := RawSecurityDescriptorAttribute(nil).Parse()
+				return err
+			},
+			target: ErrNilBytes,
+		},
+		{
+			name: "short object id",
+			parse: func() error {
+				_, err := RawObjectIDAttribute([]byte{0x40, 0x00, 0x00, 0x00}).Parse()
+				return err
+			},
+			target: ErrTruncated,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.parse(); !errors.Is(err, tt.target) {
+				t.Errorf("got error %v, want %v", err, tt.target)
+			}
+		})
+	}
+}
+
+func TestAttributeError(t *testing.T) {
+	nonResidentFileName := buildTestFileNameAttribute("broken.txt", 5)
+	nonResidentFileName[0x08] = 0x01
+	badDataRuns := buildTestNonResidentAttribute(0x80, 4096, []byte{0x11, 0x01, 0x10, 0x2F, 0x01})
+	rawMftRecord := buildTestMftRecord(40, 0, buildTestFileNameAttribute("notes.txt", 5), nonResidentFileName)
+
+	var attributeError *AttributeError
+	mftRecord, err := rawMftRecord.Parse(4096)
+	if err != nil {
+		t.Fatalf("Parse() returned %v", err)
+	} else if len(mftRecord.AttributeErrors) != 1 || !errors.As(mftRecord.AttributeErrors[0], &attributeError) {
+		t.Fatalf("Parse() returned attribute errors %v, want one attribute error", mftRecord.AttributeErrors)
+	}
+	wantOffset := 0x38 + len(buildTestFileNameAttribute("notes.txt", 5))
+	if attributeError.RecordNumber != 40 || attributeError.AttributeType != 0x30 || attributeError.Offset != wantOffset || !errors.Is(attributeError, ErrNonResident) {
+		t.Errorf("got attribute error %+v, want a $FILE_NAME error in record 40 at offset %d", attributeError, wantOffset)
+	}
+
+	_, _, _, _, err = RawAttributes{rawAttribute(buildTestFileNameAttribute("notes.txt", 5)), rawAttribute(badDataRuns)}.Parse(4096)
+	var dataRunError *DataRunError
+	if !errors.As(err, &attributeError) || attributeError.AttributeType != 0x80 || attributeError.Offset != len(buildTestFileNameAttribute("notes.txt", 5)) {
+		t.Errorf("got error %v, want a $DATA attribute error", err)
+	} else if !errors.As(err, &dataRunError) || dataRunError.Run != 1 || dataRunError.Offset != 3 {
+		t.Errorf("got error %v, want an error for data run 1 at offset 3", err)
+	}
+}
+
+func TestFixupError(t *testing.T) {
+	record := buildTestMultiSectorRecord(bytes.Repeat([]byte{0x41}, 1024), 512)
+	record[0x3FF] = 0x00
+	err := applyFixups(record, 512)
+	var fixupError *FixupError
+	if !errors.As(err, &fixupError) || fixupError.Sector != 1 {
+		t.Errorf("applyFixups() returned %v, want a fixup error for sector 1", err)
+	}
+	if err.Error() != "sector 1 does not end with the update sequence number" {
+		t.Errorf("got message %q", err.Error())
+	}
+}
@@ -0,0 +1,310 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// RawExtendedAttributeInformation is a []byte alias for a raw $EA_INFORMATION attribute. Used with the Parse() method.
+// See here for the layout: https://flatcap.org/linux-ntfs/ntfs/attributes/ea_information.html
+type RawExtendedAttributeInformation []byte
+
+// RawExtendedAttributesAttribute is a []byte alias for a raw $EA attribute. Used with the Parse() and ParseNonResident() methods.
+// See here for the layout: https://flatcap.org/linux-ntfs/ntfs/attributes/ea.html
+type RawExtendedAttributesAttribute []byte
+
+// ExtendedAttributeInformation contains a parsed $EA_INFORMATION attribute.
+type ExtendedAttributeInformation struct {
+	PackedSize   uint16
+	NeedEACount  uint16
+	UnpackedSize uint32
+}
+
+// ExtendedAttributes is a slice of ExtendedAttribute.
+type ExtendedAttributes []ExtendedAttribute
+
+// ExtendedAttribute contains a single parsed extended attribute.
+type ExtendedAttribute struct {
+	Flags byte
+	Name  string
+	Value []byte
+}
+
+// LinuxMetadata contains the Linux file metadata that WSL stores in extended attributes. Fields that aren't stored are nil.
+type LinuxMetadata struct {
+	UID         *uint32
+	GID         *uint32
+	Mode        *uint32
+	DeviceMajor *uint32
+	DeviceMinor *uint32
+}
+
+// Extended attribute flags and names.
+const (
+	extendedAttributeNeedEA = 0x80
+
+	eaNameLxUID = "$LXUID"
+	eaNameLxGID = "$LXGID"
+	eaNameLxMod = "$LXMOD"
+	eaNameLxDev = "$LXDEV"
+
+	// Windows itself stores small values, such as the $KERNEL.PURGE and $CI extended attributes. Values larger than this are worth a look.
+	unusualExtendedAttributeValueSize = 0x400
+)
+
+// Parse parses the raw $EA_INFORMATION attribute receiver and returns the sizes of the extended attributes of the file.
+func (rawExtendedAttributeInformation RawExtendedAttributeInformation) Parse() (extendedAttributeInformation ExtendedAttributeInformation, err error) {
+	const offsetResidentFlag = 0x08
+	const offsetContentOffset = 0x14
+	const lengthContentOffset = 0x02
+	const lengthContent = 0x08
+
+	// Sanity checks
+	sizeOfRawAttribute := len(rawExtendedAttributeInformation)
+	if sizeOfRawAttribute == 0 {
+		err = fmt.Errorf("RawExtendedAttributeInformation.Parse(): %w", ErrNilBytes)
+		return
+	} else if rawExtendedAttributeInformation[0x00] != 0xD0 {
+		err = fmt.Errorf("RawExtendedAttributeInformation.Parse() received an attribute thats not an $EA_INFORMATION. Attribute magic number is %x", rawExtendedAttributeInformation[0x00])
+		return
+	} else if sizeOfRawAttribute < offsetContentOffset+lengthContentOffset {
+		err = fmt.Errorf("RawExtendedAttributeInformation.Parse() expected at least %d bytes, instead received %d: %w", offsetContentOffset+lengthContentOffset, sizeOfRawAttribute, ErrTruncated)
+		return
+	} else if RawResidencyFlag(rawExtendedAttributeInformation[offsetResidentFlag]).Parse() == false {
+		err = errors.New("RawExtendedAttributeInformation.Parse() received a non-resident $EA_INFORMATION")
+		return
+	}
+
+	contentOffset := int(binary.LittleEndian.Uint16(rawExtendedAttributeInformation[offsetContentOffset : offsetContentOffset+lengthContentOffset]))
+	if contentOffset+lengthContent > sizeOfRawAttribute {
+		err = fmt.Errorf("RawExtendedAttributeInformation.Parse() content offset %d is beyond the attribute", contentOffset)
+		return
+	}
+	content := rawExtendedAttributeInformation[contentOffset : contentOffset+lengthContent]
+	extendedAttributeInformation.PackedSize = binary.LittleEndian.Uint16(content[0x00:0x02])
+	extendedAttributeInformation.NeedEACount = binary.LittleEndian.Uint16(content[0x02:0x04])
+	extendedAttributeInformation.UnpackedSize = binary.LittleEndian.Uint32(content[0x04:0x08])
+	return
+}
+
+// Parse parses the raw resident $EA attribute receiver and returns its extended attributes. Non-resident $EA attributes are read with ParseNonResident().
+func (rawExtendedAttributesAttribute RawExtendedAttributesAttribute) Parse() (extendedAttributes ExtendedAttributes, err error) {
+	const offsetResidentFlag = 0x08
+	const offsetContentLength = 0x10
+	const lengthContentLength = 0x04
+	const offsetContentOffset = 0x14
+	const lengthContentOffset = 0x02
+
+	// Sanity checks
+	err = rawExtendedAttributesAttribute.sanityCheck(offsetContentOffset + lengthContentOffset)
+	if err != nil {
+		return
+	} else if RawResidencyFlag(rawExtendedAttributesAttribute[offsetResidentFlag]).Parse() == false {
+		err = errors.New("RawExtendedAttributesAttribute.Parse() received a non-resident $EA")
+		return
+	}
+
+	contentLength := int(binary.LittleEndian.Uint32(rawExtendedAttributesAttribute[offsetContentLength : offsetContentLength+lengthContentLength]))
+	contentOffset := int(binary.LittleEndian.Uint16(rawExtendedAttributesAttribute[offsetContentOffset : offsetContentOffset+lengthContentOffset]))
+	if contentOffset+contentLength > len(rawExtendedAttributesAttribute) {
+		err = fmt.Errorf("RawExtendedAttributesAttribute.Parse() content at offset %d with length %d is beyond the attribute", contentOffset, contentLength)
+		return
+	}
+	extendedAttributes, err = parseExtendedAttributes(rawExtendedAttributesAttribute[contentOffset : contentOffset+contentLength])
+	return
+}
+
+// ParseNonResident reads the extended attributes of a non-resident $EA attribute from the volume and returns them. The volume must start at the beginning of the NTFS partition since data runs are relative to it.
+func (rawExtendedAttributesAttribute RawExtendedAttributesAttribute) ParseNonResident(volume io.ReaderAt, bytesPerCluster int64) (extendedAttributes ExtendedAttributes, err error) {
+	// Sanity checks
+	err = rawExtendedAttributesAttribute.sanityCheck(1)
+	if err != nil {
+		return
+	}
+
+	rawEntries, err := readNonResidentAttribute(volume, rawExtendedAttributesAttribute, bytesPerCluster)
+	if err != nil {
+		err = fmt.Errorf("failed to read the $EA from the volume: %w", err)
+		return
+	}
+	extendedAttributes, err = parseExtendedAttributes(rawEntries)
+	return
+}
+
+// Sanity checks shared by the $EA parse methods.
+func (rawExtendedAttributesAttribute RawExtendedAttributesAttribute) sanityCheck(minimumSize int) (err error) {
+	sizeOfRawAttribute := len(rawExtendedAttributesAttribute)
+	if sizeOfRawAttribute == 0 {
+		err = fmt.Errorf("RawExtendedAttributesAttribute: %w", ErrNilBytes)
+	} else if rawExtendedAttributesAttribute[0x00] != 0xE0 {
+		err = fmt.Errorf("RawExtendedAttributesAttribute received an attribute thats not an $EA. Attribute magic number is %x", rawExtendedAttributesAttribute[0x00])
+	} else if sizeOfRawAttribute < minimumSize {
+		err = fmt.Errorf("RawExtendedAttributesAttribute expected at least %d bytes, instead received %d: %w", minimumSize, sizeOfRawAttribute, ErrTruncated)
+	}
+	return
+}
+
+// Walks a byte slice of FILE_FULL_EA_INFORMATION entries and returns the parsed extended attributes.
+// See here for the layout of an entry: https://docs.microsoft.com/en-us/windows-hardware/drivers/ddi/wdm/ns-wdm-_file_full_ea_information
+func parseExtendedAttributes(rawEntries []byte) (extendedAttributes ExtendedAttributes, err error) {
+	const offsetNextEntryOffset = 0x00
+	const offsetFlags = 0x04
+	const offsetNameLength = 0x05
+	const offsetValueLength = 0x06
+	const offsetName = 0x08
+
+	sizeOfRawEntries := len(rawEntries)
+	offset := 0
+	for offset+offsetName <= sizeOfRawEntries {
+		rawEntry := rawEntries[offset:]
+		nextEntryOffset := int(binary.LittleEndian.Uint32(rawEntry[offsetNextEntryOffset : offsetNextEntryOffset+0x04]))
+		nameLength := int(rawEntry[offsetNameLength])
+		valueLength := int(binary.LittleEndian.Uint16(rawEntry[offsetValueLength : offsetValueLength+0x02]))
+		if nextEntryOffset == 0 && nameLength == 0 && valueLength == 0 {
+			// Zero padding after the last entry
+			break
+		}
+
+		// The name is null terminated and the value follows the null.
+		offsetValue := offsetName + nameLength + 1
+		if offsetValue+valueLength > len(rawEntry) {
+			err = fmt.Errorf("extended attribute at offset %d with name length %d and value length %d is beyond the %d bytes of entries", offset, nameLength, valueLength, sizeOfRawEntries)
+			return
+		}
+		extendedAttribute := ExtendedAttribute{
+			Flags: rawEntry[offsetFlags],
+			Name:  string(rawEntry[offsetName : offsetName+nameLength]),
+			Value: make([]byte, valueLength),
+		}
+		copy(extendedAttribute.Value, rawEntry[offsetValue:offsetValue+valueLength])
+		extendedAttributes = append(extendedAttributes, extendedAttribute)
+
+		if nextEntryOffset == 0 {
+			break
+		}
+		offset += nextEntryOffset
+	}
+	return
+}
+
+// IsUnusual reports whether the size of the extended attribute is out of the ordinary. WSL extended attributes must be their expected size and any other value must be smaller than 1KB.
+func (extendedAttribute ExtendedAttribute) IsUnusual() bool {
+	switch extendedAttribute.Name {
+	case eaNameLxUID, eaNameLxGID, eaNameLxMod:
+		return len(extendedAttribute.Value) != 0x04
+	case eaNameLxDev:
+		return len(extendedAttribute.Value) != 0x08
+	}
+	return len(extendedAttribute.Value) > unusualExtendedAttributeValueSize
+}
+
+// NeedEA reports whether the file can't be interpreted without the extended attribute.
+func (extendedAttribute ExtendedAttribute) NeedEA() bool {
+	return extendedAttribute.Flags&extendedAttributeNeedEA != 0
+}
+
+// Names returns the names of the extended attributes receiver.
+func (extendedAttributes ExtendedAttributes) Names() (names []string) {
+	for _, extendedAttribute := range extendedAttributes {
+		names = append(names, extendedAttribute.Name)
+	}
+	return
+}
+
+// IsUnusual reports whether any of the extended attributes receiver have an unusual size.
+func (extendedAttributes ExtendedAttributes) IsUnusual() bool {
+	for _, extendedAttribute := range extendedAttributes {
+		if extendedAttribute.IsUnusual() {
+			return true
+		}
+	}
+	return false
+}
+
+// Linux returns the WSL Linux metadata stored in the extended attributes receiver. Extended attributes with an unexpected size are ignored.
+func (extendedAttributes ExtendedAttributes) Linux() (linuxMetadata LinuxMetadata, ok bool) {
+	for _, extendedAttribute := range extendedAttributes {
+		if extendedAttribute.IsUnusual() {
+			continue
+		}
+		value := extendedAttribute.Value
+		switch extendedAttribute.Name {
+		case eaNameLxUID:
+			uid := binary.LittleEndian.Uint32(value)
+			linuxMetadata.UID = &uid
+		case eaNameLxGID:
+			gid := binary.LittleEndian.Uint32(value)
+			linuxMetadata.GID = &gid
+		case eaNameLxMod:
+			mode := binary.LittleEndian.Uint32(value)
+			linuxMetadata.Mode = &mode
+		case eaNameLxDev:
+			major := binary.LittleEndian.Uint32(value[0x00:0x04])
+			minor := binary.LittleEndian.Uint32(value[0x04:0x08])
+			linuxMetadata.DeviceMajor = &major
+			linuxMetadata.DeviceMinor = &minor
+		default:
+			continue
+		}
+		ok = true
+	}
+	return
+}
+
+// FormatLinuxMode returns a Linux mode in the form ls -l prints it, e.g. -rwxr-xr-x.
+func FormatLinuxMode(mode uint32) string {
+	var fileType byte
+	switch mode & 0xF000 {
+	case 0x1000:
+		fileType = 'p'
+	case 0x2000:
+		fileType = 'c'
+	case 0x4000:
+		fileType = 'd'
+	case 0x6000:
+		fileType = 'b'
+	case 0xA000:
+		fileType = 'l'
+	case 0xC000:
+		fileType = 's'
+	default:
+		fileType = '-'
+	}
+
+	formatted := []byte{fileType}
+	const permissions = "rwxrwxrwx"
+	for index := 0; index < len(permissions); index++ {
+		if mode&(1<<uint(8-index)) != 0 {
+			formatted = append(formatted, permissions[index])
+		} else {
+			formatted = append(formatted, '-')
+		}
+	}
+
+	// The setuid, setgid, and sticky bits replace the execute bit of the owner, group, and other.
+	specialBits := []struct {
+		mask     uint32
+		index    int
+		withExec byte
+		noExec   byte
+	}{
+		{0x800, 3, 's', 'S'},
+		{0x400, 6, 's', 'S'},
+		{0x200, 9, 't', 'T'},
+	}
+	for _, specialBit := range specialBits {
+		if mode&specialBit.mask == 0 {
+			continue
+		}
+		if formatted[specialBit.index] == 'x' {
+			formatted[specialBit.index] = specialBit.withExec
+		} else {
+			formatted[specialBit.index] = specialBit.noExec
+		}
+	}
+	return string(formatted)
+}
@@ -0,0 +1,282 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds a resident attribute header with the content right after it.
+func buildTestResidentAttribute(attributeType byte, content []byte) []byte {
+	const contentOffset = 0x18
+	rawAttribute := make([]byte, contentOffset+len(content))
+	rawAttribute[0x00] = attributeType
+	binary.LittleEndian.PutUint32(rawAttribute[0x04:], uint32(len(rawAttribute)))
+	binary.LittleEndian.PutUint32(rawAttribute[0x10:], uint32(len(content)))
+	binary.LittleEndian.PutUint16(rawAttribute[0x14:], contentOffset)
+	copy(rawAttribute[contentOffset:], content)
+	return rawAttribute
+}
+
+// Builds FILE_FULL_EA_INFORMATION entries padded to 4 byte boundaries. The last entry has a next entry offset of 0.
+func buildTestExtendedAttributeEntries(extendedAttributes ...ExtendedAttribute) []byte {
+	var rawEntries []byte
+	for index, extendedAttribute := range extendedAttributes {
+		size := (0x08 + len(extendedAttribute.Name) + 1 + len(extendedAttribute.Value) + 3) &^ 3
+		rawEntry := make([]byte, size)
+		if index < len(extendedAttributes)-1 {
+			binary.LittleEndian.PutUint32(rawEntry[0x00:], uint32(size))
+		}
+		rawEntry[0x04] = extendedAttribute.Flags
+		rawEntry[0x05] = byte(len(extendedAttribute.Name))
+		binary.LittleEndian.PutUint16(rawEntry[0x06:], uint16(len(extendedAttribute.Value)))
+		copy(rawEntry[0x08:], extendedAttribute.Name)
+		copy(rawEntry[0x08+len(extendedAttribute.Name)+1:], extendedAttribute.Value)
+		rawEntries = append(rawEntries, rawEntry...)
+	}
+	return rawEntries
+}
+
+var testWslExtendedAttributes = ExtendedAttributes{
+	{Name: "$LXUID", Value: []byte{0xe8, 0x03, 0x00, 0x00}},
+	{Name: "$LXGID", Value: []byte{0xe8, 0x03, 0x00, 0x00}},
+	{Name: "$LXMOD", Value: []byte{0xed, 0x81, 0x00, 0x00}},
+}
+
+func TestRawExtendedAttributeInformation_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     RawExtendedAttributeInformation
+		want    ExtendedAttributeInformation
+		wantErr bool
+	}{
+		{
+			name: "resident",
+			raw:  buildTestResidentAttribute(0xD0, []byte{0x38, 0x00, 0x01, 0x00, 0x44, 0x00, 0x00, 0x00}),
+			want: ExtendedAttributeInformation{PackedSize: 0x38, NeedEACount: 1, UnpackedSize: 0x44},
+		},
+		{
+			name:    "nil bytes",
+			wantErr: true,
+		},
+		{
+			name:    "wrong attribute type",
+			raw:     buildTestResidentAttribute(0xE0, make([]byte, 8)),
+			wantErr: true,
+		},
+		{
+			name:    "content too short",
+			raw:     buildTestResidentAttribute(0xD0, make([]byte, 4)),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawExtendedAttributesAttribute_Parse(t *testing.T) {
+	rawEntries := buildTestExtendedAttributeEntries(testWslExtendedAttributes...)
+	nonResident := buildTestResidentAttribute(0xE0, rawEntries)
+	nonResident[0x08] = 0x01
+	truncatedEntries := rawEntries[:len(rawEntries)-8]
+
+	tests := []struct {
+		name    string
+		raw     RawExtendedAttributesAttribute
+		want    ExtendedAttributes
+		wantErr bool
+	}{
+		{
+			name: "wsl metadata",
+			raw:  buildTestResidentAttribute(0xE0, rawEntries),
+			want: testWslExtendedAttributes,
+		},
+		{
+			name: "trailing padding",
+			raw:  buildTestResidentAttribute(0xE0, append(buildTestExtendedAttributeEntries(ExtendedAttribute{Flags: 0x80, Name: "$CI.CATALOGHINT", Value: []byte{0x01, 0x00}}), make([]byte, 8)...)),
+			want: ExtendedAttributes{{Flags: 0x80, Name: "$CI.CATALOGHINT", Value: []byte{0x01, 0x00}}},
+		},
+		{
+			name:    "entry beyond content",
+			raw:     buildTestResidentAttribute(0xE0, truncatedEntries),
+			want:    testWslExtendedAttributes[:2],
+			wantErr: true,
+		},
+		{
+			name:    "non-resident",
+			raw:     nonResident,
+			wantErr: true,
+		},
+		{
+			name:    "wrong attribute type",
+			raw:     buildTestResidentAttribute(0xD0, rawEntries),
+			wantErr: true,
+		},
+		{
+			name:    "nil bytes",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestRawExtendedAttributesAttribute_ParseNonResident(t *testing.T) {
+	payload := ExtendedAttribute{Name: "PAYLOAD", Value: bytes.Repeat([]byte{0x90}, 0x1800)}
+	rawEntries := buildTestExtendedAttributeEntries(payload)
+	volume := make([]byte, 4096*3)
+	copy(volume[4096:], rawEntries)
+
+	tests := []struct {
+		name    string
+		raw     RawExtendedAttributesAttribute
+		want    ExtendedAttributes
+		wantErr bool
+	}{
+		{
+			name: "non-resident",
+			raw:  buildTestNonResidentAttribute(0xE0, uint64(len(rawEntries)), []byte{0x11, 0x02, 0x01}),
+			want: ExtendedAttributes{payload},
+		},
+		{
+			name:    "resident",
+			raw:     buildTestResidentAttribute(0xE0, rawEntries[:0x10]),
+			wantErr: true,
+		},
+		{
+			name:    "not an $EA",
+			raw:     buildTestNonResidentAttribute(0x80, uint64(len(rawEntries)), []byte{0x11, 0x02, 0x01}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.ParseNonResident(bytes.NewReader(volume), 4096)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseNonResident() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseNonResident() got %d extended attributes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestExtendedAttributes_Linux(t *testing.T) {
+	uid := uint32(1000)
+	mode := uint32(0x81ed)
+	major := uint32(1)
+	minor := uint32(3)
+
+	tests := []struct {
+		name               string
+		extendedAttributes ExtendedAttributes
+		wantLinuxMetadata  LinuxMetadata
+		wantOk             bool
+		wantUnusual        bool
+	}{
+		{
+			name:               "uid gid and mode",
+			extendedAttributes: testWslExtendedAttributes,
+			wantLinuxMetadata:  LinuxMetadata{UID: &uid, GID: &uid, Mode: &mode},
+			wantOk:             true,
+		},
+		{
+			name:               "device",
+			extendedAttributes: ExtendedAttributes{{Name: "$LXDEV", Value: []byte{0x01, 0, 0, 0, 0x03, 0, 0, 0}}},
+			wantLinuxMetadata:  LinuxMetadata{DeviceMajor: &major, DeviceMinor: &minor},
+			wantOk:             true,
+		},
+		{
+			name:               "wrong size wsl value is ignored and unusual",
+			extendedAttributes: ExtendedAttributes{{Name: "$LXUID", Value: make([]byte, 64)}},
+			wantUnusual:        true,
+		},
+		{
+			name:               "large value is unusual",
+			extendedAttributes: ExtendedAttributes{{Name: "$KERNEL.PURGE.ESBCACHE", Value: make([]byte, 0x80)}, {Name: "X", Value: make([]byte, 0x401)}},
+			wantUnusual:        true,
+		},
+		{
+			name:               "no wsl metadata",
+			extendedAttributes: ExtendedAttributes{{Name: "$KERNEL.PURGE.ESBCACHE", Value: make([]byte, 0x80)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLinuxMetadata, gotOk := tt.extendedAttributes.Linux()
+			if !reflect.DeepEqual(gotLinuxMetadata, tt.wantLinuxMetadata) || gotOk != tt.wantOk {
+				t.Errorf("Linux() got = %v, %v, want %v, %v", gotLinuxMetadata, gotOk, tt.wantLinuxMetadata, tt.wantOk)
+			}
+			if gotUnusual := tt.extendedAttributes.IsUnusual(); gotUnusual != tt.wantUnusual {
+				t.Errorf("IsUnusual() got = %v, want %v", gotUnusual, tt.wantUnusual)
+			}
+		})
+	}
+}
+
+func TestFormatLinuxMode(t *testing.T) {
+	tests := []struct {
+		mode uint32
+		want string
+	}{
+		{mode: 0100755, want: "-rwxr-xr-x"},
+		{mode: 0040700, want: "drwx------"},
+		{mode: 0120777, want: "lrwxrwxrwx"},
+		{mode: 0104755, want: "-rwsr-xr-x"},
+		{mode: 0041777, want: "drwxrwxrwt"},
+		{mode: 0102644, want: "-rw-r-Sr--"},
+		{mode: 0020620, want: "crw--w----"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := FormatLinuxMode(tt.mode); got != tt.want {
+				t.Errorf("FormatLinuxMode() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsefulMftFields_setExtendedAttributeFields(t *testing.T) {
+	var got UsefulMftFields
+	got.setExtendedAttributeFields(MasterFileTableRecord{
+		ExtendedAttributeInformation: ExtendedAttributeInformation{UnpackedSize: 0x38},
+		ExtendedAttributes:           testWslExtendedAttributes,
+	})
+	want := UsefulMftFields{
+		EANames: "$LXUID;$LXGID;$LXMOD",
+		EASize:  0x38,
+		LxUID:   "1000",
+		LxGID:   "1000",
+		LxMode:  "-rwxr-xr-x",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
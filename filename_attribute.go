@@ -4,7 +4,7 @@ package mft
 
 import (
 	"encoding/binary"
-	"errors"
+	"fmt"
 	bin "github.com/AlecRandazzo/BinaryTransforms"
 	ts "github.com/AlecRandazzo/Timestamp-Parser"
 	"time"
@@ -110,15 +110,15 @@ func (rawFileNameAttribute RawFileNameAttribute) Parse() (filenameAttribute File
 
 	// Sanity check that we have data to work with
 	attributeLength := len(rawFileNameAttribute)
-	if attributeLength < 0x44 {
-		err = errors.New("FileNameAttribute.parse() did not receive valid bytes")
+	if attributeLength < offsetFileName {
+		err = fmt.Errorf("FileNameAttribute.parse() received %d bytes: %w", attributeLength, ErrTruncated)
 		return
 	}
 
 	rawResidencyFlag := RawResidencyFlag(rawFileNameAttribute[offsetResidentFlag])
 	filenameAttribute.FlagResident = rawResidencyFlag.Parse()
 	if filenameAttribute.FlagResident == false {
-		err = errors.New("parseFileNameAttribute(): non-resident filename Attribute encountered")
+		err = fmt.Errorf("filename attribute: %w", ErrNonResident)
 		return
 	}
 	filenameAttribute.AttributeSize, _ = bin.LittleEndianBinaryToUInt32(rawFileNameAttribute[offsetAttributeSize : offsetAttributeSize+lengthAttributeSize])
@@ -128,10 +128,22 @@ func (rawFileNameAttribute RawFileNameAttribute) Parse() (filenameAttribute File
 	rawFnModified := ts.RawTimestamp(rawFileNameAttribute[offsetFnModified : offsetFnModified+lengthFnModified])
 	rawFnChanged := ts.RawTimestamp(rawFileNameAttribute[offsetFnChanged : offsetFnChanged+lengthFnChanged])
 	rawFnAccessed := ts.RawTimestamp(rawFileNameAttribute[offsetFnAccessed : offsetFnAccessed+lengthFnAccessed])
-	filenameAttribute.FnCreated, _ = rawFnCreated.Parse()
-	filenameAttribute.FnModified, _ = rawFnModified.Parse()
-	filenameAttribute.FnChanged, _ = rawFnChanged.Parse()
-	filenameAttribute.FnAccessed, _ = rawFnAccessed.Parse()
+	filenameAttribute.FnCreated, err = parseTimestamp(rawFnCreated, "created")
+	if err != nil {
+		return
+	}
+	filenameAttribute.FnModified, err = parseTimestamp(rawFnModified, "modified")
+	if err != nil {
+		return
+	}
+	filenameAttribute.FnChanged, err = parseTimestamp(rawFnChanged, "changed")
+	if err != nil {
+		return
+	}
+	filenameAttribute.FnAccessed, err = parseTimestamp(rawFnAccessed, "accessed")
+	if err != nil {
+		return
+	}
 	filenameAttribute.LogicalFileSize, _ = bin.LittleEndianBinaryToUInt64(rawFileNameAttribute[offsetLogicalFileSize : offsetLogicalFileSize+lengthLogicalFileSize])
 	filenameAttribute.PhysicalFileSize, _ = bin.LittleEndianBinaryToUInt64(rawFileNameAttribute[offSetPhysicalFileSize : offSetPhysicalFileSize+lengthPhysicalFileSize])
 	flagBytes := RawFilenameFlags(rawFileNameAttribute[offsetFnFlags : offsetFnFlags+lengthFnFlags])
@@ -139,7 +151,15 @@ func (rawFileNameAttribute RawFileNameAttribute) Parse() (filenameAttribute File
 	filenameAttribute.FileNameLength = rawFileNameAttribute[offsetFileNameLength] * 2 // times two to account for unicode characters
 	rawFilenameNameSpaceFlag := RawFilenameNameSpaceFlag(rawFileNameAttribute[offsetFileNameSpace])
 	filenameAttribute.FileNamespace = rawFilenameNameSpaceFlag.Parse()
-	filenameAttribute.FileName, _ = bin.UnicodeBytesToASCII(rawFileNameAttribute[offsetFileName : offsetFileName+int(filenameAttribute.FileNameLength)])
+	if offsetFileName+int(filenameAttribute.FileNameLength) > attributeLength {
+		err = fmt.Errorf("a file name of %d bytes runs past the end of the %d byte attribute: %w", filenameAttribute.FileNameLength, attributeLength, ErrTruncated)
+		return
+	}
+	filenameAttribute.FileName, err = bin.UnicodeBytesToASCII(rawFileNameAttribute[offsetFileName : offsetFileName+int(filenameAttribute.FileNameLength)])
+	if err != nil {
+		err = fmt.Errorf("failed to decode the file name: %w", err)
+		return
+	}
 	return
 }
 
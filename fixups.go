@@ -0,0 +1,57 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+)
+
+// Applies the update sequence array fixups to a multi-sector record such as an MFT record or an index record. The last two bytes of every sector are swapped out for the originals kept in the update sequence array. An error is returned if a sector doesn't end with the update sequence number, which is a sign of a torn write.
+// See here for more details: https://flatcap.org/linux-ntfs/ntfs/concepts/fixup.html
+func applyFixups(record []byte, bytesPerSector int) (err error) {
+	const offsetUpdateSequenceOffset = 0x04
+	const lengthUpdateSequenceOffset = 0x02
+
+	const offsetUpdateSequenceCount = 0x06
+	const lengthUpdateSequenceCount = 0x02
+
+	// Sanity checks
+	sizeOfRecord := len(record)
+	if sizeOfRecord < offsetUpdateSequenceCount+lengthUpdateSequenceCount {
+		err = fmt.Errorf("record is too small to have an update sequence array: %w", ErrTruncated)
+		return
+	} else if bytesPerSector <= 0 {
+		err = fmt.Errorf("invalid bytes per sector value of %d", bytesPerSector)
+		return
+	}
+
+	updateSequenceOffset := int(binary.LittleEndian.Uint16(record[offsetUpdateSequenceOffset : offsetUpdateSequenceOffset+lengthUpdateSequenceOffset]))
+	updateSequenceCount := int(binary.LittleEndian.Uint16(record[offsetUpdateSequenceCount : offsetUpdateSequenceCount+lengthUpdateSequenceCount]))
+	if updateSequenceCount == 0 {
+		err = errors.New("update sequence count is 0")
+		return
+	} else if updateSequenceOffset+updateSequenceCount*2 > sizeOfRecord {
+		err = fmt.Errorf("update sequence array at offset %d with %d entries goes beyond the %d byte record", updateSequenceOffset, updateSequenceCount, sizeOfRecord)
+		return
+	} else if (updateSequenceCount-1)*bytesPerSector > sizeOfRecord {
+		err = fmt.Errorf("update sequence array covers %d sectors but the record is only %d bytes", updateSequenceCount-1, sizeOfRecord)
+		return
+	}
+
+	// The first entry is the update sequence number, the rest are the original sector end bytes.
+	updateSequenceNumber := record[updateSequenceOffset : updateSequenceOffset+2]
+	for i := 1; i < updateSequenceCount; i++ {
+		sectorEnd := i*bytesPerSector - 2
+		if record[sectorEnd] != updateSequenceNumber[0] || record[sectorEnd+1] != updateSequenceNumber[1] {
+			err = &FixupError{Sector: i - 1}
+			return
+		}
+	}
+	for i := 1; i < updateSequenceCount; i++ {
+		sectorEnd := i*bytesPerSector - 2
+		copy(record[sectorEnd:sectorEnd+2], record[updateSequenceOffset+i*2:updateSequenceOffset+i*2+2])
+	}
+	return
+}
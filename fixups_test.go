@@ -0,0 +1,96 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// Builds a multi-sector record out of its fixed up bytes by moving the sector end bytes into an update sequence array at offset 0x28.
+func buildTestMultiSectorRecord(fixedRecord []byte, bytesPerSector int) []byte {
+	const updateSequenceOffset = 0x28
+	record := make([]byte, len(fixedRecord))
+	copy(record, fixedRecord)
+	sectorCount := len(record) / bytesPerSector
+	binary.LittleEndian.PutUint16(record[0x04:], updateSequenceOffset)
+	binary.LittleEndian.PutUint16(record[0x06:], uint16(sectorCount+1))
+	record[updateSequenceOffset] = 0xAB
+	record[updateSequenceOffset+1] = 0xCD
+	for i := 1; i <= sectorCount; i++ {
+		sectorEnd := i*bytesPerSector - 2
+		copy(record[updateSequenceOffset+i*2:], fixedRecord[sectorEnd:sectorEnd+2])
+		record[sectorEnd] = 0xAB
+		record[sectorEnd+1] = 0xCD
+	}
+	return record
+}
+
+func Test_applyFixups(t *testing.T) {
+	fixedRecord := make([]byte, 1024)
+	copy(fixedRecord, "FILE0")
+	fixedRecord[510] = 0x11
+	fixedRecord[511] = 0x22
+	fixedRecord[1022] = 0x33
+	fixedRecord[1023] = 0x44
+	record := buildTestMultiSectorRecord(fixedRecord, 512)
+	wantRecord := make([]byte, 1024)
+	copy(wantRecord, record)
+	copy(wantRecord[510:], []byte{0x11, 0x22})
+	copy(wantRecord[1022:], []byte{0x33, 0x44})
+
+	tornRecord := make([]byte, 1024)
+	copy(tornRecord, record)
+	tornRecord[1022] = 0x00
+
+	type args struct {
+		record         []byte
+		bytesPerSector int
+	}
+	tests := []struct {
+		name       string
+		args       args
+		wantRecord []byte
+		wantErr    bool
+	}{
+		{
+			name:       "two sectors",
+			args:       args{record: record, bytesPerSector: 512},
+			wantRecord: wantRecord,
+			wantErr:    false,
+		},
+		{
+			name:    "torn write",
+			args:    args{record: tornRecord, bytesPerSector: 512},
+			wantErr: true,
+		},
+		{
+			name:    "sector size larger than record",
+			args:    args{record: record[:512], bytesPerSector: 512},
+			wantErr: true,
+		},
+		{
+			name:    "not enough bytes",
+			args:    args{record: []byte{0x46, 0x49}, bytesPerSector: 512},
+			wantErr: true,
+		},
+		{
+			name:    "bytes per sector of 0",
+			args:    args{record: record, bytesPerSector: 0},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := applyFixups(tt.args.record, tt.args.bytesPerSector)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("applyFixups() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(tt.args.record, tt.wantRecord) {
+				t.Errorf("applyFixups() did not restore the sector end bytes")
+			}
+		})
+	}
+}
@@ -3,7 +3,7 @@
 package mft
 
 import (
-	"errors"
+	"encoding/binary"
 	"fmt"
 	bin "github.com/AlecRandazzo/BinaryTransforms"
 )
@@ -13,8 +13,10 @@ type RawRecordHeader []byte
 
 // RecordHeader contains parsed record header values.
 type RecordHeader struct {
+	SequenceNumber   uint16
 	AttributesOffset uint16
 	RecordNumber     uint32
+	BaseRecordNumber uint32
 	Flags            RecordHeaderFlags
 }
 
@@ -25,6 +27,9 @@ type RawRecordHeaderFlag byte
 type RecordHeaderFlags struct {
 	FlagDeleted   bool
 	FlagDirectory bool
+
+	// FlagInUse is the in use bit of the raw flags on its own. Unlike FlagDeleted it is also cleared for deleted directories.
+	FlagInUse bool
 }
 
 // Parse parses the raw record header receiver and returns a record header.
@@ -32,34 +37,56 @@ func (rawRecordHeader RawRecordHeader) Parse() (recordHeader RecordHeader, err e
 	sizeOfRawRecordHeader := len(rawRecordHeader)
 
 	if sizeOfRawRecordHeader == 0 {
-		err = errors.New("RecordHeader.parse() received nil bytes")
+		err = fmt.Errorf("RecordHeader.parse(): %w", ErrNilBytes)
 		return
 	} else if sizeOfRawRecordHeader != 0x38 {
-		err = fmt.Errorf("RawRecordHeader.parse() expected 38 bytes, instead it received %d", sizeOfRawRecordHeader)
+		err = fmt.Errorf("RawRecordHeader.parse() expected 38 bytes, instead it received %d: %w", sizeOfRawRecordHeader, ErrTruncated)
 		return
 	}
 
+	const offsetSequenceNumber = 0x10
+	const lengthSequenceNumber = 0x02
 	const offsetAttributesOffset = 0x14
+	const offsetBaseRecordNumber = 0x20
+	const lengthBaseRecordNumber = 0x04
 	const offsetRecordNumber = 0x2C
 	const lengthRecordNumber = 0x04
 
+	recordHeader.SequenceNumber = binary.LittleEndian.Uint16(rawRecordHeader[offsetSequenceNumber : offsetSequenceNumber+lengthSequenceNumber])
 	recordHeader.AttributesOffset = uint16(rawRecordHeader[offsetAttributesOffset])
-	rawRecordHeaderFlag, _ := rawRecordHeader.GetRawRecordHeaderFlags()
-
+	rawRecordHeaderFlag, err := rawRecordHeader.GetRawRecordHeaderFlags()
+	if err != nil {
+		err = fmt.Errorf("failed to get record header flags: %w", err)
+		return
+	}
 	recordHeader.Flags = rawRecordHeaderFlag.Parse()
-	recordHeader.RecordNumber, _ = bin.LittleEndianBinaryToUInt32(rawRecordHeader[offsetRecordNumber : offsetRecordNumber+lengthRecordNumber])
+	recordHeader.RecordNumber, err = bin.LittleEndianBinaryToUInt32(rawRecordHeader[offsetRecordNumber : offsetRecordNumber+lengthRecordNumber])
+	if err != nil {
+		err = fmt.Errorf("failed to parse the record number: %w", err)
+		return
+	}
+	recordHeader.BaseRecordNumber, err = bin.LittleEndianBinaryToUInt32(rawRecordHeader[offsetBaseRecordNumber : offsetBaseRecordNumber+lengthBaseRecordNumber])
+	if err != nil {
+		err = fmt.Errorf("failed to parse the base record number: %w", err)
+		return
+	}
 	return
 }
 
+// IsExtensionRecord returns true when the record header belongs to an extension record. Extension records hold attributes that did not fit in their base record and point back to it via the base record number.
+func (recordHeader RecordHeader) IsExtensionRecord() bool {
+	return recordHeader.BaseRecordNumber != 0
+}
+
 // GetRawRecordHeaderFlags parses the raw filename attribute receiver and returns the raw record header flags.
 func (rawRecordHeader RawRecordHeader) GetRawRecordHeaderFlags() (rawRecordHeaderFlag RawRecordHeaderFlag, err error) {
 	sizeOfRawRecordHeader := len(rawRecordHeader)
 
 	if sizeOfRawRecordHeader == 0 {
-		err = errors.New("received a nil bytes")
+		err = ErrNilBytes
 		return
-	} else if sizeOfRawRecordHeader < 0x16 {
-		err = fmt.Errorf("expected at least 16 bytes, instead received %d", sizeOfRawRecordHeader)
+	} else if sizeOfRawRecordHeader <= 0x16 {
+		err = fmt.Errorf("expected at least 17 bytes, instead received %d: %w", sizeOfRawRecordHeader, ErrTruncated)
 		return
 	}
 
@@ -75,6 +102,7 @@ func (rawRecordHeaderFlag RawRecordHeaderFlag) Parse() (recordHeaderFlags Record
 	//const codeActiveFile = 0x01
 	//const codeDeletedDirectory = 0x02
 	const codeDirectory = 0x03
+	const codeInUse = 0x01
 	if rawRecordHeaderFlag == codeDeletedFile {
 		recordHeaderFlags.FlagDeleted = true
 		recordHeaderFlags.FlagDirectory = false
@@ -85,6 +113,7 @@ func (rawRecordHeaderFlag RawRecordHeaderFlag) Parse() (recordHeaderFlags Record
 		recordHeaderFlags.FlagDeleted = false
 		recordHeaderFlags.FlagDirectory = false
 	}
+	recordHeaderFlags.FlagInUse = rawRecordHeaderFlag&codeInUse != 0
 	return
 }
 
@@ -92,16 +121,16 @@ func (rawRecordHeaderFlag RawRecordHeaderFlag) Parse() (recordHeaderFlags Record
 func (rawMftRecord RawMasterFileTableRecord) GetRawRecordHeader() (rawRecordHeader RawRecordHeader, err error) {
 	sizeOfRawMftRecord := len(rawMftRecord)
 	if sizeOfRawMftRecord == 0 {
-		err = errors.New("received nil bytes")
+		err = ErrNilBytes
 		return
 	} else if sizeOfRawMftRecord < 0x38 {
-		err = fmt.Errorf("expected at least 38 bytes, instead received %d", sizeOfRawMftRecord)
+		err = fmt.Errorf("expected at least 38 bytes, instead received %d: %w", sizeOfRawMftRecord, ErrTruncated)
 		return
 	}
 
 	result, _ := rawMftRecord.IsThisAnMftRecord()
 	if result == false {
-		err = errors.New("this is not an mft record")
+		err = ErrNotMftRecord
 		return
 	}
 
@@ -116,20 +145,21 @@ func (rawMftRecord RawMasterFileTableRecord) IsThisAnMftRecord() (result bool, e
 	sizeOfRawMftRecord := len(rawMftRecord)
 
 	if sizeOfRawMftRecord == 0 {
-		err = errors.New("received nil bytes")
+		err = ErrNilBytes
 		result = false
 		return
 	}
-	if sizeOfRawMftRecord < 0x05 {
-		err = errors.New("received less than 4 bytes")
+	if sizeOfRawMftRecord < 0x04 {
+		err = fmt.Errorf("received less than 4 bytes: %w", ErrTruncated)
 		result = false
 		return
 	}
 
+	// Only the FILE signature is checked. The byte after it is the low byte of the update sequence offset, which is "0" on current versions of NTFS but differs on older ones.
 	const offsetRecordMagicNumber = 0x00
-	const lengthRecordMagicNumber = 0x05
+	const lengthRecordMagicNumber = 0x04
 	magicNumber := string(rawMftRecord[offsetRecordMagicNumber : offsetRecordMagicNumber+lengthRecordMagicNumber])
-	if magicNumber != "FILE0" {
+	if magicNumber != "FILE" {
 		result = false
 		return
 	}
@@ -28,6 +28,16 @@ func TestRawRecordHeaderFlag_Parse(t *testing.T) {
 			want: RecordHeaderFlags{
 				FlagDeleted:   false,
 				FlagDirectory: true,
+				FlagInUse:     true,
+			},
+		},
+		{
+			name:                "deleted directory 0x02",
+			rawRecordHeaderFlag: 0x02,
+			want: RecordHeaderFlags{
+				FlagDeleted:   false,
+				FlagDirectory: false,
+				FlagInUse:     false,
 			},
 		},
 		{
@@ -61,11 +71,29 @@ func TestRawRecordHeader_Parse(t *testing.T) {
 			name:            "valid raw record header",
 			rawRecordHeader: RawRecordHeader([]byte{70, 73, 76, 69, 48, 0, 3, 0, 155, 21, 101, 188, 33, 0, 0, 0, 1, 0, 1, 0, 56, 0, 1, 0, 200, 1, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 7, 0, 0, 0, 0, 0, 0, 0, 29, 7, 0, 0, 0, 0, 0, 0}),
 			want: RecordHeader{
+				SequenceNumber:   1,
 				AttributesOffset: 56,
 				RecordNumber:     0,
 				Flags: RecordHeaderFlags{
 					FlagDeleted:   false,
 					FlagDirectory: false,
+					FlagInUse:     true,
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:            "extension record header",
+			rawRecordHeader: RawRecordHeader([]byte{70, 73, 76, 69, 48, 0, 3, 0, 155, 21, 101, 188, 33, 0, 0, 0, 1, 0, 1, 0, 56, 0, 1, 0, 200, 1, 0, 0, 0, 4, 0, 0, 0x59, 0x87, 0x07, 0, 0, 0, 1, 0, 7, 0, 0, 0, 0x44, 0xb7, 0x15, 0, 0, 0, 0, 0, 0, 0, 0, 0}),
+			want: RecordHeader{
+				SequenceNumber:   1,
+				AttributesOffset: 56,
+				RecordNumber:     1423172,
+				BaseRecordNumber: 493401,
+				Flags: RecordHeaderFlags{
+					FlagDeleted:   false,
+					FlagDirectory: false,
+					FlagInUse:     true,
 				},
 			},
 			wantErr: false,
@@ -189,6 +217,18 @@ func TestRawMasterFileTableRecord_IsThisAnMftRecord(t *testing.T) {
 			wantErr:      false,
 			wantResult:   false,
 		},
+		{
+			name:         "older update sequence offset",
+			rawMftRecord: append([]byte("FILE*"), make([]byte, 1019)...),
+			wantErr:      false,
+			wantResult:   true,
+		},
+		{
+			name:         "BAAD record",
+			rawMftRecord: append([]byte("BAAD0"), make([]byte, 1019)...),
+			wantErr:      false,
+			wantResult:   false,
+		},
 		{
 			name:         "not enough btytes",
 			rawMftRecord: []byte{70, 73},
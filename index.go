@@ -0,0 +1,152 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// RawIndexRecord is a []byte alias for a raw INDX record from an $INDEX_ALLOCATION attribute. Used with the Parse() method.
+// See here for the layout: https://flatcap.org/linux-ntfs/ntfs/concepts/index_record.html
+type RawIndexRecord []byte
+
+// IndexRecord contains a parsed INDX record.
+type IndexRecord struct {
+	VCN     uint64
+	Entries IndexEntries
+}
+
+// IndexEntries is a slice of IndexEntry.
+type IndexEntries []IndexEntry
+
+// IndexEntry contains a single entry of an index node. Filename indexes store a file reference in the first 8 bytes of an entry while view indexes like $SII and $O store a data offset and length there.
+type IndexEntry struct {
+	Header [8]byte
+	Flags  uint16
+	Key    []byte
+	Data   []byte
+}
+
+// Index entry flags.
+const (
+	indexEntryHasSubNode = 0x01
+	indexEntryLast       = 0x02
+)
+
+// Parse applies fixups to a copy of the raw index record receiver and returns its parsed entries. The bytes per sector argument is typically 512.
+func (rawIndexRecord RawIndexRecord) Parse(bytesPerSector int) (indexRecord IndexRecord, err error) {
+	const offsetVCN = 0x10
+	const lengthVCN = 0x08
+
+	const offsetNodeHeader = 0x18
+
+	// Sanity checks
+	sizeOfRawIndexRecord := len(rawIndexRecord)
+	if sizeOfRawIndexRecord < offsetNodeHeader+0x10 {
+		err = fmt.Errorf("RawIndexRecord.Parse() expected at least %d bytes, instead received %d: %w", offsetNodeHeader+0x10, sizeOfRawIndexRecord, ErrTruncated)
+		return
+	} else if string(rawIndexRecord[0x00:0x04]) != "INDX" {
+		err = fmt.Errorf("RawIndexRecord.Parse(): %w", ErrNotIndexRecord)
+		return
+	}
+
+	fixedRecord := make([]byte, sizeOfRawIndexRecord)
+	copy(fixedRecord, rawIndexRecord)
+	err = applyFixups(fixedRecord, bytesPerSector)
+	if err != nil {
+		err = fmt.Errorf("failed to apply fixups to index record: %w", err)
+		return
+	}
+
+	indexRecord.VCN = binary.LittleEndian.Uint64(fixedRecord[offsetVCN : offsetVCN+lengthVCN])
+	indexRecord.Entries, err = parseIndexNode(fixedRecord[offsetNodeHeader:])
+	return
+}
+
+// Parses an index node header and the entries that follow it. The entry offsets in the node header are relative to the start of the node header.
+func parseIndexNode(rawIndexNode []byte) (indexEntries IndexEntries, err error) {
+	const offsetEntriesOffset = 0x00
+	const offsetEntriesSize = 0x04
+
+	sizeOfRawIndexNode := len(rawIndexNode)
+	if sizeOfRawIndexNode < 0x10 {
+		err = fmt.Errorf("index node header expected at least 16 bytes, instead received %d: %w", sizeOfRawIndexNode, ErrTruncated)
+		return
+	}
+	entriesOffset := int(binary.LittleEndian.Uint32(rawIndexNode[offsetEntriesOffset : offsetEntriesOffset+4]))
+	entriesEnd := int(binary.LittleEndian.Uint32(rawIndexNode[offsetEntriesSize : offsetEntriesSize+4]))
+	if entriesOffset < 0x10 || entriesEnd > sizeOfRawIndexNode || entriesOffset > entriesEnd {
+		err = fmt.Errorf("index node entries from %d to %d do not fit in %d bytes", entriesOffset, entriesEnd, sizeOfRawIndexNode)
+		return
+	}
+
+	indexEntries, _ = parseIndexEntries(rawIndexNode[entriesOffset:entriesEnd])
+	return
+}
+
+// Walks a byte slice of index entries and returns the parsed entries up to the last entry marker. The number of bytes consumed is also returned.
+func parseIndexEntries(rawEntries []byte) (indexEntries IndexEntries, consumed int) {
+	const offsetEntryLength = 0x08
+	const offsetKeyLength = 0x0A
+	const offsetFlags = 0x0C
+	const offsetKey = 0x10
+
+	for consumed+offsetKey <= len(rawEntries) {
+		rawEntry := rawEntries[consumed:]
+		entryLength := int(binary.LittleEndian.Uint16(rawEntry[offsetEntryLength : offsetEntryLength+2]))
+		keyLength := int(binary.LittleEndian.Uint16(rawEntry[offsetKeyLength : offsetKeyLength+2]))
+		flags := binary.LittleEndian.Uint16(rawEntry[offsetFlags : offsetFlags+2])
+		if entryLength < offsetKey || entryLength > len(rawEntry) || offsetKey+keyLength > entryLength {
+			return
+		}
+		consumed += entryLength
+		if flags&indexEntryLast != 0 {
+			return
+		}
+
+		indexEntry := IndexEntry{Flags: flags}
+		copy(indexEntry.Header[:], rawEntry[0x00:0x08])
+		indexEntry.Key = make([]byte, keyLength)
+		copy(indexEntry.Key, rawEntry[offsetKey:offsetKey+keyLength])
+
+		// View indexes keep their data inside the entry, referenced by the first 4 bytes of the entry header.
+		dataOffset := int(binary.LittleEndian.Uint16(rawEntry[0x00:0x02]))
+		dataLength := int(binary.LittleEndian.Uint16(rawEntry[0x02:0x04]))
+		if dataOffset >= offsetKey+keyLength && dataLength != 0 && dataOffset+dataLength <= entryLength {
+			indexEntry.Data = make([]byte, dataLength)
+			copy(indexEntry.Data, rawEntry[dataOffset:dataOffset+dataLength])
+		}
+		indexEntries = append(indexEntries, indexEntry)
+	}
+	return
+}
+
+// FileReference returns the mft record number and sequence number stored in the header of a filename index entry.
+func (indexEntry IndexEntry) FileReference() (recordNumber uint64, sequenceNumber uint16) {
+	reference := binary.LittleEndian.Uint64(indexEntry.Header[:])
+	recordNumber = reference & 0x0000ffffffffffff
+	sequenceNumber = uint16(reference >> 48)
+	return
+}
+
+// ParseIndexAllocation walks the INDX records of an $INDEX_ALLOCATION stream and returns the entries of every record that could be parsed. Unused space between records is skipped.
+func ParseIndexAllocation(indexAllocation []byte, indexRecordSize int, bytesPerSector int) (indexEntries IndexEntries, err error) {
+	// Sanity checks
+	if len(indexAllocation) == 0 {
+		err = fmt.Errorf("ParseIndexAllocation(): %w", ErrNilBytes)
+		return
+	} else if indexRecordSize <= 0 {
+		err = fmt.Errorf("ParseIndexAllocation() received an invalid index record size of %d", indexRecordSize)
+		return
+	}
+
+	for offset := 0; offset+indexRecordSize <= len(indexAllocation); offset += indexRecordSize {
+		indexRecord, parseErr := RawIndexRecord(indexAllocation[offset : offset+indexRecordSize]).Parse(bytesPerSector)
+		if parseErr != nil {
+			continue
+		}
+		indexEntries = append(indexEntries, indexRecord.Entries...)
+	}
+	return
+}
@@ -0,0 +1,176 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+// CarvedIndexEntry contains a $FILE_NAME index entry carved out of an INDX record. Live entries are part of the directory listing the record held when it was last written, slack entries are left over from earlier listings and can name files that were deleted or renamed. The parent directory reference is in the file name.
+type CarvedIndexEntry struct {
+	RecordOffset   int64
+	SourceOffset   int64
+	VCN            uint64
+	Slack          bool
+	RecordNumber   uint64
+	SequenceNumber uint16
+	FileName       FileNameAttribute
+}
+
+// defaultIndexRecordSize is the size of an INDX record on nearly every volume.
+const defaultIndexRecordSize = 4096
+
+// CarveIndexRecords scans arbitrary data, such as a memory dump or unallocated clusters, for INDX records and returns the $FILE_NAME entries of the ones that pass their header checks and fixups, from both the live part of the record and its slack. Only directory indexes hold $FILE_NAME entries, so the entries of other indexes are left out.
+func CarveIndexRecords(reader io.Reader, options CarveOptions) (carvedIndexEntries []CarvedIndexEntry, err error) {
+	recordSize, bytesPerSector := options.sizes(defaultIndexRecordSize)
+
+	// Sanity checks
+	err = checkCarveSizes(recordSize, bytesPerSector)
+	if err != nil {
+		return
+	}
+
+	err = scanForRecords(reader, recordSize, options.step(bytesPerSector), func(candidate []byte, sourceOffset int64) bool {
+		if string(candidate[0x00:0x04]) != "INDX" {
+			return false
+		}
+		entries, carveErr := carveIndexRecord(candidate, bytesPerSector)
+		if carveErr != nil {
+			return false
+		}
+		for _, carvedIndexEntry := range entries {
+			carvedIndexEntry.RecordOffset = sourceOffset
+			carvedIndexEntry.SourceOffset += sourceOffset
+			carvedIndexEntries = append(carvedIndexEntries, carvedIndexEntry)
+		}
+		return true
+	})
+	return
+}
+
+// Validates a candidate INDX record, applies fixups to a copy of it, and returns its live and slack $FILE_NAME entries. The source offsets of the entries are relative to the start of the record.
+func carveIndexRecord(candidate []byte, bytesPerSector int) (carvedIndexEntries []CarvedIndexEntry, err error) {
+	const offsetUpdateSequenceCount = 0x06
+	const offsetVCN = 0x10
+	const offsetNodeHeader = 0x18
+	const offsetEntriesOffset = offsetNodeHeader + 0x00
+	const offsetEntriesSize = offsetNodeHeader + 0x04
+	const offsetAllocatedSize = offsetNodeHeader + 0x08
+
+	recordSize := len(candidate)
+	updateSequenceCount := int(binary.LittleEndian.Uint16(candidate[offsetUpdateSequenceCount : offsetUpdateSequenceCount+0x02]))
+	if updateSequenceCount != recordSize/bytesPerSector+1 {
+		err = fmt.Errorf("update sequence count of %d does not cover a %d byte record", updateSequenceCount, recordSize)
+		return
+	}
+	// The entry offsets in the node header are relative to the start of the node header.
+	entriesStart := offsetNodeHeader + int(binary.LittleEndian.Uint32(candidate[offsetEntriesOffset:offsetEntriesOffset+0x04]))
+	entriesEnd := offsetNodeHeader + int(binary.LittleEndian.Uint32(candidate[offsetEntriesSize:offsetEntriesSize+0x04]))
+	allocatedEnd := offsetNodeHeader + int(binary.LittleEndian.Uint32(candidate[offsetAllocatedSize:offsetAllocatedSize+0x04]))
+	if entriesStart < offsetNodeHeader+0x10 || entriesStart > entriesEnd || entriesEnd > allocatedEnd || allocatedEnd > recordSize {
+		err = fmt.Errorf("index node entries from %d to %d do not fit in %d allocated bytes", entriesStart, entriesEnd, allocatedEnd)
+		return
+	}
+
+	fixedRecord := make([]byte, recordSize)
+	copy(fixedRecord, candidate)
+	err = applyFixups(fixedRecord, bytesPerSector)
+	if err != nil {
+		err = fmt.Errorf("failed to apply fixups to index record: %w", err)
+		return
+	}
+	vcn := binary.LittleEndian.Uint64(fixedRecord[offsetVCN : offsetVCN+0x08])
+
+	// Walk the live entries the same way parseIndexEntries() does, but keep track of where each one is.
+	offset := entriesStart
+	for offset < entriesEnd {
+		entryLength, last := fileNameIndexEntryLength(fixedRecord[offset:entriesEnd])
+		if entryLength == 0 {
+			break
+		}
+		if !last {
+			if carvedIndexEntry, ok := parseCarvedIndexEntry(fixedRecord[offset : offset+entryLength]); ok {
+				carvedIndexEntry.SourceOffset = int64(offset)
+				carvedIndexEntry.VCN = vcn
+				carvedIndexEntries = append(carvedIndexEntries, carvedIndexEntry)
+			}
+		}
+		offset += entryLength
+		if last {
+			break
+		}
+	}
+
+	// Slack entries don't have a chain to follow since the start of the slack can be in the middle of an old entry, so every 8 byte aligned offset is tried instead.
+	for offset = (entriesEnd + 7) &^ 7; offset+0x10 <= allocatedEnd; {
+		entryLength, last := fileNameIndexEntryLength(fixedRecord[offset:allocatedEnd])
+		if entryLength == 0 || last {
+			offset += 8
+			continue
+		}
+		carvedIndexEntry, ok := parseCarvedIndexEntry(fixedRecord[offset : offset+entryLength])
+		if !ok {
+			offset += 8
+			continue
+		}
+		carvedIndexEntry.SourceOffset = int64(offset)
+		carvedIndexEntry.VCN = vcn
+		carvedIndexEntry.Slack = true
+		carvedIndexEntries = append(carvedIndexEntries, carvedIndexEntry)
+		offset += entryLength
+	}
+	return
+}
+
+// Returns the length of the index entry at the start of the data and whether it's the last entry marker, or a length of 0 if the entry doesn't fit in the data.
+func fileNameIndexEntryLength(data []byte) (entryLength int, last bool) {
+	const offsetEntryLength = 0x08
+	const offsetKeyLength = 0x0A
+	const offsetFlags = 0x0C
+	const offsetKey = 0x10
+
+	if len(data) < offsetKey {
+		return
+	}
+	length := int(binary.LittleEndian.Uint16(data[offsetEntryLength : offsetEntryLength+0x02]))
+	keyLength := int(binary.LittleEndian.Uint16(data[offsetKeyLength : offsetKeyLength+0x02]))
+	if length < offsetKey || length%8 != 0 || length > len(data) || offsetKey+keyLength > length {
+		return
+	}
+	entryLength = length
+	last = binary.LittleEndian.Uint16(data[offsetFlags:offsetFlags+0x02])&indexEntryLast != 0
+	return
+}
+
+// Parses an index entry whose key is a $FILE_NAME. The key is rejected unless the name fits in it exactly and has a known namespace, which keeps the entries of other indexes and random slack bytes out.
+func parseCarvedIndexEntry(rawEntry []byte) (carvedIndexEntry CarvedIndexEntry, ok bool) {
+	const offsetKeyLength = 0x0A
+	const offsetKey = 0x10
+	const offsetNameLength = 0x40
+	const offsetNamespace = 0x41
+	const offsetName = 0x42
+	const lengthAttributeHeader = 0x18
+
+	keyLength := int(binary.LittleEndian.Uint16(rawEntry[offsetKeyLength : offsetKeyLength+0x02]))
+	key := rawEntry[offsetKey : offsetKey+keyLength]
+	if keyLength < offsetName+2 || key[offsetNameLength] == 0 || key[offsetNamespace] > 0x03 || (offsetName+int(key[offsetNameLength])*2+7)&^7 != (keyLength+7)&^7 {
+		return
+	}
+
+	// The key is the content of a $FILE_NAME attribute, so it gets a resident attribute header in front of it to be parsed like one.
+	rawFileNameAttribute := make(RawFileNameAttribute, lengthAttributeHeader+keyLength)
+	binary.LittleEndian.PutUint32(rawFileNameAttribute[0x04:0x08], uint32(len(rawFileNameAttribute)))
+	copy(rawFileNameAttribute[lengthAttributeHeader:], key)
+	fileNameAttribute, err := rawFileNameAttribute.Parse()
+	if err != nil {
+		return
+	}
+	indexEntry := IndexEntry{}
+	copy(indexEntry.Header[:], rawEntry[0x00:0x08])
+	carvedIndexEntry.RecordNumber, carvedIndexEntry.SequenceNumber = indexEntry.FileReference()
+	carvedIndexEntry.FileName = fileNameAttribute
+	ok = true
+	return
+}
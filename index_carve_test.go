@@ -0,0 +1,98 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// The parts of a carved index entry that are checked by the tests.
+type testCarvedIndexEntry struct {
+	RecordOffset       int64
+	SourceOffset       int64
+	VCN                uint64
+	Slack              bool
+	RecordNumber       uint64
+	SequenceNumber     uint16
+	FileName           string
+	ParentRecordNumber uint32
+}
+
+func TestCarveIndexRecords(t *testing.T) {
+	// The slack entry is kept clear of the sector ends so that copying it in doesn't disturb the fixups.
+	indexRecord := buildTestIndexRecord(2, buildTestFileNameIndexEntry("report.docx", 0x0002000000000040, 5))
+	copy(indexRecord[0x300:], buildTestFileNameIndexEntry("deleted.txt", 0x0001000000000041, 5))
+	tornRecord := buildTestIndexRecord(2, buildTestFileNameIndexEntry("report.docx", 0x0002000000000040, 5))
+	tornRecord[510] = 0x00
+	viewRecord := buildTestIndexRecord(0, buildTestViewIndexEntry([]byte{0x01, 0x01, 0x00, 0x00}, []byte{0xaa, 0xbb}))
+
+	wantEntries := func(recordOffset int64) []testCarvedIndexEntry {
+		return []testCarvedIndexEntry{
+			{RecordOffset: recordOffset, SourceOffset: recordOffset + 0x58, VCN: 2, RecordNumber: 0x40, SequenceNumber: 2, FileName: "report.docx", ParentRecordNumber: 5},
+			{RecordOffset: recordOffset, SourceOffset: recordOffset + 0x300, VCN: 2, Slack: true, RecordNumber: 0x41, SequenceNumber: 1, FileName: "deleted.txt", ParentRecordNumber: 5},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		data    []byte
+		options CarveOptions
+		want    []testCarvedIndexEntry
+		wantErr bool
+	}{
+		{
+			name: "live and slack entries",
+			data: bytes.Join([][]byte{make([]byte, 4096), indexRecord, make([]byte, 512)}, nil),
+			want: wantEntries(4096),
+		},
+		{
+			name:    "unaligned record",
+			data:    bytes.Join([][]byte{make([]byte, 5), indexRecord}, nil),
+			options: CarveOptions{Unaligned: true},
+			want:    wantEntries(5),
+		},
+		{
+			name: "torn record",
+			data: tornRecord,
+		},
+		{
+			name: "view index record",
+			data: viewRecord,
+		},
+		{
+			name:    "invalid bytes per sector",
+			data:    indexRecord,
+			options: CarveOptions{BytesPerSector: 100},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			carvedIndexEntries, err := CarveIndexRecords(bytes.NewReader(tt.data), tt.options)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CarveIndexRecords() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var got []testCarvedIndexEntry
+			for _, carvedIndexEntry := range carvedIndexEntries {
+				got = append(got, testCarvedIndexEntry{
+					RecordOffset:       carvedIndexEntry.RecordOffset,
+					SourceOffset:       carvedIndexEntry.SourceOffset,
+					VCN:                carvedIndexEntry.VCN,
+					Slack:              carvedIndexEntry.Slack,
+					RecordNumber:       carvedIndexEntry.RecordNumber,
+					SequenceNumber:     carvedIndexEntry.SequenceNumber,
+					FileName:           carvedIndexEntry.FileName.FileName,
+					ParentRecordNumber: carvedIndexEntry.FileName.ParentDirRecordNumber,
+				})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
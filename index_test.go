@@ -0,0 +1,153 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// Builds a view index entry with its data stored right after the key.
+func buildTestViewIndexEntry(key []byte, data []byte) []byte {
+	const offsetKey = 0x10
+	dataOffset := offsetKey + len(key)
+	size := (dataOffset + len(data) + 7) &^ 7
+	rawEntry := make([]byte, size)
+	binary.LittleEndian.PutUint16(rawEntry[0x00:], uint16(dataOffset))
+	binary.LittleEndian.PutUint16(rawEntry[0x02:], uint16(len(data)))
+	binary.LittleEndian.PutUint16(rawEntry[0x08:], uint16(size))
+	binary.LittleEndian.PutUint16(rawEntry[0x0A:], uint16(len(key)))
+	copy(rawEntry[offsetKey:], key)
+	copy(rawEntry[dataOffset:], data)
+	return rawEntry
+}
+
+// Builds a 4096 byte INDX record holding the provided raw entries followed by a last entry marker.
+func buildTestIndexRecord(vcn uint64, rawEntries ...[]byte) []byte {
+	const offsetNodeHeader = 0x18
+	const offsetEntries = 0x40
+	fixedRecord := make([]byte, 4096)
+	copy(fixedRecord, "INDX")
+	binary.LittleEndian.PutUint64(fixedRecord[0x10:], vcn)
+	offset := offsetNodeHeader + offsetEntries
+	for _, rawEntry := range rawEntries {
+		copy(fixedRecord[offset:], rawEntry)
+		offset += len(rawEntry)
+	}
+	binary.LittleEndian.PutUint16(fixedRecord[offset+0x08:], 0x10)
+	binary.LittleEndian.PutUint16(fixedRecord[offset+0x0C:], indexEntryLast)
+	offset += 0x10
+	binary.LittleEndian.PutUint32(fixedRecord[offsetNodeHeader:], offsetEntries)
+	binary.LittleEndian.PutUint32(fixedRecord[offsetNodeHeader+0x04:], uint32(offset-offsetNodeHeader))
+	binary.LittleEndian.PutUint32(fixedRecord[offsetNodeHeader+0x08:], 4096-offsetNodeHeader)
+	return buildTestMultiSectorRecord(fixedRecord, 512)
+}
+
+func TestRawIndexRecord_Parse(t *testing.T) {
+	filenameEntry := make([]byte, 0x18)
+	binary.LittleEndian.PutUint64(filenameEntry[0x00:], 0x0003000000000040)
+	binary.LittleEndian.PutUint16(filenameEntry[0x08:], 0x18)
+	binary.LittleEndian.PutUint16(filenameEntry[0x0A:], 0x08)
+	copy(filenameEntry[0x10:], "abcdefgh")
+
+	tests := []struct {
+		name            string
+		rawIndexRecord  RawIndexRecord
+		wantIndexRecord IndexRecord
+		wantErr         bool
+	}{
+		{
+			name:           "view index entries",
+			rawIndexRecord: buildTestIndexRecord(3, buildTestViewIndexEntry([]byte{0x01, 0x01, 0x00, 0x00}, []byte{0xaa, 0xbb})),
+			wantIndexRecord: IndexRecord{
+				VCN: 3,
+				Entries: IndexEntries{
+					{Header: [8]byte{0x14, 0x00, 0x02, 0x00}, Key: []byte{0x01, 0x01, 0x00, 0x00}, Data: []byte{0xaa, 0xbb}},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:           "filename index entry",
+			rawIndexRecord: buildTestIndexRecord(0, filenameEntry),
+			wantIndexRecord: IndexRecord{
+				VCN: 0,
+				Entries: IndexEntries{
+					{Header: [8]byte{0x40, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x00}, Key: []byte("abcdefgh")},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:           "not an index record",
+			rawIndexRecord: RawIndexRecord(make([]byte, 4096)),
+			wantErr:        true,
+		},
+		{
+			name:           "nil bytes",
+			rawIndexRecord: nil,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotIndexRecord, err := tt.rawIndexRecord.Parse(512)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotIndexRecord, tt.wantIndexRecord) {
+				t.Errorf("Parse() gotIndexRecord = %v, want %v", gotIndexRecord, tt.wantIndexRecord)
+			}
+		})
+	}
+}
+
+func TestIndexEntry_FileReference(t *testing.T) {
+	indexEntry := IndexEntry{Header: [8]byte{0x40, 0x00, 0x00, 0x00, 0x01, 0x00, 0x03, 0x00}}
+	gotRecordNumber, gotSequenceNumber := indexEntry.FileReference()
+	if gotRecordNumber != 0x0100000040 || gotSequenceNumber != 3 {
+		t.Errorf("FileReference() got = %d, %d, want %d, %d", gotRecordNumber, gotSequenceNumber, 0x0100000040, 3)
+	}
+}
+
+func TestParseIndexAllocation(t *testing.T) {
+	indexAllocation := append(buildTestIndexRecord(0, buildTestViewIndexEntry([]byte{0x01}, []byte{0x0a})), make([]byte, 4096)...)
+	indexAllocation = append(indexAllocation, buildTestIndexRecord(2, buildTestViewIndexEntry([]byte{0x02}, []byte{0x0b}))...)
+
+	tests := []struct {
+		name            string
+		indexAllocation []byte
+		wantKeys        [][]byte
+		wantErr         bool
+	}{
+		{
+			name:            "unused record between records",
+			indexAllocation: indexAllocation,
+			wantKeys:        [][]byte{{0x01}, {0x02}},
+			wantErr:         false,
+		},
+		{
+			name:            "nil bytes",
+			indexAllocation: nil,
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotIndexEntries, err := ParseIndexAllocation(tt.indexAllocation, 4096, 512)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseIndexAllocation() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var gotKeys [][]byte
+			for _, indexEntry := range gotIndexEntries {
+				gotKeys = append(gotKeys, indexEntry.Key)
+			}
+			if !reflect.DeepEqual(gotKeys, tt.wantKeys) {
+				t.Errorf("ParseIndexAllocation() gotKeys = %v, want %v", gotKeys, tt.wantKeys)
+			}
+		})
+	}
+}
@@ -0,0 +1,797 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	bin "github.com/AlecRandazzo/BinaryTransforms"
+)
+
+// RawLogFileRestartPage is a []byte alias for a raw RSTR page from the start of the $LogFile. Used with the Parse() method.
+// See here for the layout: https://flatcap.org/linux-ntfs/ntfs/files/logfile.html
+type RawLogFileRestartPage []byte
+
+// RawLogFileRecordPage is a []byte alias for a raw RCRD page of the $LogFile. Used with the Parse() method.
+type RawLogFileRecordPage []byte
+
+// RawLogFileRecord is a []byte alias for a raw LFS record, its header followed by its client data. Used with the Parse() method.
+type RawLogFileRecord []byte
+
+// LogFileRestartArea contains the parsed restart page header and restart area of the $LogFile.
+type LogFileRestartArea struct {
+	ChkdskLsn          uint64
+	SystemPageSize     uint32
+	LogPageSize        uint32
+	MajorVersion       int16
+	MinorVersion       int16
+	CurrentLsn         uint64
+	Flags              uint16
+	SequenceNumberBits uint32
+	FileSize           uint64
+	RecordHeaderLength uint16
+	LogPageDataOffset  uint16
+	Clients            []LogFileClient
+}
+
+// LogFileClient contains a client record of the restart area. NTFS is normally the only client.
+type LogFileClient struct {
+	Name             string
+	OldestLsn        uint64
+	ClientRestartLsn uint64
+}
+
+// LogFileRecordPage contains the parsed header of an RCRD page along with the fixed up page.
+type LogFileRecordPage struct {
+	LastLsn          uint64
+	Flags            uint32
+	PageCount        uint16
+	PagePosition     uint16
+	NextRecordOffset uint16
+	LastEndLsn       uint64
+	Data             []byte
+}
+
+// LogFileRecord contains a parsed LFS record. For client records the NTFS redo and undo operations that follow the LFS record header are parsed as well. The target VCN and cluster block offset point into the attribute identified by the target attribute, which for operations on MFT records is the $MFT itself.
+type LogFileRecord struct {
+	Lsn                uint64
+	PreviousLsn        uint64
+	UndoNextLsn        uint64
+	ClientDataLength   uint32
+	RecordType         uint32
+	TransactionID      uint32
+	Flags              uint16
+	RedoOperation      LogFileOperation
+	UndoOperation      LogFileOperation
+	TargetAttribute    uint16
+	RecordOffset       uint16
+	AttributeOffset    uint16
+	ClusterBlockOffset uint16
+	TargetVcn          uint64
+	Lcns               []uint64
+	RedoData           []byte
+	UndoData           []byte
+}
+
+// LogFile contains the restart area and the records of a parsed $LogFile. Records are ordered by LSN.
+type LogFile struct {
+	RestartArea LogFileRestartArea
+	Records     []LogFileRecord
+}
+
+// LogFileOperation is a uint16 alias for the operation code of a redo or undo operation.
+type LogFileOperation uint16
+
+// LogFileOperationDetails contains the decoded redo or undo data of an operation. Which fields are filled in depends on the operation, file names and parents come from file record images and index entries while timestamps come from index entries, file name updates, and standard information updates.
+type LogFileOperationDetails struct {
+	FileName             string
+	RecordNumber         uint64
+	SequenceNumber       uint16
+	ParentRecordNumber   uint64
+	ParentSequenceNumber uint16
+	Created              time.Time
+	Modified             time.Time
+	Changed              time.Time
+	Accessed             time.Time
+}
+
+// LogFileTimelineEntry contains a record of the $LogFile linked back to the MFT record it changed.
+type LogFileTimelineEntry struct {
+	Lsn             uint64
+	TransactionID   uint32
+	RedoOperation   LogFileOperation
+	UndoOperation   LogFileOperation
+	RecordNumber    uint64
+	HasRecordNumber bool
+	FileName        string
+	FullPath        string
+	Details         LogFileOperationDetails
+
+	// DetailsErr is set when the details of the record couldn't be decoded. The rest of the entry is still filled in.
+	DetailsErr error
+}
+
+// LogFileTimelineOptions contains the data used to link $LogFile records to MFT records and paths. The bytes per cluster and MFT record size default to 4096 and 1024 when they are 0.
+type LogFileTimelineOptions struct {
+	BytesPerCluster int64
+	MftRecordSize   int64
+
+	// DirectoryTree resolves the parent directories of names seen in the $LogFile. See BuildDirectoryTree().
+	DirectoryTree DirectoryTree
+
+	// FilePaths resolves record numbers whose name isn't seen in the $LogFile. See BuildFilePaths().
+	FilePaths FilePaths
+}
+
+// FilePaths maps MFT record numbers to the full path of the file.
+type FilePaths map[uint32]string
+
+// $LogFile operation codes. See here for the full list: https://flatcap.org/linux-ntfs/ntfs/files/logfile.html
+const (
+	LogFileOperationNoop                         LogFileOperation = 0x00
+	LogFileOperationCompensationLogRecord        LogFileOperation = 0x01
+	LogFileOperationInitializeFileRecordSegment  LogFileOperation = 0x02
+	LogFileOperationDeallocateFileRecordSegment  LogFileOperation = 0x03
+	LogFileOperationWriteEndOfFileRecordSegment  LogFileOperation = 0x04
+	LogFileOperationCreateAttribute              LogFileOperation = 0x05
+	LogFileOperationDeleteAttribute              LogFileOperation = 0x06
+	LogFileOperationUpdateResidentValue          LogFileOperation = 0x07
+	LogFileOperationUpdateNonresidentValue       LogFileOperation = 0x08
+	LogFileOperationUpdateMappingPairs           LogFileOperation = 0x09
+	LogFileOperationDeleteDirtyClusters          LogFileOperation = 0x0A
+	LogFileOperationSetNewAttributeSizes         LogFileOperation = 0x0B
+	LogFileOperationAddIndexEntryRoot            LogFileOperation = 0x0C
+	LogFileOperationDeleteIndexEntryRoot         LogFileOperation = 0x0D
+	LogFileOperationAddIndexEntryAllocation      LogFileOperation = 0x0E
+	LogFileOperationDeleteIndexEntryAllocation   LogFileOperation = 0x0F
+	LogFileOperationWriteEndOfIndexBuffer        LogFileOperation = 0x10
+	LogFileOperationSetIndexEntryVcnRoot         LogFileOperation = 0x11
+	LogFileOperationSetIndexEntryVcnAllocation   LogFileOperation = 0x12
+	LogFileOperationUpdateFileNameRoot           LogFileOperation = 0x13
+	LogFileOperationUpdateFileNameAllocation     LogFileOperation = 0x14
+	LogFileOperationSetBitsInNonresidentBitMap   LogFileOperation = 0x15
+	LogFileOperationClearBitsInNonresidentBitMap LogFileOperation = 0x16
+	LogFileOperationHotFix                       LogFileOperation = 0x17
+	LogFileOperationEndTopLevelAction            LogFileOperation = 0x18
+	LogFileOperationPrepareTransaction           LogFileOperation = 0x19
+	LogFileOperationCommitTransaction            LogFileOperation = 0x1A
+	LogFileOperationForgetTransaction            LogFileOperation = 0x1B
+	LogFileOperationOpenNonresidentAttribute     LogFileOperation = 0x1C
+	LogFileOperationOpenAttributeTableDump       LogFileOperation = 0x1D
+	LogFileOperationAttributeNamesDump           LogFileOperation = 0x1E
+	LogFileOperationDirtyPageTableDump           LogFileOperation = 0x1F
+	LogFileOperationTransactionTableDump         LogFileOperation = 0x20
+	LogFileOperationUpdateRecordDataRoot         LogFileOperation = 0x21
+	LogFileOperationUpdateRecordDataAllocation   LogFileOperation = 0x22
+	LogFileOperationUpdateRelativeDataInIndex    LogFileOperation = 0x23
+	LogFileOperationUpdateRelativeDataInIndex2   LogFileOperation = 0x24
+	LogFileOperationZeroEndOfFileRecord          LogFileOperation = 0x25
+)
+
+const (
+	// The update sequence arrays of $LogFile pages always cover 512 byte blocks.
+	logFileBytesPerSector = 512
+
+	// NTFS doesn't use $LogFile pages larger than 64 KiB.
+	maximumLogFilePageSize = 0x10000
+
+	lengthLogFileRecordHeader    = 0x30
+	lengthLogFileOperationHeader = 0x20
+	logFileRecordTypeClient      = 1
+	maximumLogFileClientData     = 0x10000
+
+	// The first attribute of an NTFS 3.1 MFT record, which is always $STANDARD_INFORMATION, starts at this offset.
+	offsetFirstAttribute = 0x38
+)
+
+var logFileOperationNames = map[LogFileOperation]string{
+	LogFileOperationNoop:                         "Noop",
+	LogFileOperationCompensationLogRecord:        "CompensationLogRecord",
+	LogFileOperationInitializeFileRecordSegment:  "InitializeFileRecordSegment",
+	LogFileOperationDeallocateFileRecordSegment:  "DeallocateFileRecordSegment",
+	LogFileOperationWriteEndOfFileRecordSegment:  "WriteEndOfFileRecordSegment",
+	LogFileOperationCreateAttribute:              "CreateAttribute",
+	LogFileOperationDeleteAttribute:              "DeleteAttribute",
+	LogFileOperationUpdateResidentValue:          "UpdateResidentValue",
+	LogFileOperationUpdateNonresidentValue:       "UpdateNonresidentValue",
+	LogFileOperationUpdateMappingPairs:           "UpdateMappingPairs",
+	LogFileOperationDeleteDirtyClusters:          "DeleteDirtyClusters",
+	LogFileOperationSetNewAttributeSizes:         "SetNewAttributeSizes",
+	LogFileOperationAddIndexEntryRoot:            "AddIndexEntryRoot",
+	LogFileOperationDeleteIndexEntryRoot:         "DeleteIndexEntryRoot",
+	LogFileOperationAddIndexEntryAllocation:      "AddIndexEntryAllocation",
+	LogFileOperationDeleteIndexEntryAllocation:   "DeleteIndexEntryAllocation",
+	LogFileOperationWriteEndOfIndexBuffer:        "WriteEndOfIndexBuffer",
+	LogFileOperationSetIndexEntryVcnRoot:         "SetIndexEntryVcnRoot",
+	LogFileOperationSetIndexEntryVcnAllocation:   "SetIndexEntryVcnAllocation",
+	LogFileOperationUpdateFileNameRoot:           "UpdateFileNameRoot",
+	LogFileOperationUpdateFileNameAllocation:     "UpdateFileNameAllocation",
+	LogFileOperationSetBitsInNonresidentBitMap:   "SetBitsInNonresidentBitMap",
+	LogFileOperationClearBitsInNonresidentBitMap: "ClearBitsInNonresidentBitMap",
+	LogFileOperationHotFix:                       "HotFix",
+	LogFileOperationEndTopLevelAction:            "EndTopLevelAction",
+	LogFileOperationPrepareTransaction:           "PrepareTransaction",
+	LogFileOperationCommitTransaction:            "CommitTransaction",
+	LogFileOperationForgetTransaction:            "ForgetTransaction",
+	LogFileOperationOpenNonresidentAttribute:     "OpenNonresidentAttribute",
+	LogFileOperationOpenAttributeTableDump:       "OpenAttributeTableDump",
+	LogFileOperationAttributeNamesDump:           "AttributeNamesDump",
+	LogFileOperationDirtyPageTableDump:           "DirtyPageTableDump",
+	LogFileOperationTransactionTableDump:         "TransactionTableDump",
+	LogFileOperationUpdateRecordDataRoot:         "UpdateRecordDataRoot",
+	LogFileOperationUpdateRecordDataAllocation:   "UpdateRecordDataAllocation",
+	LogFileOperationUpdateRelativeDataInIndex:    "UpdateRelativeDataInIndex",
+	LogFileOperationUpdateRelativeDataInIndex2:   "UpdateRelativeDataInIndex2",
+	LogFileOperationZeroEndOfFileRecord:          "ZeroEndOfFileRecord",
+}
+
+// String returns the name of the operation receiver. Unknown operations are returned in hex.
+func (logFileOperation LogFileOperation) String() string {
+	if name, ok := logFileOperationNames[logFileOperation]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%X", uint16(logFileOperation))
+}
+
+// Reports whether the operation receiver works on an MFT record rather than an index buffer, a bitmap, or the data of a file.
+func (logFileOperation LogFileOperation) targetsMftRecord() bool {
+	switch logFileOperation {
+	case LogFileOperationInitializeFileRecordSegment,
+		LogFileOperationDeallocateFileRecordSegment,
+		LogFileOperationWriteEndOfFileRecordSegment,
+		LogFileOperationCreateAttribute,
+		LogFileOperationDeleteAttribute,
+		LogFileOperationUpdateResidentValue,
+		LogFileOperationUpdateMappingPairs,
+		LogFileOperationSetNewAttributeSizes,
+		LogFileOperationAddIndexEntryRoot,
+		LogFileOperationDeleteIndexEntryRoot,
+		LogFileOperationSetIndexEntryVcnRoot,
+		LogFileOperationUpdateFileNameRoot,
+		LogFileOperationUpdateRecordDataRoot,
+		LogFileOperationZeroEndOfFileRecord:
+		return true
+	}
+	return false
+}
+
+// Parse applies fixups to a copy of the raw restart page receiver and returns its restart area.
+func (rawLogFileRestartPage RawLogFileRestartPage) Parse() (restartArea LogFileRestartArea, err error) {
+	const offsetChkdskLsn = 0x08
+	const offsetSystemPageSize = 0x10
+	const offsetLogPageSize = 0x14
+	const offsetRestartAreaOffset = 0x18
+	const offsetMinorVersion = 0x1A
+	const offsetMajorVersion = 0x1C
+
+	const offsetCurrentLsn = 0x00
+	const offsetLogClients = 0x08
+	const offsetFlags = 0x0E
+	const offsetSequenceNumberBits = 0x10
+	const offsetClientArrayOffset = 0x16
+	const offsetFileSize = 0x18
+	const offsetRecordHeaderLength = 0x24
+	const offsetLogPageDataOffset = 0x26
+	const lengthRestartArea = 0x30
+
+	const offsetClientOldestLsn = 0x00
+	const offsetClientRestartLsn = 0x08
+	const offsetClientNameLength = 0x1C
+	const offsetClientName = 0x20
+	const lengthClient = 0xA0
+
+	// Sanity checks
+	sizeOfRawRestartPage := len(rawLogFileRestartPage)
+	if sizeOfRawRestartPage < offsetMajorVersion+0x02 {
+		err = fmt.Errorf("RawLogFileRestartPage.Parse() expected at least %d bytes, instead received %d: %w", offsetMajorVersion+0x02, sizeOfRawRestartPage, ErrTruncated)
+		return
+	} else if string(rawLogFileRestartPage[0x00:0x04]) != "RSTR" {
+		err = errors.New("RawLogFileRestartPage.Parse() received bytes that are not an RSTR page")
+		return
+	}
+
+	fixedPage := make([]byte, sizeOfRawRestartPage)
+	copy(fixedPage, rawLogFileRestartPage)
+	err = applyFixups(fixedPage, logFileBytesPerSector)
+	if err != nil {
+		err = fmt.Errorf("failed to apply fixups to restart page: %w", err)
+		return
+	}
+
+	restartArea.ChkdskLsn = binary.LittleEndian.Uint64(fixedPage[offsetChkdskLsn : offsetChkdskLsn+0x08])
+	restartArea.SystemPageSize = binary.LittleEndian.Uint32(fixedPage[offsetSystemPageSize : offsetSystemPageSize+0x04])
+	restartArea.LogPageSize = binary.LittleEndian.Uint32(fixedPage[offsetLogPageSize : offsetLogPageSize+0x04])
+	restartArea.MinorVersion = int16(binary.LittleEndian.Uint16(fixedPage[offsetMinorVersion : offsetMinorVersion+0x02]))
+	restartArea.MajorVersion = int16(binary.LittleEndian.Uint16(fixedPage[offsetMajorVersion : offsetMajorVersion+0x02]))
+
+	restartAreaOffset := int(binary.LittleEndian.Uint16(fixedPage[offsetRestartAreaOffset : offsetRestartAreaOffset+0x02]))
+	if restartAreaOffset+lengthRestartArea > sizeOfRawRestartPage {
+		err = fmt.Errorf("restart area at offset %d is beyond the %d byte restart page", restartAreaOffset, sizeOfRawRestartPage)
+		return
+	}
+	rawRestartArea := fixedPage[restartAreaOffset:]
+	restartArea.CurrentLsn = binary.LittleEndian.Uint64(rawRestartArea[offsetCurrentLsn : offsetCurrentLsn+0x08])
+	restartArea.Flags = binary.LittleEndian.Uint16(rawRestartArea[offsetFlags : offsetFlags+0x02])
+	restartArea.SequenceNumberBits = binary.LittleEndian.Uint32(rawRestartArea[offsetSequenceNumberBits : offsetSequenceNumberBits+0x04])
+	restartArea.FileSize = binary.LittleEndian.Uint64(rawRestartArea[offsetFileSize : offsetFileSize+0x08])
+	restartArea.RecordHeaderLength = binary.LittleEndian.Uint16(rawRestartArea[offsetRecordHeaderLength : offsetRecordHeaderLength+0x02])
+	restartArea.LogPageDataOffset = binary.LittleEndian.Uint16(rawRestartArea[offsetLogPageDataOffset : offsetLogPageDataOffset+0x02])
+
+	logClients := int(binary.LittleEndian.Uint16(rawRestartArea[offsetLogClients : offsetLogClients+0x02]))
+	clientArrayOffset := int(binary.LittleEndian.Uint16(rawRestartArea[offsetClientArrayOffset : offsetClientArrayOffset+0x02]))
+	for i := 0; i < logClients; i++ {
+		clientOffset := clientArrayOffset + i*lengthClient
+		if clientOffset+lengthClient > len(rawRestartArea) {
+			break
+		}
+		rawClient := rawRestartArea[clientOffset : clientOffset+lengthClient]
+		client := LogFileClient{
+			OldestLsn:        binary.LittleEndian.Uint64(rawClient[offsetClientOldestLsn : offsetClientOldestLsn+0x08]),
+			ClientRestartLsn: binary.LittleEndian.Uint64(rawClient[offsetClientRestartLsn : offsetClientRestartLsn+0x08]),
+		}
+		nameLength := int(binary.LittleEndian.Uint32(rawClient[offsetClientNameLength : offsetClientNameLength+0x04]))
+		if nameLength != 0 && offsetClientName+nameLength <= lengthClient {
+			client.Name, _ = bin.UnicodeBytesToASCII(rawClient[offsetClientName : offsetClientName+nameLength])
+		}
+		restartArea.Clients = append(restartArea.Clients, client)
+	}
+
+	// Sanity check the values that are needed to walk the record pages.
+	if restartArea.LogPageSize == 0 || restartArea.LogPageSize > maximumLogFilePageSize || restartArea.LogPageSize%logFileBytesPerSector != 0 || uint32(restartArea.LogPageDataOffset) >= restartArea.LogPageSize {
+		err = fmt.Errorf("restart area has a log page size of %d with a data offset of %d", restartArea.LogPageSize, restartArea.LogPageDataOffset)
+		return
+	} else if restartArea.SequenceNumberBits < 3 || restartArea.SequenceNumberBits > 64 {
+		err = fmt.Errorf("restart area has an invalid sequence number bit count of %d", restartArea.SequenceNumberBits)
+		return
+	}
+	return
+}
+
+// Parse applies fixups to a copy of the raw record page receiver and returns its header along with the fixed up page.
+func (rawLogFileRecordPage RawLogFileRecordPage) Parse() (recordPage LogFileRecordPage, err error) {
+	const offsetLastLsn = 0x08
+	const offsetFlags = 0x10
+	const offsetPageCount = 0x14
+	const offsetPagePosition = 0x16
+	const offsetNextRecordOffset = 0x18
+	const offsetLastEndLsn = 0x20
+	const lengthRecordPageHeader = 0x28
+
+	// Sanity checks
+	sizeOfRawRecordPage := len(rawLogFileRecordPage)
+	if sizeOfRawRecordPage < lengthRecordPageHeader {
+		err = fmt.Errorf("RawLogFileRecordPage.Parse() expected at least %d bytes, instead received %d: %w", lengthRecordPageHeader, sizeOfRawRecordPage, ErrTruncated)
+		return
+	} else if string(rawLogFileRecordPage[0x00:0x04]) != "RCRD" {
+		err = errors.New("RawLogFileRecordPage.Parse() received bytes that are not an RCRD page")
+		return
+	}
+
+	recordPage.Data = make([]byte, sizeOfRawRecordPage)
+	copy(recordPage.Data, rawLogFileRecordPage)
+	err = applyFixups(recordPage.Data, logFileBytesPerSector)
+	if err != nil {
+		recordPage.Data = nil
+		err = fmt.Errorf("failed to apply fixups to record page: %w", err)
+		return
+	}
+
+	recordPage.LastLsn = binary.LittleEndian.Uint64(recordPage.Data[offsetLastLsn : offsetLastLsn+0x08])
+	recordPage.Flags = binary.LittleEndian.Uint32(recordPage.Data[offsetFlags : offsetFlags+0x04])
+	recordPage.PageCount = binary.LittleEndian.Uint16(recordPage.Data[offsetPageCount : offsetPageCount+0x02])
+	recordPage.PagePosition = binary.LittleEndian.Uint16(recordPage.Data[offsetPagePosition : offsetPagePosition+0x02])
+	recordPage.NextRecordOffset = binary.LittleEndian.Uint16(recordPage.Data[offsetNextRecordOffset : offsetNextRecordOffset+0x02])
+	recordPage.LastEndLsn = binary.LittleEndian.Uint64(recordPage.Data[offsetLastEndLsn : offsetLastEndLsn+0x08])
+	return
+}
+
+// Parse parses the raw LFS record receiver. The NTFS operation header is only parsed for client records, client restart records hold a checkpoint instead.
+func (rawLogFileRecord RawLogFileRecord) Parse() (logFileRecord LogFileRecord, err error) {
+	const offsetLsn = 0x00
+	const offsetPreviousLsn = 0x08
+	const offsetUndoNextLsn = 0x10
+	const offsetClientDataLength = 0x18
+	const offsetRecordType = 0x20
+	const offsetTransactionID = 0x24
+	const offsetFlags = 0x28
+
+	const offsetRedoOperation = 0x00
+	const offsetUndoOperation = 0x02
+	const offsetRedoOffset = 0x04
+	const offsetRedoLength = 0x06
+	const offsetUndoOffset = 0x08
+	const offsetUndoLength = 0x0A
+	const offsetTargetAttribute = 0x0C
+	const offsetLcnsToFollow = 0x0E
+	const offsetRecordOffset = 0x10
+	const offsetAttributeOffset = 0x12
+	const offsetClusterBlockOffset = 0x14
+	const offsetTargetVcn = 0x18
+
+	// Sanity checks
+	sizeOfRawLogFileRecord := len(rawLogFileRecord)
+	if sizeOfRawLogFileRecord < lengthLogFileRecordHeader {
+		err = fmt.Errorf("RawLogFileRecord.Parse() expected at least %d bytes, instead received %d: %w", lengthLogFileRecordHeader, sizeOfRawLogFileRecord, ErrTruncated)
+		return
+	}
+
+	logFileRecord.Lsn = binary.LittleEndian.Uint64(rawLogFileRecord[offsetLsn : offsetLsn+0x08])
+	logFileRecord.PreviousLsn = binary.LittleEndian.Uint64(rawLogFileRecord[offsetPreviousLsn : offsetPreviousLsn+0x08])
+	logFileRecord.UndoNextLsn = binary.LittleEndian.Uint64(rawLogFileRecord[offsetUndoNextLsn : offsetUndoNextLsn+0x08])
+	logFileRecord.ClientDataLength = binary.LittleEndian.Uint32(rawLogFileRecord[offsetClientDataLength : offsetClientDataLength+0x04])
+	logFileRecord.RecordType = binary.LittleEndian.Uint32(rawLogFileRecord[offsetRecordType : offsetRecordType+0x04])
+	logFileRecord.TransactionID = binary.LittleEndian.Uint32(rawLogFileRecord[offsetTransactionID : offsetTransactionID+0x04])
+	logFileRecord.Flags = binary.LittleEndian.Uint16(rawLogFileRecord[offsetFlags : offsetFlags+0x02])
+
+	clientDataEnd := lengthLogFileRecordHeader + int(logFileRecord.ClientDataLength)
+	if clientDataEnd > sizeOfRawLogFileRecord {
+		err = fmt.Errorf("LFS record has %d bytes of client data but only %d bytes follow its header", logFileRecord.ClientDataLength, sizeOfRawLogFileRecord-lengthLogFileRecordHeader)
+		return
+	}
+	if logFileRecord.RecordType != logFileRecordTypeClient {
+		return
+	}
+	clientData := rawLogFileRecord[lengthLogFileRecordHeader:clientDataEnd]
+	if len(clientData) < lengthLogFileOperationHeader {
+		err = fmt.Errorf("LFS client record has %d bytes of client data which is too small for an operation header", len(clientData))
+		return
+	}
+
+	logFileRecord.RedoOperation = LogFileOperation(binary.LittleEndian.Uint16(clientData[offsetRedoOperation : offsetRedoOperation+0x02]))
+	logFileRecord.UndoOperation = LogFileOperation(binary.LittleEndian.Uint16(clientData[offsetUndoOperation : offsetUndoOperation+0x02]))
+	logFileRecord.TargetAttribute = binary.LittleEndian.Uint16(clientData[offsetTargetAttribute : offsetTargetAttribute+0x02])
+	logFileRecord.RecordOffset = binary.LittleEndian.Uint16(clientData[offsetRecordOffset : offsetRecordOffset+0x02])
+	logFileRecord.AttributeOffset = binary.LittleEndian.Uint16(clientData[offsetAttributeOffset : offsetAttributeOffset+0x02])
+	logFileRecord.ClusterBlockOffset = binary.LittleEndian.Uint16(clientData[offsetClusterBlockOffset : offsetClusterBlockOffset+0x02])
+	logFileRecord.TargetVcn = binary.LittleEndian.Uint64(clientData[offsetTargetVcn : offsetTargetVcn+0x08])
+
+	lcnsToFollow := int(binary.LittleEndian.Uint16(clientData[offsetLcnsToFollow : offsetLcnsToFollow+0x02]))
+	for i := 0; i < lcnsToFollow && lengthLogFileOperationHeader+(i+1)*0x08 <= len(clientData); i++ {
+		offsetLcn := lengthLogFileOperationHeader + i*0x08
+		logFileRecord.Lcns = append(logFileRecord.Lcns, binary.LittleEndian.Uint64(clientData[offsetLcn:offsetLcn+0x08]))
+	}
+
+	logFileRecord.RedoData, err = logFileOperationData(clientData, offsetRedoOffset, offsetRedoLength)
+	if err != nil {
+		err = fmt.Errorf("failed to get redo data: %w", err)
+		return
+	}
+	logFileRecord.UndoData, err = logFileOperationData(clientData, offsetUndoOffset, offsetUndoLength)
+	if err != nil {
+		err = fmt.Errorf("failed to get undo data: %w", err)
+		return
+	}
+	return
+}
+
+// Returns a copy of the redo or undo data of an operation. The offset and length fields are read from the operation header at the provided offsets and the data offset is relative to the start of the client data.
+func logFileOperationData(clientData []byte, offsetDataOffset int, offsetDataLength int) (data []byte, err error) {
+	dataOffset := int(binary.LittleEndian.Uint16(clientData[offsetDataOffset : offsetDataOffset+0x02]))
+	dataLength := int(binary.LittleEndian.Uint16(clientData[offsetDataLength : offsetDataLength+0x02]))
+	if dataLength == 0 {
+		return
+	} else if dataOffset+dataLength > len(clientData) {
+		err = fmt.Errorf("data at offset %d with length %d is beyond the %d bytes of client data", dataOffset, dataLength, len(clientData))
+		return
+	}
+	data = make([]byte, dataLength)
+	copy(data, clientData[dataOffset:dataOffset+dataLength])
+	return
+}
+
+// ReadLogFile parses an extracted $LogFile of the provided size. The newest of the two restart pages is used to walk the RCRD pages. Records are found by their LSN, which encodes where in the $LogFile the record was written, so stale data and the copies of the tail pages are left out.
+func ReadLogFile(reader io.ReaderAt, size int64) (logFile LogFile, err error) {
+	logFile.RestartArea, err = readLogFileRestartArea(reader, size)
+	if err != nil {
+		return
+	}
+
+	restartArea := logFile.RestartArea
+	pageSize := int64(restartArea.LogPageSize)
+	dataOffset := int64(restartArea.LogPageDataOffset)
+	dataLength := pageSize - dataOffset
+
+	// Record pages that follow each other are joined so that records spanning pages can be read. A page that can't be parsed ends the segment.
+	var segment []byte
+	var segmentPages []int64
+	seenLsns := make(map[uint64]bool)
+	walkSegment := func() {
+		for _, logFileRecord := range walkLogFileSegment(segment, segmentPages, dataOffset, dataLength, restartArea.SequenceNumberBits) {
+			if !seenLsns[logFileRecord.Lsn] {
+				seenLsns[logFileRecord.Lsn] = true
+				logFile.Records = append(logFile.Records, logFileRecord)
+			}
+		}
+		segment = nil
+		segmentPages = nil
+	}
+	rawPage := make([]byte, pageSize)
+	for pageOffset := 2 * int64(restartArea.SystemPageSize); pageOffset+pageSize <= size; pageOffset += pageSize {
+		_, err = reader.ReadAt(rawPage, pageOffset)
+		if err != nil && err != io.EOF {
+			err = fmt.Errorf("failed to read $LogFile page at offset %d: %w", pageOffset, err)
+			return
+		}
+		err = nil
+		recordPage, parseErr := RawLogFileRecordPage(rawPage).Parse()
+		if parseErr != nil {
+			walkSegment()
+			continue
+		}
+		segment = append(segment, recordPage.Data[dataOffset:]...)
+		segmentPages = append(segmentPages, pageOffset)
+	}
+	walkSegment()
+
+	sort.Slice(logFile.Records, func(i, j int) bool {
+		return logFile.Records[i].Lsn < logFile.Records[j].Lsn
+	})
+	return
+}
+
+// Reads both restart pages and returns the restart area of the newest one that can be parsed.
+func readLogFileRestartArea(reader io.ReaderAt, size int64) (restartArea LogFileRestartArea, err error) {
+	const offsetSystemPageSize = 0x10
+
+	var found bool
+	pageOffset := int64(0)
+	for i := 0; i < 2; i++ {
+		rawHeader := make([]byte, offsetSystemPageSize+0x04)
+		_, readErr := reader.ReadAt(rawHeader, pageOffset)
+		if readErr != nil {
+			break
+		}
+		systemPageSize := int64(binary.LittleEndian.Uint32(rawHeader[offsetSystemPageSize : offsetSystemPageSize+0x04]))
+		if systemPageSize < logFileBytesPerSector || systemPageSize > maximumLogFilePageSize || pageOffset+systemPageSize > size {
+			break
+		}
+		rawRestartPage := make(RawLogFileRestartPage, systemPageSize)
+		_, readErr = reader.ReadAt(rawRestartPage, pageOffset)
+		if readErr != nil && readErr != io.EOF {
+			break
+		}
+		// The log page size is used to size the buffer that record pages are read into, so a restart area claiming pages larger than the $LogFile is ignored.
+		candidate, parseErr := rawRestartPage.Parse()
+		if parseErr == nil && int64(candidate.LogPageSize) <= size && (!found || candidate.CurrentLsn > restartArea.CurrentLsn) {
+			restartArea = candidate
+			found = true
+		}
+		pageOffset += systemPageSize
+	}
+	if !found {
+		err = errors.New("the $LogFile does not have a valid restart page")
+		return
+	}
+	return
+}
+
+// Walks the joined data of consecutive record pages and returns the records whose LSN matches where they sit in the $LogFile.
+func walkLogFileSegment(segment []byte, segmentPages []int64, dataOffset int64, dataLength int64, sequenceNumberBits uint32) (logFileRecords []LogFileRecord) {
+	position := 0
+	for position+lengthLogFileRecordHeader <= len(segment) {
+		fileOffset := segmentPages[int64(position)/dataLength] + dataOffset + int64(position)%dataLength
+		lsn := binary.LittleEndian.Uint64(segment[position : position+0x08])
+		if lsn == 0 || logFileLsnToOffset(lsn, sequenceNumberBits) != fileOffset {
+			position += 0x08
+			continue
+		}
+		clientDataLength := int(binary.LittleEndian.Uint32(segment[position+0x18 : position+0x1C]))
+		recordLength := lengthLogFileRecordHeader + clientDataLength
+		if clientDataLength > maximumLogFileClientData || position+recordLength > len(segment) {
+			position += 0x08
+			continue
+		}
+		logFileRecord, err := RawLogFileRecord(segment[position : position+recordLength]).Parse()
+		if err != nil {
+			position += 0x08
+			continue
+		}
+		logFileRecords = append(logFileRecords, logFileRecord)
+		position += (recordLength + 7) &^ 7
+	}
+	return
+}
+
+// Returns the offset in the $LogFile that an LSN points to. The upper bits of an LSN are a sequence number that counts how often the log has wrapped around, the rest is the offset in 8 byte units.
+func logFileLsnToOffset(lsn uint64, sequenceNumberBits uint32) int64 {
+	return int64((lsn << sequenceNumberBits) >> (sequenceNumberBits - 3))
+}
+
+// Details decodes the redo data, or the undo data for operations that remove something, of the log file record receiver. Operations without a decoder return empty details.
+func (logFileRecord LogFileRecord) Details() (details LogFileOperationDetails, err error) {
+	switch logFileRecord.RedoOperation {
+	case LogFileOperationInitializeFileRecordSegment:
+		details = decodeLogFileFileRecord(logFileRecord.RedoData)
+	case LogFileOperationAddIndexEntryRoot, LogFileOperationAddIndexEntryAllocation:
+		details, err = decodeLogFileIndexEntry(logFileRecord.RedoData)
+	case LogFileOperationDeleteIndexEntryRoot, LogFileOperationDeleteIndexEntryAllocation:
+		details, err = decodeLogFileIndexEntry(logFileRecord.RedoData)
+		if err == nil && details.FileName == "" {
+			details, err = decodeLogFileIndexEntry(logFileRecord.UndoData)
+		}
+	case LogFileOperationUpdateFileNameRoot, LogFileOperationUpdateFileNameAllocation:
+		details, err = decodeLogFileTimestamps(logFileRecord.RedoData, 0x00)
+	case LogFileOperationUpdateResidentValue:
+		// Only updates to the timestamps of $STANDARD_INFORMATION can be recognized without the record they were made to.
+		const offsetSiTimestamps = 0x18
+		const offsetSiTimestampsEnd = 0x38
+		attributeOffset := int(logFileRecord.AttributeOffset)
+		if logFileRecord.RecordOffset == offsetFirstAttribute && attributeOffset >= offsetSiTimestamps && attributeOffset+len(logFileRecord.RedoData) <= offsetSiTimestampsEnd && (attributeOffset-offsetSiTimestamps)%0x08 == 0 {
+			siTimestamps := make([]byte, offsetSiTimestampsEnd-offsetSiTimestamps)
+			copy(siTimestamps[attributeOffset-offsetSiTimestamps:], logFileRecord.RedoData)
+			details, err = decodeLogFileTimestamps(siTimestamps, 0x00)
+		}
+	}
+	return
+}
+
+// Decodes the file name and parent of the file record image that an InitializeFileRecordSegment writes.
+func decodeLogFileFileRecord(redoData []byte) (details LogFileOperationDetails) {
+	const codeFileName = 0x30
+	if len(redoData) < 0x38 || string(redoData[0x00:0x04]) != "FILE" {
+		return
+	}
+	rawMftRecord := make(RawMasterFileTableRecord, len(redoData))
+	copy(rawMftRecord, redoData)
+	details.SequenceNumber = binary.LittleEndian.Uint16(rawMftRecord[0x10:0x12])
+	rawAttributes, _ := rawMftRecord.getRawAttributes()
+	for _, rawAttribute := range rawAttributes {
+		if rawAttribute.attributeType() != codeFileName {
+			continue
+		}
+		fileNameAttribute, err := RawFileNameAttribute(rawAttribute).Parse()
+		if err != nil {
+			continue
+		}
+		details.FileName = fileNameAttribute.FileName
+		details.ParentRecordNumber = uint64(fileNameAttribute.ParentDirRecordNumber)
+		details.ParentSequenceNumber = fileNameAttribute.ParentDirSequenceNumber
+		details.Created = fileNameAttribute.FnCreated
+		details.Modified = fileNameAttribute.FnModified
+		details.Changed = fileNameAttribute.FnChanged
+		details.Accessed = fileNameAttribute.FnAccessed
+		if !strings.Contains(fileNameAttribute.FileNamespace, "DOS") || strings.Contains(fileNameAttribute.FileNamespace, "WIN32") {
+			break
+		}
+	}
+	return
+}
+
+// Decodes a filename index entry. The key of the entry is the content of the file's $FILE_NAME attribute.
+func decodeLogFileIndexEntry(rawIndexEntry []byte) (details LogFileOperationDetails, err error) {
+	indexEntries, _ := parseIndexEntries(rawIndexEntry)
+	if len(indexEntries) == 0 {
+		return
+	}
+	indexEntry := indexEntries[0]
+	details.FileName = fileNameIndexKeyName(indexEntry.Key)
+	if details.FileName == "" {
+		return
+	}
+	details.RecordNumber, details.SequenceNumber = indexEntry.FileReference()
+	details.ParentRecordNumber, details.ParentSequenceNumber = parseUsnFileReference(indexEntry.Key)
+	timestamps, err := decodeLogFileTimestamps(indexEntry.Key, 0x08)
+	if err != nil {
+		err = fmt.Errorf("failed to decode the timestamps of %s: %w", details.FileName, err)
+		return
+	}
+	details.Created, details.Modified, details.Changed, details.Accessed = timestamps.Created, timestamps.Modified, timestamps.Changed, timestamps.Accessed
+	return
+}
+
+// Decodes the created, modified, changed, and accessed timestamps that are stored one after the other starting at the offset. Zeroed out timestamps are left empty.
+func decodeLogFileTimestamps(rawData []byte, offset int) (details LogFileOperationDetails, err error) {
+	timestamps := []*time.Time{&details.Created, &details.Modified, &details.Changed, &details.Accessed}
+	names := []string{"created", "modified", "changed", "accessed"}
+	for i, timestamp := range timestamps {
+		offsetTimestamp := offset + i*0x08
+		if offsetTimestamp+0x08 > len(rawData) {
+			break
+		}
+		rawTimestamp := rawData[offsetTimestamp : offsetTimestamp+0x08]
+		if binary.LittleEndian.Uint64(rawTimestamp) == 0 {
+			continue
+		}
+		*timestamp, err = parseTimestamp(rawTimestamp, names[i])
+		if err != nil {
+			return
+		}
+	}
+	return
+}
+
+// Timeline links the records of the log file receiver to the MFT records they changed. Names seen in the $LogFile are remembered in LSN order so that later operations on the same record can be named even after the file is gone from the MFT.
+func (logFile LogFile) Timeline(options LogFileTimelineOptions) (timeline []LogFileTimelineEntry) {
+	bytesPerCluster := options.BytesPerCluster
+	if bytesPerCluster == 0 {
+		bytesPerCluster = 4096
+	}
+	mftRecordSize := options.MftRecordSize
+	if mftRecordSize == 0 {
+		mftRecordSize = defaultMftRecordSize
+	}
+
+	type logFileName struct {
+		name   string
+		parent uint64
+	}
+	names := make(map[uint64]logFileName)
+	for _, logFileRecord := range logFile.Records {
+		if logFileRecord.RecordType != logFileRecordTypeClient {
+			continue
+		}
+		entry := LogFileTimelineEntry{
+			Lsn:           logFileRecord.Lsn,
+			TransactionID: logFileRecord.TransactionID,
+			RedoOperation: logFileRecord.RedoOperation,
+			UndoOperation: logFileRecord.UndoOperation,
+		}
+		entry.Details, entry.DetailsErr = logFileRecord.Details()
+
+		// Index entry operations are about the file in the entry rather than the directory the index belongs to.
+		if entry.Details.FileName != "" && entry.Details.RecordNumber != 0 {
+			entry.RecordNumber, entry.HasRecordNumber = entry.Details.RecordNumber, true
+		} else if logFileRecord.RedoOperation.targetsMftRecord() || logFileRecord.UndoOperation.targetsMftRecord() {
+			recordOffset := int64(logFileRecord.TargetVcn)*bytesPerCluster + int64(logFileRecord.ClusterBlockOffset)*logFileBytesPerSector
+			entry.RecordNumber, entry.HasRecordNumber = uint64(recordOffset/mftRecordSize), true
+		}
+
+		if entry.HasRecordNumber && entry.Details.FileName != "" {
+			names[entry.RecordNumber] = logFileName{name: entry.Details.FileName, parent: entry.Details.ParentRecordNumber}
+		}
+		if name, ok := names[entry.RecordNumber]; ok && entry.HasRecordNumber {
+			entry.FileName = name.name
+			if parentPath, ok := options.DirectoryTree[uint32(name.parent)]; ok {
+				entry.FullPath = joinWindowsPath(parentPath, name.name)
+			}
+		}
+		if entry.FullPath == "" && entry.HasRecordNumber {
+			entry.FullPath = options.FilePaths[uint32(entry.RecordNumber)]
+		}
+		timeline = append(timeline, entry)
+	}
+	return
+}
+
+// BuildFilePaths takes an MFT and returns the full path of every record that has a file name. The MFT is read twice, once for its directory tree and once for its files.
+func BuildFilePaths(reader io.ReadSeeker, volumeLetter string, bytesPerCluster int64) (filePaths FilePaths, err error) {
+	directoryTree, err := BuildDirectoryTree(reader, volumeLetter)
+	if err != nil {
+		return
+	}
+	_, err = reader.Seek(0, io.SeekStart)
+	if err != nil {
+		err = fmt.Errorf("failed to seek back to the start of the MFT: %w", err)
+		return
+	}
+
+	filePaths = make(FilePaths)
+	for {
+		buffer := make(RawMasterFileTableRecord, defaultMftRecordSize)
+		_, readErr := io.ReadFull(reader, buffer)
+		if readErr != nil {
+			break
+		}
+		mftRecord, parseErr := buffer.Parse(bytesPerCluster)
+		if parseErr != nil {
+			continue
+		}
+		for _, fileNameAttribute := range mftRecord.FileNameAttributes {
+			if strings.Contains(fileNameAttribute.FileNamespace, "WIN32") || strings.Contains(fileNameAttribute.FileNamespace, "POSIX") {
+				parentPath, ok := directoryTree[fileNameAttribute.ParentDirRecordNumber]
+				if !ok {
+					parentPath = fmt.Sprintf("%s:\\$ORPHANFILE", volumeLetter)
+				}
+				filePaths[mftRecord.RecordHeader.RecordNumber] = joinWindowsPath(parentPath, fileNameAttribute.FileName)
+				break
+			}
+		}
+	}
+	return
+}
@@ -0,0 +1,612 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const (
+	testLogFilePageSize           = 4096
+	testLogFileDataOffset         = 0x40
+	testLogFileSequenceNumberBits = 44
+)
+
+// Builds an LSN with a wrap count of 1 for a record at the provided offset of the $LogFile.
+func buildTestLsn(fileOffset int64) uint64 {
+	return 1<<(64-testLogFileSequenceNumberBits) | uint64(fileOffset)>>3
+}
+
+// Builds an RSTR page whose restart area sits at offset 0x40 and has a single NTFS client.
+func buildTestLogFileRestartPage(currentLsn uint64) []byte {
+	const offsetRestartArea = 0x40
+	const offsetClientArray = 0x40
+	fixedPage := make([]byte, testLogFilePageSize)
+	copy(fixedPage, "RSTR")
+	binary.LittleEndian.PutUint32(fixedPage[0x10:], testLogFilePageSize)
+	binary.LittleEndian.PutUint32(fixedPage[0x14:], testLogFilePageSize)
+	binary.LittleEndian.PutUint16(fixedPage[0x18:], offsetRestartArea)
+	binary.LittleEndian.PutUint16(fixedPage[0x1A:], 1)
+	binary.LittleEndian.PutUint16(fixedPage[0x1C:], 1)
+
+	restartArea := fixedPage[offsetRestartArea:]
+	binary.LittleEndian.PutUint64(restartArea[0x00:], currentLsn)
+	binary.LittleEndian.PutUint16(restartArea[0x08:], 1)
+	binary.LittleEndian.PutUint16(restartArea[0x0E:], 0x02)
+	binary.LittleEndian.PutUint32(restartArea[0x10:], testLogFileSequenceNumberBits)
+	binary.LittleEndian.PutUint16(restartArea[0x16:], offsetClientArray)
+	binary.LittleEndian.PutUint64(restartArea[0x18:], 8*testLogFilePageSize)
+	binary.LittleEndian.PutUint16(restartArea[0x24:], lengthLogFileRecordHeader)
+	binary.LittleEndian.PutUint16(restartArea[0x26:], testLogFileDataOffset)
+
+	client := restartArea[offsetClientArray:]
+	binary.LittleEndian.PutUint64(client[0x00:], 0x1000)
+	binary.LittleEndian.PutUint64(client[0x08:], 0x2000)
+	binary.LittleEndian.PutUint32(client[0x1C:], 8)
+	copy(client[0x20:], buildTestUnicode("NTFS"))
+	return buildTestMultiSectorRecord(fixedPage, 512)
+}
+
+// Builds the client data of an NTFS operation with a single LCN. The redo data follows the LCN and the undo data follows the redo data.
+func buildTestLogFileClientData(redoOperation, undoOperation LogFileOperation, targetVcn uint64, clusterBlockOffset, recordOffset, attributeOffset uint16, redoData, undoData []byte) []byte {
+	const offsetRedoData = lengthLogFileOperationHeader + 0x08
+	offsetUndoData := offsetRedoData + (len(redoData)+7)&^7
+	clientData := make([]byte, offsetUndoData+len(undoData))
+	binary.LittleEndian.PutUint16(clientData[0x00:], uint16(redoOperation))
+	binary.LittleEndian.PutUint16(clientData[0x02:], uint16(undoOperation))
+	binary.LittleEndian.PutUint16(clientData[0x04:], offsetRedoData)
+	binary.LittleEndian.PutUint16(clientData[0x06:], uint16(len(redoData)))
+	binary.LittleEndian.PutUint16(clientData[0x08:], uint16(offsetUndoData))
+	binary.LittleEndian.PutUint16(clientData[0x0A:], uint16(len(undoData)))
+	binary.LittleEndian.PutUint16(clientData[0x0E:], 1)
+	binary.LittleEndian.PutUint16(clientData[0x10:], recordOffset)
+	binary.LittleEndian.PutUint16(clientData[0x12:], attributeOffset)
+	binary.LittleEndian.PutUint16(clientData[0x14:], clusterBlockOffset)
+	binary.LittleEndian.PutUint64(clientData[0x18:], targetVcn)
+	binary.LittleEndian.PutUint64(clientData[0x20:], 0x1234)
+	copy(clientData[offsetRedoData:], redoData)
+	copy(clientData[offsetUndoData:], undoData)
+	return clientData
+}
+
+// Builds an LFS record with the provided record type and client data.
+func buildTestLogFileRecord(lsn uint64, previousLsn uint64, recordType uint32, transactionID uint32, clientData []byte) []byte {
+	rawLogFileRecord := make([]byte, lengthLogFileRecordHeader+len(clientData))
+	binary.LittleEndian.PutUint64(rawLogFileRecord[0x00:], lsn)
+	binary.LittleEndian.PutUint64(rawLogFileRecord[0x08:], previousLsn)
+	binary.LittleEndian.PutUint32(rawLogFileRecord[0x18:], uint32(len(clientData)))
+	binary.LittleEndian.PutUint32(rawLogFileRecord[0x20:], recordType)
+	binary.LittleEndian.PutUint32(rawLogFileRecord[0x24:], transactionID)
+	copy(rawLogFileRecord[lengthLogFileRecordHeader:], clientData)
+	return rawLogFileRecord
+}
+
+// Builds a filename index entry for a file in the provided parent directory. Every timestamp of the key is set to testUsnTimestamp.
+func buildTestFileNameIndexEntry(fileName string, fileReference uint64, parentReference uint64) []byte {
+	key := make([]byte, 0x42)
+	binary.LittleEndian.PutUint64(key[0x00:], parentReference)
+	for offset := 0x08; offset < 0x28; offset += 0x08 {
+		binary.LittleEndian.PutUint64(key[offset:], uint64(testUsnTimestamp.UnixNano()/100+116444736000000000))
+	}
+	key[0x40] = byte(len(fileName))
+	key = append(key, buildTestUnicode(fileName)...)
+	rawEntry := make([]byte, (0x10+len(key)+7)&^7)
+	binary.LittleEndian.PutUint64(rawEntry[0x00:], fileReference)
+	binary.LittleEndian.PutUint16(rawEntry[0x08:], uint16(len(rawEntry)))
+	binary.LittleEndian.PutUint16(rawEntry[0x0A:], uint16(len(key)))
+	copy(rawEntry[0x10:], key)
+	return rawEntry
+}
+
+// Builds a $LogFile with two restart pages followed by three record pages, an empty page, and a stale copy of the first record page. The client data are written one after the other with LSNs that match where they land, so records cross page boundaries. The LSNs of the records are returned in order.
+func buildTestLogFile(recordTypes []uint32, clientData [][]byte) (rawLogFile []byte, lsns []uint64) {
+	const firstRecordPage = 2 * testLogFilePageSize
+	const dataLength = testLogFilePageSize - testLogFileDataOffset
+	const recordPageCount = 3
+
+	stream := make([]byte, recordPageCount*dataLength)
+	streamOffsetToFileOffset := func(streamOffset int) int64 {
+		return int64(firstRecordPage + streamOffset/dataLength*testLogFilePageSize + testLogFileDataOffset + streamOffset%dataLength)
+	}
+	position := 0
+	previousLsn := uint64(0)
+	for i, data := range clientData {
+		lsn := buildTestLsn(streamOffsetToFileOffset(position))
+		rawLogFileRecord := buildTestLogFileRecord(lsn, previousLsn, recordTypes[i], 7, data)
+		copy(stream[position:], rawLogFileRecord)
+		position += (len(rawLogFileRecord) + 7) &^ 7
+		lsns = append(lsns, lsn)
+		previousLsn = lsn
+	}
+
+	// A record whose LSN points elsewhere in the $LogFile is stale and must be skipped.
+	copy(stream[position:], buildTestLogFileRecord(lsns[0], 0, logFileRecordTypeClient, 7, clientData[0]))
+
+	rawLogFile = append(rawLogFile, buildTestLogFileRestartPage(lsns[1])...)
+	rawLogFile = append(rawLogFile, buildTestLogFileRestartPage(lsns[len(lsns)-1])...)
+	var firstPage []byte
+	for i := 0; i < recordPageCount; i++ {
+		fixedPage := make([]byte, testLogFilePageSize)
+		copy(fixedPage, "RCRD")
+		binary.LittleEndian.PutUint64(fixedPage[0x08:], lsns[len(lsns)-1])
+		binary.LittleEndian.PutUint16(fixedPage[0x14:], 1)
+		binary.LittleEndian.PutUint16(fixedPage[0x16:], uint16(i+1))
+		copy(fixedPage[testLogFileDataOffset:], stream[i*dataLength:(i+1)*dataLength])
+		page := buildTestMultiSectorRecord(fixedPage, 512)
+		if i == 0 {
+			firstPage = page
+		}
+		rawLogFile = append(rawLogFile, page...)
+	}
+	rawLogFile = append(rawLogFile, make([]byte, testLogFilePageSize)...)
+	rawLogFile = append(rawLogFile, firstPage...)
+	return
+}
+
+// Client data for a transaction that creates record 40 as secret.txt in the root directory and then touches two other records.
+func buildTestLogFileTransaction() (recordTypes []uint32, clientData [][]byte) {
+	rawTimestamp := make([]byte, 8)
+	binary.LittleEndian.PutUint64(rawTimestamp, uint64(testUsnTimestamp.UnixNano()/100+116444736000000000))
+	rawMftRecord := buildTestMftRecord(40, 0, buildTestFileNameAttribute("secret.txt", 5))
+	secretEntry := buildTestFileNameIndexEntry("secret.txt", 40|1<<48, 5|5<<48)
+	oldEntry := buildTestFileNameIndexEntry("old.log", 41|3<<48, 5|5<<48)
+
+	recordTypes = []uint32{1, 1, 1, 1, 1, 2, 1}
+	clientData = [][]byte{
+		buildTestLogFileClientData(LogFileOperationInitializeFileRecordSegment, LogFileOperationNoop, 10, 0, 0, 0, rawMftRecord, nil),
+		buildTestLogFileClientData(LogFileOperationAddIndexEntryAllocation, LogFileOperationDeleteIndexEntryAllocation, 3, 0, 0x28, 0, secretEntry, secretEntry),
+		buildTestLogFileClientData(LogFileOperationUpdateResidentValue, LogFileOperationUpdateResidentValue, 10, 0, offsetFirstAttribute, 0x20, rawTimestamp, make([]byte, 8)),
+		buildTestLogFileClientData(LogFileOperationSetBitsInNonresidentBitMap, LogFileOperationClearBitsInNonresidentBitMap, 0, 0, 0, 0, make([]byte, 2400), nil),
+		buildTestLogFileClientData(LogFileOperationDeleteIndexEntryAllocation, LogFileOperationAddIndexEntryAllocation, 3, 0, 0x28, 0, nil, oldEntry),
+		make([]byte, 0x20),
+		buildTestLogFileClientData(LogFileOperationUpdateResidentValue, LogFileOperationUpdateResidentValue, 10, 4, 0x98, 0x18, make([]byte, 8), make([]byte, 8)),
+	}
+	return
+}
+
+func TestRawLogFileRestartPage_Parse(t *testing.T) {
+	validPage := buildTestLogFileRestartPage(0x100000000123)
+	tornPage := RawLogFileRestartPage(buildTestLogFileRestartPage(0x100000000123))
+	tornPage[1022] = 0x00
+	hugeLogPages := RawLogFileRestartPage(buildTestLogFileRestartPage(0x100000000123))
+	binary.LittleEndian.PutUint32(hugeLogPages[0x14:], 0x100000)
+	tests := []struct {
+		name            string
+		rawRestartPage  RawLogFileRestartPage
+		wantRestartArea LogFileRestartArea
+		wantErr         bool
+	}{
+		{
+			name:           "valid restart page",
+			rawRestartPage: validPage,
+			wantRestartArea: LogFileRestartArea{
+				SystemPageSize:     testLogFilePageSize,
+				LogPageSize:        testLogFilePageSize,
+				MajorVersion:       1,
+				MinorVersion:       1,
+				CurrentLsn:         0x100000000123,
+				Flags:              0x02,
+				SequenceNumberBits: testLogFileSequenceNumberBits,
+				FileSize:           8 * testLogFilePageSize,
+				RecordHeaderLength: lengthLogFileRecordHeader,
+				LogPageDataOffset:  testLogFileDataOffset,
+				Clients:            []LogFileClient{{Name: "NTFS", OldestLsn: 0x1000, ClientRestartLsn: 0x2000}},
+			},
+		},
+		{
+			name:           "not an RSTR page",
+			rawRestartPage: append([]byte("RCRD"), validPage[0x04:]...),
+			wantErr:        true,
+		},
+		{
+			name:           "too few bytes",
+			rawRestartPage: RawLogFileRestartPage(validPage[:0x10]),
+			wantErr:        true,
+		},
+		{
+			name:           "torn write",
+			rawRestartPage: tornPage,
+			wantErr:        true,
+		},
+		{
+			name:           "log page size above 64 KiB",
+			rawRestartPage: hugeLogPages,
+			wantErr:        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRestartArea, err := tt.rawRestartPage.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(gotRestartArea, tt.wantRestartArea) {
+				t.Errorf(cmp.Diff(gotRestartArea, tt.wantRestartArea))
+			}
+		})
+	}
+}
+
+func TestRawLogFileRecordPage_Parse(t *testing.T) {
+	fixedPage := make([]byte, testLogFilePageSize)
+	copy(fixedPage, "RCRD")
+	binary.LittleEndian.PutUint64(fixedPage[0x08:], 0x100000000400)
+	binary.LittleEndian.PutUint32(fixedPage[0x10:], 0x01)
+	binary.LittleEndian.PutUint16(fixedPage[0x14:], 2)
+	binary.LittleEndian.PutUint16(fixedPage[0x16:], 1)
+	binary.LittleEndian.PutUint16(fixedPage[0x18:], 0x120)
+	binary.LittleEndian.PutUint64(fixedPage[0x20:], 0x100000000410)
+	fixedPage[510] = 0x11
+	fixedPage[511] = 0x22
+	validPage := buildTestMultiSectorRecord(fixedPage, 512)
+	wantData := make([]byte, testLogFilePageSize)
+	copy(wantData, validPage)
+	copy(wantData[510:], []byte{0x11, 0x22})
+	for sectorEnd := 1022; sectorEnd < testLogFilePageSize; sectorEnd += 512 {
+		copy(wantData[sectorEnd:], []byte{0x00, 0x00})
+	}
+
+	tests := []struct {
+		name           string
+		rawRecordPage  RawLogFileRecordPage
+		wantRecordPage LogFileRecordPage
+		wantErr        bool
+	}{
+		{
+			name:          "valid record page",
+			rawRecordPage: validPage,
+			wantRecordPage: LogFileRecordPage{
+				LastLsn:          0x100000000400,
+				Flags:            0x01,
+				PageCount:        2,
+				PagePosition:     1,
+				NextRecordOffset: 0x120,
+				LastEndLsn:       0x100000000410,
+				Data:             wantData,
+			},
+		},
+		{
+			name:          "not an RCRD page",
+			rawRecordPage: append([]byte("RSTR"), validPage[0x04:]...),
+			wantErr:       true,
+		},
+		{
+			name:          "too few bytes",
+			rawRecordPage: RawLogFileRecordPage(validPage[:0x20]),
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRecordPage, err := tt.rawRecordPage.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(gotRecordPage, tt.wantRecordPage) {
+				t.Errorf(cmp.Diff(gotRecordPage, tt.wantRecordPage))
+			}
+		})
+	}
+}
+
+func TestRawLogFileRecord_Parse(t *testing.T) {
+	clientData := buildTestLogFileClientData(LogFileOperationUpdateResidentValue, LogFileOperationUpdateResidentValue, 10, 4, 0x38, 0x20, []byte{1, 2, 3}, []byte{4, 5, 6, 7})
+	truncatedRecord := buildTestLogFileRecord(0x100000000800, 0, logFileRecordTypeClient, 7, clientData)
+	binary.LittleEndian.PutUint32(truncatedRecord[0x18:], uint32(len(clientData)+8))
+	badRedoRecord := buildTestLogFileRecord(0x100000000800, 0, logFileRecordTypeClient, 7, clientData)
+	binary.LittleEndian.PutUint16(badRedoRecord[lengthLogFileRecordHeader+0x06:], 0x100)
+
+	tests := []struct {
+		name             string
+		rawLogFileRecord RawLogFileRecord
+		wantRecord       LogFileRecord
+		wantErr          bool
+	}{
+		{
+			name:             "client record",
+			rawLogFileRecord: buildTestLogFileRecord(0x100000000800, 0x1000000007F0, logFileRecordTypeClient, 7, clientData),
+			wantRecord: LogFileRecord{
+				Lsn:                0x100000000800,
+				PreviousLsn:        0x1000000007F0,
+				ClientDataLength:   uint32(len(clientData)),
+				RecordType:         logFileRecordTypeClient,
+				TransactionID:      7,
+				RedoOperation:      LogFileOperationUpdateResidentValue,
+				UndoOperation:      LogFileOperationUpdateResidentValue,
+				RecordOffset:       0x38,
+				AttributeOffset:    0x20,
+				ClusterBlockOffset: 4,
+				TargetVcn:          10,
+				Lcns:               []uint64{0x1234},
+				RedoData:           []byte{1, 2, 3},
+				UndoData:           []byte{4, 5, 6, 7},
+			},
+		},
+		{
+			name:             "restart record",
+			rawLogFileRecord: buildTestLogFileRecord(0x100000000800, 0, 2, 0, make([]byte, 0x10)),
+			wantRecord: LogFileRecord{
+				Lsn:              0x100000000800,
+				ClientDataLength: 0x10,
+				RecordType:       2,
+			},
+		},
+		{
+			name:             "too few bytes",
+			rawLogFileRecord: make(RawLogFileRecord, 0x20),
+			wantErr:          true,
+		},
+		{
+			name:             "client data beyond the record",
+			rawLogFileRecord: truncatedRecord,
+			wantErr:          true,
+		},
+		{
+			name:             "client record without an operation header",
+			rawLogFileRecord: buildTestLogFileRecord(0x100000000800, 0, logFileRecordTypeClient, 7, make([]byte, 0x10)),
+			wantErr:          true,
+		},
+		{
+			name:             "redo data beyond the client data",
+			rawLogFileRecord: badRedoRecord,
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotRecord, err := tt.rawLogFileRecord.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(gotRecord, tt.wantRecord) {
+				t.Errorf(cmp.Diff(gotRecord, tt.wantRecord))
+			}
+		})
+	}
+}
+
+func TestLogFileOperation_String(t *testing.T) {
+	tests := []struct {
+		name             string
+		logFileOperation LogFileOperation
+		want             string
+	}{
+		{
+			name:             "known operation",
+			logFileOperation: LogFileOperationInitializeFileRecordSegment,
+			want:             "InitializeFileRecordSegment",
+		},
+		{
+			name:             "last known operation",
+			logFileOperation: LogFileOperationZeroEndOfFileRecord,
+			want:             "ZeroEndOfFileRecord",
+		},
+		{
+			name:             "unknown operation",
+			logFileOperation: 0x40,
+			want:             "0x40",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.logFileOperation.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadLogFile(t *testing.T) {
+	recordTypes, clientData := buildTestLogFileTransaction()
+	rawLogFile, lsns := buildTestLogFile(recordTypes, clientData)
+	noRestartPages := make([]byte, len(rawLogFile))
+	copy(noRestartPages[2*testLogFilePageSize:], rawLogFile[2*testLogFilePageSize:])
+	largeLogPages := bytes.Join([][]byte{buildTestLogFileRestartPage(lsns[0]), buildTestLogFileRestartPage(lsns[0])}, nil)
+	binary.LittleEndian.PutUint32(largeLogPages[0x14:], 0x8000)
+	binary.LittleEndian.PutUint32(largeLogPages[testLogFilePageSize+0x14:], 0x8000)
+
+	tests := []struct {
+		name           string
+		rawLogFile     []byte
+		wantCurrentLsn uint64
+		wantLsns       []uint64
+		wantOperations []LogFileOperation
+		wantErr        bool
+	}{
+		{
+			name:           "records across pages with a stale page copy",
+			rawLogFile:     rawLogFile,
+			wantCurrentLsn: lsns[len(lsns)-1],
+			wantLsns:       lsns,
+			wantOperations: []LogFileOperation{
+				LogFileOperationInitializeFileRecordSegment,
+				LogFileOperationAddIndexEntryAllocation,
+				LogFileOperationUpdateResidentValue,
+				LogFileOperationSetBitsInNonresidentBitMap,
+				LogFileOperationDeleteIndexEntryAllocation,
+				LogFileOperationNoop,
+				LogFileOperationUpdateResidentValue,
+			},
+		},
+		{
+			name:       "no restart pages",
+			rawLogFile: noRestartPages,
+			wantErr:    true,
+		},
+		{
+			name:       "log pages larger than the $LogFile",
+			rawLogFile: largeLogPages,
+			wantErr:    true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotLogFile, err := ReadLogFile(bytes.NewReader(tt.rawLogFile), int64(len(tt.rawLogFile)))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadLogFile() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if gotLogFile.RestartArea.CurrentLsn != tt.wantCurrentLsn {
+				t.Errorf("ReadLogFile() current lsn = 0x%X, want 0x%X", gotLogFile.RestartArea.CurrentLsn, tt.wantCurrentLsn)
+			}
+			var gotLsns []uint64
+			var gotOperations []LogFileOperation
+			for _, logFileRecord := range gotLogFile.Records {
+				gotLsns = append(gotLsns, logFileRecord.Lsn)
+				gotOperations = append(gotOperations, logFileRecord.RedoOperation)
+			}
+			if !reflect.DeepEqual(gotLsns, tt.wantLsns) {
+				t.Errorf(cmp.Diff(gotLsns, tt.wantLsns))
+			}
+			if !reflect.DeepEqual(gotOperations, tt.wantOperations) {
+				t.Errorf(cmp.Diff(gotOperations, tt.wantOperations))
+			}
+		})
+	}
+}
+
+func TestLogFile_Timeline(t *testing.T) {
+	recordTypes, clientData := buildTestLogFileTransaction()
+	rawLogFile, lsns := buildTestLogFile(recordTypes, clientData)
+	logFile, err := ReadLogFile(bytes.NewReader(rawLogFile), int64(len(rawLogFile)))
+	if err != nil {
+		t.Fatalf("ReadLogFile() error = %v", err)
+	}
+	fileNameAttribute, _ := RawFileNameAttribute(buildTestFileNameAttribute("secret.txt", 5)).Parse()
+
+	want := []LogFileTimelineEntry{
+		{
+			Lsn:             lsns[0],
+			TransactionID:   7,
+			RedoOperation:   LogFileOperationInitializeFileRecordSegment,
+			UndoOperation:   LogFileOperationNoop,
+			RecordNumber:    40,
+			HasRecordNumber: true,
+			FileName:        "secret.txt",
+			FullPath:        "C:\\secret.txt",
+			Details: LogFileOperationDetails{
+				FileName:           "secret.txt",
+				ParentRecordNumber: 5,
+				Created:            fileNameAttribute.FnCreated,
+				Modified:           fileNameAttribute.FnModified,
+				Changed:            fileNameAttribute.FnChanged,
+				Accessed:           fileNameAttribute.FnAccessed,
+			},
+		},
+		{
+			Lsn:             lsns[1],
+			TransactionID:   7,
+			RedoOperation:   LogFileOperationAddIndexEntryAllocation,
+			UndoOperation:   LogFileOperationDeleteIndexEntryAllocation,
+			RecordNumber:    40,
+			HasRecordNumber: true,
+			FileName:        "secret.txt",
+			FullPath:        "C:\\secret.txt",
+			Details: LogFileOperationDetails{
+				FileName:             "secret.txt",
+				RecordNumber:         40,
+				SequenceNumber:       1,
+				ParentRecordNumber:   5,
+				ParentSequenceNumber: 5,
+				Created:              testUsnTimestamp,
+				Modified:             testUsnTimestamp,
+				Changed:              testUsnTimestamp,
+				Accessed:             testUsnTimestamp,
+			},
+		},
+		{
+			Lsn:             lsns[2],
+			TransactionID:   7,
+			RedoOperation:   LogFileOperationUpdateResidentValue,
+			UndoOperation:   LogFileOperationUpdateResidentValue,
+			RecordNumber:    40,
+			HasRecordNumber: true,
+			FileName:        "secret.txt",
+			FullPath:        "C:\\secret.txt",
+			Details:         LogFileOperationDetails{Modified: testUsnTimestamp},
+		},
+		{
+			Lsn:           lsns[3],
+			TransactionID: 7,
+			RedoOperation: LogFileOperationSetBitsInNonresidentBitMap,
+			UndoOperation: LogFileOperationClearBitsInNonresidentBitMap,
+		},
+		{
+			Lsn:             lsns[4],
+			TransactionID:   7,
+			RedoOperation:   LogFileOperationDeleteIndexEntryAllocation,
+			UndoOperation:   LogFileOperationAddIndexEntryAllocation,
+			RecordNumber:    41,
+			HasRecordNumber: true,
+			FileName:        "old.log",
+			FullPath:        "C:\\old.log",
+			Details: LogFileOperationDetails{
+				FileName:             "old.log",
+				RecordNumber:         41,
+				SequenceNumber:       3,
+				ParentRecordNumber:   5,
+				ParentSequenceNumber: 5,
+				Created:              testUsnTimestamp,
+				Modified:             testUsnTimestamp,
+				Changed:              testUsnTimestamp,
+				Accessed:             testUsnTimestamp,
+			},
+		},
+		{
+			Lsn:             lsns[6],
+			TransactionID:   7,
+			RedoOperation:   LogFileOperationUpdateResidentValue,
+			UndoOperation:   LogFileOperationUpdateResidentValue,
+			RecordNumber:    42,
+			HasRecordNumber: true,
+			FullPath:        "C:\\Windows\\notepad.exe",
+		},
+	}
+
+	options := LogFileTimelineOptions{
+		DirectoryTree: DirectoryTree{5: "C:\\"},
+		FilePaths:     FilePaths{42: "C:\\Windows\\notepad.exe"},
+	}
+	got := logFile.Timeline(options)
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
+
+func TestBuildFilePaths(t *testing.T) {
+	rootDirectory := buildTestMftRecord(5, 0, buildTestFileNameAttribute(".", 5))
+	rootDirectory[0x16] = 0x03
+	windowsDirectory := buildTestMftRecord(30, 0, buildTestFileNameAttribute("Windows", 5))
+	windowsDirectory[0x16] = 0x03
+	notepad := buildTestMftRecord(42, 0, buildTestFileNameAttribute("notepad.exe", 30))
+	orphan := buildTestMftRecord(43, 0, buildTestFileNameAttribute("orphan.txt", 99))
+	var rawMft []byte
+	for _, rawMftRecord := range []RawMasterFileTableRecord{rootDirectory, windowsDirectory, notepad, orphan} {
+		rawMft = append(rawMft, rawMftRecord...)
+	}
+
+	want := FilePaths{
+		5:  "C:\\.",
+		30: "C:\\Windows",
+		42: "C:\\Windows\\notepad.exe",
+		43: "C:\\$ORPHANFILE\\orphan.txt",
+	}
+	got, err := BuildFilePaths(bytes.NewReader(rawMft), "C", 4096)
+	if err != nil {
+		t.Fatalf("BuildFilePaths() error = %v", err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RawLoggedUtilityStreamAttribute is a []byte alias for a raw $LOGGED_UTILITY_STREAM attribute. Used with the Parse() and ParseNonResident() methods.
+// See here for the layout: https://flatcap.org/linux-ntfs/ntfs/attributes/logged_utility_stream.html
+type RawLoggedUtilityStreamAttribute []byte
+
+// RawEFSMetadata is a []byte alias for the raw contents of an $EFS logged utility stream. Used with the Parse() method.
+type RawEFSMetadata []byte
+
+// LoggedUtilityStream contains a parsed $LOGGED_UTILITY_STREAM attribute. The stream is named after the feature that owns it, $EFS for encrypted files and $TXF_DATA for transactional NTFS.
+type LoggedUtilityStream struct {
+	Name     string
+	Resident bool
+
+	// EFS is only filled in for resident $EFS streams or after ParseNonResident() is used.
+	EFS *EFSMetadata
+}
+
+// EFSMetadata contains the parsed header of an $EFS stream. Every DDF entry is a user that can decrypt the file and every DRF entry is a recovery agent that can decrypt the file.
+type EFSMetadata struct {
+	Version          uint32
+	CryptoAPIVersion uint32
+	DDF              []EFSKeyEntry
+	DRF              []EFSKeyEntry
+}
+
+// EFSKeyEntry contains an entry of the data decryption field or data recovery field of an $EFS stream. The encrypted file encryption key isn't decoded.
+type EFSKeyEntry struct {
+	CredentialType        uint32
+	SID                   string
+	CertificateThumbprint string
+	ContainerName         string
+	ProviderName          string
+	UserName              string
+}
+
+// Logged utility stream names.
+const (
+	loggedUtilityStreamEFS = "$EFS"
+	loggedUtilityStreamTxF = "$TXF_DATA"
+
+	efsCredentialTypeCertificate = 3
+)
+
+// Parse parses the raw logged utility stream attribute receiver. The $EFS metadata of resident streams is also parsed. Non-resident $EFS metadata is read with ParseNonResident().
+func (rawLoggedUtilityStreamAttribute RawLoggedUtilityStreamAttribute) Parse() (loggedUtilityStream LoggedUtilityStream, err error) {
+	const offsetResidentFlag = 0x08
+	const offsetContentLength = 0x10
+	const lengthContentLength = 0x04
+	const offsetContentOffset = 0x14
+	const lengthContentOffset = 0x02
+
+	// Sanity checks
+	err = rawLoggedUtilityStreamAttribute.sanityCheck(offsetContentOffset + lengthContentOffset)
+	if err != nil {
+		return
+	}
+
+	loggedUtilityStream.Name = rawAttribute(rawLoggedUtilityStreamAttribute).name()
+	loggedUtilityStream.Resident = RawResidencyFlag(rawLoggedUtilityStreamAttribute[offsetResidentFlag]).Parse()
+	if loggedUtilityStream.Resident == false || loggedUtilityStream.Name != loggedUtilityStreamEFS {
+		return
+	}
+
+	contentLength := int(binary.LittleEndian.Uint32(rawLoggedUtilityStreamAttribute[offsetContentLength : offsetContentLength+lengthContentLength]))
+	contentOffset := int(binary.LittleEndian.Uint16(rawLoggedUtilityStreamAttribute[offsetContentOffset : offsetContentOffset+lengthContentOffset]))
+	if contentOffset+contentLength > len(rawLoggedUtilityStreamAttribute) {
+		err = fmt.Errorf("RawLoggedUtilityStreamAttribute.Parse() content at offset %d with length %d is beyond the attribute", contentOffset, contentLength)
+		return
+	}
+	var efsMetadata EFSMetadata
+	efsMetadata, err = RawEFSMetadata(rawLoggedUtilityStreamAttribute[contentOffset : contentOffset+contentLength]).Parse()
+	if err != nil {
+		err = fmt.Errorf("failed to parse $EFS metadata: %w", err)
+		return
+	}
+	loggedUtilityStream.EFS = &efsMetadata
+	return
+}
+
+// ParseNonResident reads a non-resident logged utility stream from the volume and parses it. The volume must start at the beginning of the NTFS partition since data runs are relative to it.
+func (rawLoggedUtilityStreamAttribute RawLoggedUtilityStreamAttribute) ParseNonResident(volume io.ReaderAt, bytesPerCluster int64) (loggedUtilityStream LoggedUtilityStream, err error) {
+	const offsetResidentFlag = 0x08
+	const offsetActualSize = 0x30
+	const lengthActualSize = 0x08
+
+	// Sanity checks
+	err = rawLoggedUtilityStreamAttribute.sanityCheck(offsetActualSize + lengthActualSize)
+	if err != nil {
+		return
+	} else if RawResidencyFlag(rawLoggedUtilityStreamAttribute[offsetResidentFlag]).Parse() == true {
+		err = errors.New("RawLoggedUtilityStreamAttribute.ParseNonResident() received a resident logged utility stream")
+		return
+	} else if volume == nil {
+		err = errors.New("RawLoggedUtilityStreamAttribute.ParseNonResident() did not receive a volume to read from")
+		return
+	}
+
+	loggedUtilityStream.Name = rawAttribute(rawLoggedUtilityStreamAttribute).name()
+	if loggedUtilityStream.Name != loggedUtilityStreamEFS {
+		return
+	}
+
+	rawStream, err := readNonResidentAttribute(volume, rawLoggedUtilityStreamAttribute, bytesPerCluster)
+	if err != nil {
+		err = fmt.Errorf("failed to read the logged utility stream from the volume: %w", err)
+		return
+	}
+	var efsMetadata EFSMetadata
+	efsMetadata, err = RawEFSMetadata(rawStream).Parse()
+	if err != nil {
+		err = fmt.Errorf("failed to parse $EFS metadata: %w", err)
+		return
+	}
+	loggedUtilityStream.EFS = &efsMetadata
+	return
+}
+
+// Sanity checks shared by the logged utility stream parse methods.
+func (rawLoggedUtilityStreamAttribute RawLoggedUtilityStreamAttribute) sanityCheck(minimumSize int) (err error) {
+	const codeLoggedUtilityStream = 0x100
+
+	sizeOfRawAttribute := len(rawLoggedUtilityStreamAttribute)
+	if sizeOfRawAttribute < minimumSize {
+		err = fmt.Errorf("RawLoggedUtilityStreamAttribute expected at least %d bytes, instead received %d: %w", minimumSize, sizeOfRawAttribute, ErrTruncated)
+	} else if attributeType := binary.LittleEndian.Uint32(rawLoggedUtilityStreamAttribute[0x00:0x04]); attributeType != codeLoggedUtilityStream {
+		err = fmt.Errorf("RawLoggedUtilityStreamAttribute received an attribute thats not a logged utility stream. Attribute type is %x", attributeType)
+	}
+	return
+}
+
+// IsEFS reports whether the logged utility stream receiver holds the EFS metadata of an encrypted file.
+func (loggedUtilityStream LoggedUtilityStream) IsEFS() bool {
+	return loggedUtilityStream.Name == loggedUtilityStreamEFS
+}
+
+// IsTxF reports whether the logged utility stream receiver holds transactional NTFS data. Files touched by a transaction keep this stream, which is worth knowing since TxF has been used to hide file changes.
+func (loggedUtilityStream LoggedUtilityStream) IsTxF() bool {
+	return loggedUtilityStream.Name == loggedUtilityStreamTxF
+}
+
+// Parse parses the raw $EFS metadata receiver and returns its DDF and DRF entries.
+// See here for the layout: https://github.com/tuxera/ntfs-3g/blob/edge/include/ntfs-3g/layout.h
+func (rawEFSMetadata RawEFSMetadata) Parse() (efsMetadata EFSMetadata, err error) {
+	const offsetVersion = 0x08
+	const offsetCryptoAPIVersion = 0x0C
+	const offsetDDF = 0x40
+	const offsetDRF = 0x44
+	const lengthHeader = 0x54
+
+	// Sanity checks
+	sizeOfRawEFSMetadata := len(rawEFSMetadata)
+	if sizeOfRawEFSMetadata < lengthHeader {
+		err = fmt.Errorf("RawEFSMetadata.Parse() expected at least %d bytes, instead received %d: %w", lengthHeader, sizeOfRawEFSMetadata, ErrTruncated)
+		return
+	}
+
+	efsMetadata.Version = binary.LittleEndian.Uint32(rawEFSMetadata[offsetVersion : offsetVersion+0x04])
+	efsMetadata.CryptoAPIVersion = binary.LittleEndian.Uint32(rawEFSMetadata[offsetCryptoAPIVersion : offsetCryptoAPIVersion+0x04])
+	ddfOffset := int(binary.LittleEndian.Uint32(rawEFSMetadata[offsetDDF : offsetDDF+0x04]))
+	drfOffset := int(binary.LittleEndian.Uint32(rawEFSMetadata[offsetDRF : offsetDRF+0x04]))
+
+	// Every encrypted file has a DDF, the DRF is only there when a recovery agent is configured.
+	efsMetadata.DDF, err = parseEFSKeyEntries(rawEFSMetadata, ddfOffset)
+	if err != nil {
+		err = fmt.Errorf("failed to parse the DDF: %w", err)
+		return
+	}
+	if drfOffset != 0 {
+		efsMetadata.DRF, err = parseEFSKeyEntries(rawEFSMetadata, drfOffset)
+		if err != nil {
+			err = fmt.Errorf("failed to parse the DRF: %w", err)
+			return
+		}
+	}
+	return
+}
+
+// Parses a DDF or DRF key field array, which is an entry count followed by the entries.
+func parseEFSKeyEntries(rawEFSMetadata []byte, arrayOffset int) (efsKeyEntries []EFSKeyEntry, err error) {
+	const lengthEntryCount = 0x04
+	const offsetCredentialHeaderOffset = 0x04
+	const lengthEntryHeader = 0x14
+
+	sizeOfRawEFSMetadata := len(rawEFSMetadata)
+	if arrayOffset+lengthEntryCount > sizeOfRawEFSMetadata {
+		err = fmt.Errorf("key field array offset %d is beyond the %d byte stream", arrayOffset, sizeOfRawEFSMetadata)
+		return
+	}
+	entryCount := int(binary.LittleEndian.Uint32(rawEFSMetadata[arrayOffset : arrayOffset+lengthEntryCount]))
+	entryOffset := arrayOffset + lengthEntryCount
+	for index := 0; index < entryCount; index++ {
+		if entryOffset+lengthEntryHeader > sizeOfRawEFSMetadata {
+			err = fmt.Errorf("key field entry %d at offset %d is beyond the %d byte stream", index, entryOffset, sizeOfRawEFSMetadata)
+			return
+		}
+		entryLength := int(binary.LittleEndian.Uint32(rawEFSMetadata[entryOffset : entryOffset+0x04]))
+		if entryLength < lengthEntryHeader || entryOffset+entryLength > sizeOfRawEFSMetadata {
+			err = fmt.Errorf("key field entry %d has an invalid length of %d", index, entryLength)
+			return
+		}
+		rawEntry := rawEFSMetadata[entryOffset : entryOffset+entryLength]
+		credentialHeaderOffset := int(binary.LittleEndian.Uint32(rawEntry[offsetCredentialHeaderOffset : offsetCredentialHeaderOffset+0x04]))
+		var efsKeyEntry EFSKeyEntry
+		efsKeyEntry, err = parseEFSCredentialHeader(rawEntry, credentialHeaderOffset)
+		if err != nil {
+			err = fmt.Errorf("failed to parse the credential of key field entry %d: %w", index, err)
+			return
+		}
+		efsKeyEntries = append(efsKeyEntries, efsKeyEntry)
+		entryOffset += entryLength
+	}
+	return
+}
+
+// Parses the credential header of a key field entry. Certificate credentials point to a thumbprint header holding the certificate hash and the names of the key container, provider, and user.
+func parseEFSCredentialHeader(rawEntry []byte, credentialHeaderOffset int) (efsKeyEntry EFSKeyEntry, err error) {
+	const offsetSIDOffset = 0x04
+	const offsetType = 0x08
+	const offsetThumbprintHeaderOffset = 0x10
+	const lengthCredentialHeader = 0x1C
+
+	const offsetThumbprintOffset = 0x00
+	const offsetThumbprintSize = 0x04
+	const offsetContainerNameOffset = 0x08
+	const offsetProviderNameOffset = 0x0C
+	const offsetUserNameOffset = 0x10
+	const lengthThumbprintHeader = 0x14
+
+	if credentialHeaderOffset+lengthCredentialHeader > len(rawEntry) {
+		err = fmt.Errorf("credential header at offset %d is beyond the %d byte entry", credentialHeaderOffset, len(rawEntry))
+		return
+	}
+	rawCredential := rawEntry[credentialHeaderOffset:]
+	efsKeyEntry.CredentialType = binary.LittleEndian.Uint32(rawCredential[offsetType : offsetType+0x04])
+
+	sidOffset := int(binary.LittleEndian.Uint32(rawCredential[offsetSIDOffset : offsetSIDOffset+0x04]))
+	if sidOffset != 0 && sidOffset < len(rawCredential) {
+		efsKeyEntry.SID, _, err = RawSID(rawCredential[sidOffset:]).Parse()
+		if err != nil {
+			err = fmt.Errorf("failed to parse the SID: %w", err)
+			return
+		}
+	}
+
+	if efsKeyEntry.CredentialType != efsCredentialTypeCertificate {
+		return
+	}
+	thumbprintHeaderOffset := int(binary.LittleEndian.Uint32(rawCredential[offsetThumbprintHeaderOffset : offsetThumbprintHeaderOffset+0x04]))
+	if thumbprintHeaderOffset+lengthThumbprintHeader > len(rawCredential) {
+		err = fmt.Errorf("certificate thumbprint header at offset %d is beyond the credential", thumbprintHeaderOffset)
+		return
+	}
+	rawThumbprintHeader := rawCredential[thumbprintHeaderOffset:]
+	thumbprintOffset := int(binary.LittleEndian.Uint32(rawThumbprintHeader[offsetThumbprintOffset : offsetThumbprintOffset+0x04]))
+	thumbprintSize := int(binary.LittleEndian.Uint32(rawThumbprintHeader[offsetThumbprintSize : offsetThumbprintSize+0x04]))
+	if thumbprintOffset+thumbprintSize > len(rawThumbprintHeader) {
+		err = fmt.Errorf("certificate thumbprint at offset %d with size %d is beyond the credential", thumbprintOffset, thumbprintSize)
+		return
+	}
+	efsKeyEntry.CertificateThumbprint = fmt.Sprintf("%X", rawThumbprintHeader[thumbprintOffset:thumbprintOffset+thumbprintSize])
+
+	// The names are null terminated utf16 strings. An offset of 0 means the name isn't stored.
+	names := []struct {
+		offsetNameOffset int
+		name             *string
+	}{
+		{offsetContainerNameOffset, &efsKeyEntry.ContainerName},
+		{offsetProviderNameOffset, &efsKeyEntry.ProviderName},
+		{offsetUserNameOffset, &efsKeyEntry.UserName},
+	}
+	for _, name := range names {
+		nameOffset := int(binary.LittleEndian.Uint32(rawThumbprintHeader[name.offsetNameOffset : name.offsetNameOffset+0x04]))
+		if nameOffset == 0 || nameOffset >= len(rawThumbprintHeader) {
+			continue
+		}
+		if strs := splitNullTerminatedUnicode(rawThumbprintHeader[nameOffset:]); len(strs) > 0 {
+			*name.name = strs[0]
+		}
+	}
+	return
+}
+
+// String returns the key entry receiver as SID:user name:certificate thumbprint. Fields that aren't stored are left empty.
+func (efsKeyEntry EFSKeyEntry) String() string {
+	return strings.Join([]string{efsKeyEntry.SID, efsKeyEntry.UserName, efsKeyEntry.CertificateThumbprint}, ":")
+}
@@ -0,0 +1,358 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds a raw SID out of the identifier authority and sub authorities.
+func buildTestSID(identifierAuthority byte, subAuthorities ...uint32) []byte {
+	rawSID := make([]byte, 0x08+len(subAuthorities)*4)
+	rawSID[0x00] = 0x01
+	rawSID[0x01] = byte(len(subAuthorities))
+	rawSID[0x07] = identifierAuthority
+	for index, subAuthority := range subAuthorities {
+		binary.LittleEndian.PutUint32(rawSID[0x08+index*4:], subAuthority)
+	}
+	return rawSID
+}
+
+// Builds a DDF or DRF key field entry holding a certificate credential.
+func buildTestEFSKeyEntry(rawSID []byte, thumbprint []byte, containerName, providerName, userName string) []byte {
+	const offsetCredentialHeader = 0x14
+	const lengthCredentialHeader = 0x1C
+	const lengthThumbprintHeader = 0x14
+
+	// Thumbprint header followed by the thumbprint and the null terminated names.
+	rawThumbprintHeader := make([]byte, lengthThumbprintHeader)
+	binary.LittleEndian.PutUint32(rawThumbprintHeader[0x00:], lengthThumbprintHeader)
+	binary.LittleEndian.PutUint32(rawThumbprintHeader[0x04:], uint32(len(thumbprint)))
+	rawThumbprintHeader = append(rawThumbprintHeader, thumbprint...)
+	for index, name := range []string{containerName, providerName, userName} {
+		binary.LittleEndian.PutUint32(rawThumbprintHeader[0x08+index*4:], uint32(len(rawThumbprintHeader)))
+		rawThumbprintHeader = append(rawThumbprintHeader, buildTestUnicode(name)...)
+		rawThumbprintHeader = append(rawThumbprintHeader, 0x00, 0x00)
+	}
+
+	rawCredential := make([]byte, lengthCredentialHeader)
+	binary.LittleEndian.PutUint32(rawCredential[0x04:], lengthCredentialHeader)
+	binary.LittleEndian.PutUint32(rawCredential[0x08:], efsCredentialTypeCertificate)
+	binary.LittleEndian.PutUint32(rawCredential[0x10:], uint32(lengthCredentialHeader+len(rawSID)))
+	rawCredential = append(rawCredential, rawSID...)
+	rawCredential = append(rawCredential, rawThumbprintHeader...)
+	binary.LittleEndian.PutUint32(rawCredential[0x00:], uint32(len(rawCredential)))
+
+	rawEntry := make([]byte, offsetCredentialHeader)
+	binary.LittleEndian.PutUint32(rawEntry[0x04:], offsetCredentialHeader)
+	rawEntry = append(rawEntry, rawCredential...)
+	binary.LittleEndian.PutUint32(rawEntry[0x00:], uint32(len(rawEntry)))
+	return rawEntry
+}
+
+// Builds an $EFS stream. The DRF offset is left at 0 when no recovery entries are provided.
+func buildTestEFSMetadata(ddfEntries [][]byte, drfEntries [][]byte) []byte {
+	const lengthHeader = 0x54
+	rawEFSMetadata := make([]byte, lengthHeader)
+	binary.LittleEndian.PutUint32(rawEFSMetadata[0x08:], 2)
+	binary.LittleEndian.PutUint32(rawEFSMetadata[0x0C:], 3)
+	buildKeyField := func(offsetKeyFieldOffset int, rawEntries [][]byte) {
+		binary.LittleEndian.PutUint32(rawEFSMetadata[offsetKeyFieldOffset:], uint32(len(rawEFSMetadata)))
+		entryCount := make([]byte, 4)
+		binary.LittleEndian.PutUint32(entryCount, uint32(len(rawEntries)))
+		rawEFSMetadata = append(rawEFSMetadata, entryCount...)
+		for _, rawEntry := range rawEntries {
+			rawEFSMetadata = append(rawEFSMetadata, rawEntry...)
+		}
+	}
+	buildKeyField(0x40, ddfEntries)
+	if len(drfEntries) != 0 {
+		buildKeyField(0x44, drfEntries)
+	}
+	binary.LittleEndian.PutUint32(rawEFSMetadata[0x00:], uint32(len(rawEFSMetadata)))
+	return rawEFSMetadata
+}
+
+// Builds a named resident logged utility stream attribute with the content right after the name.
+func buildTestLoggedUtilityStreamAttribute(name string, content []byte) []byte {
+	const nameOffset = 0x18
+	rawName := buildTestUnicode(name)
+	contentOffset := (nameOffset + len(rawName) + 7) &^ 7
+	rawAttribute := make([]byte, contentOffset+len(content))
+	binary.LittleEndian.PutUint32(rawAttribute[0x00:], 0x100)
+	binary.LittleEndian.PutUint32(rawAttribute[0x04:], uint32(len(rawAttribute)))
+	rawAttribute[0x09] = byte(len(name))
+	binary.LittleEndian.PutUint16(rawAttribute[0x0A:], nameOffset)
+	binary.LittleEndian.PutUint32(rawAttribute[0x10:], uint32(len(content)))
+	binary.LittleEndian.PutUint16(rawAttribute[0x14:], uint16(contentOffset))
+	copy(rawAttribute[nameOffset:], rawName)
+	copy(rawAttribute[contentOffset:], content)
+	return rawAttribute
+}
+
+// Builds a named non-resident logged utility stream attribute using the provided raw data runs.
+func buildTestNonResidentLoggedUtilityStreamAttribute(name string, actualSize uint64, rawDataRuns []byte) []byte {
+	const nameOffset = 0x40
+	rawName := buildTestUnicode(name)
+	offsetDataRuns := (nameOffset + len(rawName) + 7) &^ 7
+	size := (offsetDataRuns + len(rawDataRuns) + 1 + 7) &^ 7
+	rawAttribute := make([]byte, size)
+	binary.LittleEndian.PutUint32(rawAttribute[0x00:], 0x100)
+	binary.LittleEndian.PutUint32(rawAttribute[0x04:], uint32(size))
+	rawAttribute[0x08] = 0x01
+	rawAttribute[0x09] = byte(len(name))
+	binary.LittleEndian.PutUint16(rawAttribute[0x0A:], nameOffset)
+	binary.LittleEndian.PutUint16(rawAttribute[0x20:], uint16(offsetDataRuns))
+	binary.LittleEndian.PutUint64(rawAttribute[0x30:], actualSize)
+	copy(rawAttribute[nameOffset:], rawName)
+	copy(rawAttribute[offsetDataRuns:], rawDataRuns)
+	return rawAttribute
+}
+
+var (
+	testEFSUserThumbprint     = bytes.Repeat([]byte{0xAB}, 20)
+	testEFSRecoveryThumbprint = bytes.Repeat([]byte{0x0C}, 20)
+	testEFSUserEntry          = buildTestEFSKeyEntry(buildTestSID(5, 21, 1, 2, 3, 1001), testEFSUserThumbprint, "container-1", "Microsoft Enhanced Cryptographic Provider v1.0", "alec")
+	testEFSRecoveryEntry      = buildTestEFSKeyEntry(buildTestSID(5, 21, 1, 2, 3, 500), testEFSRecoveryThumbprint, "container-2", "Microsoft Enhanced Cryptographic Provider v1.0", "Administrator")
+	testEFSMetadata           = buildTestEFSMetadata([][]byte{testEFSUserEntry}, [][]byte{testEFSRecoveryEntry})
+
+	testEFSUser = EFSKeyEntry{
+		CredentialType:        efsCredentialTypeCertificate,
+		SID:                   "S-1-5-21-1-2-3-1001",
+		CertificateThumbprint: "ABABABABABABABABABABABABABABABABABABABAB",
+		ContainerName:         "container-1",
+		ProviderName:          "Microsoft Enhanced Cryptographic Provider v1.0",
+		UserName:              "alec",
+	}
+	testEFSRecoveryAgent = EFSKeyEntry{
+		CredentialType:        efsCredentialTypeCertificate,
+		SID:                   "S-1-5-21-1-2-3-500",
+		CertificateThumbprint: "0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C",
+		ContainerName:         "container-2",
+		ProviderName:          "Microsoft Enhanced Cryptographic Provider v1.0",
+		UserName:              "Administrator",
+	}
+)
+
+func TestRawEFSMetadata_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     RawEFSMetadata
+		want    EFSMetadata
+		wantErr bool
+	}{
+		{
+			name: "ddf and drf",
+			raw:  testEFSMetadata,
+			want: EFSMetadata{Version: 2, CryptoAPIVersion: 3, DDF: []EFSKeyEntry{testEFSUser}, DRF: []EFSKeyEntry{testEFSRecoveryAgent}},
+		},
+		{
+			name: "no recovery agent",
+			raw:  buildTestEFSMetadata([][]byte{testEFSUserEntry, testEFSUserEntry}, nil),
+			want: EFSMetadata{Version: 2, CryptoAPIVersion: 3, DDF: []EFSKeyEntry{testEFSUser, testEFSUser}},
+		},
+		{
+			name:    "truncated header",
+			raw:     testEFSMetadata[:0x40],
+			wantErr: true,
+		},
+		{
+			name:    "truncated entry",
+			raw:     buildTestEFSMetadata([][]byte{testEFSUserEntry[:0x20]}, nil),
+			want:    EFSMetadata{Version: 2, CryptoAPIVersion: 3},
+			wantErr: true,
+		},
+		{
+			name:    "entry count beyond the stream",
+			raw:     buildTestEFSMetadata([][]byte{}, nil)[:0x54],
+			want:    EFSMetadata{Version: 2, CryptoAPIVersion: 3},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestRawLoggedUtilityStreamAttribute_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     RawLoggedUtilityStreamAttribute
+		want    LoggedUtilityStream
+		wantEFS bool
+		wantTxF bool
+		wantErr bool
+	}{
+		{
+			name:    "resident $EFS",
+			raw:     buildTestLoggedUtilityStreamAttribute("$EFS", testEFSMetadata),
+			want:    LoggedUtilityStream{Name: "$EFS", Resident: true, EFS: &EFSMetadata{Version: 2, CryptoAPIVersion: 3, DDF: []EFSKeyEntry{testEFSUser}, DRF: []EFSKeyEntry{testEFSRecoveryAgent}}},
+			wantEFS: true,
+		},
+		{
+			name:    "non-resident $EFS",
+			raw:     buildTestNonResidentLoggedUtilityStreamAttribute("$EFS", uint64(len(testEFSMetadata)), []byte{0x11, 0x01, 0x01}),
+			want:    LoggedUtilityStream{Name: "$EFS"},
+			wantEFS: true,
+		},
+		{
+			name:    "$TXF_DATA",
+			raw:     buildTestLoggedUtilityStreamAttribute("$TXF_DATA", make([]byte, 0x38)),
+			want:    LoggedUtilityStream{Name: "$TXF_DATA", Resident: true},
+			wantTxF: true,
+		},
+		{
+			name:    "corrupt $EFS",
+			raw:     buildTestLoggedUtilityStreamAttribute("$EFS", testEFSMetadata[:0x20]),
+			want:    LoggedUtilityStream{Name: "$EFS", Resident: true},
+			wantEFS: true,
+			wantErr: true,
+		},
+		{
+			name:    "not a logged utility stream",
+			raw:     buildTestResidentAttribute(0x80, testEFSMetadata),
+			wantErr: true,
+		},
+		{
+			name:    "nil bytes",
+			raw:     nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+			if got.IsEFS() != tt.wantEFS || got.IsTxF() != tt.wantTxF {
+				t.Errorf("IsEFS() = %v, IsTxF() = %v, want %v and %v", got.IsEFS(), got.IsTxF(), tt.wantEFS, tt.wantTxF)
+			}
+		})
+	}
+}
+
+func TestRawLoggedUtilityStreamAttribute_ParseNonResident(t *testing.T) {
+	volume := make([]byte, 4096*2)
+	copy(volume[4096:], testEFSMetadata)
+
+	tests := []struct {
+		name    string
+		raw     RawLoggedUtilityStreamAttribute
+		want    LoggedUtilityStream
+		wantErr bool
+	}{
+		{
+			name: "non-resident $EFS",
+			raw:  buildTestNonResidentLoggedUtilityStreamAttribute("$EFS", uint64(len(testEFSMetadata)), []byte{0x11, 0x01, 0x01}),
+			want: LoggedUtilityStream{Name: "$EFS", EFS: &EFSMetadata{Version: 2, CryptoAPIVersion: 3, DDF: []EFSKeyEntry{testEFSUser}, DRF: []EFSKeyEntry{testEFSRecoveryAgent}}},
+		},
+		{
+			name: "non-resident $TXF_DATA is not read",
+			raw:  buildTestNonResidentLoggedUtilityStreamAttribute("$TXF_DATA", 0x38, []byte{0x11, 0x01, 0x01}),
+			want: LoggedUtilityStream{Name: "$TXF_DATA"},
+		},
+		{
+			name:    "resident",
+			raw:     buildTestLoggedUtilityStreamAttribute("$EFS", testEFSMetadata),
+			wantErr: true,
+		},
+		{
+			name:    "not a logged utility stream",
+			raw:     buildTestNonResidentAttribute(0x80, uint64(len(testEFSMetadata)), []byte{0x11, 0x01, 0x01}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.ParseNonResident(bytes.NewReader(volume), 4096)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseNonResident() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestEFSKeyEntry_String(t *testing.T) {
+	tests := []struct {
+		name        string
+		efsKeyEntry EFSKeyEntry
+		want        string
+	}{
+		{
+			name:        "certificate",
+			efsKeyEntry: testEFSUser,
+			want:        "S-1-5-21-1-2-3-1001:alec:ABABABABABABABABABABABABABABABABABABABAB",
+		},
+		{
+			name:        "sid only",
+			efsKeyEntry: EFSKeyEntry{SID: "S-1-5-21-1-2-3-1001"},
+			want:        "S-1-5-21-1-2-3-1001::",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.efsKeyEntry.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsefulMftFields_setLoggedUtilityStreamFields(t *testing.T) {
+	efsMetadata, _ := RawEFSMetadata(testEFSMetadata).Parse()
+	tests := []struct {
+		name      string
+		mftRecord MasterFileTableRecord
+		want      UsefulMftFields
+	}{
+		{
+			name:      "resident $EFS",
+			mftRecord: MasterFileTableRecord{LoggedUtilityStreams: []LoggedUtilityStream{{Name: "$EFS", Resident: true, EFS: &efsMetadata}}},
+			want: UsefulMftFields{
+				EFSEncrypted: true,
+				EFSUsers:     "S-1-5-21-1-2-3-1001:alec:ABABABABABABABABABABABABABABABABABABABAB",
+				EFSRecovery:  "S-1-5-21-1-2-3-500:Administrator:0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C0C",
+			},
+		},
+		{
+			name:      "non-resident $EFS and $TXF_DATA",
+			mftRecord: MasterFileTableRecord{LoggedUtilityStreams: []LoggedUtilityStream{{Name: "$EFS"}, {Name: "$TXF_DATA", Resident: true}}},
+			want:      UsefulMftFields{EFSEncrypted: true, TxF: true},
+		},
+		{
+			name: "no logged utility streams",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got UsefulMftFields
+			got.setLoggedUtilityStreamFields(tt.mftRecord)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
@@ -20,6 +20,24 @@ type MasterFileTableRecord struct {
 	FileNameAttributes            []FileNameAttribute
 	DataAttribute                 DataAttribute
 	AttributeList                 AttributeListAttributes
+	SecurityDescriptor            SecurityDescriptor
+	ReparsePoint                  ReparsePoint
+	ObjectID                      ObjectIDAttribute
+	ExtendedAttributeInformation  ExtendedAttributeInformation
+	ExtendedAttributes            ExtendedAttributes
+	VolumeName                    string
+	VolumeInformation             VolumeInformation
+	LoggedUtilityStreams          []LoggedUtilityStream
+	Attributes                    []AttributeInfo
+
+	// Slack is only filled in when ParseOptions.RecordSlack is set. See RawMasterFileTableRecord.Slack().
+	Slack RecordSlack
+
+	// ParsedAttributes contains the results of the parsers registered with DefaultAttributeParsers. See RegisterAttributeParser().
+	ParsedAttributes ParsedAttributes
+
+	// AttributeErrors lists the attributes of the record that failed to parse. A record with attribute errors is partial. See RawMasterFileTableRecord.ParseLenient().
+	AttributeErrors []*AttributeError
 }
 
 //TODO fill out these tags for json, csv, bson, and protobuf
@@ -44,6 +62,80 @@ type UsefulMftFields struct {
 	SiAccessed       time.Time `json:"SiAccessed"`
 	SiChanged        time.Time `json:"SiChanged"`
 	PhysicalFileSize uint64    `json:"PhysicalFileSize,number"`
+	OwnerSID         string    `json:"OwnerSID,omitempty"`
+	OwnerAccountName string    `json:"OwnerAccountName,omitempty"`
+	SDDL             string    `json:"SDDL,omitempty"`
+	ReparseTag       string    `json:"ReparseTag,omitempty"`
+	ReparseTarget    string    `json:"ReparseTarget,omitempty"`
+	ObjectID         string    `json:"ObjectID,omitempty"`
+	BirthVolumeID    string    `json:"BirthVolumeID,omitempty"`
+	BirthObjectID    string    `json:"BirthObjectID,omitempty"`
+	DomainID         string    `json:"DomainID,omitempty"`
+	ObjectIDCreated  time.Time `json:"ObjectIDCreated"`
+	ObjectIDMAC      string    `json:"ObjectIDMAC,omitempty"`
+	EANames          string    `json:"EANames,omitempty"`
+	EASize           uint32    `json:"EASize,omitempty"`
+	EAUnusual        bool      `json:"EAUnusual,omitempty"`
+	LxUID            string    `json:"LxUID,omitempty"`
+	LxGID            string    `json:"LxGID,omitempty"`
+	LxMode           string    `json:"LxMode,omitempty"`
+	LxDevice         string    `json:"LxDevice,omitempty"`
+	VolumeLabel      string    `json:"VolumeLabel,omitempty"`
+	VolumeSerial     string    `json:"VolumeSerial,omitempty"`
+	EFSEncrypted     bool      `json:"EFSEncrypted,omitempty"`
+	EFSUsers         string    `json:"EFSUsers,omitempty"`
+	EFSRecovery      string    `json:"EFSRecovery,omitempty"`
+	TxF              bool      `json:"TxF,omitempty"`
+	Attributes       string    `json:"Attributes,omitempty"`
+	SiUsn            int64     `json:"SiUsn,omitempty"`
+	UsnTimestamp     time.Time `json:"UsnTimestamp"`
+	UsnReason        string    `json:"UsnReason,omitempty"`
+	UsnAnomaly       string    `json:"UsnAnomaly,omitempty"`
+	MftAllocated     bool      `json:"MftAllocated,omitempty"`
+	MftAnomaly       string    `json:"MftAnomaly,omitempty"`
+	SlackSize        int       `json:"SlackSize,omitempty"`
+	SlackAttributes  string    `json:"SlackAttributes,omitempty"`
+	SlackFileNames   string    `json:"SlackFileNames,omitempty"`
+	CarvedOffset     int64     `json:"CarvedOffset,omitempty"`
+	CarvedSignature  string    `json:"CarvedSignature,omitempty"`
+	Partial          bool      `json:"Partial,omitempty"`
+	AttributeErrors  string    `json:"AttributeErrors,omitempty"`
+}
+
+// ParseOptions contains optional data used to enrich parsed MFT records.
+type ParseOptions struct {
+	// VolumeImage is the volume the MFT was extracted from. It's only used to read attribute lists that are non-resident, without it the extension records of those files aren't merged in and the failure is passed on to Diagnostics.
+	VolumeImage io.ReaderAt
+
+	// SecurityDescriptors resolves the security id of a record's standard information attribute to its owner and SDDL. See BuildSecurityDescriptors().
+	SecurityDescriptors SecurityDescriptors
+
+	// AccountNames resolves owner SIDs to account names. Well-known SIDs are resolved even when this is nil. See LoadAccountNames().
+	AccountNames AccountNames
+
+	// Volume adds the volume label and serial number to every record so results from many volumes can be told apart. See ReadVolumeMetadata().
+	Volume *VolumeMetadata
+
+	// AttributeDefinitions names the attribute types of a record using the volume's own $AttrDef, and the attribute walk of a record and its attribute list stops at types that aren't in it. DefaultAttributeDefinitions is used for naming when this is nil, and any attribute type is accepted. See ReadAttributeDefinitions().
+	AttributeDefinitions AttributeDefinitions
+
+	// AttributeParsers decodes attribute types with the parsers registered with it, the results end up in the parsed attributes of a record. DefaultAttributeParsers is used when this is nil.
+	AttributeParsers *AttributeParsers
+
+	// UsnJournal adds the timestamp and reasons of the journal record that a record's $STANDARD_INFORMATION usn points at, and flags usns that disagree with the journal. See BuildUsnJournalIndex().
+	UsnJournal *UsnJournalIndex
+
+	// MftBitmap adds whether the $MFT bitmap marks a record as allocated, and flags records whose in use flag disagrees with it. See ReadMftBitmap() and LoadMftBitmap().
+	MftBitmap MftBitmap
+
+	// RecordSlack keeps the bytes after the end marker of every record and lists the attributes left behind in them. See RawMasterFileTableRecord.Slack().
+	RecordSlack bool
+
+	// Lenient keeps every attribute of a record that parsed when another one of its attributes fails, and flags the record as partial along with the attributes that failed. See RawMasterFileTableRecord.ParseLenient().
+	Lenient bool
+
+	// Diagnostics receives every record that was skipped or only partially parsed, and a summary once the run is over. Records that have never been used are all zeros and are only counted in the summary. See DiagnosticLog.
+	Diagnostics DiagnosticHandler
 }
 
 // RawMasterFileTableRecord is a []byte alias for raw mft record. Used with the Parse() method.
@@ -51,6 +143,12 @@ type RawMasterFileTableRecord []byte
 
 // ParseMFT takes an input file os.File and writes the results to the io.Writer. The format of the data sent to the io.Writer is dependent on what ResultWriter is used. The bytes per cluster input is typically 4096
 func ParseMFT(volumeLetter string, inputFile *os.File, writer ResultWriter, streamer io.Writer, bytesPerCluster int64) {
+	ParseMFTWithOptions(volumeLetter, inputFile, writer, streamer, bytesPerCluster, ParseOptions{})
+	return
+}
+
+// ParseMFTWithOptions works like ParseMFT() but enriches the results with the data provided in the parse options.
+func ParseMFTWithOptions(volumeLetter string, inputFile *os.File, writer ResultWriter, streamer io.Writer, bytesPerCluster int64, options ParseOptions) {
 	directoryTree, _ := BuildDirectoryTree(inputFile, volumeLetter)
 	outputChannel := make(chan UsefulMftFields, 100)
 	var waitGroup sync.WaitGroup
@@ -58,34 +156,128 @@ func ParseMFT(volumeLetter string, inputFile *os.File, writer ResultWriter, stre
 	go writer.ResultWriter(streamer, &outputChannel, &waitGroup)
 	// Seek back to the beginning of the file
 	_, _ = inputFile.Seek(0, 0)
-	ParseMftRecords(inputFile, bytesPerCluster, directoryTree, &outputChannel)
+	ParseMftRecordsWithOptions(inputFile, bytesPerCluster, directoryTree, &outputChannel, options)
 	waitGroup.Wait()
 	return
 }
 
 // ParseMftRecords parses a stream of mft record bytes and sends the results to an output channel. Records from this output channel are popped off by the ResultWriter used in the ParseMFT() method.
+// If the reader also implements io.ReaderAt, attributes stored in extension records are merged into their base records. Extension records are never sent to the output channel on their own.
 func ParseMftRecords(reader io.Reader, bytesPerCluster int64, directoryTree DirectoryTree, outputChannel *chan UsefulMftFields) {
+	ParseMftRecordsWithOptions(reader, bytesPerCluster, directoryTree, outputChannel, ParseOptions{})
+	return
+}
+
+// ParseMftRecordsWithOptions works like ParseMftRecords() but enriches the results with the data provided in the parse options.
+func ParseMftRecordsWithOptions(reader io.Reader, bytesPerCluster int64, directoryTree DirectoryTree, outputChannel *chan UsefulMftFields, options ParseOptions) {
+	var assembler *RecordAssembler
+	if readerAt, ok := reader.(io.ReaderAt); ok {
+		assembler = &RecordAssembler{
+			Mft:             readerAt,
+			Volume:          options.VolumeImage,
+			BytesPerCluster: bytesPerCluster,
+			Lenient:         options.Lenient,
+		}
+	}
+
+	var parseSummary ParseSummary
+	offset := int64(0)
 	for {
 		buffer := make([]byte, 1024)
-		_, err := reader.Read(buffer)
+		read, err := io.ReadFull(reader, buffer)
 		if err == io.EOF {
 			err = nil
 			break
 		}
 		rawMftRecord := RawMasterFileTableRecord(buffer)
-		mftRecord, err := rawMftRecord.Parse(bytesPerCluster)
+		recordOffset := offset
+		offset += int64(read)
+		parseSummary.RecordsRead++
+		if err != nil {
+			// The input ended part way through a record, or couldn't be read any further.
+			options.reportSkipped(&parseSummary, rawMftRecord[:read], recordOffset, err)
+			break
+		}
+		if rawMftRecord.isEmpty() {
+			parseSummary.EmptyRecords++
+			continue
+		}
+		mftRecord, err := rawMftRecord.parse(bytesPerCluster, options)
 		if err != nil {
+			options.reportSkipped(&parseSummary, rawMftRecord, recordOffset, err)
+			continue
+		}
+		parseSummary.RecordsParsed++
+		if mftRecord.IsPartial() {
+			parseSummary.PartialRecords++
+			for _, attributeError := range mftRecord.AttributeErrors {
+				recordError := newAttributeRecordError(attributeError)
+				recordError.Offset = recordOffset
+				parseSummary.add(recordError)
+				if options.Diagnostics != nil {
+					options.Diagnostics.HandleRecordError(recordError)
+				}
+			}
+		}
+		if mftRecord.RecordHeader.IsExtensionRecord() {
 			continue
 		}
+		if assembler != nil && rawMftRecord.hasAttributeList(mftRecord.RecordHeader) {
+			// Fall back to the base record alone if the extension records can't be merged in.
+			if assembledRecord, err := assembler.Assemble(rawMftRecord); err == nil {
+				mftRecord = assembledRecord
+			} else {
+				options.reportUnassembled(&parseSummary, mftRecord, recordOffset, err)
+			}
+		}
+		if options.RecordSlack {
+			mftRecord.Slack, _ = rawMftRecord.Slack()
+		}
 
 		usefulMftFields := GetUsefulMftFields(mftRecord, directoryTree)
+		options.enrich(&usefulMftFields, mftRecord)
 		*outputChannel <- usefulMftFields
 
 	}
+	if options.Diagnostics != nil {
+		options.Diagnostics.HandleSummary(parseSummary)
+	}
 	close(*outputChannel)
 	return
 }
 
+// Counts a record that couldn't be parsed in the parse summary and passes it on to the diagnostic handler of the parse options receiver.
+func (options ParseOptions) reportSkipped(parseSummary *ParseSummary, rawMftRecord RawMasterFileTableRecord, offset int64, err error) {
+	recordError := newSkippedRecordError(rawMftRecord, offset, err)
+	parseSummary.SkippedRecords++
+	parseSummary.add(recordError)
+	if options.Diagnostics != nil {
+		options.Diagnostics.HandleRecordError(recordError)
+	}
+	return
+}
+
+// Counts a base record whose extension records couldn't be merged in as partial in the parse summary and passes it on to the diagnostic handler of the parse options receiver.
+func (options ParseOptions) reportUnassembled(parseSummary *ParseSummary, mftRecord MasterFileTableRecord, offset int64, err error) {
+	const codeAttributeList = 0x20
+	recordError := &RecordError{
+		Offset:        offset,
+		RecordNumber:  mftRecord.RecordHeader.RecordNumber,
+		AttributeType: codeAttributeList,
+		Reason:        RecordErrorBadAttribute,
+		Partial:       true,
+		Err:           err,
+	}
+	if !mftRecord.IsPartial() {
+		parseSummary.PartialRecords++
+	}
+	parseSummary.add(recordError)
+	if options.Diagnostics != nil {
+		options.Diagnostics.HandleRecordError(recordError)
+	}
+	return
+}
+
 // GetUsefulMftFields will pull out and return just the MFT record fields that are useful to an analyst.
 func GetUsefulMftFields(mftRecord MasterFileTableRecord, directoryTree DirectoryTree) (useFulMftFields UsefulMftFields) {
 	for _, record := range mftRecord.FileNameAttributes {
@@ -113,7 +305,25 @@ func GetUsefulMftFields(mftRecord MasterFileTableRecord, directoryTree Directory
 			useFulMftFields.SiModified = mftRecord.StandardInformationAttributes.SiModified
 			useFulMftFields.SiAccessed = mftRecord.StandardInformationAttributes.SiAccessed
 			useFulMftFields.SiChanged = mftRecord.StandardInformationAttributes.SiChanged
+			useFulMftFields.SiUsn = mftRecord.StandardInformationAttributes.Usn
 			useFulMftFields.PhysicalFileSize = record.PhysicalFileSize
+			if mftRecord.SecurityDescriptor.Revision != 0 {
+				useFulMftFields.OwnerSID = mftRecord.SecurityDescriptor.Owner
+				useFulMftFields.SDDL = mftRecord.SecurityDescriptor.SDDL()
+			}
+			if mftRecord.ReparsePoint.Tag != 0 {
+				useFulMftFields.ReparseTag = mftRecord.ReparsePoint.describeTag()
+				useFulMftFields.ReparseTarget = mftRecord.ReparsePoint.Target()
+			}
+			useFulMftFields.ObjectID = mftRecord.ObjectID.ObjectID.GUID
+			useFulMftFields.BirthVolumeID = mftRecord.ObjectID.BirthVolumeID.GUID
+			useFulMftFields.BirthObjectID = mftRecord.ObjectID.BirthObjectID.GUID
+			useFulMftFields.DomainID = mftRecord.ObjectID.DomainID.GUID
+			useFulMftFields.ObjectIDCreated = mftRecord.ObjectID.ObjectID.Created
+			useFulMftFields.ObjectIDMAC = mftRecord.ObjectID.ObjectID.MAC
+			useFulMftFields.setExtendedAttributeFields(mftRecord)
+			useFulMftFields.setLoggedUtilityStreamFields(mftRecord)
+			useFulMftFields.Attributes = DescribeAttributes(mftRecord.Attributes, nil)
 			break
 		}
 	}
@@ -121,12 +331,82 @@ func GetUsefulMftFields(mftRecord MasterFileTableRecord, directoryTree Directory
 	return
 }
 
+// Fills in the extended attribute fields. The $EA_INFORMATION size is also checked since the entries of a non-resident $EA aren't parsed.
+func (usefulMftFields *UsefulMftFields) setExtendedAttributeFields(mftRecord MasterFileTableRecord) {
+	usefulMftFields.EANames = strings.Join(mftRecord.ExtendedAttributes.Names(), ";")
+	usefulMftFields.EASize = mftRecord.ExtendedAttributeInformation.UnpackedSize
+	usefulMftFields.EAUnusual = mftRecord.ExtendedAttributes.IsUnusual() || usefulMftFields.EASize > unusualExtendedAttributeValueSize
+
+	linuxMetadata, ok := mftRecord.ExtendedAttributes.Linux()
+	if ok == false {
+		return
+	}
+	if linuxMetadata.UID != nil {
+		usefulMftFields.LxUID = fmt.Sprint(*linuxMetadata.UID)
+	}
+	if linuxMetadata.GID != nil {
+		usefulMftFields.LxGID = fmt.Sprint(*linuxMetadata.GID)
+	}
+	if linuxMetadata.Mode != nil {
+		usefulMftFields.LxMode = FormatLinuxMode(*linuxMetadata.Mode)
+	}
+	if linuxMetadata.DeviceMajor != nil {
+		usefulMftFields.LxDevice = fmt.Sprintf("%d,%d", *linuxMetadata.DeviceMajor, *linuxMetadata.DeviceMinor)
+	}
+	return
+}
+
+// Fills in the EFS and TxF fields. The users and recovery agents are only known when the $EFS stream is resident.
+func (usefulMftFields *UsefulMftFields) setLoggedUtilityStreamFields(mftRecord MasterFileTableRecord) {
+	for _, loggedUtilityStream := range mftRecord.LoggedUtilityStreams {
+		switch {
+		case loggedUtilityStream.IsTxF():
+			usefulMftFields.TxF = true
+		case loggedUtilityStream.IsEFS():
+			usefulMftFields.EFSEncrypted = true
+			if loggedUtilityStream.EFS == nil {
+				continue
+			}
+			var users, recovery []string
+			for _, efsKeyEntry := range loggedUtilityStream.EFS.DDF {
+				users = append(users, efsKeyEntry.String())
+			}
+			for _, efsKeyEntry := range loggedUtilityStream.EFS.DRF {
+				recovery = append(recovery, efsKeyEntry.String())
+			}
+			usefulMftFields.EFSUsers = strings.Join(users, ";")
+			usefulMftFields.EFSRecovery = strings.Join(recovery, ";")
+		}
+	}
+	return
+}
+
 // Parse parses the raw MFT record receiver and returns a parsed mft record.
 func (rawMftRecord RawMasterFileTableRecord) Parse(bytesPerCluster int64) (mftRecord MasterFileTableRecord, err error) {
+	mftRecord, err = rawMftRecord.parse(bytesPerCluster, ParseOptions{})
+	return
+}
+
+// ParseLenient works like Parse() but keeps every attribute that parsed when another attribute of the record fails. With Parse() a bad $FILE_NAME, $STANDARD_INFORMATION, $DATA, or $ATTRIBUTE_LIST attribute leaves the filename, standard information, and data attributes of the record at their zero value. Either way the attributes that failed are listed in the attribute errors of the mft record.
+func (rawMftRecord RawMasterFileTableRecord) ParseLenient(bytesPerCluster int64) (mftRecord MasterFileTableRecord, err error) {
+	mftRecord, err = rawMftRecord.parse(bytesPerCluster, ParseOptions{Lenient: true})
+	return
+}
+
+// Returns the attribute parsers of the options, or DefaultAttributeParsers when they are nil.
+func (options ParseOptions) attributeParsers() *AttributeParsers {
+	if options.AttributeParsers == nil {
+		return DefaultAttributeParsers
+	}
+	return options.AttributeParsers
+}
+
+// Parses the raw mft record receiver. Attributes that fail to parse are left out of the mft record, or left at their zero value, rather than failing the whole record, and are listed in its attribute errors.
+func (rawMftRecord RawMasterFileTableRecord) parse(bytesPerCluster int64, options ParseOptions) (mftRecord MasterFileTableRecord, err error) {
 	// Sanity checks
 	sizeOfRawMftRecord := len(rawMftRecord)
 	if sizeOfRawMftRecord == 0 {
-		err = errors.New("received nil bytes")
+		err = ErrNilBytes
 		return
 	}
 	if bytesPerCluster == 0 {
@@ -139,7 +419,7 @@ func (rawMftRecord RawMasterFileTableRecord) Parse(bytesPerCluster int64) (mftRe
 		return
 	}
 	if result == false {
-		err = fmt.Errorf("this is not an mft record: %w", err)
+		err = fmt.Errorf("failed to parse the raw mft record: %w", ErrNotMftRecord)
 		return
 	}
 
@@ -151,16 +431,126 @@ func (rawMftRecord RawMasterFileTableRecord) Parse(bytesPerCluster int64) (mftRe
 		return
 	}
 
-	mftRecord.RecordHeader, _ = rawRecordHeader.Parse()
+	mftRecord.RecordHeader, err = rawRecordHeader.Parse()
+	if err != nil {
+		err = fmt.Errorf("failed to parse MFT record header: %w", err)
+		return
+	}
 
 	var rawAttributes RawAttributes
-	rawAttributes, err = rawMftRecord.GetRawAttributes(mftRecord.RecordHeader)
+	rawAttributes, err = rawMftRecord.GetRawAttributesWithDefinitions(mftRecord.RecordHeader, options.AttributeDefinitions)
 	if err != nil {
 		err = fmt.Errorf("failed to get raw data attributes: %w", err)
 		return
 	}
 
-	mftRecord.FileNameAttributes, mftRecord.StandardInformationAttributes, mftRecord.DataAttribute, mftRecord.AttributeList, _ = rawAttributes.Parse(bytesPerCluster)
+	recordAttributes, attributesErr := rawAttributes.parse(bytesPerCluster, options)
+	mftRecord.FileNameAttributes = recordAttributes.FileNameAttributes
+	mftRecord.StandardInformationAttributes = recordAttributes.StandardInformationAttribute
+	mftRecord.DataAttribute = recordAttributes.DataAttribute
+	mftRecord.AttributeList = recordAttributes.AttributeList
+	mftRecord.AttributeErrors = recordAttributes.AttributeErrors
+	if attributesErr != nil {
+		var attributeError *AttributeError
+		if !errors.As(attributesErr, &attributeError) {
+			attributeError = &AttributeError{Err: attributesErr}
+		}
+		mftRecord.AttributeErrors = append(mftRecord.AttributeErrors, attributeError)
+	}
+	// The offsets of RawAttributes.Parse() are relative to the first attribute.
+	for _, attributeError := range mftRecord.AttributeErrors {
+		attributeError.Offset += int(mftRecord.RecordHeader.AttributesOffset)
+	}
+
+	// These are the type codes for attributes that are parsed straight into the mft record. Every attribute, parsed or not, is listed in the record's attributes.
+	const codeObjectID = 0x40
+	const codeSecurityDescriptor = 0x50
+	const codeVolumeName = 0x60
+	const codeVolumeInformation = 0x70
+	const codeReparsePoint = 0xC0
+	const codeEaInformation = 0xD0
+	const codeEa = 0xE0
+	const codeLoggedUtilityStream = 0x100
+	// The offsets of the attributes of each type are kept in order so that the errors of the registered parsers can be placed too.
+	attributeOffsets := make(map[uint32][]int)
+	offset := int(mftRecord.RecordHeader.AttributesOffset)
+	for _, rawAttribute := range rawAttributes {
+		attributeOffset := offset
+		offset += len(rawAttribute)
+		attributeOffsets[rawAttribute.attributeType()] = append(attributeOffsets[rawAttribute.attributeType()], attributeOffset)
+		mftRecord.Attributes = append(mftRecord.Attributes, rawAttribute.info())
+		var attributeErr error
+		switch rawAttribute.attributeType() {
+		case codeObjectID:
+			mftRecord.ObjectID, attributeErr = RawObjectIDAttribute(rawAttribute).Parse()
+		case codeSecurityDescriptor:
+			mftRecord.SecurityDescriptor, attributeErr = RawSecurityDescriptorAttribute(rawAttribute).Parse()
+		case codeVolumeName:
+			mftRecord.VolumeName, attributeErr = RawVolumeNameAttribute(rawAttribute).Parse()
+		case codeVolumeInformation:
+			mftRecord.VolumeInformation, attributeErr = RawVolumeInformationAttribute(rawAttribute).Parse()
+		case codeReparsePoint:
+			mftRecord.ReparsePoint, attributeErr = RawReparsePointAttribute(rawAttribute).Parse()
+		case codeEaInformation:
+			mftRecord.ExtendedAttributeInformation, attributeErr = RawExtendedAttributeInformation(rawAttribute).Parse()
+		case codeEa:
+			// Non-resident extended attributes live outside of the MFT record. Those are read with RawExtendedAttributesAttribute.ParseNonResident().
+			mftRecord.ExtendedAttributes, attributeErr = RawExtendedAttributesAttribute(rawAttribute).Parse()
+		case codeLoggedUtilityStream:
+			// The stream is kept even if its EFS metadata can't be parsed since its presence alone says the file is encrypted.
+			var loggedUtilityStream LoggedUtilityStream
+			loggedUtilityStream, attributeErr = RawLoggedUtilityStreamAttribute(rawAttribute).Parse()
+			mftRecord.LoggedUtilityStreams = append(mftRecord.LoggedUtilityStreams, loggedUtilityStream)
+		}
+		if attributeErr != nil {
+			mftRecord.AttributeErrors = append(mftRecord.AttributeErrors, &AttributeError{AttributeType: rawAttribute.attributeType(), Offset: attributeOffset, Err: attributeErr})
+		}
+	}
+	mftRecord.ParsedAttributes = options.attributeParsers().Parse(rawAttributes, bytesPerCluster)
+	for attributeType, parsedAttributes := range mftRecord.ParsedAttributes {
+		for i, parsedAttribute := range parsedAttributes {
+			if parsedAttribute.Err != nil && i < len(attributeOffsets[attributeType]) {
+				mftRecord.AttributeErrors = append(mftRecord.AttributeErrors, &AttributeError{AttributeType: attributeType, Offset: attributeOffsets[attributeType][i], Err: parsedAttribute.Err})
+			}
+		}
+	}
+	for _, attributeError := range mftRecord.AttributeErrors {
+		attributeError.RecordNumber = mftRecord.RecordHeader.RecordNumber
+	}
+	return
+}
+
+// Fills in the fields of a parsed record that depend on the data provided in the parse options receiver.
+func (options ParseOptions) enrich(usefulMftFields *UsefulMftFields, mftRecord MasterFileTableRecord) {
+	if usefulMftFields.OwnerSID == "" && options.SecurityDescriptors != nil {
+		if securityDescriptor, ok := options.SecurityDescriptors[mftRecord.StandardInformationAttributes.SecurityID]; ok {
+			usefulMftFields.OwnerSID = securityDescriptor.Owner
+			usefulMftFields.SDDL = securityDescriptor.SDDL()
+		}
+	}
+	if usefulMftFields.OwnerAccountName == "" {
+		usefulMftFields.OwnerAccountName, _ = options.AccountNames.Lookup(usefulMftFields.OwnerSID)
+	}
+	if options.Volume != nil {
+		usefulMftFields.VolumeLabel = options.Volume.Label
+		usefulMftFields.VolumeSerial = options.Volume.BootSector.SerialNumberString()
+	}
+	if options.AttributeDefinitions != nil && usefulMftFields.Attributes != "" {
+		usefulMftFields.Attributes = DescribeAttributes(mftRecord.Attributes, options.AttributeDefinitions)
+	}
+	if options.UsnJournal != nil {
+		usefulMftFields.setUsnJournalFields(mftRecord, options.UsnJournal)
+	}
+	if options.MftBitmap != nil {
+		usefulMftFields.MftAllocated = options.MftBitmap.IsAllocated(uint64(mftRecord.RecordHeader.RecordNumber))
+		usefulMftFields.MftAnomaly = options.MftBitmap.Check(mftRecord.RecordHeader).String()
+	}
+	if options.RecordSlack {
+		usefulMftFields.setRecordSlackFields(mftRecord.Slack)
+	}
+	if options.Lenient {
+		usefulMftFields.setAttributeErrorFields(mftRecord)
+	}
 	return
 }
 
@@ -0,0 +1,171 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// MftBitmap is a []byte alias for the content of the $BITMAP attribute of the $MFT record. Bit n is set when MFT record n is allocated.
+type MftBitmap []byte
+
+// MftBitmapAnomaly is a uint8 alias describing how the in use flag of a record header disagrees with the MFT bitmap.
+type MftBitmapAnomaly uint8
+
+// MftBitmapAnomalyEntry contains an MFT record whose in use flag disagrees with the MFT bitmap. The record number is the position of the record in the MFT.
+type MftBitmapAnomalyEntry struct {
+	RecordNumber   uint64
+	SequenceNumber uint16
+	FullPath       string
+	InUse          bool
+	Allocated      bool
+	Anomaly        MftBitmapAnomaly
+}
+
+// MFT bitmap anomalies.
+const (
+	// MftBitmapAnomalyNone means the in use flag and the bitmap agree.
+	MftBitmapAnomalyNone MftBitmapAnomaly = iota
+
+	// MftBitmapAnomalyInUseNotAllocated means the record header says the record is in use but the bitmap says it's free, so NTFS could hand the record out to another file at any time.
+	MftBitmapAnomalyInUseNotAllocated
+
+	// MftBitmapAnomalyAllocatedNotInUse means the bitmap says the record is allocated but the record header says it's free or the record couldn't be parsed.
+	MftBitmapAnomalyAllocatedNotInUse
+
+	// MftBitmapAnomalyBeyondBitmap means the record is in use but lies past the end of the bitmap.
+	MftBitmapAnomalyBeyondBitmap
+)
+
+var mftBitmapAnomalyNames = map[MftBitmapAnomaly]string{
+	MftBitmapAnomalyNone:              "",
+	MftBitmapAnomalyInUseNotAllocated: "IN_USE_NOT_ALLOCATED",
+	MftBitmapAnomalyAllocatedNotInUse: "ALLOCATED_NOT_IN_USE",
+	MftBitmapAnomalyBeyondBitmap:      "BEYOND_BITMAP",
+}
+
+// String returns the name of the MFT bitmap anomaly receiver. No anomaly is an empty string.
+func (mftBitmapAnomaly MftBitmapAnomaly) String() string {
+	if name, ok := mftBitmapAnomalyNames[mftBitmapAnomaly]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%X", uint8(mftBitmapAnomaly))
+}
+
+// IsAllocated returns true when the MFT bitmap receiver marks the record as allocated. Records past the end of the bitmap are never allocated.
+func (mftBitmap MftBitmap) IsAllocated(recordNumber uint64) bool {
+	byteOffset := recordNumber / 8
+	if byteOffset >= uint64(len(mftBitmap)) {
+		return false
+	}
+	return mftBitmap[byteOffset]&(1<<(recordNumber%8)) != 0
+}
+
+// RecordCount returns the number of records covered by the MFT bitmap receiver.
+func (mftBitmap MftBitmap) RecordCount() uint64 {
+	return uint64(len(mftBitmap)) * 8
+}
+
+// Check compares the in use flag of a record against the MFT bitmap receiver. The record number is taken from the record header.
+func (mftBitmap MftBitmap) Check(recordHeader RecordHeader) (mftBitmapAnomaly MftBitmapAnomaly) {
+	return mftBitmap.check(uint64(recordHeader.RecordNumber), recordHeader.Flags.FlagInUse)
+}
+
+func (mftBitmap MftBitmap) check(recordNumber uint64, inUse bool) (mftBitmapAnomaly MftBitmapAnomaly) {
+	allocated := mftBitmap.IsAllocated(recordNumber)
+	switch {
+	case inUse && recordNumber >= mftBitmap.RecordCount():
+		mftBitmapAnomaly = MftBitmapAnomalyBeyondBitmap
+	case inUse && !allocated:
+		mftBitmapAnomaly = MftBitmapAnomalyInUseNotAllocated
+	case !inUse && allocated:
+		mftBitmapAnomaly = MftBitmapAnomalyAllocatedNotInUse
+	}
+	return
+}
+
+// LoadMftBitmap reads an extracted $MFT:$BITMAP stream.
+func LoadMftBitmap(reader io.Reader) (mftBitmap MftBitmap, err error) {
+	mftBitmap, err = ioutil.ReadAll(reader)
+	if err != nil {
+		err = fmt.Errorf("failed to read the MFT bitmap: %w", err)
+		return
+	}
+	return
+}
+
+// ReadMftBitmap reads the $BITMAP attribute of the $MFT record of a volume. The volume must start at the beginning of the NTFS partition since the boot sector is used to find the MFT. The $MFTMirr copy of the $MFT record is used if the $MFT copy is corrupt.
+func ReadMftBitmap(volume io.ReaderAt) (mftBitmap MftBitmap, err error) {
+	bootSector, err := readBootSector(volume)
+	if err != nil {
+		return
+	}
+	rawMftRecord, _, err := readSystemRecord(volume, bootSector, 0)
+	if err != nil {
+		err = fmt.Errorf("failed to read the $MFT record: %w", err)
+		return
+	}
+	mftBitmap, err = rawMftRecord.GetMftBitmap(volume, int64(bootSector.BytesPerCluster()))
+	return
+}
+
+// GetMftBitmap returns the content of the unnamed $BITMAP attribute of the raw $MFT record receiver, which has fixups applied. The volume is only read when the bitmap is non-resident, so it may be nil for a small MFT.
+func (rawMftRecord RawMasterFileTableRecord) GetMftBitmap(volume io.ReaderAt, bytesPerCluster int64) (mftBitmap MftBitmap, err error) {
+	const codeBitmap = 0xB0
+
+	rawAttributes, err := rawMftRecord.getRawAttributes()
+	if err != nil {
+		err = fmt.Errorf("failed to get the attributes of the $MFT record: %w", err)
+		return
+	}
+	for _, rawAttribute := range rawAttributes {
+		attributeInfo := rawAttribute.info()
+		if attributeInfo.Type != codeBitmap || attributeInfo.Name != "" {
+			continue
+		}
+		if attributeInfo.Resident {
+			mftBitmap, err = residentContent(rawAttribute, codeBitmap, "MftBitmap")
+		} else if volume == nil {
+			err = errors.New("the MFT bitmap is non-resident and no volume was provided")
+		} else {
+			mftBitmap, err = readNonResidentAttribute(volume, rawAttribute, bytesPerCluster)
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to read the MFT bitmap: %w", err)
+		}
+		return
+	}
+	err = errors.New("the $MFT record does not have a $BITMAP attribute")
+	return
+}
+
+// FindMftBitmapAnomalies compares the in use flag of every record of an MFT against the MFT bitmap and returns the records that disagree. Records are numbered by their position in the MFT since the record number in the header isn't there before NTFS 3.1.
+func FindMftBitmapAnomalies(reader io.Reader, bytesPerCluster int64, directoryTree DirectoryTree, mftBitmap MftBitmap) (mftBitmapAnomalyEntries []MftBitmapAnomalyEntry) {
+	for recordNumber := uint64(0); ; recordNumber++ {
+		buffer := make(RawMasterFileTableRecord, defaultMftRecordSize)
+		_, err := io.ReadFull(reader, buffer)
+		if err != nil {
+			break
+		}
+
+		// A record that can't be parsed is treated as free.
+		mftRecord, err := buffer.Parse(bytesPerCluster)
+		inUse := err == nil && mftRecord.RecordHeader.Flags.FlagInUse
+		mftBitmapAnomaly := mftBitmap.check(recordNumber, inUse)
+		if mftBitmapAnomaly == MftBitmapAnomalyNone {
+			continue
+		}
+		mftBitmapAnomalyEntries = append(mftBitmapAnomalyEntries, MftBitmapAnomalyEntry{
+			RecordNumber:   recordNumber,
+			SequenceNumber: mftRecord.RecordHeader.SequenceNumber,
+			FullPath:       GetUsefulMftFields(mftRecord, directoryTree).FullPath,
+			InUse:          inUse,
+			Allocated:      mftBitmap.IsAllocated(recordNumber),
+			Anomaly:        mftBitmapAnomaly,
+		})
+	}
+	return
+}
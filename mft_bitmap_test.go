@@ -0,0 +1,240 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds a volume whose MFT starts at cluster 4. The $MFT record has the provided $BITMAP attribute, and cluster 8 holds the provided cluster data.
+func buildTestMftBitmapVolume(rawBitmapAttribute []byte, clusterData []byte) []byte {
+	const bytesPerCluster = 4096
+	volume := make([]byte, 9*bytesPerCluster)
+	copy(volume, buildTestBootSector(4, 0x1234567890abcdef))
+	rawMftRecord := buildTestVolumeMftRecord(0, 1, buildTestNonResidentAttribute(0x80, 16*1024, []byte{0x11, 0x04, 0x04}), rawBitmapAttribute)
+	copy(volume[4*bytesPerCluster:], rawMftRecord)
+	copy(volume[8*bytesPerCluster:], clusterData)
+	return volume
+}
+
+func TestMftBitmap_IsAllocated(t *testing.T) {
+	mftBitmap := MftBitmap{0x05, 0x80}
+	tests := []struct {
+		name         string
+		recordNumber uint64
+		want         bool
+	}{
+		{
+			name:         "first record",
+			recordNumber: 0,
+			want:         true,
+		},
+		{
+			name:         "free record",
+			recordNumber: 1,
+			want:         false,
+		},
+		{
+			name:         "last bit of the second byte",
+			recordNumber: 15,
+			want:         true,
+		},
+		{
+			name:         "beyond the bitmap",
+			recordNumber: 16,
+			want:         false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mftBitmap.IsAllocated(tt.recordNumber); got != tt.want {
+				t.Errorf("IsAllocated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMftBitmap_Check(t *testing.T) {
+	mftBitmap := MftBitmap{0x05}
+	tests := []struct {
+		name         string
+		recordHeader RecordHeader
+		want         MftBitmapAnomaly
+	}{
+		{
+			name:         "in use and allocated",
+			recordHeader: RecordHeader{RecordNumber: 0, Flags: RecordHeaderFlags{FlagInUse: true}},
+			want:         MftBitmapAnomalyNone,
+		},
+		{
+			name:         "free and not allocated",
+			recordHeader: RecordHeader{RecordNumber: 1, Flags: RecordHeaderFlags{FlagDeleted: true}},
+			want:         MftBitmapAnomalyNone,
+		},
+		{
+			name:         "in use but not allocated",
+			recordHeader: RecordHeader{RecordNumber: 1, Flags: RecordHeaderFlags{FlagInUse: true}},
+			want:         MftBitmapAnomalyInUseNotAllocated,
+		},
+		{
+			name:         "allocated but free",
+			recordHeader: RecordHeader{RecordNumber: 2, Flags: RecordHeaderFlags{FlagDeleted: true}},
+			want:         MftBitmapAnomalyAllocatedNotInUse,
+		},
+		{
+			name:         "in use beyond the bitmap",
+			recordHeader: RecordHeader{RecordNumber: 8, Flags: RecordHeaderFlags{FlagInUse: true}},
+			want:         MftBitmapAnomalyBeyondBitmap,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := mftBitmap.Check(tt.recordHeader); got != tt.want {
+				t.Errorf("Check() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMftBitmapAnomaly_String(t *testing.T) {
+	tests := []struct {
+		name             string
+		mftBitmapAnomaly MftBitmapAnomaly
+		want             string
+	}{
+		{
+			name:             "no anomaly",
+			mftBitmapAnomaly: MftBitmapAnomalyNone,
+			want:             "",
+		},
+		{
+			name:             "in use but not allocated",
+			mftBitmapAnomaly: MftBitmapAnomalyInUseNotAllocated,
+			want:             "IN_USE_NOT_ALLOCATED",
+		},
+		{
+			name:             "unknown anomaly",
+			mftBitmapAnomaly: 0x10,
+			want:             "0x10",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mftBitmapAnomaly.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadMftBitmap(t *testing.T) {
+	got, err := LoadMftBitmap(bytes.NewReader([]byte{0xFF, 0x0F}))
+	if err != nil {
+		t.Fatalf("LoadMftBitmap() error = %v", err)
+	}
+	if want := (MftBitmap{0xFF, 0x0F}); !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
+
+func TestReadMftBitmap(t *testing.T) {
+	clusterData := make([]byte, 4096)
+	clusterData[0] = 0x0F
+	clusterData[1] = 0x01
+
+	tests := []struct {
+		name    string
+		volume  []byte
+		want    MftBitmap
+		wantErr bool
+	}{
+		{
+			name:   "non-resident bitmap",
+			volume: buildTestMftBitmapVolume(buildTestNonResidentAttribute(0xB0, 2, []byte{0x11, 0x01, 0x08}), clusterData),
+			want:   MftBitmap{0x0F, 0x01},
+		},
+		{
+			name:   "resident bitmap",
+			volume: buildTestMftBitmapVolume(buildTestResidentAttribute(0xB0, []byte{0x3F, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00}), nil),
+			want:   MftBitmap{0x3F, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+		},
+		{
+			name:    "no bitmap",
+			volume:  buildTestMftBitmapVolume(nil, nil),
+			wantErr: true,
+		},
+		{
+			name:    "volume without a boot sector",
+			volume:  make([]byte, 4096),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadMftBitmap(bytes.NewReader(tt.volume))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadMftBitmap() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestRawMasterFileTableRecord_GetMftBitmap(t *testing.T) {
+	tests := []struct {
+		name         string
+		rawMftRecord RawMasterFileTableRecord
+		want         MftBitmap
+		wantErr      bool
+	}{
+		{
+			name:         "resident bitmap without a volume",
+			rawMftRecord: buildTestMftRecord(0, 0, buildTestResidentAttribute(0xB0, []byte{0x07})),
+			want:         MftBitmap{0x07},
+		},
+		{
+			name:         "non-resident bitmap without a volume",
+			rawMftRecord: buildTestMftRecord(0, 0, buildTestNonResidentAttribute(0xB0, 2, []byte{0x11, 0x01, 0x08})),
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rawMftRecord.GetMftBitmap(nil, 4096)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("GetMftBitmap() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestFindMftBitmapAnomalies(t *testing.T) {
+	freeRecord := buildTestMftRecord(2, 0, buildTestFileNameAttribute("gone.txt", 5))
+	freeRecord[0x16] = 0x00
+	rawMft := make([]byte, 9*1024)
+	copy(rawMft[0*1024:], buildTestMftRecord(0, 0, buildTestFileNameAttribute("$MFT", 5)))
+	copy(rawMft[1*1024:], buildTestMftRecord(1, 0, buildTestFileNameAttribute("hidden.txt", 5)))
+	copy(rawMft[2*1024:], freeRecord)
+	copy(rawMft[8*1024:], buildTestMftRecord(8, 0, buildTestFileNameAttribute("late.txt", 5)))
+
+	want := []MftBitmapAnomalyEntry{
+		{RecordNumber: 1, FullPath: "C:\\hidden.txt", InUse: true, Allocated: false, Anomaly: MftBitmapAnomalyInUseNotAllocated},
+		{RecordNumber: 2, FullPath: "C:\\gone.txt", InUse: false, Allocated: true, Anomaly: MftBitmapAnomalyAllocatedNotInUse},
+		{RecordNumber: 8, FullPath: "C:\\late.txt", InUse: true, Allocated: false, Anomaly: MftBitmapAnomalyBeyondBitmap},
+	}
+	got := FindMftBitmapAnomalies(bytes.NewReader(rawMft), 4096, DirectoryTree{5: "C:\\"}, MftBitmap{0x05})
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
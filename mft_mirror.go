@@ -0,0 +1,127 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+)
+
+// MftMirrorDifference contains a field of a system record that differs between the $MFT and the $MFTMirr. A field named Error means the record couldn't be read or parsed from one of the copies, and holds the error for each copy.
+type MftMirrorDifference struct {
+	RecordNumber uint64
+	Field        string
+	Mft          string
+	MftMirr      string
+}
+
+// mftMirrorRecordCount is the number of records that $MFTMirr holds: $MFT, $MFTMirr, $LogFile, and $Volume.
+const mftMirrorRecordCount = 4
+
+// CompareMftMirror reads the records mirrored by $MFTMirr from both the $MFT and the $MFTMirr of a volume and returns the fields that differ between the two copies. The volume must start at the beginning of the NTFS partition since the boot sector is used to find both copies.
+func CompareMftMirror(volume io.ReaderAt) (mftMirrorDifferences []MftMirrorDifference, err error) {
+	bootSector, err := readBootSector(volume)
+	if err != nil {
+		return
+	}
+	for recordNumber := uint64(0); recordNumber < mftMirrorRecordCount; recordNumber++ {
+		mftRecord, mftErr := readMirroredRecord(volume, bootSector, bootSector.MftCluster, recordNumber)
+		mirrorRecord, mirrorErr := readMirroredRecord(volume, bootSector, bootSector.MftMirrCluster, recordNumber)
+		if mftErr != nil || mirrorErr != nil {
+			mftMirrorDifferences = append(mftMirrorDifferences, MftMirrorDifference{
+				RecordNumber: recordNumber,
+				Field:        "Error",
+				Mft:          errorString(mftErr),
+				MftMirr:      errorString(mirrorErr),
+			})
+			continue
+		}
+		compareFields(reflect.ValueOf(mftRecord), reflect.ValueOf(mirrorRecord), "", func(field string, mftValue string, mirrorValue string) {
+			mftMirrorDifferences = append(mftMirrorDifferences, MftMirrorDifference{
+				RecordNumber: recordNumber,
+				Field:        field,
+				Mft:          mftValue,
+				MftMirr:      mirrorValue,
+			})
+		})
+	}
+	return
+}
+
+// Reads a record from the copy of the MFT that starts at mftCluster, applies its fixups, and parses it.
+func readMirroredRecord(volume io.ReaderAt, bootSector BootSector, mftCluster uint64, recordNumber uint64) (mftRecord MasterFileTableRecord, err error) {
+	bytesPerCluster := int64(bootSector.BytesPerCluster())
+	rawMftRecord, err := readRawMftRecord(volume, int64(mftCluster)*bytesPerCluster, int64(bootSector.MftRecordSize), int(bootSector.BytesPerSector), int64(recordNumber))
+	if err != nil {
+		return
+	}
+	mftRecord, err = rawMftRecord.Parse(bytesPerCluster)
+	return
+}
+
+// Reads one of the system records mirrored by $MFTMirr. The $MFT copy is used unless it fails its fixups or can't be parsed, in which case the $MFTMirr copy is used instead so that a volume with a damaged $MFT record 0 or $Volume record can still be parsed. Records past the mirror only come from the $MFT.
+func readSystemRecord(volume io.ReaderAt, bootSector BootSector, recordNumber uint64) (rawMftRecord RawMasterFileTableRecord, mftRecord MasterFileTableRecord, err error) {
+	bytesPerCluster := int64(bootSector.BytesPerCluster())
+	recordSize := int64(bootSector.MftRecordSize)
+	bytesPerSector := int(bootSector.BytesPerSector)
+
+	rawMftRecord, err = readRawMftRecord(volume, int64(bootSector.MftCluster)*bytesPerCluster, recordSize, bytesPerSector, int64(recordNumber))
+	if err == nil {
+		mftRecord, err = rawMftRecord.Parse(bytesPerCluster)
+	}
+	if err == nil || recordNumber >= mftMirrorRecordCount {
+		return
+	}
+
+	mftErr := err
+	rawMftRecord, err = readRawMftRecord(volume, int64(bootSector.MftMirrCluster)*bytesPerCluster, recordSize, bytesPerSector, int64(recordNumber))
+	if err == nil {
+		mftRecord, err = rawMftRecord.Parse(bytesPerCluster)
+	}
+	if err != nil {
+		err = fmt.Errorf("record %d is corrupt in both the $MFT (%v) and the $MFTMirr: %w", recordNumber, mftErr, err)
+		return
+	}
+	return
+}
+
+// Walks the exported fields of two values of the same type and calls report for every field that differs. Structs with exported fields are walked into so that differences are reported against the innermost field, e.g. RecordHeader.SequenceNumber. Everything else, including slices, is compared as a whole.
+func compareFields(mftValue reflect.Value, mirrorValue reflect.Value, field string, report func(field string, mftValue string, mirrorValue string)) {
+	if mftValue.Kind() == reflect.Struct && hasExportedFields(mftValue.Type()) {
+		for i := 0; i < mftValue.NumField(); i++ {
+			structField := mftValue.Type().Field(i)
+			if structField.PkgPath != "" {
+				continue
+			}
+			fieldName := structField.Name
+			if field != "" {
+				fieldName = field + "." + fieldName
+			}
+			compareFields(mftValue.Field(i), mirrorValue.Field(i), fieldName, report)
+		}
+		return
+	}
+	if !reflect.DeepEqual(mftValue.Interface(), mirrorValue.Interface()) {
+		report(field, fmt.Sprintf("%v", mftValue.Interface()), fmt.Sprintf("%v", mirrorValue.Interface()))
+	}
+	return
+}
+
+// Reports whether a struct type has any exported fields.
+func hasExportedFields(structType reflect.Type) bool {
+	for i := 0; i < structType.NumField(); i++ {
+		if structType.Field(i).PkgPath == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the message of an error, or an empty string for a nil error.
+func errorString(err error) string {
+	if err == nil {
+		return ""
+	}
+	return err.Error()
+}
@@ -0,0 +1,152 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds a volume whose MFT starts at cluster 4 and whose MFT mirror starts at cluster 16. Both copies hold the first four records, and the $Volume record of each copy has the provided volume flags.
+func buildTestMftMirrorVolume(mftVolumeFlags byte, mirrorVolumeFlags byte) []byte {
+	const bytesPerCluster = 4096
+	volume := make([]byte, 17*bytesPerCluster)
+	copy(volume, buildTestBootSector(4, 0x1234567890abcdef))
+	for _, mftCopy := range []struct {
+		cluster     int
+		volumeFlags byte
+	}{
+		{cluster: 4, volumeFlags: mftVolumeFlags},
+		{cluster: 16, volumeFlags: mirrorVolumeFlags},
+	} {
+		offset := mftCopy.cluster * bytesPerCluster
+		copy(volume[offset:], buildTestVolumeMftRecord(0, 1, buildTestFileNameAttribute("$MFT", 5), buildTestNonResidentAttribute(0x80, 16*1024, []byte{0x11, 0x04, 0x04})))
+		copy(volume[offset+1024:], buildTestVolumeMftRecord(1, 1, buildTestFileNameAttribute("$MFTMirr", 5)))
+		copy(volume[offset+2*1024:], buildTestVolumeMftRecord(2, 2, buildTestFileNameAttribute("$LogFile", 5)))
+		copy(volume[offset+3*1024:], buildTestVolumeMftRecord(3, 3, buildTestFileNameAttribute("$Volume", 5), buildTestResidentAttribute(0x60, buildTestUnicode("DATA")), buildTestResidentAttribute(0x70, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0x03, 0x01, mftCopy.volumeFlags, 0x00})))
+	}
+	return volume
+}
+
+// Breaks the fixups of a record in a volume built by buildTestMftMirrorVolume().
+func corruptTestMftMirrorRecord(volume []byte, cluster int, recordNumber int) {
+	volume[cluster*4096+recordNumber*1024+510] = 0xFF
+	return
+}
+
+func TestCompareMftMirror(t *testing.T) {
+	corruptVolume := buildTestMftMirrorVolume(0x00, 0x00)
+	corruptTestMftMirrorRecord(corruptVolume, 4, 0)
+
+	tests := []struct {
+		name    string
+		volume  []byte
+		want    []MftMirrorDifference
+		wantErr bool
+	}{
+		{
+			name:   "matching copies",
+			volume: buildTestMftMirrorVolume(0x00, 0x00),
+		},
+		{
+			name:   "different volume flags",
+			volume: buildTestMftMirrorVolume(0x01, 0x00),
+			want: []MftMirrorDifference{
+				{RecordNumber: 3, Field: "VolumeInformation.Flags", Mft: "DIRTY", MftMirr: ""},
+			},
+		},
+		{
+			name:   "corrupt $MFT record",
+			volume: corruptVolume,
+			want: []MftMirrorDifference{
+				{RecordNumber: 0, Field: "Error", Mft: "failed to apply fixups: sector 0 does not end with the update sequence number", MftMirr: ""},
+			},
+		},
+		{
+			name:    "volume without a boot sector",
+			volume:  make([]byte, 4096),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CompareMftMirror(bytes.NewReader(tt.volume))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("CompareMftMirror() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestReadSystemRecord(t *testing.T) {
+	bootSector, _ := RawBootSector(buildTestBootSector(4, 0x1234567890abcdef)).Parse()
+	corruptMftVolume := buildTestMftMirrorVolume(0x01, 0x00)
+	corruptTestMftMirrorRecord(corruptMftVolume, 4, 3)
+	corruptBothVolume := buildTestMftMirrorVolume(0x01, 0x00)
+	corruptTestMftMirrorRecord(corruptBothVolume, 4, 3)
+	corruptTestMftMirrorRecord(corruptBothVolume, 16, 3)
+
+	tests := []struct {
+		name            string
+		volume          []byte
+		recordNumber    uint64
+		wantVolumeFlags VolumeFlags
+		wantErr         bool
+	}{
+		{
+			name:            "$MFT copy",
+			volume:          buildTestMftMirrorVolume(0x01, 0x00),
+			recordNumber:    3,
+			wantVolumeFlags: VolumeFlagDirty,
+		},
+		{
+			name:            "corrupt $MFT copy falls back to the $MFTMirr copy",
+			volume:          corruptMftVolume,
+			recordNumber:    3,
+			wantVolumeFlags: 0,
+		},
+		{
+			name:         "both copies corrupt",
+			volume:       corruptBothVolume,
+			recordNumber: 3,
+			wantErr:      true,
+		},
+		{
+			name:         "record past the mirror",
+			volume:       buildTestMftMirrorVolume(0x00, 0x00),
+			recordNumber: 4,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, mftRecord, err := readSystemRecord(bytes.NewReader(tt.volume), bootSector, tt.recordNumber)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("readSystemRecord() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if mftRecord.VolumeInformation.Flags != tt.wantVolumeFlags {
+				t.Errorf("readSystemRecord() volume flags = %v, want %v", mftRecord.VolumeInformation.Flags, tt.wantVolumeFlags)
+			}
+		})
+	}
+}
+
+func TestReadVolumeMetadata_mirrorFallback(t *testing.T) {
+	volume := buildTestMftMirrorVolume(0x00, 0x00)
+	corruptTestMftMirrorRecord(volume, 4, 3)
+	got, err := ReadVolumeMetadata(bytes.NewReader(volume))
+	if err != nil {
+		t.Fatalf("ReadVolumeMetadata() error = %v", err)
+	}
+	if got.Label != "DATA" || got.Version() != "3.1" {
+		t.Errorf("ReadVolumeMetadata() label = %q, version = %s, want DATA and 3.1", got.Label, got.Version())
+	}
+}
@@ -0,0 +1,234 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"container/list"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// MFT provides random access to the records of an extracted $MFT, or of anything else that holds MFT records back to back. It is safe for concurrent use as long as the underlying reader is, which is the case for an os.File.
+type MFT struct {
+	reader          io.ReaderAt
+	recordSize      int64
+	recordCount     uint64
+	bytesPerCluster int64
+	lenient         bool
+	cache           *recordCache
+}
+
+// MFTOptions contains the optional settings of an MFT.
+type MFTOptions struct {
+	// RecordSize is the size of a record, 1024 when left at 0.
+	RecordSize int64
+
+	// CacheSize is the number of parsed records kept in a least recently used cache by MFT.Record(). The cache is disabled when this is 0.
+	CacheSize int
+
+	// Lenient parses records with RawMasterFileTableRecord.ParseLenient().
+	Lenient bool
+}
+
+// NewMFT returns an MFT for the records in the first size bytes of the reader. Bytes past the last whole record are ignored. The bytes per cluster argument is used to calculate data run information, typically 4096.
+func NewMFT(reader io.ReaderAt, size int64, bytesPerCluster int64, options MFTOptions) (mft *MFT, err error) {
+	recordSize := options.RecordSize
+	if recordSize == 0 {
+		recordSize = defaultMftRecordSize
+	}
+
+	// Sanity checks
+	if reader == nil {
+		err = fmt.Errorf("NewMFT(): %w", ErrNilBytes)
+		return
+	} else if size < 0 {
+		err = fmt.Errorf("received a negative size of %d", size)
+		return
+	} else if bytesPerCluster <= 0 {
+		err = fmt.Errorf("invalid bytes per cluster value of %d", bytesPerCluster)
+		return
+	} else if recordSize < 0x200 || recordSize%0x200 != 0 {
+		err = fmt.Errorf("invalid record size of %d", recordSize)
+		return
+	} else if options.CacheSize < 0 {
+		err = fmt.Errorf("invalid cache size of %d", options.CacheSize)
+		return
+	}
+
+	mft = &MFT{
+		reader:          reader,
+		recordSize:      recordSize,
+		recordCount:     uint64(size / recordSize),
+		bytesPerCluster: bytesPerCluster,
+		lenient:         options.Lenient,
+	}
+	if options.CacheSize != 0 {
+		mft.cache = newRecordCache(options.CacheSize)
+	}
+	return
+}
+
+// RecordCount returns the number of records in the mft receiver.
+func (mft *MFT) RecordCount() uint64 {
+	return mft.recordCount
+}
+
+// RecordSize returns the size of the records of the mft receiver.
+func (mft *MFT) RecordSize() int64 {
+	return mft.recordSize
+}
+
+// RawRecord reads record n of the mft receiver. ErrRecordOutOfRange is returned for record numbers past the last record.
+func (mft *MFT) RawRecord(n uint64) (rawMftRecord RawMasterFileTableRecord, err error) {
+	// Sanity checks
+	if n >= mft.recordCount {
+		err = fmt.Errorf("record %d of %d: %w", n, mft.recordCount, ErrRecordOutOfRange)
+		return
+	}
+
+	rawMftRecord = make(RawMasterFileTableRecord, mft.recordSize)
+	bytesRead, err := mft.reader.ReadAt(rawMftRecord, int64(n)*mft.recordSize)
+	if err == io.EOF && int64(bytesRead) == mft.recordSize {
+		err = nil
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to read record %d: %w", n, err)
+		rawMftRecord = nil
+		return
+	}
+	return
+}
+
+// Record reads and parses record n of the mft receiver. Records are served from the cache when it's enabled, and records returned from the cache share their slices with it, so they must not be modified.
+func (mft *MFT) Record(n uint64) (mftRecord MasterFileTableRecord, err error) {
+	if mft.cache != nil {
+		var ok bool
+		if mftRecord, ok = mft.cache.get(n); ok {
+			return
+		}
+	}
+
+	mftRecord, err = mft.parseRecord(n)
+	if err != nil {
+		return
+	}
+	if mft.cache != nil {
+		mft.cache.add(n, mftRecord)
+	}
+	return
+}
+
+// Records returns an iterator over the records of the mft receiver in order. The iterator reads the records itself rather than going through the cache, so walking the whole MFT doesn't push out the records that were cached by Record().
+func (mft *MFT) Records() (recordIterator *RecordIterator) {
+	recordIterator = &RecordIterator{mft: mft}
+	return
+}
+
+// Reads and parses record n of the mft receiver.
+func (mft *MFT) parseRecord(n uint64) (mftRecord MasterFileTableRecord, err error) {
+	rawMftRecord, err := mft.RawRecord(n)
+	if err != nil {
+		return
+	}
+	mftRecord, err = rawMftRecord.parse(mft.bytesPerCluster, ParseOptions{Lenient: mft.lenient})
+	if err != nil {
+		err = fmt.Errorf("failed to parse record %d: %w", n, err)
+		return
+	}
+	return
+}
+
+// RecordIterator walks the records of an MFT in order. It isn't safe for concurrent use, but any number of iterators can walk the same MFT at once.
+type RecordIterator struct {
+	mft  *MFT
+	next uint64
+}
+
+// Next returns the next record of the record iterator receiver along with its record number. Records that have never been used are all zeros and are skipped. An error for a single record is returned along with its record number, and the next call moves on to the record after it. io.EOF is returned once every record has been read.
+func (recordIterator *RecordIterator) Next() (recordNumber uint64, mftRecord MasterFileTableRecord, err error) {
+	for recordIterator.next < recordIterator.mft.recordCount {
+		recordNumber = recordIterator.next
+		recordIterator.next++
+
+		var rawMftRecord RawMasterFileTableRecord
+		rawMftRecord, err = recordIterator.mft.RawRecord(recordNumber)
+		if err != nil {
+			return
+		}
+		if rawMftRecord.isEmpty() {
+			continue
+		}
+		mftRecord, err = rawMftRecord.parse(recordIterator.mft.bytesPerCluster, ParseOptions{Lenient: recordIterator.mft.lenient})
+		if err != nil {
+			err = fmt.Errorf("failed to parse record %d: %w", recordNumber, err)
+		}
+		return
+	}
+	recordNumber = 0
+	err = io.EOF
+	return
+}
+
+// recordCache is a least recently used cache of parsed records keyed by record number.
+type recordCache struct {
+	mutex    sync.Mutex
+	capacity int
+	entries  map[uint64]*list.Element
+	order    *list.List
+}
+
+// Entry of a record cache, kept in the order list so that the evicted record can be removed from the map.
+type recordCacheEntry struct {
+	recordNumber uint64
+	mftRecord    MasterFileTableRecord
+}
+
+// Returns an empty record cache that holds up to capacity records.
+func newRecordCache(capacity int) (cache *recordCache) {
+	cache = &recordCache{
+		capacity: capacity,
+		entries:  make(map[uint64]*list.Element),
+		order:    list.New(),
+	}
+	return
+}
+
+// Returns the cached record and marks it as the most recently used.
+func (cache *recordCache) get(recordNumber uint64) (mftRecord MasterFileTableRecord, ok bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	element, ok := cache.entries[recordNumber]
+	if !ok {
+		return
+	}
+	cache.order.MoveToFront(element)
+	mftRecord = element.Value.(*recordCacheEntry).mftRecord
+	return
+}
+
+// Adds a record to the cache, evicting the least recently used record when the cache is full.
+func (cache *recordCache) add(recordNumber uint64, mftRecord MasterFileTableRecord) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if element, ok := cache.entries[recordNumber]; ok {
+		// Another caller parsed the same record in the meantime.
+		element.Value.(*recordCacheEntry).mftRecord = mftRecord
+		cache.order.MoveToFront(element)
+		return
+	}
+	cache.entries[recordNumber] = cache.order.PushFront(&recordCacheEntry{recordNumber: recordNumber, mftRecord: mftRecord})
+	if cache.order.Len() > cache.capacity {
+		oldest := cache.order.Back()
+		cache.order.Remove(oldest)
+		delete(cache.entries, oldest.Value.(*recordCacheEntry).recordNumber)
+	}
+	return
+}
+
+// Returns the number of records in the cache.
+func (cache *recordCache) len() int {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	return cache.order.Len()
+}
@@ -0,0 +1,233 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"errors"
+	"github.com/google/go-cmp/cmp"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// Counts the reads of a reader at.
+type countingReaderAt struct {
+	reader io.ReaderAt
+	mutex  sync.Mutex
+	reads  int
+}
+
+func (countingReaderAt *countingReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
+	countingReaderAt.mutex.Lock()
+	countingReaderAt.reads++
+	countingReaderAt.mutex.Unlock()
+	return countingReaderAt.reader.ReadAt(p, off)
+}
+
+// Builds an MFT with an in use record, an empty record, a record that isn't an MFT record, and another in use record, followed by part of a record.
+func buildTestMft() []byte {
+	return bytes.Join([][]byte{
+		buildTestMftRecord(0, 0, buildTestFileNameAttribute("notes.txt", 5)),
+		make([]byte, 1024),
+		bytes.Repeat([]byte("junk"), 256),
+		buildTestMftRecord(3, 0, buildTestFileNameAttribute("docs.txt", 5)),
+		make([]byte, 100),
+	}, nil)
+}
+
+func TestNewMFT(t *testing.T) {
+	type args struct {
+		reader          io.ReaderAt
+		size            int64
+		bytesPerCluster int64
+		options         MFTOptions
+	}
+	rawMft := buildTestMft()
+	tests := []struct {
+		name            string
+		args            args
+		wantRecordCount uint64
+		wantRecordSize  int64
+		wantErr         bool
+	}{
+		{
+			name:            "default record size",
+			args:            args{reader: bytes.NewReader(rawMft), size: int64(len(rawMft)), bytesPerCluster: 4096},
+			wantRecordCount: 4,
+			wantRecordSize:  1024,
+		},
+		{
+			name:            "4096 byte records",
+			args:            args{reader: bytes.NewReader(rawMft), size: int64(len(rawMft)), bytesPerCluster: 4096, options: MFTOptions{RecordSize: 4096}},
+			wantRecordCount: 1,
+			wantRecordSize:  4096,
+		},
+		{
+			name:    "nil reader",
+			args:    args{size: 1024, bytesPerCluster: 4096},
+			wantErr: true,
+		},
+		{
+			name:    "no bytes per cluster",
+			args:    args{reader: bytes.NewReader(rawMft), size: int64(len(rawMft))},
+			wantErr: true,
+		},
+		{
+			name:    "bad record size",
+			args:    args{reader: bytes.NewReader(rawMft), size: int64(len(rawMft)), bytesPerCluster: 4096, options: MFTOptions{RecordSize: 1000}},
+			wantErr: true,
+		},
+		{
+			name:    "negative cache size",
+			args:    args{reader: bytes.NewReader(rawMft), size: int64(len(rawMft)), bytesPerCluster: 4096, options: MFTOptions{CacheSize: -1}},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mft, err := NewMFT(tt.args.reader, tt.args.size, tt.args.bytesPerCluster, tt.args.options)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("NewMFT() error = %v, wantErr %v", err, tt.wantErr)
+			} else if err != nil {
+				return
+			}
+			if mft.RecordCount() != tt.wantRecordCount || mft.RecordSize() != tt.wantRecordSize {
+				t.Errorf("got %d records of %d bytes, want %d records of %d bytes", mft.RecordCount(), mft.RecordSize(), tt.wantRecordCount, tt.wantRecordSize)
+			}
+		})
+	}
+}
+
+func TestMFT_Record(t *testing.T) {
+	rawMft := buildTestMft()
+	mft, err := NewMFT(bytes.NewReader(rawMft), int64(len(rawMft)), 4096, MFTOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name         string
+		recordNumber uint64
+		wantFileName string
+		wantErr      error
+	}{
+		{
+			name:         "first record",
+			recordNumber: 0,
+			wantFileName: "notes.txt",
+		},
+		{
+			name:         "last record",
+			recordNumber: 3,
+			wantFileName: "docs.txt",
+		},
+		{
+			name:         "not an mft record",
+			recordNumber: 2,
+			wantErr:      ErrNotMftRecord,
+		},
+		{
+			name:         "partial record past the end",
+			recordNumber: 4,
+			wantErr:      ErrRecordOutOfRange,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mftRecord, err := mft.Record(tt.recordNumber)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("Record() error = %v, want %v", err, tt.wantErr)
+			} else if err != nil {
+				return
+			}
+			if len(mftRecord.FileNameAttributes) != 1 || mftRecord.FileNameAttributes[0].FileName != tt.wantFileName {
+				t.Errorf("Record() returned the filename attributes %+v, want %s", mftRecord.FileNameAttributes, tt.wantFileName)
+			}
+		})
+	}
+}
+
+func TestMFT_Record_cache(t *testing.T) {
+	rawMft := buildTestMft()
+	reader := &countingReaderAt{reader: bytes.NewReader(rawMft)}
+	mft, err := NewMFT(reader, int64(len(rawMft)), 4096, MFTOptions{CacheSize: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The second read of record 0 is a cache hit, then record 3 pushes record 0 out of the cache.
+	for _, recordNumber := range []uint64{0, 0, 3, 0} {
+		_, err = mft.Record(recordNumber)
+		if err != nil {
+			t.Fatalf("Record(%d) returned %v", recordNumber, err)
+		}
+	}
+	if reader.reads != 3 {
+		t.Errorf("got %d reads, want 3", reader.reads)
+	}
+	if mft.cache.len() != 1 {
+		t.Errorf("got %d cached records, want 1", mft.cache.len())
+	}
+
+	// Records that fail to parse aren't cached.
+	_, _ = mft.Record(2)
+	_, _ = mft.Record(2)
+	if reader.reads != 5 {
+		t.Errorf("got %d reads, want 5", reader.reads)
+	}
+}
+
+func TestMFT_Record_concurrent(t *testing.T) {
+	rawMft := buildTestMft()
+	mft, err := NewMFT(bytes.NewReader(rawMft), int64(len(rawMft)), 4096, MFTOptions{CacheSize: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var waitGroup sync.WaitGroup
+	for i := 0; i < 16; i++ {
+		waitGroup.Add(1)
+		go func(recordNumber uint64) {
+			defer waitGroup.Done()
+			mftRecord, err := mft.Record(recordNumber)
+			if err != nil || mftRecord.RecordHeader.RecordNumber != uint32(recordNumber) {
+				t.Errorf("Record(%d) returned record %d and error %v", recordNumber, mftRecord.RecordHeader.RecordNumber, err)
+			}
+		}(uint64(i%2) * 3)
+	}
+	waitGroup.Wait()
+}
+
+func TestMFT_Records(t *testing.T) {
+	rawMft := buildTestMft()
+	mft, err := NewMFT(bytes.NewReader(rawMft), int64(len(rawMft)), 4096, MFTOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	type testRecord struct {
+		RecordNumber uint64
+		FileName     string
+		Err          bool
+	}
+	var got []testRecord
+	recordIterator := mft.Records()
+	for {
+		recordNumber, mftRecord, err := recordIterator.Next()
+		if err == io.EOF {
+			break
+		}
+		record := testRecord{RecordNumber: recordNumber, Err: err != nil}
+		if len(mftRecord.FileNameAttributes) != 0 {
+			record.FileName = mftRecord.FileNameAttributes[0].FileName
+		}
+		got = append(got, record)
+	}
+	want := []testRecord{
+		{RecordNumber: 0, FileName: "notes.txt"},
+		{RecordNumber: 2, Err: true},
+		{RecordNumber: 3, FileName: "docs.txt"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
@@ -4,6 +4,8 @@ package mft
 
 import (
 	"bytes"
+	"encoding/binary"
+	"fmt"
 	"github.com/google/go-cmp/cmp"
 	"io"
 	"os"
@@ -54,11 +56,13 @@ func TestRawMasterFileTableRecord_Parse(t *testing.T) {
 			wantErr:      false,
 			wantMftRecord: MasterFileTableRecord{
 				RecordHeader: RecordHeader{
+					SequenceNumber:   1,
 					AttributesOffset: 56,
 					RecordNumber:     0,
 					Flags: RecordHeaderFlags{
 						FlagDeleted:   false,
 						FlagDirectory: false,
+						FlagInUse:     true,
 					},
 				},
 				StandardInformationAttributes: StandardInformationAttribute{
@@ -67,6 +71,7 @@ func TestRawMasterFileTableRecord_Parse(t *testing.T) {
 					SiAccessed:   time.Date(2018, 2, 25, 00, 10, 45, 642455000, time.UTC),
 					SiChanged:    time.Date(2018, 2, 25, 00, 10, 45, 642455000, time.UTC),
 					FlagResident: true,
+					SecurityID:   256,
 				},
 				FileNameAttributes: FileNameAttributes{
 					0: FileNameAttribute{
@@ -148,6 +153,12 @@ func TestRawMasterFileTableRecord_Parse(t *testing.T) {
 						},
 					},
 				},
+				Attributes: []AttributeInfo{
+					{Type: 0x10, Resident: true, Size: 0x60},
+					{Type: 0x30, Resident: true, Size: 0x68, ID: 0x03},
+					{Type: 0x80, Size: 0x80, ID: 0x06},
+					{Type: 0xB0, Size: 0x50, ID: 0x05},
+				},
 			},
 		},
 		{
@@ -185,6 +196,9 @@ func TestRawMasterFileTableRecord_Parse(t *testing.T) {
 			rawMftRecord: []byte{70, 73, 76, 69, 48, 0, 3, 0, 113, 250, 76, 78, 8, 0, 0, 0, 1, 0, 1, 0, 0, 0, 1, 0, 216, 1, 0, 0, 0, 4, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 7, 0, 0, 0, 0, 0, 0, 0, 199, 5, 0, 0, 0, 0, 0, 0, 16, 0, 0, 0, 96, 0, 0, 0, 0, 0, 24, 0, 0, 0, 0, 0, 72, 0, 0, 0, 24, 0, 0, 0, 102, 248, 4, 21, 205, 173, 211, 1, 102, 248, 4, 21, 205, 173, 211, 1, 102, 248, 4, 21, 205, 173, 211, 1, 102, 248, 4, 21, 205, 173, 211, 1, 6, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 48, 0, 0, 0, 104, 0, 0, 0, 0, 0, 24, 0, 0, 0, 3, 0, 74, 0, 0, 0, 24, 0, 1, 0, 5, 0, 0, 0, 0, 0, 5, 0, 102, 248, 4, 21, 205, 173, 211, 1, 102, 248, 4, 21, 205, 173, 211, 1, 102, 248, 4, 21, 205, 173, 211, 1, 102, 248, 4, 21, 205, 173, 211, 1, 0, 64, 0, 0, 0, 0, 0, 0, 0, 64, 0, 0, 0, 0, 0, 0, 6, 0, 0, 0, 0, 0, 0, 0, 4, 3, 36, 0, 77, 0, 70, 0, 84, 0, 0, 0, 0, 0, 0, 0, 128, 0, 0, 0, 128, 0, 0, 0, 1, 0, 64, 0, 0, 0, 6, 0, 0, 0, 0, 0, 0, 0, 0, 0, 255, 81, 3, 0, 0, 0, 0, 0, 64, 0, 0, 0, 0, 0, 0, 0, 0, 0, 32, 53, 0, 0, 0, 0, 0, 0, 32, 53, 0, 0, 0, 0, 0, 0, 32, 53, 0, 0, 0, 0, 51, 32, 200, 0, 0, 0, 12, 50, 96, 5, 194, 0, 56, 67, 16, 219, 0, 78, 89, 133, 0, 66, 176, 108, 91, 31, 119, 255, 66, 192, 69, 205, 200, 190, 0, 66, 0, 56, 8, 170, 148, 0, 66, 128, 80, 188, 200, 136, 1, 66, 64, 25, 2, 118, 2, 253, 66, 64, 85, 48, 135, 101, 2, 0, 176, 0, 0, 0, 80, 0, 0, 0, 1, 0, 64, 0, 0, 0, 5, 0, 0, 0, 0, 0, 0, 0, 0, 0, 27, 0, 0, 0, 0, 0, 0, 0, 64, 0, 0, 0, 0, 0, 0, 0, 0, 192, 1, 0, 0, 0, 0, 0, 8, 176, 1, 0, 0, 0, 0, 0, 8, 176, 1, 0, 0, 0, 0, 0, 49, 25, 115, 210, 0, 65, 3, 176, 243, 197, 0, 0, 0, 0, 0, 0, 255, 255, 255, 255, 0, 0, 0, 0, 0, 32, 0, 0, 0, 0, 0, 0, 8, 16, 0, 0, 0, 0, 0, 0, 8, 16, 0, 0, 0, 0, 0, 0, 49, 1, 255, 255, 11, 49, 1, 38, 0, 244, 0, 0, 0, 0, 199, 5, 255, 255, 255, 255, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 199, 5},
 			wantErr:      true,
 			args:         args{bytesPerCluster: 4096},
+			wantMftRecord: MasterFileTableRecord{
+				RecordHeader: RecordHeader{SequenceNumber: 1, Flags: RecordHeaderFlags{FlagInUse: true}},
+			},
 		},
 	}
 	for _, tt := range tests {
@@ -524,6 +538,7 @@ func TestParseMftRecords(t *testing.T) {
 					SiAccessed:       time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					SiChanged:        time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					PhysicalFileSize: 4096,
+					Attributes:       "$STANDARD_INFORMATION;$FILE_NAME;$DATA",
 				},
 				1: {
 					RecordNumber:     0,
@@ -610,6 +625,7 @@ func TestParseMFT(t *testing.T) {
 					SiAccessed:       time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					SiChanged:        time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					PhysicalFileSize: 16384,
+					Attributes:       "$STANDARD_INFORMATION;$FILE_NAME;$DATA;$BITMAP",
 				},
 				1: {
 					RecordNumber:     1,
@@ -630,6 +646,7 @@ func TestParseMFT(t *testing.T) {
 					SiAccessed:       time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					SiChanged:        time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					PhysicalFileSize: 4096,
+					Attributes:       "$STANDARD_INFORMATION;$FILE_NAME;$DATA",
 				},
 				2: {
 					RecordNumber:     2,
@@ -650,6 +667,7 @@ func TestParseMFT(t *testing.T) {
 					SiAccessed:       time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					SiChanged:        time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					PhysicalFileSize: 67108864,
+					Attributes:       "$STANDARD_INFORMATION;$FILE_NAME;$DATA",
 				},
 				3: {
 					RecordNumber:     3,
@@ -670,6 +688,8 @@ func TestParseMFT(t *testing.T) {
 					SiAccessed:       time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					SiChanged:        time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					PhysicalFileSize: 0,
+					ObjectID:         "1cda7cc4-95cf-4789-badc-72b81b3c2603",
+					Attributes:       "$STANDARD_INFORMATION;$FILE_NAME;$OBJECT_ID;$VOLUME_NAME;$VOLUME_INFORMATION;$DATA",
 				},
 				4: {
 					RecordNumber:     4,
@@ -690,6 +710,10 @@ func TestParseMFT(t *testing.T) {
 					SiAccessed:       time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					SiChanged:        time.Date(2018, 2, 25, 0, 10, 45, 642455000, time.UTC),
 					PhysicalFileSize: 2400,
+					OwnerSID:         "S-1-5-18",
+					OwnerAccountName: "NT AUTHORITY\\SYSTEM",
+					SDDL:             "O:S-1-5-18G:S-1-5-32-544D:(A;;FR;;;S-1-5-18)(A;;FR;;;S-1-5-32-544)",
+					Attributes:       "$STANDARD_INFORMATION;$FILE_NAME;$SECURITY_DESCRIPTOR;$DATA",
 				},
 				5: {
 					RecordNumber:     5,
@@ -710,6 +734,12 @@ func TestParseMFT(t *testing.T) {
 					SiAccessed:       time.Date(2019, 9, 8, 14, 53, 21, 936932600, time.UTC),
 					SiChanged:        time.Date(2019, 9, 8, 14, 53, 21, 936932600, time.UTC),
 					PhysicalFileSize: 0,
+					ObjectID:         "d17f1f53-fdc2-11e8-a853-34028681dca5",
+					ObjectIDCreated:  time.Date(2018, 12, 12, 4, 3, 1, 505211500, time.UTC),
+					ObjectIDMAC:      "34:02:86:81:dc:a5",
+					TxF:              true,
+					Attributes:       "$STANDARD_INFORMATION;$FILE_NAME;$OBJECT_ID;$INDEX_ROOT:$I30;$INDEX_ALLOCATION:$I30;$BITMAP:$I30;$LOGGED_UTILITY_STREAM:$TXF_DATA",
+					SiUsn:            3929582672,
 				},
 				6: UsefulMftFields{
 					RecordNumber:     0,
@@ -745,3 +775,189 @@ func TestParseMFT(t *testing.T) {
 		})
 	}
 }
+
+func TestParseOptions_enrich(t *testing.T) {
+	type args struct {
+		usefulMftFields UsefulMftFields
+		mftRecord       MasterFileTableRecord
+	}
+	tests := []struct {
+		name    string
+		options ParseOptions
+		args    args
+		want    UsefulMftFields
+	}{
+		{
+			name:    "security id resolved",
+			options: ParseOptions{SecurityDescriptors: SecurityDescriptors{256: testSecurityDescriptor}},
+			args: args{
+				mftRecord: MasterFileTableRecord{StandardInformationAttributes: StandardInformationAttribute{SecurityID: 256}},
+			},
+			want: UsefulMftFields{
+				OwnerSID:         "S-1-5-18",
+				OwnerAccountName: "NT AUTHORITY\\SYSTEM",
+				SDDL:             "O:S-1-5-18G:S-1-5-32-544D:(A;;FR;;;S-1-5-18)(A;;FR;;;S-1-5-32-544)",
+			},
+		},
+		{
+			name:    "volume context",
+			options: ParseOptions{Volume: &VolumeMetadata{Label: "DATA", BootSector: BootSector{SerialNumber: 0x1234567890abcdef}}},
+			want:    UsefulMftFields{VolumeLabel: "DATA", VolumeSerial: "90AB-CDEF"},
+		},
+		{
+			name:    "volume attribute definitions",
+			options: ParseOptions{AttributeDefinitions: AttributeDefinitions{0x1000: testCustomAttributeDefinition}},
+			args: args{
+				usefulMftFields: UsefulMftFields{Attributes: "0x1000"},
+				mftRecord:       MasterFileTableRecord{Attributes: []AttributeInfo{{Type: 0x1000, Name: "payload"}}},
+			},
+			want: UsefulMftFields{Attributes: "$CUSTOM:payload"},
+		},
+		{
+			name: "account name from mapping",
+			options: ParseOptions{
+				SecurityDescriptors: SecurityDescriptors{256: SecurityDescriptor{Revision: 1, Owner: "S-1-5-21-1-2-3-1001"}},
+				AccountNames:        AccountNames{"S-1-5-21-1-2-3-1001": "CORP\\alice"},
+			},
+			args: args{
+				mftRecord: MasterFileTableRecord{StandardInformationAttributes: StandardInformationAttribute{SecurityID: 256}},
+			},
+			want: UsefulMftFields{
+				OwnerSID:         "S-1-5-21-1-2-3-1001",
+				OwnerAccountName: "CORP\\alice",
+				SDDL:             "O:S-1-5-21-1-2-3-1001",
+			},
+		},
+		{
+			name:    "usn journal record",
+			options: ParseOptions{UsnJournal: testUsnJournalIndex()},
+			args: args{
+				usefulMftFields: UsefulMftFields{SiUsn: 0x2000},
+				mftRecord: MasterFileTableRecord{
+					RecordHeader:                  RecordHeader{RecordNumber: 70, SequenceNumber: 3},
+					StandardInformationAttributes: StandardInformationAttribute{Usn: 0x2000},
+				},
+			},
+			want: UsefulMftFields{SiUsn: 0x2000, UsnTimestamp: testUsnTimestamp, UsnReason: "FILE_CREATE;CLOSE"},
+		},
+		{
+			name:    "usn journal anomaly",
+			options: ParseOptions{UsnJournal: testUsnJournalIndex()},
+			args: args{
+				usefulMftFields: UsefulMftFields{SiUsn: 0x2000},
+				mftRecord: MasterFileTableRecord{
+					RecordHeader:                  RecordHeader{RecordNumber: 90, SequenceNumber: 1},
+					StandardInformationAttributes: StandardInformationAttribute{Usn: 0x2000},
+				},
+			},
+			want: UsefulMftFields{SiUsn: 0x2000, UsnAnomaly: "FILE_REFERENCE_MISMATCH"},
+		},
+		{
+			name:    "mft bitmap allocated",
+			options: ParseOptions{MftBitmap: MftBitmap{0x01}},
+			args: args{
+				mftRecord: MasterFileTableRecord{RecordHeader: RecordHeader{RecordNumber: 0, Flags: RecordHeaderFlags{FlagInUse: true}}},
+			},
+			want: UsefulMftFields{MftAllocated: true},
+		},
+		{
+			name:    "mft bitmap anomaly",
+			options: ParseOptions{MftBitmap: MftBitmap{0x01}},
+			args: args{
+				mftRecord: MasterFileTableRecord{RecordHeader: RecordHeader{RecordNumber: 3, Flags: RecordHeaderFlags{FlagInUse: true}}},
+			},
+			want: UsefulMftFields{MftAnomaly: "IN_USE_NOT_ALLOCATED"},
+		},
+		{
+			name:    "record slack",
+			options: ParseOptions{RecordSlack: true},
+			args: args{
+				mftRecord: MasterFileTableRecord{Slack: RecordSlack{
+					Offset: 0x100,
+					Data:   make([]byte, 0x300),
+					ResidualAttributes: []ResidualAttribute{
+						{Offset: 0x108, AttributeInfo: AttributeInfo{Type: 0x30, Resident: true}, FileName: FileNameAttribute{FileName: "old.txt"}},
+						{Offset: 0x170, AttributeInfo: AttributeInfo{Type: 0x80, Resident: true}},
+					},
+				}},
+			},
+			want: UsefulMftFields{SlackSize: 0x300, SlackAttributes: "$FILE_NAME@0x108;$DATA@0x170", SlackFileNames: "old.txt"},
+		},
+		{
+			name:    "partial record",
+			options: ParseOptions{Lenient: true},
+			args: args{
+				mftRecord: MasterFileTableRecord{AttributeErrors: []*AttributeError{
+					{AttributeType: 0x30, Offset: 0x98, Err: ErrNonResident},
+					{AttributeType: 0x80, Offset: 0x100, Err: ErrBadDataRun},
+				}},
+			},
+			want: UsefulMftFields{Partial: true, AttributeErrors: "$FILE_NAME@0x98: attribute is non-resident;$DATA@0x100: bad data run"},
+		},
+		{
+			name:    "complete record",
+			options: ParseOptions{Lenient: true},
+			want:    UsefulMftFields{},
+		},
+		{
+			name:    "unknown security id",
+			options: ParseOptions{SecurityDescriptors: SecurityDescriptors{256: testSecurityDescriptor}},
+			args: args{
+				mftRecord: MasterFileTableRecord{StandardInformationAttributes: StandardInformationAttribute{SecurityID: 300}},
+			},
+			want: UsefulMftFields{},
+		},
+		{
+			name:    "resident security descriptor takes precedence",
+			options: ParseOptions{SecurityDescriptors: SecurityDescriptors{256: testSecurityDescriptor}},
+			args: args{
+				usefulMftFields: UsefulMftFields{OwnerSID: "S-1-5-32-544", SDDL: "O:S-1-5-32-544"},
+				mftRecord:       MasterFileTableRecord{StandardInformationAttributes: StandardInformationAttribute{SecurityID: 256}},
+			},
+			want: UsefulMftFields{OwnerSID: "S-1-5-32-544", OwnerAccountName: "BUILTIN\\Administrators", SDDL: "O:S-1-5-32-544"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.options.enrich(&tt.args.usefulMftFields, tt.args.mftRecord)
+			if !reflect.DeepEqual(tt.args.usefulMftFields, tt.want) {
+				t.Errorf(cmp.Diff(tt.args.usefulMftFields, tt.want))
+			}
+		})
+	}
+}
+
+func TestRawMasterFileTableRecord_ParseLenient(t *testing.T) {
+	standardInformation := make([]byte, 0x48)
+	binary.LittleEndian.PutUint64(standardInformation[0x00:], 0x01D1D4744ACD24EA)
+	brokenFileName := buildTestFileNameAttribute("broken.txt", 5)
+	brokenFileName[0x08] = 0x01
+	rawMftRecord := buildTestMftRecord(40, 0, buildTestResidentAttribute(0x10, standardInformation), brokenFileName, buildTestFileNameAttribute("notes.txt", 5))
+	wantAttributeErrors := []*AttributeError{{RecordNumber: 40, AttributeType: 0x30, Offset: 0x38 + 0x18 + 0x48, Err: fmt.Errorf("filename attribute: %w", ErrNonResident)}}
+
+	mftRecord, err := rawMftRecord.ParseLenient(4096)
+	if err != nil {
+		t.Fatalf("ParseLenient() returned %v", err)
+	}
+	if !mftRecord.IsPartial() || !reflect.DeepEqual(mftRecord.AttributeErrors, wantAttributeErrors) {
+		t.Errorf(cmp.Diff(mftRecord.AttributeErrors, wantAttributeErrors))
+	}
+	if want := time.Date(2016, 7, 2, 15, 13, 30, 0, time.UTC); !mftRecord.StandardInformationAttributes.SiCreated.Truncate(time.Second).Equal(want) {
+		t.Errorf("ParseLenient() returned a created timestamp of %v, want %v", mftRecord.StandardInformationAttributes.SiCreated, want)
+	}
+	if len(mftRecord.FileNameAttributes) != 1 || mftRecord.FileNameAttributes[0].FileName != "notes.txt" {
+		t.Errorf("ParseLenient() returned the filename attributes %+v, want notes.txt", mftRecord.FileNameAttributes)
+	}
+
+	// Strict parsing reports the same error but drops the attributes that did parse.
+	mftRecord, err = rawMftRecord.Parse(4096)
+	if err != nil {
+		t.Fatalf("Parse() returned %v", err)
+	}
+	if !reflect.DeepEqual(mftRecord.AttributeErrors, wantAttributeErrors) {
+		t.Errorf(cmp.Diff(mftRecord.AttributeErrors, wantAttributeErrors))
+	}
+	if mftRecord.StandardInformationAttributes != (StandardInformationAttribute{}) || mftRecord.FileNameAttributes != nil {
+		t.Errorf("Parse() kept attributes of a record with a bad $FILE_NAME")
+	}
+}
@@ -0,0 +1,198 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+)
+
+// RawObjectIDAttribute is a []byte alias for a raw object id attribute. Used with the Parse() method.
+// See here for the layout: https://flatcap.org/linux-ntfs/ntfs/attributes/object_id.html
+type RawObjectIDAttribute []byte
+
+// RawObjectID is a []byte alias for a raw 16 byte object id. Used with the Parse() method.
+type RawObjectID []byte
+
+// RawObjectIDIndex is a []byte alias for the raw contents of the $Extend\$ObjId:$O index allocation. Used with the Parse() method.
+type RawObjectIDIndex []byte
+
+// ObjectIDAttribute contains a parsed object id attribute. These are the distributed link tracking ids that LNK files and jump lists refer to. Only the object id is required, the birth ids and domain id are zero when they aren't stored.
+type ObjectIDAttribute struct {
+	ObjectID      ObjectID
+	BirthVolumeID ObjectID
+	BirthObjectID ObjectID
+	DomainID      ObjectID
+}
+
+// ObjectID contains a parsed object id. Object ids are normally version 1 UUIDs which embed the time they were created and the MAC address of the machine that created them.
+type ObjectID struct {
+	GUID          string
+	Version       uint8
+	Created       time.Time
+	ClockSequence uint16
+	MAC           string
+}
+
+// ObjectIDIndex maps object ids to their $ObjId:$O index entries.
+type ObjectIDIndex map[string]ObjectIDIndexEntry
+
+// ObjectIDIndexEntry contains an $ObjId:$O index entry. The index ties an object id to the mft record that holds it.
+type ObjectIDIndexEntry struct {
+	RecordNumber   uint64
+	SequenceNumber uint16
+	ObjectIDAttribute
+}
+
+const (
+	lengthObjectID = 0x10
+
+	// Number of 100 nanosecond intervals between the UUID epoch of 1582-10-15 and the unix epoch.
+	uuidEpochOffset = 0x01B21DD213814000
+	uuidVersionTime = 1
+)
+
+// Parse parses the raw object id attribute receiver and returns a parsed object id attribute.
+func (rawObjectIDAttribute RawObjectIDAttribute) Parse() (objectIDAttribute ObjectIDAttribute, err error) {
+	const offsetResidentFlag = 0x08
+	const offsetContentLength = 0x10
+	const lengthContentLength = 0x04
+	const offsetContentOffset = 0x14
+	const lengthContentOffset = 0x02
+
+	// Sanity checks
+	sizeOfRawAttribute := len(rawObjectIDAttribute)
+	if sizeOfRawAttribute == 0 {
+		err = fmt.Errorf("RawObjectIDAttribute.Parse(): %w", ErrNilBytes)
+		return
+	} else if rawObjectIDAttribute[0x00] != 0x40 {
+		err = fmt.Errorf("RawObjectIDAttribute.Parse() received an attribute thats not an object id. Attribute magic number is %x", rawObjectIDAttribute[0x00])
+		return
+	} else if sizeOfRawAttribute < offsetContentOffset+lengthContentOffset {
+		err = fmt.Errorf("RawObjectIDAttribute.Parse() expected at least %d bytes, instead received %d: %w", offsetContentOffset+lengthContentOffset, sizeOfRawAttribute, ErrTruncated)
+		return
+	} else if RawResidencyFlag(rawObjectIDAttribute[offsetResidentFlag]).Parse() == false {
+		err = errors.New("RawObjectIDAttribute.Parse() received a non-resident object id")
+		return
+	}
+
+	contentLength := int(binary.LittleEndian.Uint32(rawObjectIDAttribute[offsetContentLength : offsetContentLength+lengthContentLength]))
+	contentOffset := int(binary.LittleEndian.Uint16(rawObjectIDAttribute[offsetContentOffset : offsetContentOffset+lengthContentOffset]))
+	if contentLength < lengthObjectID || contentOffset+contentLength > sizeOfRawAttribute {
+		err = fmt.Errorf("RawObjectIDAttribute.Parse() content at offset %d with length %d is invalid", contentOffset, contentLength)
+		return
+	}
+	objectIDAttribute = parseObjectIDs(rawObjectIDAttribute[contentOffset : contentOffset+contentLength])
+	return
+}
+
+// Parses up to four consecutive object ids. Object id attributes and $O index entries both store the ids in the same order.
+func parseObjectIDs(rawObjectIDs []byte) (objectIDAttribute ObjectIDAttribute) {
+	objectIDs := []*ObjectID{
+		&objectIDAttribute.ObjectID,
+		&objectIDAttribute.BirthVolumeID,
+		&objectIDAttribute.BirthObjectID,
+		&objectIDAttribute.DomainID,
+	}
+	for index, objectID := range objectIDs {
+		offset := index * lengthObjectID
+		if offset+lengthObjectID > len(rawObjectIDs) {
+			break
+		}
+		*objectID, _ = RawObjectID(rawObjectIDs[offset : offset+lengthObjectID]).Parse()
+	}
+	return
+}
+
+// Parse parses the raw object id receiver. The creation time, clock sequence, and MAC address are only filled in for version 1 UUIDs. Object ids that are all zero return an empty object id.
+func (rawObjectID RawObjectID) Parse() (objectID ObjectID, err error) {
+	const offsetTimeLow = 0x00
+	const offsetTimeMid = 0x04
+	const offsetTimeHiAndVersion = 0x06
+	const offsetClockSequence = 0x08
+	const offsetNode = 0x0A
+
+	// Sanity checks
+	if len(rawObjectID) != lengthObjectID {
+		err = fmt.Errorf("RawObjectID.Parse() expected %d bytes, instead received %d", lengthObjectID, len(rawObjectID))
+		return
+	}
+	if isAllZero(rawObjectID) {
+		return
+	}
+
+	objectID.GUID = formatGUID(rawObjectID)
+	timeLow := uint64(binary.LittleEndian.Uint32(rawObjectID[offsetTimeLow : offsetTimeLow+0x04]))
+	timeMid := uint64(binary.LittleEndian.Uint16(rawObjectID[offsetTimeMid : offsetTimeMid+0x02]))
+	timeHiAndVersion := binary.LittleEndian.Uint16(rawObjectID[offsetTimeHiAndVersion : offsetTimeHiAndVersion+0x02])
+	objectID.Version = uint8(timeHiAndVersion >> 12)
+	if objectID.Version != uuidVersionTime {
+		return
+	}
+
+	timestamp := uint64(timeHiAndVersion&0x0FFF)<<48 | timeMid<<32 | timeLow
+	if timestamp >= uuidEpochOffset {
+		sinceUnixEpoch := timestamp - uuidEpochOffset
+		objectID.Created = time.Unix(int64(sinceUnixEpoch/10000000), int64(sinceUnixEpoch%10000000)*100).UTC()
+	}
+	objectID.ClockSequence = binary.BigEndian.Uint16(rawObjectID[offsetClockSequence:offsetClockSequence+0x02]) & 0x3FFF
+	node := rawObjectID[offsetNode:lengthObjectID]
+	objectID.MAC = fmt.Sprintf("%02x:%02x:%02x:%02x:%02x:%02x", node[0], node[1], node[2], node[3], node[4], node[5])
+	return
+}
+
+// Checks if every byte of the slice is zero.
+func isAllZero(rawBytes []byte) bool {
+	for _, rawByte := range rawBytes {
+		if rawByte != 0x00 {
+			return false
+		}
+	}
+	return true
+}
+
+// Parse walks the INDX records of the raw $ObjId:$O index allocation receiver and returns every entry keyed by object id.
+func (rawObjectIDIndex RawObjectIDIndex) Parse(indexRecordSize int, bytesPerSector int) (objectIDIndex ObjectIDIndex, err error) {
+	const offsetRecordNumber = 0x00
+	const offsetSequenceNumber = 0x06
+	const lengthFileReference = 0x08
+
+	indexEntries, err := ParseIndexAllocation(rawObjectIDIndex, indexRecordSize, bytesPerSector)
+	if err != nil {
+		err = fmt.Errorf("failed to parse $O index allocation: %w", err)
+		return
+	}
+
+	objectIDIndex = make(ObjectIDIndex)
+	for _, indexEntry := range indexEntries {
+		if len(indexEntry.Key) < lengthObjectID || len(indexEntry.Data) < lengthFileReference+lengthObjectID*3 {
+			continue
+		}
+		var objectIDIndexEntry ObjectIDIndexEntry
+		objectIDIndexEntry.RecordNumber = binary.LittleEndian.Uint64(indexEntry.Data[offsetRecordNumber:lengthFileReference]) & 0x0000ffffffffffff
+		objectIDIndexEntry.SequenceNumber = binary.LittleEndian.Uint16(indexEntry.Data[offsetSequenceNumber:lengthFileReference])
+
+		// The object id is the key of the entry. The birth ids and domain id follow the file reference in the data.
+		rawObjectIDs := make([]byte, 0, lengthObjectID*4)
+		rawObjectIDs = append(rawObjectIDs, indexEntry.Key[:lengthObjectID]...)
+		rawObjectIDs = append(rawObjectIDs, indexEntry.Data[lengthFileReference:]...)
+		objectIDIndexEntry.ObjectIDAttribute = parseObjectIDs(rawObjectIDs)
+		objectIDIndex[objectIDIndexEntry.ObjectID.GUID] = objectIDIndexEntry
+	}
+	return
+}
+
+// BuildObjectIDIndex reads an extracted $Extend\$ObjId:$O index allocation and returns every entry keyed by object id. The index record size is typically 4096 and the bytes per sector is typically 512.
+func BuildObjectIDIndex(reader io.Reader, indexRecordSize int, bytesPerSector int) (objectIDIndex ObjectIDIndex, err error) {
+	rawIndex, err := ioutil.ReadAll(reader)
+	if err != nil {
+		err = fmt.Errorf("failed to read $O index allocation: %w", err)
+		return
+	}
+	objectIDIndex, err = RawObjectIDIndex(rawIndex).Parse(indexRecordSize, bytesPerSector)
+	return
+}
@@ -0,0 +1,208 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Object id of the root directory in the test MFT. It's a version 1 UUID.
+var testRawObjectID = []byte{0x53, 0x1f, 0x7f, 0xd1, 0xc2, 0xfd, 0xe8, 0x11, 0xa8, 0x53, 0x34, 0x02, 0x86, 0x81, 0xdc, 0xa5}
+
+var testObjectID = ObjectID{
+	GUID:          "d17f1f53-fdc2-11e8-a853-34028681dca5",
+	Version:       1,
+	Created:       time.Date(2018, 12, 12, 4, 3, 1, 505211500, time.UTC),
+	ClockSequence: 0x2853,
+	MAC:           "34:02:86:81:dc:a5",
+}
+
+// Object id of the $Volume record in the test MFT. It's a version 4 UUID so it has no timestamp or MAC address.
+var testRawVolumeObjectID = []byte{0xc4, 0x7c, 0xda, 0x1c, 0xcf, 0x95, 0x89, 0x47, 0xba, 0xdc, 0x72, 0xb8, 0x1b, 0x3c, 0x26, 0x03}
+
+var testVolumeObjectID = ObjectID{
+	GUID:    "1cda7cc4-95cf-4789-badc-72b81b3c2603",
+	Version: 4,
+}
+
+func buildTestObjectIDAttribute(rawObjectIDs ...[]byte) []byte {
+	const contentOffset = 0x18
+	content := bytes.Join(rawObjectIDs, nil)
+	rawAttribute := make([]byte, contentOffset+len(content))
+	rawAttribute[0x00] = 0x40
+	binary.LittleEndian.PutUint32(rawAttribute[0x04:], uint32(len(rawAttribute)))
+	binary.LittleEndian.PutUint32(rawAttribute[0x10:], uint32(len(content)))
+	binary.LittleEndian.PutUint16(rawAttribute[0x14:], contentOffset)
+	copy(rawAttribute[contentOffset:], content)
+	return rawAttribute
+}
+
+func TestRawObjectID_Parse(t *testing.T) {
+	tests := []struct {
+		name        string
+		rawObjectID RawObjectID
+		want        ObjectID
+		wantErr     bool
+	}{
+		{
+			name:        "version 1",
+			rawObjectID: testRawObjectID,
+			want:        testObjectID,
+		},
+		{
+			name:        "version 4",
+			rawObjectID: testRawVolumeObjectID,
+			want:        testVolumeObjectID,
+		},
+		{
+			name:        "all zero",
+			rawObjectID: make([]byte, 16),
+		},
+		{
+			name:        "wrong size",
+			rawObjectID: testRawObjectID[:8],
+			wantErr:     true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rawObjectID.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestRawObjectIDAttribute_Parse(t *testing.T) {
+	zero := make([]byte, 16)
+	nonResident := buildTestObjectIDAttribute(testRawObjectID)
+	nonResident[0x08] = 0x01
+
+	tests := []struct {
+		name         string
+		rawAttribute RawObjectIDAttribute
+		want         ObjectIDAttribute
+		wantErr      bool
+	}{
+		{
+			name:         "object id only",
+			rawAttribute: buildTestObjectIDAttribute(testRawObjectID),
+			want:         ObjectIDAttribute{ObjectID: testObjectID},
+		},
+		{
+			name:         "all ids",
+			rawAttribute: buildTestObjectIDAttribute(testRawObjectID, testRawVolumeObjectID, testRawObjectID, zero),
+			want: ObjectIDAttribute{
+				ObjectID:      testObjectID,
+				BirthVolumeID: testVolumeObjectID,
+				BirthObjectID: testObjectID,
+			},
+		},
+		{
+			name:    "nil bytes",
+			wantErr: true,
+		},
+		{
+			name:         "wrong attribute type",
+			rawAttribute: RawObjectIDAttribute{0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			wantErr:      true,
+		},
+		{
+			name:         "non-resident",
+			rawAttribute: nonResident,
+			wantErr:      true,
+		},
+		{
+			name:         "content too short",
+			rawAttribute: buildTestObjectIDAttribute(testRawObjectID[:8]),
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rawAttribute.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestRawObjectIDIndex_Parse(t *testing.T) {
+	// The data of an $O entry is the file reference followed by the birth volume id, birth object id, and domain id.
+	fileReference := []byte{0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x00}
+	data := bytes.Join([][]byte{fileReference, testRawVolumeObjectID, testRawObjectID, make([]byte, 16)}, nil)
+
+	tests := []struct {
+		name              string
+		rawObjectIDIndex  RawObjectIDIndex
+		wantObjectIDIndex ObjectIDIndex
+		wantErr           bool
+	}{
+		{
+			name:             "one entry",
+			rawObjectIDIndex: buildTestIndexRecord(0, buildTestViewIndexEntry(testRawObjectID, data)),
+			wantObjectIDIndex: ObjectIDIndex{
+				"d17f1f53-fdc2-11e8-a853-34028681dca5": ObjectIDIndexEntry{
+					RecordNumber:   5,
+					SequenceNumber: 5,
+					ObjectIDAttribute: ObjectIDAttribute{
+						ObjectID:      testObjectID,
+						BirthVolumeID: testVolumeObjectID,
+						BirthObjectID: testObjectID,
+					},
+				},
+			},
+		},
+		{
+			name:              "short entry is skipped",
+			rawObjectIDIndex:  buildTestIndexRecord(0, buildTestViewIndexEntry(testRawObjectID, fileReference)),
+			wantObjectIDIndex: ObjectIDIndex{},
+		},
+		{
+			name:             "nil bytes",
+			rawObjectIDIndex: nil,
+			wantErr:          true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotObjectIDIndex, err := tt.rawObjectIDIndex.Parse(4096, 512)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotObjectIDIndex, tt.wantObjectIDIndex) {
+				t.Errorf(cmp.Diff(gotObjectIDIndex, tt.wantObjectIDIndex))
+			}
+		})
+	}
+}
+
+func TestBuildObjectIDIndex(t *testing.T) {
+	data := bytes.Join([][]byte{{0x05, 0, 0, 0, 0, 0, 0x05, 0}, make([]byte, 48)}, nil)
+	rawIndex := buildTestIndexRecord(0, buildTestViewIndexEntry(testRawObjectID, data))
+	gotObjectIDIndex, err := BuildObjectIDIndex(bytes.NewReader(rawIndex), 4096, 512)
+	if err != nil {
+		t.Errorf("BuildObjectIDIndex() error = %v", err)
+		return
+	}
+	if got := gotObjectIDIndex[testObjectID.GUID].RecordNumber; got != 5 {
+		t.Errorf("BuildObjectIDIndex() record number = %v, want 5", got)
+	}
+}
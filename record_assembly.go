@@ -0,0 +1,242 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+)
+
+// RecordAssembler uses random access to an MFT to pull in attributes that live in extension records. Files that are heavily fragmented or have many hard links can have their attributes spread across several MFT records, these are referenced by the attribute list attribute of the base record.
+type RecordAssembler struct {
+	// Mft is the MFT itself. Record N is expected at offset N * RecordSize.
+	Mft io.ReaderAt
+
+	// Volume is optional. It is only needed to read attribute lists that are non-resident.
+	Volume io.ReaderAt
+
+	BytesPerCluster int64
+
+	// RecordSize defaults to 1024 when it is 0.
+	RecordSize int64
+
+	// Lenient parses the base and extension records with RawMasterFileTableRecord.ParseLenient().
+	Lenient bool
+}
+
+// Assemble parses the raw base mft record receiver and merges in the attributes found in its extension records. If an extension record can't be read, the parsed base record is still returned alongside the error.
+func (assembler RecordAssembler) Assemble(rawMftRecord RawMasterFileTableRecord) (mftRecord MasterFileTableRecord, err error) {
+	// Sanity checks
+	if assembler.Mft == nil {
+		err = errors.New("RecordAssembler.Assemble() did not receive an mft to read from")
+		return
+	}
+
+	mftRecord, err = rawMftRecord.parse(assembler.BytesPerCluster, ParseOptions{Lenient: assembler.Lenient})
+	if err != nil {
+		err = fmt.Errorf("failed to parse base record: %w", err)
+		return
+	}
+	if mftRecord.RecordHeader.IsExtensionRecord() {
+		err = fmt.Errorf("record %d is an extension record of record %d", mftRecord.RecordHeader.RecordNumber, mftRecord.RecordHeader.BaseRecordNumber)
+		return
+	}
+
+	attributeList, err := assembler.getAttributeList(rawMftRecord, mftRecord)
+	if err != nil {
+		err = fmt.Errorf("failed to get attribute list of record %d: %w", mftRecord.RecordHeader.RecordNumber, err)
+		return
+	}
+	mftRecord.AttributeList = attributeList
+
+	baseRecordNumber := uint64(mftRecord.RecordHeader.RecordNumber)
+	records := make(map[uint64]MasterFileTableRecord)
+	records[baseRecordNumber] = mftRecord
+	for _, entry := range attributeList {
+		if _, ok := records[entry.MFTReferenceRecordNumber]; ok {
+			continue
+		}
+
+		var extensionRecord MasterFileTableRecord
+		extensionRecord, err = assembler.getExtensionRecord(entry.MFTReferenceRecordNumber, baseRecordNumber)
+		if err != nil {
+			err = fmt.Errorf("failed to get extension record %d of record %d: %w", entry.MFTReferenceRecordNumber, baseRecordNumber, err)
+			return
+		}
+		records[entry.MFTReferenceRecordNumber] = extensionRecord
+		mftRecord.merge(extensionRecord)
+	}
+
+	mftRecord.DataAttribute = assembleDataAttribute(baseRecordNumber, attributeList, records)
+	return
+}
+
+// Puts the segments of the unnamed data attribute back together in order of their starting VCN. Each segment's data runs start over from cluster 0, so once resolved they can simply be appended.
+func assembleDataAttribute(baseRecordNumber uint64, attributeList AttributeListAttributes, records map[uint64]MasterFileTableRecord) (dataAttribute DataAttribute) {
+	const codeData = 0x80
+
+	var segments AttributeListAttributes
+	for _, entry := range attributeList {
+		if entry.Type == codeData && entry.Name == "" {
+			segments = append(segments, entry)
+		}
+	}
+	sort.SliceStable(segments, func(i, j int) bool {
+		return segments[i].StartingVCN < segments[j].StartingVCN
+	})
+
+	dataAttribute = records[baseRecordNumber].DataAttribute
+	if len(segments) == 0 || len(segments) == 1 && segments[0].MFTReferenceRecordNumber == baseRecordNumber {
+		return
+	}
+
+	dataAttribute = DataAttribute{}
+	usedRecords := make(map[uint64]bool)
+	for _, segment := range segments {
+		if usedRecords[segment.MFTReferenceRecordNumber] {
+			continue
+		}
+		usedRecords[segment.MFTReferenceRecordNumber] = true
+
+		segmentDataAttribute := records[segment.MFTReferenceRecordNumber].DataAttribute
+		if len(segmentDataAttribute.NonResidentDataAttribute.DataRuns) == 0 {
+			if dataAttribute.ResidentDataAttribute == nil {
+				dataAttribute.ResidentDataAttribute = segmentDataAttribute.ResidentDataAttribute
+			}
+			continue
+		}
+		if dataAttribute.NonResidentDataAttribute.DataRuns == nil {
+			dataAttribute.NonResidentDataAttribute.DataRuns = make(DataRuns)
+		}
+		numberOfDataRuns := len(dataAttribute.NonResidentDataAttribute.DataRuns)
+		for i := 0; i < len(segmentDataAttribute.NonResidentDataAttribute.DataRuns); i++ {
+			dataAttribute.NonResidentDataAttribute.DataRuns[numberOfDataRuns+i] = segmentDataAttribute.NonResidentDataAttribute.DataRuns[i]
+		}
+	}
+	return
+}
+
+// Returns the attribute list of the base record, reading it from the volume if it is non-resident.
+func (assembler RecordAssembler) getAttributeList(rawBaseRecord RawMasterFileTableRecord, mftRecord MasterFileTableRecord) (attributeList AttributeListAttributes, err error) {
+	const codeAttributeList = 0x20
+	const offsetResidentFlag = 0x08
+
+	rawBaseRecord.trimSlackSpace()
+	rawAttributes, err := rawBaseRecord.GetRawAttributes(mftRecord.RecordHeader)
+	if err != nil {
+		err = fmt.Errorf("failed to get raw attributes: %w", err)
+		return
+	}
+	for _, rawAttribute := range rawAttributes {
+		if len(rawAttribute) <= offsetResidentFlag || rawAttribute.attributeType() != codeAttributeList {
+			continue
+		}
+		if RawResidencyFlag(rawAttribute[offsetResidentFlag]).Parse() == true {
+			attributeList = mftRecord.AttributeList
+			return
+		}
+		rawAttributeListAttribute := RawAttributeListAttribute(make([]byte, len(rawAttribute)))
+		copy(rawAttributeListAttribute, rawAttribute)
+		attributeList, err = rawAttributeListAttribute.ParseNonResident(assembler.Volume, assembler.BytesPerCluster)
+		return
+	}
+	return
+}
+
+// Checks if the raw mft record receiver has an attribute list attribute, resident or not.
+func (rawMftRecord RawMasterFileTableRecord) hasAttributeList(recordHeader RecordHeader) (result bool) {
+	const codeAttributeList = 0x20
+	rawAttributes, err := rawMftRecord.GetRawAttributes(recordHeader)
+	if err != nil {
+		return
+	}
+	for _, rawAttribute := range rawAttributes {
+		if rawAttribute.attributeType() == codeAttributeList {
+			result = true
+			return
+		}
+	}
+	return
+}
+
+// Reads and parses an extension record, verifying that it belongs to the expected base record.
+func (assembler RecordAssembler) getExtensionRecord(recordNumber uint64, baseRecordNumber uint64) (extensionRecord MasterFileTableRecord, err error) {
+	recordSize := assembler.RecordSize
+	if recordSize == 0 {
+		recordSize = defaultMftRecordSize
+	}
+
+	buffer := make(RawMasterFileTableRecord, recordSize)
+	bytesRead, err := assembler.Mft.ReadAt(buffer, int64(recordNumber)*recordSize)
+	if err == io.EOF && int64(bytesRead) == recordSize {
+		err = nil
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to read record: %w", err)
+		return
+	}
+
+	extensionRecord, err = buffer.parse(assembler.BytesPerCluster, ParseOptions{Lenient: assembler.Lenient})
+	if err != nil {
+		err = fmt.Errorf("failed to parse record: %w", err)
+		return
+	}
+	if uint64(extensionRecord.RecordHeader.BaseRecordNumber) != baseRecordNumber {
+		err = fmt.Errorf("record references base record %d instead", extensionRecord.RecordHeader.BaseRecordNumber)
+		return
+	}
+	return
+}
+
+// Merges the attributes of an extension record into the base record receiver. Extension records are merged in attribute list order, so for attributes that a file only has one of, the base record wins and then the first extension record that has the attribute. An attribute that failed to parse counts as present, a broken attribute in the base record is not silently replaced by a copy from an extension record. Data attributes are handled separately by assembleDataAttribute() since their segments have to be ordered. Attribute errors of the extension record are kept so the assembled record shows up as partial, their record numbers and offsets point into the extension record.
+func (mftRecord *MasterFileTableRecord) merge(extensionRecord MasterFileTableRecord) {
+	const codeStandardInformation = 0x10
+	const codeObjectID = 0x40
+	const codeSecurityDescriptor = 0x50
+	const codeVolumeName = 0x60
+	const codeVolumeInformation = 0x70
+	const codeReparsePoint = 0xC0
+	const codeExtendedAttributeInformation = 0xD0
+
+	mftRecord.FileNameAttributes = append(mftRecord.FileNameAttributes, extensionRecord.FileNameAttributes...)
+
+	if mftRecord.StandardInformationAttributes == (StandardInformationAttribute{}) && !mftRecord.hasAttributeError(codeStandardInformation) {
+		mftRecord.StandardInformationAttributes = extensionRecord.StandardInformationAttributes
+	}
+	if mftRecord.SecurityDescriptor.Revision == 0 && !mftRecord.hasAttributeError(codeSecurityDescriptor) {
+		mftRecord.SecurityDescriptor = extensionRecord.SecurityDescriptor
+	}
+	if mftRecord.ReparsePoint.Tag == 0 && !mftRecord.hasAttributeError(codeReparsePoint) {
+		mftRecord.ReparsePoint = extensionRecord.ReparsePoint
+	}
+	if mftRecord.ObjectID == (ObjectIDAttribute{}) && !mftRecord.hasAttributeError(codeObjectID) {
+		mftRecord.ObjectID = extensionRecord.ObjectID
+	}
+	if mftRecord.ExtendedAttributeInformation == (ExtendedAttributeInformation{}) && !mftRecord.hasAttributeError(codeExtendedAttributeInformation) {
+		mftRecord.ExtendedAttributeInformation = extensionRecord.ExtendedAttributeInformation
+	}
+	mftRecord.ExtendedAttributes = append(mftRecord.ExtendedAttributes, extensionRecord.ExtendedAttributes...)
+	if mftRecord.VolumeName == "" && !mftRecord.hasAttributeError(codeVolumeName) {
+		mftRecord.VolumeName = extensionRecord.VolumeName
+	}
+	if mftRecord.VolumeInformation == (VolumeInformation{}) && !mftRecord.hasAttributeError(codeVolumeInformation) {
+		mftRecord.VolumeInformation = extensionRecord.VolumeInformation
+	}
+	mftRecord.LoggedUtilityStreams = append(mftRecord.LoggedUtilityStreams, extensionRecord.LoggedUtilityStreams...)
+	mftRecord.Attributes = append(mftRecord.Attributes, extensionRecord.Attributes...)
+	mftRecord.ParsedAttributes.merge(extensionRecord.ParsedAttributes)
+	mftRecord.AttributeErrors = append(mftRecord.AttributeErrors, extensionRecord.AttributeErrors...)
+	return
+}
+
+// Checks if an attribute of the given type failed to parse in the mft record receiver, or in any extension record merged into it so far.
+func (mftRecord MasterFileTableRecord) hasAttributeError(attributeType uint32) bool {
+	for _, attributeError := range mftRecord.AttributeErrors {
+		if attributeError.AttributeType == attributeType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,297 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// Builds a 1024 byte mft record containing the provided raw attributes.
+func buildTestMftRecord(recordNumber uint32, baseRecordNumber uint32, rawAttributes ...[]byte) RawMasterFileTableRecord {
+	rawMftRecord := make(RawMasterFileTableRecord, 1024)
+	copy(rawMftRecord, "FILE0")
+	rawMftRecord[0x14] = 0x38
+	rawMftRecord[0x16] = 0x01
+	binary.LittleEndian.PutUint32(rawMftRecord[0x20:], baseRecordNumber)
+	binary.LittleEndian.PutUint32(rawMftRecord[0x2C:], recordNumber)
+	offset := 0x38
+	for _, rawAttribute := range rawAttributes {
+		copy(rawMftRecord[offset:], rawAttribute)
+		offset += len(rawAttribute)
+	}
+	copy(rawMftRecord[offset:], []byte{0xff, 0xff, 0xff, 0xff})
+	return rawMftRecord
+}
+
+// Builds a resident WIN32 filename attribute.
+func buildTestFileNameAttribute(fileName string, parentRecordNumber uint32) []byte {
+	const offsetFileName = 0x5a
+	size := (offsetFileName + len(fileName)*2 + 7) &^ 7
+	rawAttribute := make([]byte, size)
+	rawAttribute[0x00] = 0x30
+	binary.LittleEndian.PutUint32(rawAttribute[0x04:], uint32(size))
+	binary.LittleEndian.PutUint32(rawAttribute[0x18:], parentRecordNumber)
+	rawAttribute[0x58] = byte(len(fileName))
+	rawAttribute[0x59] = 0x01
+	for i, character := range fileName {
+		rawAttribute[offsetFileName+i*2] = byte(character)
+	}
+	return rawAttribute
+}
+
+// Builds a non-resident attribute of the provided type using the provided raw data runs.
+func buildTestNonResidentAttribute(attributeType byte, actualSize uint64, rawDataRuns []byte) []byte {
+	const offsetDataRuns = 0x40
+	size := (offsetDataRuns + len(rawDataRuns) + 1 + 7) &^ 7
+	rawAttribute := make([]byte, size)
+	rawAttribute[0x00] = attributeType
+	binary.LittleEndian.PutUint32(rawAttribute[0x04:], uint32(size))
+	rawAttribute[0x08] = 0x01
+	rawAttribute[0x20] = offsetDataRuns
+	binary.LittleEndian.PutUint64(rawAttribute[0x30:], actualSize)
+	copy(rawAttribute[offsetDataRuns:], rawDataRuns)
+	return rawAttribute
+}
+
+// Builds raw attribute list entries out of the provided parsed entries.
+func buildTestAttributeListEntries(entries ...AttributeListAttribute) []byte {
+	var rawEntries []byte
+	for _, entry := range entries {
+		size := (0x1a + len(entry.Name)*2 + 7) &^ 7
+		rawEntry := make([]byte, size)
+		binary.LittleEndian.PutUint32(rawEntry[0x00:], entry.Type)
+		binary.LittleEndian.PutUint16(rawEntry[0x04:], uint16(size))
+		rawEntry[0x06] = byte(len(entry.Name))
+		rawEntry[0x07] = 0x1a
+		binary.LittleEndian.PutUint64(rawEntry[0x08:], entry.StartingVCN)
+		binary.LittleEndian.PutUint64(rawEntry[0x10:], entry.MFTReferenceRecordNumber|uint64(entry.MFTReferenceSequenceNumber)<<48)
+		binary.LittleEndian.PutUint16(rawEntry[0x18:], entry.AttributeID)
+		for i, character := range entry.Name {
+			rawEntry[0x1a+i*2] = byte(character)
+		}
+		rawEntries = append(rawEntries, rawEntry...)
+	}
+	return rawEntries
+}
+
+// Builds a resident attribute list attribute out of raw attribute list entries.
+func buildTestAttributeListAttribute(rawEntries []byte) []byte {
+	rawAttribute := make([]byte, 0x18+len(rawEntries))
+	rawAttribute[0x00] = 0x20
+	binary.LittleEndian.PutUint32(rawAttribute[0x04:], uint32(len(rawAttribute)))
+	binary.LittleEndian.PutUint32(rawAttribute[0x10:], uint32(len(rawEntries)))
+	rawAttribute[0x14] = 0x18
+	copy(rawAttribute[0x18:], rawEntries)
+	return rawAttribute
+}
+
+func TestRecordAssembler_Assemble(t *testing.T) {
+	residentListEntries := buildTestAttributeListEntries(
+		AttributeListAttribute{Type: 0x30, MFTReferenceRecordNumber: 2},
+		AttributeListAttribute{Type: 0x30, MFTReferenceRecordNumber: 3},
+		AttributeListAttribute{Type: 0x80, MFTReferenceRecordNumber: 4},
+		AttributeListAttribute{Type: 0x80, StartingVCN: 1, MFTReferenceRecordNumber: 3},
+	)
+	residentMft := bytes.Join([][]byte{
+		make([]byte, 2048),
+		buildTestMftRecord(2, 0, buildTestAttributeListAttribute(residentListEntries), buildTestFileNameAttribute("base.txt", 5)),
+		buildTestMftRecord(3, 2, buildTestFileNameAttribute("hardlink.txt", 5), buildTestNonResidentAttribute(0x80, 12288, []byte{0x11, 0x02, 0x10})),
+		buildTestMftRecord(4, 2, buildTestNonResidentAttribute(0x80, 12288, []byte{0x11, 0x01, 0x20}), testRawSecurityDescriptorAttribute),
+	}, nil)
+
+	nonResidentListEntries := buildTestAttributeListEntries(
+		AttributeListAttribute{Type: 0x30, MFTReferenceRecordNumber: 2},
+		AttributeListAttribute{Type: 0x30, MFTReferenceRecordNumber: 3},
+	)
+	nonResidentMft := bytes.Join([][]byte{
+		make([]byte, 2048),
+		buildTestMftRecord(2, 0, buildTestNonResidentAttribute(0x20, uint64(len(nonResidentListEntries)), []byte{0x11, 0x01, 0x01}), buildTestFileNameAttribute("base.txt", 5)),
+		buildTestMftRecord(3, 2, buildTestFileNameAttribute("hardlink.txt", 5)),
+	}, nil)
+	volume := make([]byte, 8192)
+	copy(volume[4096:], nonResidentListEntries)
+
+	wrongBaseMft := bytes.Join([][]byte{
+		make([]byte, 2048),
+		buildTestMftRecord(2, 0, buildTestAttributeListAttribute(buildTestAttributeListEntries(AttributeListAttribute{Type: 0x30, MFTReferenceRecordNumber: 3})), buildTestFileNameAttribute("base.txt", 5)),
+		buildTestMftRecord(3, 7, buildTestFileNameAttribute("hardlink.txt", 5)),
+	}, nil)
+
+	tests := []struct {
+		name          string
+		assembler     RecordAssembler
+		wantFileNames []string
+		wantDataRuns  DataRuns
+		wantOwner     string
+		wantErr       bool
+	}{
+		{
+			name:          "resident attribute list",
+			assembler:     RecordAssembler{Mft: bytes.NewReader(residentMft), BytesPerCluster: 4096},
+			wantFileNames: []string{"base.txt", "hardlink.txt"},
+			wantDataRuns: DataRuns{
+				0: DataRun{AbsoluteOffset: 131072, Length: 4096},
+				1: DataRun{AbsoluteOffset: 65536, Length: 8192},
+			},
+			wantOwner: "S-1-5-18",
+			wantErr:   false,
+		},
+		{
+			name:          "non-resident attribute list",
+			assembler:     RecordAssembler{Mft: bytes.NewReader(nonResidentMft), Volume: bytes.NewReader(volume), BytesPerCluster: 4096},
+			wantFileNames: []string{"base.txt", "hardlink.txt"},
+			wantErr:       false,
+		},
+		{
+			name:          "non-resident attribute list without a volume",
+			assembler:     RecordAssembler{Mft: bytes.NewReader(nonResidentMft), BytesPerCluster: 4096},
+			wantFileNames: []string{"base.txt"},
+			wantErr:       true,
+		},
+		{
+			name:          "extension record points to another base record",
+			assembler:     RecordAssembler{Mft: bytes.NewReader(wrongBaseMft), BytesPerCluster: 4096},
+			wantFileNames: []string{"base.txt"},
+			wantErr:       true,
+		},
+		{
+			name:      "no mft",
+			assembler: RecordAssembler{BytesPerCluster: 4096},
+			wantErr:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawMftRecord := make(RawMasterFileTableRecord, 1024)
+			if tt.assembler.Mft != nil {
+				_, _ = tt.assembler.Mft.ReadAt(rawMftRecord, 2048)
+			}
+			gotMftRecord, err := tt.assembler.Assemble(rawMftRecord)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Assemble() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			var gotFileNames []string
+			for _, fileNameAttribute := range gotMftRecord.FileNameAttributes {
+				gotFileNames = append(gotFileNames, fileNameAttribute.FileName)
+			}
+			if !reflect.DeepEqual(gotFileNames, tt.wantFileNames) {
+				t.Errorf("Assemble() got file names = %v, want %v", gotFileNames, tt.wantFileNames)
+			}
+			if tt.wantDataRuns != nil && !reflect.DeepEqual(gotMftRecord.DataAttribute.NonResidentDataAttribute.DataRuns, tt.wantDataRuns) {
+				t.Errorf("Assemble() got data runs = %v, want %v", gotMftRecord.DataAttribute.NonResidentDataAttribute.DataRuns, tt.wantDataRuns)
+			}
+			if gotMftRecord.SecurityDescriptor.Owner != tt.wantOwner {
+				t.Errorf("Assemble() got security descriptor owner = %s, want %s", gotMftRecord.SecurityDescriptor.Owner, tt.wantOwner)
+			}
+		})
+	}
+}
+
+func TestRecordAssembler_AssembleBrokenAttributes(t *testing.T) {
+	brokenSecurityDescriptor := make([]byte, len(testRawSecurityDescriptorAttribute))
+	copy(brokenSecurityDescriptor, testRawSecurityDescriptorAttribute)
+	brokenSecurityDescriptor[0x08] = 0x01
+	brokenFileName := buildTestFileNameAttribute("broken.txt", 5)
+	brokenFileName[0x08] = 0x01
+	rawEntries := buildTestAttributeListEntries(
+		AttributeListAttribute{Type: 0x30, MFTReferenceRecordNumber: 3},
+		AttributeListAttribute{Type: 0x50, MFTReferenceRecordNumber: 3},
+	)
+	rawMft := bytes.Join([][]byte{
+		make([]byte, 2048),
+		buildTestMftRecord(2, 0, buildTestAttributeListAttribute(rawEntries), buildTestFileNameAttribute("base.txt", 5), brokenSecurityDescriptor),
+		buildTestMftRecord(3, 2, brokenFileName, testRawSecurityDescriptorAttribute),
+	}, nil)
+	assembler := RecordAssembler{Mft: bytes.NewReader(rawMft), BytesPerCluster: 4096, Lenient: true}
+
+	mftRecord, err := assembler.Assemble(RawMasterFileTableRecord(rawMft[2048:3072]))
+	if err != nil {
+		t.Fatalf("Assemble() returned %v", err)
+	}
+	if mftRecord.SecurityDescriptor.Owner != "" {
+		t.Errorf("Assemble() replaced the broken security descriptor of the base record with the one owned by %s", mftRecord.SecurityDescriptor.Owner)
+	}
+	var gotErrors [][2]uint32
+	for _, attributeError := range mftRecord.AttributeErrors {
+		gotErrors = append(gotErrors, [2]uint32{attributeError.RecordNumber, attributeError.AttributeType})
+	}
+	if wantErrors := [][2]uint32{{2, 0x50}, {3, 0x30}}; !reflect.DeepEqual(gotErrors, wantErrors) {
+		t.Errorf("Assemble() got attribute errors in records and types %v, want %v", gotErrors, wantErrors)
+	}
+}
+
+func TestParseMftRecords_ExtensionRecords(t *testing.T) {
+	rawEntries := buildTestAttributeListEntries(
+		AttributeListAttribute{Type: 0x30, MFTReferenceRecordNumber: 3},
+		AttributeListAttribute{Type: 0x80, MFTReferenceRecordNumber: 2},
+	)
+	rawMft := bytes.Join([][]byte{
+		make([]byte, 2048),
+		buildTestMftRecord(2, 0, buildTestAttributeListAttribute(rawEntries), buildTestNonResidentAttribute(0x80, 4096, []byte{0x11, 0x01, 0x10})),
+		buildTestMftRecord(3, 2, buildTestFileNameAttribute("extended.txt", 5)),
+	}, nil)
+
+	nonResidentEntries := buildTestAttributeListEntries(
+		AttributeListAttribute{Type: 0x30, MFTReferenceRecordNumber: 3},
+	)
+	nonResidentMft := bytes.Join([][]byte{
+		make([]byte, 2048),
+		buildTestMftRecord(2, 0, buildTestNonResidentAttribute(0x20, uint64(len(nonResidentEntries)), []byte{0x11, 0x01, 0x01})),
+		buildTestMftRecord(3, 2, buildTestFileNameAttribute("extended.txt", 5)),
+	}, nil)
+	volume := make([]byte, 8192)
+	copy(volume[4096:], nonResidentEntries)
+
+	tests := []struct {
+		name             string
+		reader           *bytes.Reader
+		options          ParseOptions
+		wantNames        []string
+		wantRecordErrors int
+	}{
+		{
+			name:      "extension records are merged and skipped",
+			reader:    bytes.NewReader(rawMft),
+			wantNames: []string{"extended.txt"},
+		},
+		{
+			name:      "non-resident attribute list",
+			reader:    bytes.NewReader(nonResidentMft),
+			options:   ParseOptions{VolumeImage: bytes.NewReader(volume)},
+			wantNames: []string{"extended.txt"},
+		},
+		{
+			name:             "non-resident attribute list without a volume image",
+			reader:           bytes.NewReader(nonResidentMft),
+			wantNames:        []string{""},
+			wantRecordErrors: 1,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var diagnosticLog DiagnosticLog
+			tt.options.Diagnostics = &diagnosticLog
+			outputChannel := make(chan UsefulMftFields, 100)
+			ParseMftRecordsWithOptions(tt.reader, 4096, DirectoryTree{5: "C:\\"}, &outputChannel, tt.options)
+			var gotNames []string
+			for usefulMftFields := range outputChannel {
+				gotNames = append(gotNames, usefulMftFields.FileName)
+			}
+			if !reflect.DeepEqual(gotNames, tt.wantNames) {
+				t.Errorf("ParseMftRecordsWithOptions() got = %v, want %v", gotNames, tt.wantNames)
+			}
+			if len(diagnosticLog.RecordErrors) != tt.wantRecordErrors || diagnosticLog.Summary.PartialRecords != tt.wantRecordErrors {
+				t.Errorf("ParseMftRecordsWithOptions() reported record errors %v and %d partial records, want %d", diagnosticLog.RecordErrors, diagnosticLog.Summary.PartialRecords, tt.wantRecordErrors)
+			}
+			for _, recordError := range diagnosticLog.RecordErrors {
+				if recordError.AttributeType != 0x20 || recordError.RecordNumber != 2 || !recordError.Partial {
+					t.Errorf("got record error %v, want a partial $ATTRIBUTE_LIST error for record 2", recordError)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,432 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+
+	bin "github.com/AlecRandazzo/BinaryTransforms"
+)
+
+// RawReparsePointAttribute is a []byte alias for a raw reparse point attribute. Used with the Parse() method.
+type RawReparsePointAttribute []byte
+
+// RawReparsePoint is a []byte alias for a raw reparse data buffer. Used with the Parse() method.
+// See here for the layout: https://docs.microsoft.com/en-us/windows-hardware/drivers/ddi/ntifs/ns-ntifs-_reparse_data_buffer
+type RawReparsePoint []byte
+
+// ReparseTag is a uint32 alias for a reparse point tag.
+type ReparseTag uint32
+
+// ReparsePoint contains a parsed reparse point. Only the fields that apply to the reparse tag are filled in.
+type ReparsePoint struct {
+	Tag ReparseTag
+
+	// GUID is only present on third party reparse points.
+	GUID string
+
+	// Symbolic links and mount points (junctions).
+	SubstituteName string
+	PrintName      string
+	Relative       bool
+
+	// App execution aliases, e.g. the ones found in %LOCALAPPDATA%\Microsoft\WindowsApps.
+	AppExecLink *AppExecLink
+
+	// OneDrive and other cloud files placeholders.
+	CloudPlaceholder *CloudPlaceholder
+
+	// Windows overlay filter (compact os, wimboot) backed files.
+	Wof *WofInfo
+
+	// WSL symbolic links.
+	LxSymlinkTarget string
+}
+
+// AppExecLink contains the data of an app execution alias reparse point.
+type AppExecLink struct {
+	Version        uint32
+	PackageID      string
+	AppUserModelID string
+	TargetPath     string
+}
+
+// CloudPlaceholder contains the data of a cloud files placeholder reparse point. The provider data is undocumented so only the header is decoded.
+type CloudPlaceholder struct {
+	Flags        uint16
+	Compressed   bool
+	ElementCount uint16
+}
+
+// WofInfo contains the data of a Windows overlay filter reparse point.
+type WofInfo struct {
+	Version         uint32
+	Provider        uint32
+	ProviderVersion uint32
+	Algorithm       uint32
+}
+
+// Reparse tags that are decoded or named. See here for the full list: https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-fscc/c8e77b37-3909-4fe6-a4ea-2b9d423b1ee4
+const (
+	ReparseTagMountPoint      ReparseTag = 0xA0000003
+	ReparseTagHsm             ReparseTag = 0xC0000004
+	ReparseTagHsm2            ReparseTag = 0x80000006
+	ReparseTagSis             ReparseTag = 0x80000007
+	ReparseTagWim             ReparseTag = 0x80000008
+	ReparseTagCsv             ReparseTag = 0x80000009
+	ReparseTagDfs             ReparseTag = 0x8000000A
+	ReparseTagSymlink         ReparseTag = 0xA000000C
+	ReparseTagDfsr            ReparseTag = 0x80000012
+	ReparseTagDedup           ReparseTag = 0x80000013
+	ReparseTagNfs             ReparseTag = 0x80000014
+	ReparseTagFilePlaceholder ReparseTag = 0x80000015
+	ReparseTagWof             ReparseTag = 0x80000017
+	ReparseTagWci             ReparseTag = 0x80000018
+	ReparseTagGlobalReparse   ReparseTag = 0xA0000019
+	ReparseTagCloud           ReparseTag = 0x9000001A
+	ReparseTagAppExecLink     ReparseTag = 0x8000001B
+	ReparseTagProjFs          ReparseTag = 0x9000001C
+	ReparseTagLxSymlink       ReparseTag = 0xA000001D
+	ReparseTagStorageSync     ReparseTag = 0x8000001E
+	ReparseTagWciTombstone    ReparseTag = 0xA000001F
+	ReparseTagUnhandled       ReparseTag = 0x80000020
+	ReparseTagOneDrive        ReparseTag = 0x80000021
+	ReparseTagProjFsTombstone ReparseTag = 0xA0000022
+	ReparseTagAfUnix          ReparseTag = 0x80000023
+	ReparseTagLxFifo          ReparseTag = 0x80000024
+	ReparseTagLxChr           ReparseTag = 0x80000025
+	ReparseTagLxBlk           ReparseTag = 0x80000026
+	ReparseTagWciLink         ReparseTag = 0xA0000027
+
+	// Cloud files tags carry a sub type in bits 12 through 15, e.g. 0x9000101A.
+	reparseTagCloudMask = 0xFFFF0FFF
+
+	reparseTagMicrosoftBit = 0x80000000
+	symlinkFlagRelative    = 0x00000001
+	cloudFlagCompressed    = 0x8000
+	cloudMagic             = 0x70526546 // "FeRp"
+)
+
+var reparseTagNames = map[ReparseTag]string{
+	ReparseTagMountPoint:      "MOUNT_POINT",
+	ReparseTagHsm:             "HSM",
+	ReparseTagHsm2:            "HSM2",
+	ReparseTagSis:             "SIS",
+	ReparseTagWim:             "WIM",
+	ReparseTagCsv:             "CSV",
+	ReparseTagDfs:             "DFS",
+	ReparseTagSymlink:         "SYMLINK",
+	ReparseTagDfsr:            "DFSR",
+	ReparseTagDedup:           "DEDUP",
+	ReparseTagNfs:             "NFS",
+	ReparseTagFilePlaceholder: "FILE_PLACEHOLDER",
+	ReparseTagWof:             "WOF",
+	ReparseTagWci:             "WCI",
+	ReparseTagGlobalReparse:   "GLOBAL_REPARSE",
+	ReparseTagCloud:           "CLOUD",
+	ReparseTagAppExecLink:     "APPEXECLINK",
+	ReparseTagProjFs:          "PROJFS",
+	ReparseTagLxSymlink:       "LX_SYMLINK",
+	ReparseTagStorageSync:     "STORAGE_SYNC",
+	ReparseTagWciTombstone:    "WCI_TOMBSTONE",
+	ReparseTagUnhandled:       "UNHANDLED",
+	ReparseTagOneDrive:        "ONEDRIVE",
+	ReparseTagProjFsTombstone: "PROJFS_TOMBSTONE",
+	ReparseTagAfUnix:          "AF_UNIX",
+	ReparseTagLxFifo:          "LX_FIFO",
+	ReparseTagLxChr:           "LX_CHR",
+	ReparseTagLxBlk:           "LX_BLK",
+	ReparseTagWciLink:         "WCI_LINK",
+}
+
+// IsCloud reports whether the tag is one of the cloud files tags, regardless of its sub type.
+func (reparseTag ReparseTag) IsCloud() bool {
+	return reparseTag&reparseTagCloudMask == ReparseTagCloud
+}
+
+// IsMicrosoft reports whether the tag is owned by Microsoft. Third party reparse points carry a GUID.
+func (reparseTag ReparseTag) IsMicrosoft() bool {
+	return reparseTag&reparseTagMicrosoftBit != 0
+}
+
+// String returns the name of the reparse tag, or its hex value if the tag is unknown.
+func (reparseTag ReparseTag) String() string {
+	if name, ok := reparseTagNames[reparseTag]; ok {
+		return name
+	}
+	if reparseTag.IsCloud() {
+		return fmt.Sprintf("CLOUD_%X", (uint32(reparseTag)>>12)&0xF)
+	}
+	return fmt.Sprintf("0x%08X", uint32(reparseTag))
+}
+
+// String returns the name of the overlay provider and compression algorithm.
+func (wofInfo WofInfo) String() string {
+	const providerWim = 1
+	const providerFile = 2
+	switch wofInfo.Provider {
+	case providerWim:
+		return "WIM"
+	case providerFile:
+		algorithms := []string{"XPRESS4K", "LZX", "XPRESS8K", "XPRESS16K"}
+		if int(wofInfo.Algorithm) < len(algorithms) {
+			return "FILE:" + algorithms[wofInfo.Algorithm]
+		}
+		return fmt.Sprintf("FILE:%d", wofInfo.Algorithm)
+	}
+	return fmt.Sprintf("%d", wofInfo.Provider)
+}
+
+// Target returns where the reparse point redirects to. Symbolic links and mount points return the substitute name since that is what the file system follows, the print name is only for display and can be set to anything.
+func (reparsePoint ReparsePoint) Target() string {
+	switch {
+	case reparsePoint.SubstituteName != "":
+		return reparsePoint.SubstituteName
+	case reparsePoint.AppExecLink != nil:
+		return reparsePoint.AppExecLink.TargetPath
+	case reparsePoint.LxSymlinkTarget != "":
+		return reparsePoint.LxSymlinkTarget
+	}
+	return ""
+}
+
+// Parse parses the raw reparse point attribute receiver and returns a parsed reparse point. Only resident attributes can be parsed.
+func (rawReparsePointAttribute RawReparsePointAttribute) Parse() (reparsePoint ReparsePoint, err error) {
+	const offsetResidentFlag = 0x08
+	const offsetContentLength = 0x10
+	const lengthContentLength = 0x04
+	const offsetContentOffset = 0x14
+	const lengthContentOffset = 0x02
+
+	// Sanity checks
+	sizeOfRawAttribute := len(rawReparsePointAttribute)
+	if sizeOfRawAttribute == 0 {
+		err = fmt.Errorf("RawReparsePointAttribute.Parse(): %w", ErrNilBytes)
+		return
+	} else if rawReparsePointAttribute[0x00] != 0xC0 {
+		err = fmt.Errorf("RawReparsePointAttribute.Parse() received an attribute thats not a reparse point. Attribute magic number is %x", rawReparsePointAttribute[0x00])
+		return
+	} else if sizeOfRawAttribute < offsetContentOffset+lengthContentOffset {
+		err = fmt.Errorf("RawReparsePointAttribute.Parse() expected at least %d bytes, instead received %d: %w", offsetContentOffset+lengthContentOffset, sizeOfRawAttribute, ErrTruncated)
+		return
+	} else if RawResidencyFlag(rawReparsePointAttribute[offsetResidentFlag]).Parse() == false {
+		err = errors.New("RawReparsePointAttribute.Parse() received a non-resident reparse point")
+		return
+	}
+
+	contentLength := binary.LittleEndian.Uint32(rawReparsePointAttribute[offsetContentLength : offsetContentLength+lengthContentLength])
+	contentOffset := binary.LittleEndian.Uint16(rawReparsePointAttribute[offsetContentOffset : offsetContentOffset+lengthContentOffset])
+	end := int(contentOffset) + int(contentLength)
+	if int(contentOffset) >= sizeOfRawAttribute || end > sizeOfRawAttribute {
+		err = fmt.Errorf("RawReparsePointAttribute.Parse() content at offset %d with length %d is beyond the attribute", contentOffset, contentLength)
+		return
+	}
+	reparsePoint, err = RawReparsePoint(rawReparsePointAttribute[contentOffset:end]).Parse()
+	return
+}
+
+// Parse parses the raw reparse point receiver and returns a parsed reparse point. Unknown tags only have their tag filled in.
+func (rawReparsePoint RawReparsePoint) Parse() (reparsePoint ReparsePoint, err error) {
+	const offsetTag = 0x00
+	const lengthTag = 0x04
+	const offsetDataLength = 0x04
+	const lengthDataLength = 0x02
+	const lengthHeader = 0x08
+
+	// Sanity checks
+	sizeOfRawReparsePoint := len(rawReparsePoint)
+	if sizeOfRawReparsePoint < lengthHeader {
+		err = fmt.Errorf("RawReparsePoint.Parse() expected at least %d bytes, instead received %d: %w", lengthHeader, sizeOfRawReparsePoint, ErrTruncated)
+		return
+	}
+
+	reparsePoint.Tag = ReparseTag(binary.LittleEndian.Uint32(rawReparsePoint[offsetTag : offsetTag+lengthTag]))
+	dataLength := int(binary.LittleEndian.Uint16(rawReparsePoint[offsetDataLength : offsetDataLength+lengthDataLength]))
+	dataOffset := lengthHeader
+	if reparsePoint.Tag.IsMicrosoft() == false {
+		if sizeOfRawReparsePoint < lengthHeader+lengthGUID {
+			err = fmt.Errorf("RawReparsePoint.Parse() third party reparse point is too short to hold its GUID: %w", ErrTruncated)
+			return
+		}
+		reparsePoint.GUID = formatGUID(rawReparsePoint[lengthHeader : lengthHeader+lengthGUID])
+		dataOffset += lengthGUID
+	}
+	if dataOffset+dataLength > sizeOfRawReparsePoint {
+		err = fmt.Errorf("RawReparsePoint.Parse() reparse data length %d is beyond the %d bytes received", dataLength, sizeOfRawReparsePoint)
+		return
+	}
+	rawData := rawReparsePoint[dataOffset : dataOffset+dataLength]
+
+	switch {
+	case reparsePoint.Tag == ReparseTagSymlink:
+		reparsePoint.SubstituteName, reparsePoint.PrintName, reparsePoint.Relative, err = parseSymlinkReparseData(rawData, true)
+	case reparsePoint.Tag == ReparseTagMountPoint:
+		reparsePoint.SubstituteName, reparsePoint.PrintName, _, err = parseSymlinkReparseData(rawData, false)
+	case reparsePoint.Tag == ReparseTagAppExecLink:
+		reparsePoint.AppExecLink, err = parseAppExecLinkReparseData(rawData)
+	case reparsePoint.Tag.IsCloud():
+		reparsePoint.CloudPlaceholder, err = parseCloudReparseData(rawData)
+	case reparsePoint.Tag == ReparseTagWof:
+		reparsePoint.Wof, err = parseWofReparseData(rawData)
+	case reparsePoint.Tag == ReparseTagLxSymlink:
+		// A version number is followed by the utf8 target.
+		const offsetTarget = 0x04
+		if len(rawData) > offsetTarget {
+			reparsePoint.LxSymlinkTarget = string(rawData[offsetTarget:])
+		}
+	}
+	if err != nil {
+		err = fmt.Errorf("failed to parse %s reparse data: %w", reparsePoint.Tag, err)
+	}
+	return
+}
+
+// Parses the data of a symbolic link or mount point reparse point. Symbolic links have a flags field before the path buffer that mount points lack.
+func parseSymlinkReparseData(rawData []byte, hasFlags bool) (substituteName, printName string, relative bool, err error) {
+	const offsetSubstituteNameOffset = 0x00
+	const offsetSubstituteNameLength = 0x02
+	const offsetPrintNameOffset = 0x04
+	const offsetPrintNameLength = 0x06
+	const offsetFlags = 0x08
+	const lengthField = 0x02
+
+	pathBufferOffset := 0x08
+	if hasFlags {
+		pathBufferOffset = 0x0C
+	}
+	if len(rawData) < pathBufferOffset {
+		err = fmt.Errorf("expected at least %d bytes, instead received %d: %w", pathBufferOffset, len(rawData), ErrTruncated)
+		return
+	}
+	if hasFlags {
+		relative = binary.LittleEndian.Uint32(rawData[offsetFlags:offsetFlags+0x04])&symlinkFlagRelative != 0
+	}
+
+	pathBuffer := rawData[pathBufferOffset:]
+	readName := func(offsetNameOffset, offsetNameLength int) (name string, err error) {
+		nameOffset := int(binary.LittleEndian.Uint16(rawData[offsetNameOffset : offsetNameOffset+lengthField]))
+		nameLength := int(binary.LittleEndian.Uint16(rawData[offsetNameLength : offsetNameLength+lengthField]))
+		if nameOffset+nameLength > len(pathBuffer) {
+			err = fmt.Errorf("name at offset %d with length %d is beyond the path buffer", nameOffset, nameLength)
+			return
+		}
+		if nameLength == 0 {
+			return
+		}
+		name, err = bin.UnicodeBytesToASCII(pathBuffer[nameOffset : nameOffset+nameLength])
+		return
+	}
+	substituteName, err = readName(offsetSubstituteNameOffset, offsetSubstituteNameLength)
+	if err != nil {
+		err = fmt.Errorf("failed to get substitute name: %w", err)
+		return
+	}
+	printName, err = readName(offsetPrintNameOffset, offsetPrintNameLength)
+	if err != nil {
+		err = fmt.Errorf("failed to get print name: %w", err)
+		return
+	}
+	return
+}
+
+// Parses the data of an app execution alias reparse point. A version number is followed by null terminated utf16 strings.
+func parseAppExecLinkReparseData(rawData []byte) (appExecLink *AppExecLink, err error) {
+	const offsetVersion = 0x00
+	const lengthVersion = 0x04
+
+	if len(rawData) < lengthVersion {
+		err = fmt.Errorf("expected at least %d bytes, instead received %d: %w", lengthVersion, len(rawData), ErrTruncated)
+		return
+	}
+	appExecLink = &AppExecLink{
+		Version: binary.LittleEndian.Uint32(rawData[offsetVersion : offsetVersion+lengthVersion]),
+	}
+	strs := splitNullTerminatedUnicode(rawData[lengthVersion:])
+	fields := []*string{&appExecLink.PackageID, &appExecLink.AppUserModelID, &appExecLink.TargetPath}
+	for index := range fields {
+		if index >= len(strs) {
+			break
+		}
+		*fields[index] = strs[index]
+	}
+	return
+}
+
+// Splits a buffer of null terminated utf16 strings.
+func splitNullTerminatedUnicode(rawStrings []byte) (strs []string) {
+	start := 0
+	for index := 0; index+1 < len(rawStrings); index += 2 {
+		if rawStrings[index] != 0x00 || rawStrings[index+1] != 0x00 {
+			continue
+		}
+		str := ""
+		if index > start {
+			str, _ = bin.UnicodeBytesToASCII(rawStrings[start:index])
+		}
+		strs = append(strs, str)
+		start = index + 2
+	}
+	return
+}
+
+// Parses the header of a cloud files placeholder reparse point. The provider data that follows is compressed when the compressed flag is set.
+func parseCloudReparseData(rawData []byte) (cloudPlaceholder *CloudPlaceholder, err error) {
+	const offsetFlags = 0x00
+	const lengthFlags = 0x02
+	const offsetMagic = 0x04
+	const lengthMagic = 0x04
+	const offsetElementCount = 0x14
+	const lengthElementCount = 0x02
+
+	if len(rawData) < offsetFlags+lengthFlags {
+		err = fmt.Errorf("expected at least %d bytes, instead received %d: %w", offsetFlags+lengthFlags, len(rawData), ErrTruncated)
+		return
+	}
+	cloudPlaceholder = &CloudPlaceholder{
+		Flags: binary.LittleEndian.Uint16(rawData[offsetFlags : offsetFlags+lengthFlags]),
+	}
+	cloudPlaceholder.Compressed = cloudPlaceholder.Flags&cloudFlagCompressed != 0
+	if cloudPlaceholder.Compressed || len(rawData) < offsetElementCount+lengthElementCount {
+		return
+	}
+	if binary.LittleEndian.Uint32(rawData[offsetMagic:offsetMagic+lengthMagic]) == cloudMagic {
+		cloudPlaceholder.ElementCount = binary.LittleEndian.Uint16(rawData[offsetElementCount : offsetElementCount+lengthElementCount])
+	}
+	return
+}
+
+// Parses the data of a Windows overlay filter reparse point. The generic header is followed by the provider specific header.
+func parseWofReparseData(rawData []byte) (wofInfo *WofInfo, err error) {
+	const offsetVersion = 0x00
+	const offsetProvider = 0x04
+	const offsetProviderVersion = 0x08
+	const offsetAlgorithm = 0x0C
+	const lengthField = 0x04
+
+	if len(rawData) < offsetProviderVersion {
+		err = fmt.Errorf("expected at least %d bytes, instead received %d: %w", offsetProviderVersion, len(rawData), ErrTruncated)
+		return
+	}
+	wofInfo = &WofInfo{
+		Version:  binary.LittleEndian.Uint32(rawData[offsetVersion : offsetVersion+lengthField]),
+		Provider: binary.LittleEndian.Uint32(rawData[offsetProvider : offsetProvider+lengthField]),
+	}
+	if len(rawData) >= offsetAlgorithm+lengthField {
+		wofInfo.ProviderVersion = binary.LittleEndian.Uint32(rawData[offsetProviderVersion : offsetProviderVersion+lengthField])
+		wofInfo.Algorithm = binary.LittleEndian.Uint32(rawData[offsetAlgorithm : offsetAlgorithm+lengthField])
+	}
+	return
+}
+
+// Describes the reparse point for the reparse tag output column. Overlay and cloud reparse points have their provider details appended since they have no target.
+func (reparsePoint ReparsePoint) describeTag() string {
+	switch {
+	case reparsePoint.Wof != nil:
+		return reparsePoint.Tag.String() + ":" + reparsePoint.Wof.String()
+	case reparsePoint.CloudPlaceholder != nil && reparsePoint.CloudPlaceholder.Compressed:
+		return reparsePoint.Tag.String() + ":COMPRESSED"
+	}
+	return reparsePoint.Tag.String()
+}
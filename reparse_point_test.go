@@ -0,0 +1,265 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"reflect"
+	"testing"
+	"unicode/utf16"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func buildTestUnicode(str string) []byte {
+	encoded := utf16.Encode([]rune(str))
+	rawStr := make([]byte, len(encoded)*2)
+	for index, char := range encoded {
+		binary.LittleEndian.PutUint16(rawStr[index*2:], char)
+	}
+	return rawStr
+}
+
+func buildTestReparsePoint(tag ReparseTag, rawData []byte) []byte {
+	rawReparsePoint := make([]byte, 0x08, 0x08+len(rawData))
+	binary.LittleEndian.PutUint32(rawReparsePoint[0x00:], uint32(tag))
+	binary.LittleEndian.PutUint16(rawReparsePoint[0x04:], uint16(len(rawData)))
+	return append(rawReparsePoint, rawData...)
+}
+
+func buildTestSymlinkReparseData(substituteName, printName string, flags uint32, hasFlags bool) []byte {
+	rawSubstituteName := buildTestUnicode(substituteName)
+	rawPrintName := buildTestUnicode(printName)
+	header := make([]byte, 0x08)
+	binary.LittleEndian.PutUint16(header[0x00:], 0)
+	binary.LittleEndian.PutUint16(header[0x02:], uint16(len(rawSubstituteName)))
+	binary.LittleEndian.PutUint16(header[0x04:], uint16(len(rawSubstituteName)))
+	binary.LittleEndian.PutUint16(header[0x06:], uint16(len(rawPrintName)))
+	if hasFlags {
+		rawFlags := make([]byte, 0x04)
+		binary.LittleEndian.PutUint32(rawFlags, flags)
+		header = append(header, rawFlags...)
+	}
+	return append(append(header, rawSubstituteName...), rawPrintName...)
+}
+
+func buildTestReparsePointAttribute(rawReparsePoint []byte) []byte {
+	const contentOffset = 0x18
+	rawAttribute := make([]byte, contentOffset+len(rawReparsePoint))
+	rawAttribute[0x00] = 0xC0
+	binary.LittleEndian.PutUint32(rawAttribute[0x04:], uint32(len(rawAttribute)))
+	binary.LittleEndian.PutUint32(rawAttribute[0x10:], uint32(len(rawReparsePoint)))
+	binary.LittleEndian.PutUint16(rawAttribute[0x14:], contentOffset)
+	copy(rawAttribute[contentOffset:], rawReparsePoint)
+	return rawAttribute
+}
+
+func TestRawReparsePoint_Parse(t *testing.T) {
+	appExecLinkData := []byte{0x03, 0x00, 0x00, 0x00}
+	for _, str := range []string{"Microsoft.WindowsTerminal_8wekyb3d8bbwe", "Microsoft.WindowsTerminal_8wekyb3d8bbwe!App", "C:\\Program Files\\WindowsApps\\wt.exe", "0"} {
+		appExecLinkData = append(appExecLinkData, buildTestUnicode(str)...)
+		appExecLinkData = append(appExecLinkData, 0x00, 0x00)
+	}
+	wofData := []byte{0x01, 0, 0, 0, 0x02, 0, 0, 0, 0x01, 0, 0, 0, 0x01, 0, 0, 0}
+	cloudData := []byte{0x01, 0x00, 0x00, 0x00, 0x46, 0x65, 0x52, 0x70, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0x05, 0x00}
+	thirdParty := buildTestReparsePoint(ReparseTag(0x00000042), nil)
+	thirdParty = append(thirdParty, 0x33, 0x22, 0x11, 0x00, 0x55, 0x44, 0x77, 0x66, 0x88, 0x99, 0xaa, 0xbb, 0xcc, 0xdd, 0xee, 0xff)
+
+	tests := []struct {
+		name            string
+		rawReparsePoint RawReparsePoint
+		want            ReparsePoint
+		wantTarget      string
+		wantErr         bool
+	}{
+		{
+			name:            "absolute symlink",
+			rawReparsePoint: buildTestReparsePoint(ReparseTagSymlink, buildTestSymlinkReparseData("\\??\\C:\\Windows\\System32", "C:\\Users\\Public\\Docs", 0, true)),
+			want: ReparsePoint{
+				Tag:            ReparseTagSymlink,
+				SubstituteName: "\\??\\C:\\Windows\\System32",
+				PrintName:      "C:\\Users\\Public\\Docs",
+			},
+			wantTarget: "\\??\\C:\\Windows\\System32",
+		},
+		{
+			name:            "relative symlink",
+			rawReparsePoint: buildTestReparsePoint(ReparseTagSymlink, buildTestSymlinkReparseData("..\\target", "..\\target", symlinkFlagRelative, true)),
+			want: ReparsePoint{
+				Tag:            ReparseTagSymlink,
+				SubstituteName: "..\\target",
+				PrintName:      "..\\target",
+				Relative:       true,
+			},
+			wantTarget: "..\\target",
+		},
+		{
+			name:            "junction",
+			rawReparsePoint: buildTestReparsePoint(ReparseTagMountPoint, buildTestSymlinkReparseData("\\??\\C:\\Users\\Default", "", 0, false)),
+			want: ReparsePoint{
+				Tag:            ReparseTagMountPoint,
+				SubstituteName: "\\??\\C:\\Users\\Default",
+			},
+			wantTarget: "\\??\\C:\\Users\\Default",
+		},
+		{
+			name:            "app execution alias",
+			rawReparsePoint: buildTestReparsePoint(ReparseTagAppExecLink, appExecLinkData),
+			want: ReparsePoint{
+				Tag: ReparseTagAppExecLink,
+				AppExecLink: &AppExecLink{
+					Version:        3,
+					PackageID:      "Microsoft.WindowsTerminal_8wekyb3d8bbwe",
+					AppUserModelID: "Microsoft.WindowsTerminal_8wekyb3d8bbwe!App",
+					TargetPath:     "C:\\Program Files\\WindowsApps\\wt.exe",
+				},
+			},
+			wantTarget: "C:\\Program Files\\WindowsApps\\wt.exe",
+		},
+		{
+			name:            "cloud placeholder with sub type",
+			rawReparsePoint: buildTestReparsePoint(ReparseTag(0x9000301A), cloudData),
+			want: ReparsePoint{
+				Tag:              ReparseTag(0x9000301A),
+				CloudPlaceholder: &CloudPlaceholder{Flags: 1, ElementCount: 5},
+			},
+		},
+		{
+			name:            "compressed cloud placeholder",
+			rawReparsePoint: buildTestReparsePoint(ReparseTagCloud, []byte{0x01, 0x80, 0x10, 0x00}),
+			want: ReparsePoint{
+				Tag:              ReparseTagCloud,
+				CloudPlaceholder: &CloudPlaceholder{Flags: 0x8001, Compressed: true},
+			},
+		},
+		{
+			name:            "wof",
+			rawReparsePoint: buildTestReparsePoint(ReparseTagWof, wofData),
+			want: ReparsePoint{
+				Tag: ReparseTagWof,
+				Wof: &WofInfo{Version: 1, Provider: 2, ProviderVersion: 1, Algorithm: 1},
+			},
+		},
+		{
+			name:            "dedup",
+			rawReparsePoint: buildTestReparsePoint(ReparseTagDedup, []byte{0x01, 0x02}),
+			want:            ReparsePoint{Tag: ReparseTagDedup},
+		},
+		{
+			name:            "wsl symlink",
+			rawReparsePoint: buildTestReparsePoint(ReparseTagLxSymlink, append([]byte{0x02, 0, 0, 0}, "/usr/bin/python3"...)),
+			want:            ReparsePoint{Tag: ReparseTagLxSymlink, LxSymlinkTarget: "/usr/bin/python3"},
+			wantTarget:      "/usr/bin/python3",
+		},
+		{
+			name:            "third party",
+			rawReparsePoint: thirdParty,
+			want:            ReparsePoint{Tag: ReparseTag(0x42), GUID: "00112233-4455-6677-8899-aabbccddeeff"},
+		},
+		{
+			name:            "too short",
+			rawReparsePoint: []byte{0x0C, 0x00, 0x00, 0xA0},
+			wantErr:         true,
+		},
+		{
+			name:            "data length beyond buffer",
+			rawReparsePoint: []byte{0x0C, 0x00, 0x00, 0xA0, 0xFF, 0x00, 0x00, 0x00},
+			want:            ReparsePoint{Tag: ReparseTagSymlink},
+			wantErr:         true,
+		},
+		{
+			name:            "symlink name beyond path buffer",
+			rawReparsePoint: buildTestReparsePoint(ReparseTagSymlink, []byte{0x00, 0x00, 0x40, 0x00, 0, 0, 0, 0, 0, 0, 0, 0}),
+			want:            ReparsePoint{Tag: ReparseTagSymlink},
+			wantErr:         true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rawReparsePoint.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+			if gotTarget := got.Target(); gotTarget != tt.wantTarget {
+				t.Errorf("Target() got = %v, want %v", gotTarget, tt.wantTarget)
+			}
+		})
+	}
+}
+
+func TestRawReparsePointAttribute_Parse(t *testing.T) {
+	symlink := buildTestReparsePoint(ReparseTagSymlink, buildTestSymlinkReparseData("\\??\\D:\\", "D:\\", 0, true))
+	nonResident := buildTestReparsePointAttribute(symlink)
+	nonResident[0x08] = 0x01
+	truncated := buildTestReparsePointAttribute(symlink)
+	binary.LittleEndian.PutUint32(truncated[0x10:], 0x1000)
+
+	tests := []struct {
+		name         string
+		rawAttribute RawReparsePointAttribute
+		want         ReparsePoint
+		wantErr      bool
+	}{
+		{
+			name:         "resident symlink",
+			rawAttribute: buildTestReparsePointAttribute(symlink),
+			want:         ReparsePoint{Tag: ReparseTagSymlink, SubstituteName: "\\??\\D:\\", PrintName: "D:\\"},
+		},
+		{
+			name:    "nil bytes",
+			wantErr: true,
+		},
+		{
+			name:         "wrong attribute type",
+			rawAttribute: RawReparsePointAttribute{0x80, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0},
+			wantErr:      true,
+		},
+		{
+			name:         "non-resident",
+			rawAttribute: nonResident,
+			wantErr:      true,
+		},
+		{
+			name:         "content beyond attribute",
+			rawAttribute: truncated,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rawAttribute.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestReparsePoint_describeTag(t *testing.T) {
+	tests := []struct {
+		name         string
+		reparsePoint ReparsePoint
+		want         string
+	}{
+		{name: "symlink", reparsePoint: ReparsePoint{Tag: ReparseTagSymlink}, want: "SYMLINK"},
+		{name: "wof file provider", reparsePoint: ReparsePoint{Tag: ReparseTagWof, Wof: &WofInfo{Provider: 2, Algorithm: 3}}, want: "WOF:FILE:XPRESS16K"},
+		{name: "wof wim provider", reparsePoint: ReparsePoint{Tag: ReparseTagWof, Wof: &WofInfo{Provider: 1}}, want: "WOF:WIM"},
+		{name: "compressed cloud", reparsePoint: ReparsePoint{Tag: ReparseTag(0x9000101A), CloudPlaceholder: &CloudPlaceholder{Compressed: true}}, want: "CLOUD_1:COMPRESSED"},
+		{name: "unknown", reparsePoint: ReparsePoint{Tag: ReparseTag(0x80001234)}, want: "0x80001234"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.reparsePoint.describeTag(); got != tt.want {
+				t.Errorf("describeTag() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
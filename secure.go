@@ -0,0 +1,128 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// RawSecureDescriptorStream is a []byte alias for the raw contents of the $Secure:$SDS stream. Used with the Parse() and Lookup() methods.
+// See here for the layout: https://flatcap.org/linux-ntfs/ntfs/files/secure.html
+type RawSecureDescriptorStream []byte
+
+// RawSecurityIDIndex is a []byte alias for the raw contents of the $Secure:$SII index allocation. Used with the Parse() method.
+type RawSecurityIDIndex []byte
+
+// SecurityDescriptors maps security ids from $STANDARD_INFORMATION to their security descriptors.
+type SecurityDescriptors map[uint32]SecurityDescriptor
+
+// SecurityIndex maps security ids to the location of their security descriptor in the $SDS stream.
+type SecurityIndex map[uint32]SecurityIndexEntry
+
+// SecurityIndexEntry contains the header of a security descriptor entry in the $SDS stream. These headers are also the data of the $SII index entries.
+type SecurityIndexEntry struct {
+	Hash       uint32
+	SecurityID uint32
+	Offset     uint64
+	Length     uint32
+}
+
+// The $SDS stream is written in 256KB blocks, each followed by a mirror copy of itself.
+const sdsBlockSize = 0x40000
+
+const lengthSecurityIndexEntry = 0x14
+
+// Parse walks the raw $SDS stream receiver and returns every security descriptor it contains keyed by security id. Mirror blocks are skipped.
+func (rawSecureDescriptorStream RawSecureDescriptorStream) Parse() (securityDescriptors SecurityDescriptors, err error) {
+	sizeOfStream := len(rawSecureDescriptorStream)
+	if sizeOfStream == 0 {
+		err = fmt.Errorf("RawSecureDescriptorStream.Parse(): %w", ErrNilBytes)
+		return
+	}
+
+	securityDescriptors = make(SecurityDescriptors)
+	offset := 0
+	for offset+lengthSecurityIndexEntry <= sizeOfStream {
+		// Skip over the mirror copy of the previous block
+		if (offset/sdsBlockSize)%2 == 1 {
+			offset = (offset/sdsBlockSize + 1) * sdsBlockSize
+			continue
+		}
+
+		entry := parseSecurityIndexEntry(rawSecureDescriptorStream[offset : offset+lengthSecurityIndexEntry])
+		if entry.Length < lengthSecurityIndexEntry || entry.Offset != uint64(offset) || offset+int(entry.Length) > sizeOfStream {
+			// The rest of this block is padding
+			offset = (offset/sdsBlockSize + 1) * sdsBlockSize
+			continue
+		}
+
+		securityDescriptor, parseErr := RawSecurityDescriptor(rawSecureDescriptorStream[offset+lengthSecurityIndexEntry : offset+int(entry.Length)]).Parse()
+		if parseErr == nil {
+			securityDescriptors[entry.SecurityID] = securityDescriptor
+		}
+
+		// Entries are aligned to 16 bytes
+		offset += (int(entry.Length) + 0x0F) &^ 0x0F
+	}
+	return
+}
+
+// Lookup returns the security descriptor the $SII index entry points to in the raw $SDS stream receiver.
+func (rawSecureDescriptorStream RawSecureDescriptorStream) Lookup(securityIndexEntry SecurityIndexEntry) (securityDescriptor SecurityDescriptor, err error) {
+	sizeOfStream := uint64(len(rawSecureDescriptorStream))
+	if securityIndexEntry.Length < lengthSecurityIndexEntry || securityIndexEntry.Offset+uint64(securityIndexEntry.Length) > sizeOfStream {
+		err = fmt.Errorf("security id %d at offset %d with length %d is outside of the %d byte $SDS stream", securityIndexEntry.SecurityID, securityIndexEntry.Offset, securityIndexEntry.Length, sizeOfStream)
+		return
+	}
+
+	rawEntry := rawSecureDescriptorStream[securityIndexEntry.Offset : securityIndexEntry.Offset+uint64(securityIndexEntry.Length)]
+	header := parseSecurityIndexEntry(rawEntry)
+	if header.SecurityID != securityIndexEntry.SecurityID {
+		err = fmt.Errorf("$SDS entry at offset %d belongs to security id %d instead of %d", securityIndexEntry.Offset, header.SecurityID, securityIndexEntry.SecurityID)
+		return
+	}
+	securityDescriptor, err = RawSecurityDescriptor(rawEntry[lengthSecurityIndexEntry:]).Parse()
+	return
+}
+
+// Parse walks the INDX records of the raw $SII index allocation receiver and returns the location of every security descriptor keyed by security id.
+func (rawSecurityIDIndex RawSecurityIDIndex) Parse(indexRecordSize int, bytesPerSector int) (securityIndex SecurityIndex, err error) {
+	indexEntries, err := ParseIndexAllocation(rawSecurityIDIndex, indexRecordSize, bytesPerSector)
+	if err != nil {
+		err = fmt.Errorf("failed to parse $SII index allocation: %w", err)
+		return
+	}
+
+	securityIndex = make(SecurityIndex)
+	for _, indexEntry := range indexEntries {
+		if len(indexEntry.Data) < lengthSecurityIndexEntry {
+			continue
+		}
+		securityIndexEntry := parseSecurityIndexEntry(indexEntry.Data)
+		securityIndex[securityIndexEntry.SecurityID] = securityIndexEntry
+	}
+	return
+}
+
+// Parses the 20 byte header shared by $SDS entries and $SII index entry data.
+func parseSecurityIndexEntry(rawEntry []byte) (securityIndexEntry SecurityIndexEntry) {
+	securityIndexEntry.Hash = binary.LittleEndian.Uint32(rawEntry[0x00:0x04])
+	securityIndexEntry.SecurityID = binary.LittleEndian.Uint32(rawEntry[0x04:0x08])
+	securityIndexEntry.Offset = binary.LittleEndian.Uint64(rawEntry[0x08:0x10])
+	securityIndexEntry.Length = binary.LittleEndian.Uint32(rawEntry[0x10:0x14])
+	return
+}
+
+// BuildSecurityDescriptors reads an extracted $Secure:$SDS stream and returns every security descriptor it contains keyed by security id.
+func BuildSecurityDescriptors(reader io.Reader) (securityDescriptors SecurityDescriptors, err error) {
+	rawStream, err := ioutil.ReadAll(reader)
+	if err != nil {
+		err = fmt.Errorf("failed to read $SDS stream: %w", err)
+		return
+	}
+	securityDescriptors, err = RawSecureDescriptorStream(rawStream).Parse()
+	return
+}
@@ -0,0 +1,156 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+)
+
+// Builds the 20 byte header shared by $SDS entries and $SII index entry data.
+func buildTestSecurityIndexEntry(securityID uint32, offset uint64, length uint32) []byte {
+	rawEntry := make([]byte, lengthSecurityIndexEntry)
+	binary.LittleEndian.PutUint32(rawEntry[0x00:], 0xdeadbeef)
+	binary.LittleEndian.PutUint32(rawEntry[0x04:], securityID)
+	binary.LittleEndian.PutUint64(rawEntry[0x08:], offset)
+	binary.LittleEndian.PutUint32(rawEntry[0x10:], length)
+	return rawEntry
+}
+
+// Builds an $SDS stream with the test security descriptor stored under security ids 256 and 257 in the first block, a mirror block, and 258 in the third block.
+func buildTestSecureDescriptorStream() RawSecureDescriptorStream {
+	rawSecurityDescriptor := testRawSecurityDescriptorAttribute[0x18 : 0x18+0x64]
+	length := uint32(lengthSecurityIndexEntry + len(rawSecurityDescriptor))
+	stream := make([]byte, 3*sdsBlockSize)
+	for i, offset := range []uint64{0, 0x80, 2 * sdsBlockSize} {
+		copy(stream[offset:], buildTestSecurityIndexEntry(uint32(256+i), offset, length))
+		copy(stream[offset+lengthSecurityIndexEntry:], rawSecurityDescriptor)
+	}
+	copy(stream[sdsBlockSize:], stream[:sdsBlockSize])
+	return stream
+}
+
+func TestRawSecureDescriptorStream_Parse(t *testing.T) {
+	tests := []struct {
+		name                      string
+		rawSecureDescriptorStream RawSecureDescriptorStream
+		wantSecurityDescriptors   SecurityDescriptors
+		wantErr                   bool
+	}{
+		{
+			name:                      "three blocks",
+			rawSecureDescriptorStream: buildTestSecureDescriptorStream(),
+			wantSecurityDescriptors: SecurityDescriptors{
+				256: testSecurityDescriptor,
+				257: testSecurityDescriptor,
+				258: testSecurityDescriptor,
+			},
+			wantErr: false,
+		},
+		{
+			name:                      "nil bytes",
+			rawSecureDescriptorStream: nil,
+			wantErr:                   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSecurityDescriptors, err := tt.rawSecureDescriptorStream.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotSecurityDescriptors, tt.wantSecurityDescriptors) {
+				t.Errorf("Parse() gotSecurityDescriptors = %v, want %v", gotSecurityDescriptors, tt.wantSecurityDescriptors)
+			}
+		})
+	}
+}
+
+func TestRawSecureDescriptorStream_Lookup(t *testing.T) {
+	rawSecureDescriptorStream := buildTestSecureDescriptorStream()
+	tests := []struct {
+		name                   string
+		securityIndexEntry     SecurityIndexEntry
+		wantSecurityDescriptor SecurityDescriptor
+		wantErr                bool
+	}{
+		{
+			name:                   "valid entry",
+			securityIndexEntry:     SecurityIndexEntry{SecurityID: 257, Offset: 0x80, Length: 0x78},
+			wantSecurityDescriptor: testSecurityDescriptor,
+			wantErr:                false,
+		},
+		{
+			name:               "entry belongs to another security id",
+			securityIndexEntry: SecurityIndexEntry{SecurityID: 300, Offset: 0x80, Length: 0x78},
+			wantErr:            true,
+		},
+		{
+			name:               "entry beyond the stream",
+			securityIndexEntry: SecurityIndexEntry{SecurityID: 257, Offset: 3 * sdsBlockSize, Length: 0x78},
+			wantErr:            true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSecurityDescriptor, err := rawSecureDescriptorStream.Lookup(tt.securityIndexEntry)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Lookup() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotSecurityDescriptor, tt.wantSecurityDescriptor) {
+				t.Errorf("Lookup() gotSecurityDescriptor = %v, want %v", gotSecurityDescriptor, tt.wantSecurityDescriptor)
+			}
+		})
+	}
+}
+
+func TestRawSecurityIDIndex_Parse(t *testing.T) {
+	key := []byte{0x01, 0x01, 0x00, 0x00}
+	tests := []struct {
+		name               string
+		rawSecurityIDIndex RawSecurityIDIndex
+		wantSecurityIndex  SecurityIndex
+		wantErr            bool
+	}{
+		{
+			name:               "one entry",
+			rawSecurityIDIndex: buildTestIndexRecord(0, buildTestViewIndexEntry(key, buildTestSecurityIndexEntry(257, 0x80, 0x78))),
+			wantSecurityIndex: SecurityIndex{
+				257: SecurityIndexEntry{Hash: 0xdeadbeef, SecurityID: 257, Offset: 0x80, Length: 0x78},
+			},
+			wantErr: false,
+		},
+		{
+			name:               "nil bytes",
+			rawSecurityIDIndex: nil,
+			wantErr:            true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSecurityIndex, err := tt.rawSecurityIDIndex.Parse(4096, 512)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotSecurityIndex, tt.wantSecurityIndex) {
+				t.Errorf("Parse() gotSecurityIndex = %v, want %v", gotSecurityIndex, tt.wantSecurityIndex)
+			}
+		})
+	}
+}
+
+func TestBuildSecurityDescriptors(t *testing.T) {
+	gotSecurityDescriptors, err := BuildSecurityDescriptors(bytes.NewReader(buildTestSecureDescriptorStream()))
+	if err != nil {
+		t.Errorf("BuildSecurityDescriptors() error = %v", err)
+		return
+	}
+	if len(gotSecurityDescriptors) != 3 {
+		t.Errorf("BuildSecurityDescriptors() got %d security descriptors, want 3", len(gotSecurityDescriptors))
+	}
+}
@@ -0,0 +1,487 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// RawSecurityDescriptor is a []byte alias for a raw self-relative security descriptor. Used with the Parse() method.
+// See here for the layout: https://docs.microsoft.com/en-us/openspecs/windows_protocols/ms-dtyp/7d4dac05-9cef-4563-a058-f108abecce1d
+type RawSecurityDescriptor []byte
+
+// RawSecurityDescriptorAttribute is a []byte alias for a raw security descriptor attribute. Used with the Parse() method.
+type RawSecurityDescriptorAttribute []byte
+
+// RawSID is a []byte alias for a raw security identifier. Used with the Parse() method.
+type RawSID []byte
+
+// RawACL is a []byte alias for a raw access control list. Used with the Parse() method.
+type RawACL []byte
+
+// SecurityDescriptor contains a parsed security descriptor.
+type SecurityDescriptor struct {
+	Revision byte
+	Control  uint16
+	Owner    string
+	Group    string
+	Sacl     *ACL
+	Dacl     *ACL
+}
+
+// ACL contains a parsed access control list.
+type ACL struct {
+	Revision byte
+	Entries  []ACE
+}
+
+// ACE contains a parsed access control entry.
+type ACE struct {
+	Type                byte
+	Flags               byte
+	AccessMask          AccessMask
+	ObjectType          string
+	InheritedObjectType string
+	SID                 string
+}
+
+// AccessMask is a uint32 alias for an ACE access mask.
+type AccessMask uint32
+
+// Security descriptor control flags and structure sizes.
+const (
+	controlDaclPresent         = 0x0004
+	controlSaclPresent         = 0x0010
+	controlDaclAutoInheritReq  = 0x0100
+	controlSaclAutoInheritReq  = 0x0200
+	controlDaclAutoInherited   = 0x0400
+	controlSaclAutoInherited   = 0x0800
+	controlDaclProtected       = 0x1000
+	controlSaclProtected       = 0x2000
+	lengthSecurityDescriptor   = 0x14
+	lengthACLHeader            = 0x08
+	lengthACEHeader            = 0x04
+	aceObjectTypePresent       = 0x01
+	aceInheritedObjectPresent  = 0x02
+	lengthGUID                 = 0x10
+	securityDescriptorRevision = 0x01
+)
+
+// Parse parses the raw security descriptor attribute receiver and returns a parsed security descriptor. Only resident attributes can be parsed.
+func (rawSecurityDescriptorAttribute RawSecurityDescriptorAttribute) Parse() (securityDescriptor SecurityDescriptor, err error) {
+	const offsetResidentFlag = 0x08
+	const offsetContentOffset = 0x14
+	const lengthContentOffset = 0x02
+
+	// Sanity checks
+	sizeOfRawAttribute := len(rawSecurityDescriptorAttribute)
+	if sizeOfRawAttribute == 0 {
+		err = fmt.Errorf("RawSecurityDescriptorAttribute.Parse(): %w", ErrNilBytes)
+		return
+	} else if rawSecurityDescriptorAttribute[0x00] != 0x50 {
+		err = fmt.Errorf("RawSecurityDescriptorAttribute.Parse() received an attribute thats not a security descriptor. Attribute magic number is %x", rawSecurityDescriptorAttribute[0x00])
+		return
+	} else if sizeOfRawAttribute < offsetContentOffset+lengthContentOffset {
+		err = fmt.Errorf("RawSecurityDescriptorAttribute.Parse() expected at least %d bytes, instead received %d: %w", offsetContentOffset+lengthContentOffset, sizeOfRawAttribute, ErrTruncated)
+		return
+	} else if RawResidencyFlag(rawSecurityDescriptorAttribute[offsetResidentFlag]).Parse() == false {
+		err = errors.New("RawSecurityDescriptorAttribute.Parse() received a non-resident security descriptor")
+		return
+	}
+
+	contentOffset := binary.LittleEndian.Uint16(rawSecurityDescriptorAttribute[offsetContentOffset : offsetContentOffset+lengthContentOffset])
+	if int(contentOffset) >= sizeOfRawAttribute {
+		err = fmt.Errorf("RawSecurityDescriptorAttribute.Parse() content offset %d is beyond the attribute", contentOffset)
+		return
+	}
+	securityDescriptor, err = RawSecurityDescriptor(rawSecurityDescriptorAttribute[contentOffset:]).Parse()
+	return
+}
+
+// Parse parses the raw security descriptor receiver and returns a parsed security descriptor.
+func (rawSecurityDescriptor RawSecurityDescriptor) Parse() (securityDescriptor SecurityDescriptor, err error) {
+	const offsetRevision = 0x00
+	const offsetControl = 0x02
+	const offsetOwner = 0x04
+	const offsetGroup = 0x08
+	const offsetSacl = 0x0C
+	const offsetDacl = 0x10
+
+	// Sanity checks
+	sizeOfRawSecurityDescriptor := len(rawSecurityDescriptor)
+	if sizeOfRawSecurityDescriptor < lengthSecurityDescriptor {
+		err = fmt.Errorf("RawSecurityDescriptor.Parse() expected at least %d bytes, instead received %d: %w", lengthSecurityDescriptor, sizeOfRawSecurityDescriptor, ErrTruncated)
+		return
+	} else if rawSecurityDescriptor[offsetRevision] != securityDescriptorRevision {
+		err = fmt.Errorf("RawSecurityDescriptor.Parse() received an unknown revision of %d", rawSecurityDescriptor[offsetRevision])
+		return
+	}
+
+	securityDescriptor.Revision = rawSecurityDescriptor[offsetRevision]
+	securityDescriptor.Control = binary.LittleEndian.Uint16(rawSecurityDescriptor[offsetControl : offsetControl+2])
+
+	// Every part of a self-relative security descriptor is referenced by an offset from its start. An offset of 0 means it isn't there.
+	ownerOffset := binary.LittleEndian.Uint32(rawSecurityDescriptor[offsetOwner : offsetOwner+4])
+	groupOffset := binary.LittleEndian.Uint32(rawSecurityDescriptor[offsetGroup : offsetGroup+4])
+	saclOffset := binary.LittleEndian.Uint32(rawSecurityDescriptor[offsetSacl : offsetSacl+4])
+	daclOffset := binary.LittleEndian.Uint32(rawSecurityDescriptor[offsetDacl : offsetDacl+4])
+
+	if ownerOffset != 0 {
+		securityDescriptor.Owner, err = rawSecurityDescriptor.sidAt(ownerOffset)
+		if err != nil {
+			err = fmt.Errorf("failed to parse owner sid: %w", err)
+			return
+		}
+	}
+	if groupOffset != 0 {
+		securityDescriptor.Group, err = rawSecurityDescriptor.sidAt(groupOffset)
+		if err != nil {
+			err = fmt.Errorf("failed to parse group sid: %w", err)
+			return
+		}
+	}
+	if saclOffset != 0 && securityDescriptor.Control&controlSaclPresent != 0 {
+		securityDescriptor.Sacl, err = rawSecurityDescriptor.aclAt(saclOffset)
+		if err != nil {
+			err = fmt.Errorf("failed to parse sacl: %w", err)
+			return
+		}
+	}
+	if daclOffset != 0 && securityDescriptor.Control&controlDaclPresent != 0 {
+		securityDescriptor.Dacl, err = rawSecurityDescriptor.aclAt(daclOffset)
+		if err != nil {
+			err = fmt.Errorf("failed to parse dacl: %w", err)
+			return
+		}
+	}
+	return
+}
+
+// Returns the sid string found at the offset of the raw security descriptor receiver.
+func (rawSecurityDescriptor RawSecurityDescriptor) sidAt(offset uint32) (sid string, err error) {
+	if int(offset) >= len(rawSecurityDescriptor) {
+		err = fmt.Errorf("offset %d is beyond the security descriptor", offset)
+		return
+	}
+	sid, _, err = RawSID(rawSecurityDescriptor[offset:]).Parse()
+	return
+}
+
+// Returns the acl found at the offset of the raw security descriptor receiver.
+func (rawSecurityDescriptor RawSecurityDescriptor) aclAt(offset uint32) (acl *ACL, err error) {
+	if int(offset) >= len(rawSecurityDescriptor) {
+		err = fmt.Errorf("offset %d is beyond the security descriptor", offset)
+		return
+	}
+	parsedACL, err := RawACL(rawSecurityDescriptor[offset:]).Parse()
+	if err != nil {
+		return
+	}
+	acl = &parsedACL
+	return
+}
+
+// Parse parses the raw sid receiver and returns it in its string form, for example S-1-5-18. The size of the sid in bytes is also returned. Trailing bytes are ignored.
+func (rawSID RawSID) Parse() (sid string, size int, err error) {
+	const offsetRevision = 0x00
+	const offsetSubAuthorityCount = 0x01
+	const offsetIdentifierAuthority = 0x02
+	const lengthIdentifierAuthority = 0x06
+	const offsetSubAuthorities = 0x08
+	const lengthSubAuthority = 0x04
+
+	// Sanity checks
+	sizeOfRawSID := len(rawSID)
+	if sizeOfRawSID < offsetSubAuthorities {
+		err = fmt.Errorf("RawSID.Parse() expected at least %d bytes, instead received %d: %w", offsetSubAuthorities, sizeOfRawSID, ErrTruncated)
+		return
+	}
+	subAuthorityCount := int(rawSID[offsetSubAuthorityCount])
+	size = offsetSubAuthorities + subAuthorityCount*lengthSubAuthority
+	if sizeOfRawSID < size {
+		err = fmt.Errorf("RawSID.Parse() sid has %d sub authorities but only received %d bytes", subAuthorityCount, sizeOfRawSID)
+		size = 0
+		return
+	}
+
+	// The identifier authority is the only big endian value in a sid.
+	var identifierAuthority uint64
+	for _, value := range rawSID[offsetIdentifierAuthority : offsetIdentifierAuthority+lengthIdentifierAuthority] {
+		identifierAuthority = identifierAuthority<<8 | uint64(value)
+	}
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "S-%d-", rawSID[offsetRevision])
+	if identifierAuthority >= 1<<32 {
+		fmt.Fprintf(&builder, "0x%012X", identifierAuthority)
+	} else {
+		fmt.Fprintf(&builder, "%d", identifierAuthority)
+	}
+	for i := 0; i < subAuthorityCount; i++ {
+		offset := offsetSubAuthorities + i*lengthSubAuthority
+		fmt.Fprintf(&builder, "-%d", binary.LittleEndian.Uint32(rawSID[offset:offset+lengthSubAuthority]))
+	}
+	sid = builder.String()
+	return
+}
+
+// Parse parses the raw acl receiver and returns a parsed acl.
+func (rawACL RawACL) Parse() (acl ACL, err error) {
+	const offsetRevision = 0x00
+	const offsetSize = 0x02
+	const offsetCount = 0x04
+
+	// Sanity checks
+	sizeOfRawACL := len(rawACL)
+	if sizeOfRawACL < lengthACLHeader {
+		err = fmt.Errorf("RawACL.Parse() expected at least %d bytes, instead received %d: %w", lengthACLHeader, sizeOfRawACL, ErrTruncated)
+		return
+	}
+	aclSize := int(binary.LittleEndian.Uint16(rawACL[offsetSize : offsetSize+2]))
+	if aclSize < lengthACLHeader || aclSize > sizeOfRawACL {
+		err = fmt.Errorf("RawACL.Parse() acl size of %d is invalid for the %d bytes received", aclSize, sizeOfRawACL)
+		return
+	}
+
+	acl.Revision = rawACL[offsetRevision]
+	aceCount := int(binary.LittleEndian.Uint16(rawACL[offsetCount : offsetCount+2]))
+	offset := lengthACLHeader
+	for i := 0; i < aceCount; i++ {
+		var ace ACE
+		var aceSize int
+		ace, aceSize, err = parseACE(rawACL[offset:aclSize])
+		if err != nil {
+			err = fmt.Errorf("failed to parse ace %d: %w", i, err)
+			return
+		}
+		acl.Entries = append(acl.Entries, ace)
+		offset += aceSize
+	}
+	return
+}
+
+// Parses a single ace and returns it along with its size in bytes.
+func parseACE(rawACE []byte) (ace ACE, size int, err error) {
+	const offsetType = 0x00
+	const offsetFlags = 0x01
+	const offsetSize = 0x02
+	const offsetAccessMask = 0x04
+	const offsetObjectFlags = 0x08
+
+	// Sanity checks
+	if len(rawACE) < lengthACEHeader {
+		err = fmt.Errorf("expected at least %d bytes, instead received %d: %w", lengthACEHeader, len(rawACE), ErrTruncated)
+		return
+	}
+	size = int(binary.LittleEndian.Uint16(rawACE[offsetSize : offsetSize+2]))
+	if size < offsetAccessMask+4 || size > len(rawACE) {
+		err = fmt.Errorf("ace size of %d is invalid for the %d bytes received", size, len(rawACE))
+		size = 0
+		return
+	}
+	rawACE = rawACE[:size]
+
+	ace.Type = rawACE[offsetType]
+	ace.Flags = rawACE[offsetFlags]
+	ace.AccessMask = AccessMask(binary.LittleEndian.Uint32(rawACE[offsetAccessMask : offsetAccessMask+4]))
+
+	offsetSID := offsetAccessMask + 4
+	if isObjectACE(ace.Type) {
+		if size < offsetObjectFlags+4 {
+			err = fmt.Errorf("object ace size of %d is too small", size)
+			return
+		}
+		objectFlags := binary.LittleEndian.Uint32(rawACE[offsetObjectFlags : offsetObjectFlags+4])
+		offsetSID = offsetObjectFlags + 4
+		if objectFlags&aceObjectTypePresent != 0 {
+			if size < offsetSID+lengthGUID {
+				err = errors.New("object ace is too small to hold its object type")
+				return
+			}
+			ace.ObjectType = formatGUID(rawACE[offsetSID : offsetSID+lengthGUID])
+			offsetSID += lengthGUID
+		}
+		if objectFlags&aceInheritedObjectPresent != 0 {
+			if size < offsetSID+lengthGUID {
+				err = errors.New("object ace is too small to hold its inherited object type")
+				return
+			}
+			ace.InheritedObjectType = formatGUID(rawACE[offsetSID : offsetSID+lengthGUID])
+			offsetSID += lengthGUID
+		}
+	}
+
+	ace.SID, _, err = RawSID(rawACE[offsetSID:]).Parse()
+	if err != nil {
+		err = fmt.Errorf("failed to parse ace sid: %w", err)
+		return
+	}
+	return
+}
+
+// Checks if the ace type is one of the object ace types, which carry optional guids before their sid.
+func isObjectACE(aceType byte) bool {
+	switch aceType {
+	case 0x05, 0x06, 0x07, 0x08, 0x0B, 0x0C, 0x0F, 0x10:
+		return true
+	default:
+		return false
+	}
+}
+
+// Formats a mixed endian guid in its usual string form.
+func formatGUID(rawGUID []byte) string {
+	return fmt.Sprintf("%08x-%04x-%04x-%x-%x",
+		binary.LittleEndian.Uint32(rawGUID[0x00:0x04]),
+		binary.LittleEndian.Uint16(rawGUID[0x04:0x06]),
+		binary.LittleEndian.Uint16(rawGUID[0x06:0x08]),
+		rawGUID[0x08:0x0A],
+		rawGUID[0x0A:0x10])
+}
+
+// Rights returns the names of the rights set in the access mask receiver.
+func (accessMask AccessMask) Rights() (rights []string) {
+	rightNames := []struct {
+		mask AccessMask
+		name string
+	}{
+		{0x00000001, "FILE_READ_DATA"},
+		{0x00000002, "FILE_WRITE_DATA"},
+		{0x00000004, "FILE_APPEND_DATA"},
+		{0x00000008, "FILE_READ_EA"},
+		{0x00000010, "FILE_WRITE_EA"},
+		{0x00000020, "FILE_EXECUTE"},
+		{0x00000040, "FILE_DELETE_CHILD"},
+		{0x00000080, "FILE_READ_ATTRIBUTES"},
+		{0x00000100, "FILE_WRITE_ATTRIBUTES"},
+		{0x00010000, "DELETE"},
+		{0x00020000, "READ_CONTROL"},
+		{0x00040000, "WRITE_DAC"},
+		{0x00080000, "WRITE_OWNER"},
+		{0x00100000, "SYNCHRONIZE"},
+		{0x01000000, "ACCESS_SYSTEM_SECURITY"},
+		{0x02000000, "MAXIMUM_ALLOWED"},
+		{0x10000000, "GENERIC_ALL"},
+		{0x20000000, "GENERIC_EXECUTE"},
+		{0x40000000, "GENERIC_WRITE"},
+		{0x80000000, "GENERIC_READ"},
+	}
+	for _, right := range rightNames {
+		if accessMask&right.mask != 0 {
+			rights = append(rights, right.name)
+		}
+	}
+	return
+}
+
+// String returns the rights of the access mask receiver joined by a pipe.
+func (accessMask AccessMask) String() string {
+	return strings.Join(accessMask.Rights(), "|")
+}
+
+// SDDL returns the security descriptor receiver in security descriptor definition language form.
+// See here for details on the format: https://docs.microsoft.com/en-us/windows/win32/secauthz/security-descriptor-string-format
+func (securityDescriptor SecurityDescriptor) SDDL() string {
+	var builder strings.Builder
+	if securityDescriptor.Owner != "" {
+		builder.WriteString("O:" + securityDescriptor.Owner)
+	}
+	if securityDescriptor.Group != "" {
+		builder.WriteString("G:" + securityDescriptor.Group)
+	}
+	if securityDescriptor.Control&controlDaclPresent != 0 {
+		builder.WriteString("D:")
+		builder.WriteString(aclFlagsSDDL(securityDescriptor.Control, controlDaclProtected, controlDaclAutoInheritReq, controlDaclAutoInherited))
+		if securityDescriptor.Dacl == nil {
+			builder.WriteString("NO_ACCESS_CONTROL")
+		} else {
+			builder.WriteString(securityDescriptor.Dacl.sddl())
+		}
+	}
+	if securityDescriptor.Control&controlSaclPresent != 0 && securityDescriptor.Sacl != nil {
+		builder.WriteString("S:")
+		builder.WriteString(aclFlagsSDDL(securityDescriptor.Control, controlSaclProtected, controlSaclAutoInheritReq, controlSaclAutoInherited))
+		builder.WriteString(securityDescriptor.Sacl.sddl())
+	}
+	return builder.String()
+}
+
+// Returns the sddl acl flags that are set in the control flags.
+func aclFlagsSDDL(control uint16, protected uint16, autoInheritReq uint16, autoInherited uint16) (flags string) {
+	if control&protected != 0 {
+		flags += "P"
+	}
+	if control&autoInheritReq != 0 {
+		flags += "AR"
+	}
+	if control&autoInherited != 0 {
+		flags += "AI"
+	}
+	return
+}
+
+// Returns the sddl form of each ace in the acl receiver.
+func (acl ACL) sddl() string {
+	aceTypes := map[byte]string{
+		0x00: "A",
+		0x01: "D",
+		0x02: "AU",
+		0x03: "AL",
+		0x05: "OA",
+		0x06: "OD",
+		0x07: "OU",
+		0x08: "OL",
+		0x09: "XA",
+		0x0A: "XD",
+		0x0B: "ZA",
+		0x0D: "XU",
+		0x11: "ML",
+		0x12: "RA",
+		0x13: "SP",
+	}
+	aceFlags := []struct {
+		flag byte
+		name string
+	}{
+		{0x01, "OI"},
+		{0x02, "CI"},
+		{0x04, "NP"},
+		{0x08, "IO"},
+		{0x10, "ID"},
+		{0x40, "SA"},
+		{0x80, "FA"},
+	}
+	rights := map[AccessMask]string{
+		0x001F01FF: "FA",
+		0x00120089: "FR",
+		0x00120116: "FW",
+		0x001200A0: "FX",
+		0x10000000: "GA",
+		0x80000000: "GR",
+		0x40000000: "GW",
+		0x20000000: "GX",
+	}
+
+	var builder strings.Builder
+	for _, ace := range acl.Entries {
+		aceType, ok := aceTypes[ace.Type]
+		if !ok {
+			aceType = fmt.Sprintf("0x%x", ace.Type)
+		}
+		var flags string
+		for _, aceFlag := range aceFlags {
+			if ace.Flags&aceFlag.flag != 0 {
+				flags += aceFlag.name
+			}
+		}
+		right, ok := rights[ace.AccessMask]
+		if !ok {
+			right = fmt.Sprintf("0x%x", uint32(ace.AccessMask))
+		}
+		fmt.Fprintf(&builder, "(%s;%s;%s;%s;%s;%s)", aceType, flags, right, ace.ObjectType, ace.InheritedObjectType, ace.SID)
+	}
+	return builder.String()
+}
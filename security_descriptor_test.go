@@ -0,0 +1,232 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"reflect"
+	"testing"
+)
+
+var testRawSecurityDescriptorAttribute = RawSecurityDescriptorAttribute([]byte{0x50, 0x00, 0x00, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00, 0x03, 0x00, 0x64, 0x00, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00, 0x01, 0x00, 0x04, 0x80, 0x48, 0x00, 0x00, 0x00, 0x54, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x14, 0x00, 0x00, 0x00, 0x02, 0x00, 0x34, 0x00, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x14, 0x00, 0x89, 0x00, 0x12, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x12, 0x00, 0x00, 0x00, 0x00, 0x00, 0x18, 0x00, 0x89, 0x00, 0x12, 0x00, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x20, 0x00, 0x00, 0x00, 0x20, 0x02, 0x00, 0x00, 0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x12, 0x00, 0x00, 0x00, 0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x20, 0x00, 0x00, 0x00, 0x20, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00})
+
+var testSecurityDescriptor = SecurityDescriptor{
+	Revision: 1,
+	Control:  0x8004,
+	Owner:    "S-1-5-18",
+	Group:    "S-1-5-32-544",
+	Dacl: &ACL{
+		Revision: 2,
+		Entries: []ACE{
+			{Type: 0x00, Flags: 0x00, AccessMask: 0x120089, SID: "S-1-5-18"},
+			{Type: 0x00, Flags: 0x00, AccessMask: 0x120089, SID: "S-1-5-32-544"},
+		},
+	},
+}
+
+func TestRawSecurityDescriptorAttribute_Parse(t *testing.T) {
+	tests := []struct {
+		name                           string
+		rawSecurityDescriptorAttribute RawSecurityDescriptorAttribute
+		wantSecurityDescriptor         SecurityDescriptor
+		wantErr                        bool
+	}{
+		{
+			name:                           "resident security descriptor",
+			rawSecurityDescriptorAttribute: testRawSecurityDescriptorAttribute,
+			wantSecurityDescriptor:         testSecurityDescriptor,
+			wantErr:                        false,
+		},
+		{
+			name:                           "nil bytes",
+			rawSecurityDescriptorAttribute: nil,
+			wantErr:                        true,
+		},
+		{
+			name:                           "wrong attribute",
+			rawSecurityDescriptorAttribute: RawSecurityDescriptorAttribute([]byte{0x10, 0x00, 0x00, 0x00, 0x80, 0x00, 0x00, 0x00, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00, 0x03, 0x00, 0x64, 0x00, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00}),
+			wantErr:                        true,
+		},
+		{
+			name:                           "non-resident",
+			rawSecurityDescriptorAttribute: RawSecurityDescriptorAttribute([]byte{0x50, 0x00, 0x00, 0x00, 0x80, 0x00, 0x00, 0x00, 0x01, 0x00, 0x18, 0x00, 0x00, 0x00, 0x03, 0x00, 0x64, 0x00, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00}),
+			wantErr:                        true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSecurityDescriptor, err := tt.rawSecurityDescriptorAttribute.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(gotSecurityDescriptor, tt.wantSecurityDescriptor) {
+				t.Errorf("Parse() gotSecurityDescriptor = %v, want %v", gotSecurityDescriptor, tt.wantSecurityDescriptor)
+			}
+		})
+	}
+}
+
+func TestRawSID_Parse(t *testing.T) {
+	tests := []struct {
+		name     string
+		rawSID   RawSID
+		wantSID  string
+		wantSize int
+		wantErr  bool
+	}{
+		{
+			name:     "local system",
+			rawSID:   RawSID([]byte{0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x12, 0x00, 0x00, 0x00}),
+			wantSID:  "S-1-5-18",
+			wantSize: 12,
+			wantErr:  false,
+		},
+		{
+			name:     "domain user with trailing bytes",
+			rawSID:   RawSID([]byte{0x01, 0x05, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x15, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00, 0x03, 0x00, 0x00, 0x00, 0xe9, 0x03, 0x00, 0x00, 0xff, 0xff}),
+			wantSID:  "S-1-5-21-1-2-3-1001",
+			wantSize: 28,
+			wantErr:  false,
+		},
+		{
+			name:    "truncated",
+			rawSID:  RawSID([]byte{0x01, 0x02, 0x00, 0x00, 0x00, 0x00, 0x00, 0x05, 0x20, 0x00, 0x00, 0x00}),
+			wantErr: true,
+		},
+		{
+			name:    "nil bytes",
+			rawSID:  nil,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotSID, gotSize, err := tt.rawSID.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if gotSID != tt.wantSID || gotSize != tt.wantSize {
+				t.Errorf("Parse() gotSID = %v, gotSize = %v, want %v, %v", gotSID, gotSize, tt.wantSID, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestRawACL_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		rawACL  RawACL
+		wantACL ACL
+		wantErr bool
+	}{
+		{
+			name: "object ace with an object type",
+			rawACL: RawACL([]byte{0x04, 0x00, 0x30, 0x00, 0x01, 0x00, 0x00, 0x00,
+				0x05, 0x02, 0x28, 0x00, 0x00, 0x01, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00,
+				0x78, 0x56, 0x34, 0x12, 0x34, 0x12, 0x78, 0x56, 0x01, 0x02, 0x03, 0x04, 0x05, 0x06, 0x07, 0x08,
+				0x01, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01, 0x00, 0x00, 0x00, 0x00}),
+			wantACL: ACL{
+				Revision: 4,
+				Entries: []ACE{
+					{Type: 0x05, Flags: 0x02, AccessMask: 0x100, ObjectType: "12345678-1234-5678-0102-030405060708", SID: "S-1-1-0"},
+				},
+			},
+			wantErr: false,
+		},
+		{
+			name:    "ace count beyond the acl",
+			rawACL:  RawACL([]byte{0x02, 0x00, 0x08, 0x00, 0x01, 0x00, 0x00, 0x00}),
+			wantErr: true,
+		},
+		{
+			name:    "acl size larger than bytes",
+			rawACL:  RawACL([]byte{0x02, 0x00, 0x30, 0x00, 0x00, 0x00, 0x00, 0x00}),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotACL, err := tt.rawACL.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(gotACL, tt.wantACL) {
+				t.Errorf("Parse() gotACL = %v, want %v", gotACL, tt.wantACL)
+			}
+		})
+	}
+}
+
+func TestAccessMask_String(t *testing.T) {
+	tests := []struct {
+		name       string
+		accessMask AccessMask
+		want       string
+	}{
+		{
+			name:       "file read",
+			accessMask: 0x120089,
+			want:       "FILE_READ_DATA|FILE_READ_EA|FILE_READ_ATTRIBUTES|READ_CONTROL|SYNCHRONIZE",
+		},
+		{
+			name:       "generic all",
+			accessMask: 0x10000000,
+			want:       "GENERIC_ALL",
+		},
+		{
+			name:       "none",
+			accessMask: 0,
+			want:       "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.accessMask.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSecurityDescriptor_SDDL(t *testing.T) {
+	tests := []struct {
+		name               string
+		securityDescriptor SecurityDescriptor
+		want               string
+	}{
+		{
+			name:               "owner group and dacl",
+			securityDescriptor: testSecurityDescriptor,
+			want:               "O:S-1-5-18G:S-1-5-32-544D:(A;;FR;;;S-1-5-18)(A;;FR;;;S-1-5-32-544)",
+		},
+		{
+			name: "protected dacl with inherited deny ace",
+			securityDescriptor: SecurityDescriptor{
+				Revision: 1,
+				Control:  0x1404,
+				Owner:    "S-1-5-32-544",
+				Dacl: &ACL{
+					Revision: 2,
+					Entries: []ACE{
+						{Type: 0x01, Flags: 0x13, AccessMask: 0x00010000, SID: "S-1-1-0"},
+					},
+				},
+			},
+			want: "O:S-1-5-32-544D:PAI(D;OICIID;0x10000;;;S-1-1-0)",
+		},
+		{
+			name:               "null dacl",
+			securityDescriptor: SecurityDescriptor{Revision: 1, Control: 0x0004},
+			want:               "D:NO_ACCESS_CONTROL",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.securityDescriptor.SDDL(); got != tt.want {
+				t.Errorf("SDDL() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
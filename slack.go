@@ -0,0 +1,258 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// RecordSlack contains the bytes of an MFT record that follow its end marker. NTFS doesn't clear a record when attributes shrink or are removed, so this can hold remnants of earlier attributes.
+type RecordSlack struct {
+	Offset             int
+	Data               []byte
+	ResidualAttributes []ResidualAttribute
+}
+
+// ResidualAttribute contains an attribute header found in the slack of an MFT record. The offset is relative to the start of the record. The file name is only filled in for residual $FILE_NAME attributes that parse.
+type ResidualAttribute struct {
+	Offset        int
+	AttributeInfo AttributeInfo
+	FileName      FileNameAttribute
+}
+
+// FileSlack contains the location of the slack of a non-resident stream, the bytes between the stream's actual size and the end of its last cluster. The output offset is where the slack starts in the output of ExtractFileSlack().
+type FileSlack struct {
+	RecordNumber   uint32
+	SequenceNumber uint16
+	FullPath       string
+	Stream         string
+	VolumeOffset   int64
+	Length         int64
+	OutputOffset   int64
+}
+
+// Slack returns the bytes that follow the end marker of the raw mft record receiver, along with any attributes left behind in them. The attributes are walked to find the end marker rather than searching for 0xFFFFFFFF, which could also show up inside an attribute.
+func (rawMftRecord RawMasterFileTableRecord) Slack() (recordSlack RecordSlack, err error) {
+	const lengthEndMarker = 0x04
+
+	rawRecordHeader, err := rawMftRecord.GetRawRecordHeader()
+	if err != nil {
+		err = fmt.Errorf("failed to get record header: %w", err)
+		return
+	}
+	recordHeader, err := rawRecordHeader.Parse()
+	if err != nil {
+		err = fmt.Errorf("failed to parse record header: %w", err)
+		return
+	}
+	rawAttributes, err := rawMftRecord.GetRawAttributes(recordHeader)
+	if err != nil {
+		err = fmt.Errorf("failed to get raw attributes: %w", err)
+		return
+	}
+	endMarkerOffset := int(recordHeader.AttributesOffset)
+	for _, rawAttribute := range rawAttributes {
+		endMarkerOffset += len(rawAttribute)
+	}
+	if endMarkerOffset+lengthEndMarker > len(rawMftRecord) || binary.LittleEndian.Uint32(rawMftRecord[endMarkerOffset:endMarkerOffset+lengthEndMarker]) != attributeTypeEnd {
+		err = errors.New("the record does not have an end marker after its attributes")
+		return
+	}
+
+	recordSlack.Offset = endMarkerOffset + lengthEndMarker
+	recordSlack.Data = make([]byte, len(rawMftRecord)-recordSlack.Offset)
+	copy(recordSlack.Data, rawMftRecord[recordSlack.Offset:])
+	recordSlack.ResidualAttributes = findResidualAttributes(rawMftRecord, recordSlack.Offset)
+	return
+}
+
+// Walks the 8 byte aligned offsets of a raw mft record from the start offset onwards and returns the attribute headers found there. Attributes are always 8 byte aligned within a record, and a found attribute is skipped over so that its content isn't mistaken for another attribute.
+func findResidualAttributes(rawMftRecord RawMasterFileTableRecord, start int) (residualAttributes []ResidualAttribute) {
+	const codeFileName = 0x30
+
+	offset := (start + 7) &^ 7
+	for offset+0x18 <= len(rawMftRecord) {
+		size := residualAttributeSize(rawMftRecord[offset:])
+		if size == 0 {
+			offset += 8
+			continue
+		}
+		rawAttribute := rawAttribute(rawMftRecord[offset : offset+size])
+		residualAttribute := ResidualAttribute{
+			Offset:        offset,
+			AttributeInfo: rawAttribute.info(),
+		}
+		if residualAttribute.AttributeInfo.Type == codeFileName && residualAttribute.AttributeInfo.Resident {
+			rawFileNameAttribute := make(RawFileNameAttribute, size)
+			copy(rawFileNameAttribute, rawAttribute)
+			residualAttribute.FileName, _ = rawFileNameAttribute.Parse()
+		}
+		residualAttributes = append(residualAttributes, residualAttribute)
+		offset += size
+	}
+	return
+}
+
+// Returns the size of the attribute at the start of the data if its header is believable, or 0 if it isn't. Only attribute types in the default attribute definitions are accepted since slack is mostly old data and any multiple of 0x10 would otherwise pass.
+func residualAttributeSize(data []byte) (size int) {
+	const offsetResidentFlag = 0x08
+	const offsetNameLength = 0x09
+	const offsetNameOffset = 0x0A
+	const offsetContentLength = 0x10
+	const offsetContentOffset = 0x14
+	const offsetDataRunOffset = 0x20
+	const lengthNonResidentHeader = 0x40
+
+	attributeType := binary.LittleEndian.Uint32(data[0x00:0x04])
+	if attributeType == 0 || attributeType == attributeTypeEnd || !DefaultAttributeDefinitions.IsDefined(attributeType) {
+		return
+	}
+	attributeSize := int(binary.LittleEndian.Uint32(data[0x04:0x08]))
+	if attributeSize < 0x18 || attributeSize%8 != 0 || attributeSize > len(data) {
+		return
+	}
+	nameEnd := int(binary.LittleEndian.Uint16(data[offsetNameOffset:offsetNameOffset+0x02])) + int(data[offsetNameLength])*2
+	if data[offsetNameLength] != 0 && nameEnd > attributeSize {
+		return
+	}
+	switch data[offsetResidentFlag] {
+	case 0x00:
+		contentLength := int(binary.LittleEndian.Uint32(data[offsetContentLength : offsetContentLength+0x04]))
+		contentOffset := int(binary.LittleEndian.Uint16(data[offsetContentOffset : offsetContentOffset+0x02]))
+		if contentOffset < 0x18 || contentOffset+contentLength > attributeSize {
+			return
+		}
+	case 0x01:
+		if attributeSize < lengthNonResidentHeader || int(data[offsetDataRunOffset]) >= attributeSize {
+			return
+		}
+	default:
+		return
+	}
+	size = attributeSize
+	return
+}
+
+// Fills in the record slack fields of a parsed record.
+func (usefulMftFields *UsefulMftFields) setRecordSlackFields(recordSlack RecordSlack) {
+	usefulMftFields.SlackSize = len(recordSlack.Data)
+	var residualAttributes, residualFileNames []string
+	for _, residualAttribute := range recordSlack.ResidualAttributes {
+		residualAttributes = append(residualAttributes, fmt.Sprintf("%s@0x%X", residualAttribute.AttributeInfo.Describe(nil), residualAttribute.Offset))
+		if residualAttribute.FileName.FileName != "" {
+			residualFileNames = append(residualFileNames, residualAttribute.FileName.FileName)
+		}
+	}
+	usefulMftFields.SlackAttributes = strings.Join(residualAttributes, ";")
+	usefulMftFields.SlackFileNames = strings.Join(residualFileNames, ";")
+	return
+}
+
+// ExtractFileSlack copies the file slack of every non-resident $DATA stream of the in use records of an MFT from the volume to the writer, one after the other, and returns where each one came from. The volume must start at the beginning of the NTFS partition.
+// Streams whose size is a multiple of the cluster size have no slack, and streams whose last cluster is sparse or kept in an extension record are skipped.
+func ExtractFileSlack(writer io.Writer, mft io.Reader, volume io.ReaderAt, bytesPerCluster int64, directoryTree DirectoryTree) (fileSlacks []FileSlack, err error) {
+	const codeData = 0x80
+
+	// Sanity checks
+	if bytesPerCluster <= 0 {
+		err = fmt.Errorf("invalid bytes per cluster value of %d", bytesPerCluster)
+		return
+	}
+
+	outputOffset := int64(0)
+	for {
+		buffer := make(RawMasterFileTableRecord, defaultMftRecordSize)
+		_, readErr := io.ReadFull(mft, buffer)
+		if readErr != nil {
+			break
+		}
+		mftRecord, parseErr := buffer.Parse(bytesPerCluster)
+		if parseErr != nil || mftRecord.RecordHeader.IsExtensionRecord() || !mftRecord.RecordHeader.Flags.FlagInUse {
+			continue
+		}
+		rawAttributes, attributesErr := buffer.GetRawAttributes(mftRecord.RecordHeader)
+		if attributesErr != nil {
+			continue
+		}
+
+		var fullPath string
+		for _, rawAttribute := range rawAttributes {
+			attributeInfo := rawAttribute.info()
+			if attributeInfo.Type != codeData || attributeInfo.Resident {
+				continue
+			}
+			volumeOffset, length, ok := fileSlackLocation(rawAttribute, bytesPerCluster)
+			if !ok {
+				continue
+			}
+			slack := make([]byte, length)
+			_, err = volume.ReadAt(slack, volumeOffset)
+			if err != nil {
+				err = fmt.Errorf("failed to read the file slack of record %d at offset %d: %w", mftRecord.RecordHeader.RecordNumber, volumeOffset, err)
+				return
+			}
+			_, err = writer.Write(slack)
+			if err != nil {
+				err = fmt.Errorf("failed to write the file slack of record %d: %w", mftRecord.RecordHeader.RecordNumber, err)
+				return
+			}
+
+			if fullPath == "" {
+				fullPath = GetUsefulMftFields(mftRecord, directoryTree).FullPath
+			}
+			fileSlacks = append(fileSlacks, FileSlack{
+				RecordNumber:   mftRecord.RecordHeader.RecordNumber,
+				SequenceNumber: mftRecord.RecordHeader.SequenceNumber,
+				FullPath:       fullPath,
+				Stream:         attributeInfo.Describe(nil),
+				VolumeOffset:   volumeOffset,
+				Length:         length,
+				OutputOffset:   outputOffset,
+			})
+			outputOffset += length
+		}
+	}
+	return
+}
+
+// Returns where the file slack of a raw non-resident attribute is on the volume. Only the first segment of an attribute has its actual size, so the slack is only found when that segment's data runs reach the stream's last cluster.
+func fileSlackLocation(rawAttribute rawAttribute, bytesPerCluster int64) (volumeOffset int64, length int64, ok bool) {
+	const offsetStartingVcn = 0x10
+	const offsetActualSize = 0x30
+	const lengthActualSize = 0x08
+
+	if len(rawAttribute) < offsetActualSize+lengthActualSize || binary.LittleEndian.Uint64(rawAttribute[offsetStartingVcn:offsetStartingVcn+0x08]) != 0 {
+		return
+	}
+	actualSize := int64(binary.LittleEndian.Uint64(rawAttribute[offsetActualSize : offsetActualSize+lengthActualSize]))
+	offsetInCluster := actualSize % bytesPerCluster
+	if actualSize == 0 || offsetInCluster == 0 {
+		return
+	}
+	nonResidentDataAttribute, err := RawNonResidentDataAttribute(rawAttribute).Parse(bytesPerCluster)
+	if err != nil {
+		return
+	}
+
+	// Walk the data runs to the one holding the last byte of the stream.
+	remaining := actualSize
+	for i := 0; i < len(nonResidentDataAttribute.DataRuns); i++ {
+		dataRun := nonResidentDataAttribute.DataRuns[i]
+		if remaining > dataRun.Length {
+			remaining -= dataRun.Length
+			continue
+		}
+		if dataRun.Sparse {
+			return
+		}
+		volumeOffset = dataRun.AbsoluteOffset + remaining
+		length = bytesPerCluster - offsetInCluster
+		ok = true
+		return
+	}
+	return
+}
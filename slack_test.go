@@ -0,0 +1,178 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds an mft record for new.txt whose slack still holds the $FILE_NAME attribute of old.txt, 4 bytes after the end marker.
+func buildTestSlackMftRecord() (rawMftRecord RawMasterFileTableRecord, residualOffset int) {
+	rawFileNameAttribute := buildTestFileNameAttribute("new.txt", 5)
+	rawMftRecord = buildTestMftRecord(40, 0, rawFileNameAttribute)
+	residualOffset = 0x38 + len(rawFileNameAttribute) + 0x08
+	copy(rawMftRecord[residualOffset:], buildTestResidualFileNameAttribute("old.txt"))
+	return
+}
+
+// Builds a $FILE_NAME attribute with its content length and offset filled in, which buildTestFileNameAttribute() leaves empty.
+func buildTestResidualFileNameAttribute(fileName string) []byte {
+	rawFileNameAttribute := buildTestFileNameAttribute(fileName, 5)
+	binary.LittleEndian.PutUint32(rawFileNameAttribute[0x10:], uint32(0x42+len(fileName)*2))
+	binary.LittleEndian.PutUint16(rawFileNameAttribute[0x14:], 0x18)
+	return rawFileNameAttribute
+}
+
+func TestRawMasterFileTableRecord_Slack(t *testing.T) {
+	rawMftRecord, residualOffset := buildTestSlackMftRecord()
+	oldFileName, _ := RawFileNameAttribute(buildTestResidualFileNameAttribute("old.txt")).Parse()
+	noEndMarker := buildTestMftRecord(40, 0, buildTestFileNameAttribute("new.txt", 5))
+	binary.LittleEndian.PutUint32(noEndMarker[residualOffset-0x08:], 0x12345678)
+
+	tests := []struct {
+		name         string
+		rawMftRecord RawMasterFileTableRecord
+		want         RecordSlack
+		wantErr      bool
+	}{
+		{
+			name:         "residual file name",
+			rawMftRecord: rawMftRecord,
+			want: RecordSlack{
+				Offset: residualOffset - 0x04,
+				Data:   []byte(rawMftRecord[residualOffset-0x04:]),
+				ResidualAttributes: []ResidualAttribute{
+					{
+						Offset:        residualOffset,
+						AttributeInfo: AttributeInfo{Type: 0x30, Resident: true, Size: uint32(len(buildTestFileNameAttribute("old.txt", 5)))},
+						FileName:      oldFileName,
+					},
+				},
+			},
+		},
+		{
+			name:         "empty slack",
+			rawMftRecord: buildTestMftRecord(40, 0, buildTestFileNameAttribute("new.txt", 5)),
+			want: RecordSlack{
+				Offset: residualOffset - 0x04,
+				Data:   make([]byte, 1024-residualOffset+0x04),
+			},
+		},
+		{
+			name:         "no end marker",
+			rawMftRecord: noEndMarker,
+			wantErr:      true,
+		},
+		{
+			name:         "nil bytes",
+			rawMftRecord: nil,
+			wantErr:      true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.rawMftRecord.Slack()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Slack() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestResidualAttributeSize(t *testing.T) {
+	badResidentContent := buildTestResidentAttribute(0x80, make([]byte, 8))
+	binary.LittleEndian.PutUint32(badResidentContent[0x10:], 0x100)
+	badResidentFlag := buildTestResidentAttribute(0x80, make([]byte, 8))
+	badResidentFlag[0x08] = 0x02
+	misalignedSize := buildTestResidentAttribute(0x80, make([]byte, 8))
+	binary.LittleEndian.PutUint32(misalignedSize[0x04:], 0x1C)
+	shortNonResident := buildTestNonResidentAttribute(0x80, 0, nil)
+	binary.LittleEndian.PutUint32(shortNonResident[0x04:], 0x30)
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{
+			name: "resident attribute",
+			data: buildTestResidentAttribute(0x80, make([]byte, 8)),
+			want: 0x20,
+		},
+		{
+			name: "non-resident attribute",
+			data: buildTestNonResidentAttribute(0x80, 0x1000, []byte{0x11, 0x01, 0x04}),
+			want: 0x48,
+		},
+		{
+			name: "unknown attribute type",
+			data: buildTestResidentAttribute(0x00, make([]byte, 8)),
+		},
+		{
+			name: "resident content past the attribute",
+			data: badResidentContent,
+		},
+		{
+			name: "invalid resident flag",
+			data: badResidentFlag,
+		},
+		{
+			name: "size not a multiple of 8",
+			data: append(misalignedSize, make([]byte, 8)...),
+		},
+		{
+			name: "non-resident attribute shorter than its header",
+			data: shortNonResident,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := residualAttributeSize(tt.data); got != tt.want {
+				t.Errorf("residualAttributeSize() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExtractFileSlack(t *testing.T) {
+	volume := make([]byte, 8*4096)
+	for i := 5*4096 + 0x800; i < 6*4096; i++ {
+		volume[i] = 0xAB
+	}
+	deletedRecord := buildTestMftRecord(32, 0, buildTestFileNameAttribute("deleted.txt", 5), buildTestNonResidentAttribute(0x80, 0x100, []byte{0x11, 0x01, 0x06}))
+	deletedRecord[0x16] = 0x00
+	var rawMft []byte
+	for _, rawMftRecord := range [][]byte{
+		buildTestMftRecord(30, 0, buildTestFileNameAttribute("report.docx", 5), buildTestNonResidentAttribute(0x80, 0x1800, []byte{0x11, 0x02, 0x04})),
+		buildTestMftRecord(31, 0, buildTestFileNameAttribute("full.bin", 5), buildTestNonResidentAttribute(0x80, 0x1000, []byte{0x11, 0x01, 0x06})),
+		deletedRecord,
+		buildTestMftRecord(33, 0, buildTestFileNameAttribute("sparse.bin", 5), buildTestNonResidentAttribute(0x80, 0x1100, []byte{0x11, 0x01, 0x07, 0x01, 0x01})),
+		buildTestMftRecord(34, 0, buildTestFileNameAttribute("small.txt", 5), buildTestResidentAttribute(0x80, []byte("hi"))),
+	} {
+		rawMft = append(rawMft, rawMftRecord...)
+	}
+
+	var output bytes.Buffer
+	got, err := ExtractFileSlack(&output, bytes.NewReader(rawMft), bytes.NewReader(volume), 4096, DirectoryTree{5: "C:\\"})
+	if err != nil {
+		t.Fatalf("ExtractFileSlack() error = %v", err)
+	}
+	want := []FileSlack{
+		{RecordNumber: 30, FullPath: "C:\\report.docx", Stream: "$DATA", VolumeOffset: 5*4096 + 0x800, Length: 0x800},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+	if wantOutput := bytes.Repeat([]byte{0xAB}, 0x800); !bytes.Equal(output.Bytes(), wantOutput) {
+		t.Errorf("ExtractFileSlack() wrote %d bytes, want %d bytes of 0xAB", output.Len(), len(wantOutput))
+	}
+}
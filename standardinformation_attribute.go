@@ -3,7 +3,9 @@
 package mft
 
 import (
-	"errors"
+	"encoding/binary"
+	"fmt"
+	bin "github.com/AlecRandazzo/BinaryTransforms"
 	ts "github.com/AlecRandazzo/Timestamp-Parser"
 	"time"
 )
@@ -18,6 +20,8 @@ type StandardInformationAttribute struct {
 	SiAccessed   time.Time
 	SiChanged    time.Time
 	FlagResident bool
+	SecurityID   uint32
+	Usn          int64
 }
 
 // Parse parses the raw standard information attribute receiver and returns a parsed standard information attribute.
@@ -36,16 +40,22 @@ func (rawStandardInformationAttribute RawStandardInformationAttribute) Parse() (
 	const offsetSiAccessed = 0x30
 	const lengthSiAccessed = 0x08
 
-	// The standard information Attribute has a minimum length of 0x30
-	if len(rawStandardInformationAttribute) < 0x30 {
-		err = errors.New("StandardInformationAttributes.parse() received invalid bytes")
+	const offsetSecurityID = 0x4C
+	const lengthSecurityID = 0x04
+
+	const offsetUsn = 0x58
+	const lengthUsn = 0x08
+
+	// The standard information Attribute has a minimum length of 0x38, which covers the header and the timestamps
+	if len(rawStandardInformationAttribute) < offsetSiAccessed+lengthSiAccessed {
+		err = fmt.Errorf("StandardInformationAttributes.parse() received %d bytes: %w", len(rawStandardInformationAttribute), ErrTruncated)
 		return
 	}
 	// Check to see if the standard information Attribute is resident to the MFT or not
 	rawResidencyFlag := RawResidencyFlag(rawStandardInformationAttribute[offsetResidentFlag])
 	standardInformationAttribute.FlagResident = rawResidencyFlag.Parse()
 	if standardInformationAttribute.FlagResident == false {
-		err = errors.New("non resident standard information flag found")
+		err = fmt.Errorf("standard information attribute: %w", ErrNonResident)
 		return
 	}
 
@@ -55,9 +65,41 @@ func (rawStandardInformationAttribute RawStandardInformationAttribute) Parse() (
 	rawSiChanged := ts.RawTimestamp(rawStandardInformationAttribute[offsetSiChanged : offsetSiChanged+lengthSiChanged])
 	rawSiAccessed := ts.RawTimestamp(rawStandardInformationAttribute[offsetSiAccessed : offsetSiAccessed+lengthSiAccessed])
 
-	standardInformationAttribute.SiCreated, _ = rawSiCreated.Parse()
-	standardInformationAttribute.SiModified, _ = rawSiModified.Parse()
-	standardInformationAttribute.SiChanged, _ = rawSiChanged.Parse()
-	standardInformationAttribute.SiAccessed, _ = rawSiAccessed.Parse()
+	standardInformationAttribute.SiCreated, err = parseTimestamp(rawSiCreated, "created")
+	if err != nil {
+		return
+	}
+	standardInformationAttribute.SiModified, err = parseTimestamp(rawSiModified, "modified")
+	if err != nil {
+		return
+	}
+	standardInformationAttribute.SiChanged, err = parseTimestamp(rawSiChanged, "changed")
+	if err != nil {
+		return
+	}
+	standardInformationAttribute.SiAccessed, err = parseTimestamp(rawSiAccessed, "accessed")
+	if err != nil {
+		return
+	}
+
+	// The security id was added in NTFS 3.0, older standard information attributes are shorter.
+	if len(rawStandardInformationAttribute) >= offsetSecurityID+lengthSecurityID {
+		standardInformationAttribute.SecurityID, _ = bin.LittleEndianBinaryToUInt32(rawStandardInformationAttribute[offsetSecurityID : offsetSecurityID+lengthSecurityID])
+	}
+
+	// The usn of the file's last $UsnJrnl record was added with the security id.
+	if len(rawStandardInformationAttribute) >= offsetUsn+lengthUsn {
+		standardInformationAttribute.Usn = int64(binary.LittleEndian.Uint64(rawStandardInformationAttribute[offsetUsn : offsetUsn+lengthUsn]))
+	}
+	return
+}
+
+// Parses a raw timestamp. The name of the timestamp is added to the error, which wraps ErrBadTimestamp.
+func parseTimestamp(rawTimestamp ts.RawTimestamp, name string) (timestamp time.Time, err error) {
+	timestamp, err = rawTimestamp.Parse()
+	if err != nil {
+		err = fmt.Errorf("failed to parse the %s timestamp: %v: %w", name, err, ErrBadTimestamp)
+		return
+	}
 	return
 }
@@ -27,6 +27,8 @@ func TestStandardInformationAttributes_Parse(t *testing.T) {
 				SiAccessed:   time.Date(2018, 4, 11, 23, 34, 40, 104324900, time.UTC),
 				SiChanged:    time.Date(2018, 5, 27, 17, 48, 19, 181726000, time.UTC),
 				FlagResident: true,
+				SecurityID:   509,
+				Usn:          1487527080,
 			},
 		},
 		{
@@ -0,0 +1,168 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"fmt"
+	"io"
+	"strings"
+)
+
+// UsnJournalIndex contains the records of a $UsnJrnl:$J stream keyed by their usn so that the usn kept in a file's $STANDARD_INFORMATION attribute can be looked up.
+type UsnJournalIndex struct {
+	records  map[int64]UsnRecord
+	FirstUsn int64
+	LastUsn  int64
+}
+
+// UsnAnomaly is a uint8 alias describing how the $STANDARD_INFORMATION usn of a file disagrees with the $UsnJrnl.
+type UsnAnomaly uint8
+
+// UsnAnomalyEntry contains an MFT record whose $STANDARD_INFORMATION usn disagrees with the $UsnJrnl. The journal record is only filled in when one was found at the usn.
+type UsnAnomalyEntry struct {
+	RecordNumber   uint32
+	SequenceNumber uint16
+	FullPath       string
+	SiUsn          int64
+	Anomaly        UsnAnomaly
+	JournalRecord  UsnRecord
+}
+
+// $STANDARD_INFORMATION usn anomalies.
+const (
+	// UsnAnomalyNone means the usn is 0, which happens when the journal wasn't active when the file last changed, or that it points at a journal record for the same file.
+	UsnAnomalyNone UsnAnomaly = iota
+
+	// UsnAnomalyBeforeJournal means the usn is older than the oldest journal record. This is normal for files that haven't changed since the journal wrapped, but a large number of these can mean the journal was deleted and recreated.
+	UsnAnomalyBeforeJournal
+
+	// UsnAnomalyAfterJournal means the usn is newer than the newest journal record, so records were removed from the end of the journal or the journal was swapped out.
+	UsnAnomalyAfterJournal
+
+	// UsnAnomalyMissingRecord means the usn is inside the journal's range but no record starts there, so the journal was overwritten or the usn was altered.
+	UsnAnomalyMissingRecord
+
+	// UsnAnomalyFileReferenceMismatch means the journal record at the usn belongs to a different file.
+	UsnAnomalyFileReferenceMismatch
+)
+
+var usnAnomalyNames = map[UsnAnomaly]string{
+	UsnAnomalyNone:                  "",
+	UsnAnomalyBeforeJournal:         "BEFORE_JOURNAL",
+	UsnAnomalyAfterJournal:          "AFTER_JOURNAL",
+	UsnAnomalyMissingRecord:         "MISSING_RECORD",
+	UsnAnomalyFileReferenceMismatch: "FILE_REFERENCE_MISMATCH",
+}
+
+// String returns the name of the usn anomaly receiver. No anomaly is an empty string.
+func (usnAnomaly UsnAnomaly) String() string {
+	if name, ok := usnAnomalyNames[usnAnomaly]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%X", uint8(usnAnomaly))
+}
+
+// BuildUsnJournalIndex reads every record of the usn journal reader into a usn journal index.
+func BuildUsnJournalIndex(usnJournalReader *UsnJournalReader) (usnJournalIndex *UsnJournalIndex, err error) {
+	usnJournalIndex = &UsnJournalIndex{records: make(map[int64]UsnRecord)}
+	for {
+		var usnRecord UsnRecord
+		usnRecord, err = usnJournalReader.Next()
+		if err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			err = fmt.Errorf("failed to read the usn journal: %w", err)
+			return
+		}
+		usnJournalIndex.Add(usnRecord)
+	}
+	return
+}
+
+// Add adds a usn record to the usn journal index receiver and widens the range of usns it covers.
+func (usnJournalIndex *UsnJournalIndex) Add(usnRecord UsnRecord) {
+	if usnJournalIndex.records == nil {
+		usnJournalIndex.records = make(map[int64]UsnRecord)
+	}
+	if len(usnJournalIndex.records) == 0 || usnRecord.Usn < usnJournalIndex.FirstUsn {
+		usnJournalIndex.FirstUsn = usnRecord.Usn
+	}
+	if len(usnJournalIndex.records) == 0 || usnRecord.Usn > usnJournalIndex.LastUsn {
+		usnJournalIndex.LastUsn = usnRecord.Usn
+	}
+	usnJournalIndex.records[usnRecord.Usn] = usnRecord
+	return
+}
+
+// Lookup returns the usn record that starts at the usn.
+func (usnJournalIndex *UsnJournalIndex) Lookup(usn int64) (usnRecord UsnRecord, ok bool) {
+	usnRecord, ok = usnJournalIndex.records[usn]
+	return
+}
+
+// Check looks up the $STANDARD_INFORMATION usn of an mft record in the usn journal index receiver. The journal record is returned whenever one starts at the usn, even if it belongs to another file.
+// NTFS bumps the sequence number of a record when the file is deleted, so the journal record of a deleted file may be one sequence number behind.
+func (usnJournalIndex *UsnJournalIndex) Check(mftRecord MasterFileTableRecord) (usnRecord UsnRecord, usnAnomaly UsnAnomaly) {
+	siUsn := mftRecord.StandardInformationAttributes.Usn
+	if siUsn == 0 || len(usnJournalIndex.records) == 0 {
+		return
+	} else if siUsn < usnJournalIndex.FirstUsn {
+		usnAnomaly = UsnAnomalyBeforeJournal
+		return
+	} else if siUsn > usnJournalIndex.LastUsn {
+		usnAnomaly = UsnAnomalyAfterJournal
+		return
+	}
+
+	usnRecord, ok := usnJournalIndex.Lookup(siUsn)
+	if !ok {
+		usnAnomaly = UsnAnomalyMissingRecord
+		return
+	}
+	recordHeader := mftRecord.RecordHeader
+	sequenceMatches := usnRecord.SequenceNumber == recordHeader.SequenceNumber || (recordHeader.Flags.FlagDeleted && usnRecord.SequenceNumber+1 == recordHeader.SequenceNumber)
+	if usnRecord.RecordNumber != uint64(recordHeader.RecordNumber) || !sequenceMatches {
+		usnAnomaly = UsnAnomalyFileReferenceMismatch
+	}
+	return
+}
+
+// FindUsnAnomalies checks the $STANDARD_INFORMATION usn of every record of an MFT against the usn journal index and returns the records that disagree with the journal. Records older than the journal are left out unless includeBeforeJournal is set since most files on a volume that has been in use for a while are older than its journal.
+func FindUsnAnomalies(reader io.Reader, bytesPerCluster int64, directoryTree DirectoryTree, usnJournalIndex *UsnJournalIndex, includeBeforeJournal bool) (usnAnomalyEntries []UsnAnomalyEntry) {
+	for {
+		buffer := make(RawMasterFileTableRecord, defaultMftRecordSize)
+		_, err := io.ReadFull(reader, buffer)
+		if err != nil {
+			break
+		}
+		mftRecord, err := buffer.Parse(bytesPerCluster)
+		if err != nil || mftRecord.RecordHeader.IsExtensionRecord() {
+			continue
+		}
+		journalRecord, usnAnomaly := usnJournalIndex.Check(mftRecord)
+		if usnAnomaly == UsnAnomalyNone || (usnAnomaly == UsnAnomalyBeforeJournal && !includeBeforeJournal) {
+			continue
+		}
+		usnAnomalyEntries = append(usnAnomalyEntries, UsnAnomalyEntry{
+			RecordNumber:   mftRecord.RecordHeader.RecordNumber,
+			SequenceNumber: mftRecord.RecordHeader.SequenceNumber,
+			FullPath:       GetUsefulMftFields(mftRecord, directoryTree).FullPath,
+			SiUsn:          mftRecord.StandardInformationAttributes.Usn,
+			Anomaly:        usnAnomaly,
+			JournalRecord:  journalRecord,
+		})
+	}
+	return
+}
+
+// Fills in the usn journal fields of a parsed record from the journal record its $STANDARD_INFORMATION usn points at.
+func (usefulMftFields *UsefulMftFields) setUsnJournalFields(mftRecord MasterFileTableRecord, usnJournalIndex *UsnJournalIndex) {
+	usnRecord, usnAnomaly := usnJournalIndex.Check(mftRecord)
+	usefulMftFields.UsnAnomaly = usnAnomaly.String()
+	if usnAnomaly == UsnAnomalyNone && usnRecord.Usn != 0 {
+		usefulMftFields.UsnTimestamp = usnRecord.Timestamp
+		usefulMftFields.UsnReason = strings.Join(usnRecord.Reason.Names(), ";")
+	}
+	return
+}
@@ -0,0 +1,222 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds an mft record with the provided sequence number whose $STANDARD_INFORMATION attribute holds the usn.
+func buildTestUsnMftRecord(recordNumber uint32, sequenceNumber uint16, usn int64, fileName string) RawMasterFileTableRecord {
+	const offsetUsn = 0x40
+	standardInformation := make([]byte, 0x48)
+	binary.LittleEndian.PutUint64(standardInformation[offsetUsn:], uint64(usn))
+	rawMftRecord := buildTestMftRecord(recordNumber, 0, buildTestResidentAttribute(0x10, standardInformation), buildTestFileNameAttribute(fileName, 5))
+	binary.LittleEndian.PutUint16(rawMftRecord[0x10:], sequenceNumber)
+	return rawMftRecord
+}
+
+// Builds a usn journal index out of the journal built by buildTestUsnJournal(). The index covers usns 0x2000 to 0x20C0.
+func buildTestUsnJournalIndex(t *testing.T) *UsnJournalIndex {
+	rawJournal, _ := buildTestUsnJournal(2)
+	usnJournalIndex, err := BuildUsnJournalIndex(NewUsnJournalReader(bytes.NewReader(rawJournal)))
+	if err != nil {
+		t.Fatalf("BuildUsnJournalIndex() error = %v", err)
+	}
+	return usnJournalIndex
+}
+
+// Builds a usn journal index holding only testUsnRecordV2.
+func testUsnJournalIndex() *UsnJournalIndex {
+	usnJournalIndex := &UsnJournalIndex{}
+	usnJournalIndex.Add(testUsnRecordV2)
+	return usnJournalIndex
+}
+
+func TestBuildUsnJournalIndex(t *testing.T) {
+	usnJournalIndex := buildTestUsnJournalIndex(t)
+	if usnJournalIndex.FirstUsn != 0x2000 || usnJournalIndex.LastUsn != 0x20C0 {
+		t.Errorf("BuildUsnJournalIndex() range = 0x%X-0x%X, want 0x2000-0x20C0", usnJournalIndex.FirstUsn, usnJournalIndex.LastUsn)
+	}
+	for _, usn := range []int64{0x2000, 0x2060, 0x20C0} {
+		if _, ok := usnJournalIndex.Lookup(usn); !ok {
+			t.Errorf("Lookup(0x%X) found no record", usn)
+		}
+	}
+	if _, ok := usnJournalIndex.Lookup(0x2010); ok {
+		t.Errorf("Lookup(0x2010) found a record in the middle of another")
+	}
+}
+
+func TestUsnJournalIndex_Check(t *testing.T) {
+	usnJournalIndex := buildTestUsnJournalIndex(t)
+	_, wantRecords := buildTestUsnJournal(2)
+	wantRecordV2, wantRecordV3 := wantRecords[0], wantRecords[1]
+	mftRecord := func(recordNumber uint32, sequenceNumber uint16, deleted bool, usn int64) MasterFileTableRecord {
+		return MasterFileTableRecord{
+			RecordHeader:                  RecordHeader{RecordNumber: recordNumber, SequenceNumber: sequenceNumber, Flags: RecordHeaderFlags{FlagDeleted: deleted}},
+			StandardInformationAttributes: StandardInformationAttribute{Usn: usn},
+		}
+	}
+	tests := []struct {
+		name            string
+		usnJournalIndex *UsnJournalIndex
+		mftRecord       MasterFileTableRecord
+		wantUsnRecord   UsnRecord
+		wantUsnAnomaly  UsnAnomaly
+	}{
+		{
+			name:            "matching journal record",
+			usnJournalIndex: usnJournalIndex,
+			mftRecord:       mftRecord(70, 3, false, 0x2000),
+			wantUsnRecord:   wantRecordV2,
+			wantUsnAnomaly:  UsnAnomalyNone,
+		},
+		{
+			name:            "deleted record one sequence number ahead",
+			usnJournalIndex: usnJournalIndex,
+			mftRecord:       mftRecord(71, 2, true, 0x2060),
+			wantUsnRecord:   wantRecordV3,
+			wantUsnAnomaly:  UsnAnomalyNone,
+		},
+		{
+			name:            "in use record one sequence number ahead",
+			usnJournalIndex: usnJournalIndex,
+			mftRecord:       mftRecord(71, 2, false, 0x2060),
+			wantUsnRecord:   wantRecordV3,
+			wantUsnAnomaly:  UsnAnomalyFileReferenceMismatch,
+		},
+		{
+			name:            "journal record of another file",
+			usnJournalIndex: usnJournalIndex,
+			mftRecord:       mftRecord(80, 3, false, 0x2000),
+			wantUsnRecord:   wantRecordV2,
+			wantUsnAnomaly:  UsnAnomalyFileReferenceMismatch,
+		},
+		{
+			name:            "no record at the usn",
+			usnJournalIndex: usnJournalIndex,
+			mftRecord:       mftRecord(70, 3, false, 0x2010),
+			wantUsnAnomaly:  UsnAnomalyMissingRecord,
+		},
+		{
+			name:            "before the journal",
+			usnJournalIndex: usnJournalIndex,
+			mftRecord:       mftRecord(70, 3, false, 0x1000),
+			wantUsnAnomaly:  UsnAnomalyBeforeJournal,
+		},
+		{
+			name:            "after the journal",
+			usnJournalIndex: usnJournalIndex,
+			mftRecord:       mftRecord(70, 3, false, 0x3000),
+			wantUsnAnomaly:  UsnAnomalyAfterJournal,
+		},
+		{
+			name:            "usn of 0",
+			usnJournalIndex: usnJournalIndex,
+			mftRecord:       mftRecord(70, 3, false, 0),
+			wantUsnAnomaly:  UsnAnomalyNone,
+		},
+		{
+			name:            "empty journal",
+			usnJournalIndex: &UsnJournalIndex{},
+			mftRecord:       mftRecord(70, 3, false, 0x2000),
+			wantUsnAnomaly:  UsnAnomalyNone,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			gotUsnRecord, gotUsnAnomaly := tt.usnJournalIndex.Check(tt.mftRecord)
+			if !reflect.DeepEqual(gotUsnRecord, tt.wantUsnRecord) {
+				t.Errorf(cmp.Diff(gotUsnRecord, tt.wantUsnRecord))
+			}
+			if gotUsnAnomaly != tt.wantUsnAnomaly {
+				t.Errorf("Check() anomaly = %v, want %v", gotUsnAnomaly, tt.wantUsnAnomaly)
+			}
+		})
+	}
+}
+
+func TestUsnAnomaly_String(t *testing.T) {
+	tests := []struct {
+		name       string
+		usnAnomaly UsnAnomaly
+		want       string
+	}{
+		{
+			name:       "no anomaly",
+			usnAnomaly: UsnAnomalyNone,
+			want:       "",
+		},
+		{
+			name:       "file reference mismatch",
+			usnAnomaly: UsnAnomalyFileReferenceMismatch,
+			want:       "FILE_REFERENCE_MISMATCH",
+		},
+		{
+			name:       "unknown anomaly",
+			usnAnomaly: 0x20,
+			want:       "0x20",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.usnAnomaly.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFindUsnAnomalies(t *testing.T) {
+	usnJournalIndex := buildTestUsnJournalIndex(t)
+	var rawMft []byte
+	for _, rawMftRecord := range []RawMasterFileTableRecord{
+		buildTestUsnMftRecord(70, 3, 0x2000, "notes.txt"),
+		buildTestUsnMftRecord(71, 4, 0x2060, "staging"),
+		buildTestUsnMftRecord(72, 1, 0x1000, "old.txt"),
+		buildTestUsnMftRecord(73, 1, 0x2010, "wiped.txt"),
+		buildTestUsnMftRecord(74, 1, 0, "quiet.txt"),
+	} {
+		rawMft = append(rawMft, rawMftRecord...)
+	}
+	_, wantRecords := buildTestUsnJournal(2)
+	wantRecordV3 := wantRecords[1]
+	directoryTree := DirectoryTree{5: "C:\\"}
+
+	tests := []struct {
+		name                 string
+		includeBeforeJournal bool
+		want                 []UsnAnomalyEntry
+	}{
+		{
+			name: "without records older than the journal",
+			want: []UsnAnomalyEntry{
+				{RecordNumber: 71, SequenceNumber: 4, FullPath: "C:\\staging", SiUsn: 0x2060, Anomaly: UsnAnomalyFileReferenceMismatch, JournalRecord: wantRecordV3},
+				{RecordNumber: 73, SequenceNumber: 1, FullPath: "C:\\wiped.txt", SiUsn: 0x2010, Anomaly: UsnAnomalyMissingRecord},
+			},
+		},
+		{
+			name:                 "with records older than the journal",
+			includeBeforeJournal: true,
+			want: []UsnAnomalyEntry{
+				{RecordNumber: 71, SequenceNumber: 4, FullPath: "C:\\staging", SiUsn: 0x2060, Anomaly: UsnAnomalyFileReferenceMismatch, JournalRecord: wantRecordV3},
+				{RecordNumber: 72, SequenceNumber: 1, FullPath: "C:\\old.txt", SiUsn: 0x1000, Anomaly: UsnAnomalyBeforeJournal},
+				{RecordNumber: 73, SequenceNumber: 1, FullPath: "C:\\wiped.txt", SiUsn: 0x2010, Anomaly: UsnAnomalyMissingRecord},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := FindUsnAnomalies(bytes.NewReader(rawMft), 4096, directoryTree, usnJournalIndex, tt.includeBeforeJournal)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
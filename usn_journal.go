@@ -0,0 +1,771 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	bin "github.com/AlecRandazzo/BinaryTransforms"
+)
+
+// RawUsnRecord is a []byte alias for a raw USN_RECORD_V2, USN_RECORD_V3, or USN_RECORD_V4 from the $UsnJrnl:$J stream. Used with the Parse() method.
+// See here for the layouts: https://docs.microsoft.com/en-us/windows/win32/api/winioctl/ns-winioctl-usn_record_v2
+type RawUsnRecord []byte
+
+// UsnRecord contains a parsed USN record. Version 3 and 4 records use 128 bit file ids, for NTFS the lower 64 bits of those are the mft reference. Version 4 records describe the ranges of a file that changed and don't carry a file name, timestamp, or file attributes.
+type UsnRecord struct {
+	Offset               int64
+	MajorVersion         uint16
+	MinorVersion         uint16
+	RecordNumber         uint64
+	SequenceNumber       uint16
+	ParentRecordNumber   uint64
+	ParentSequenceNumber uint16
+	Usn                  int64
+	Timestamp            time.Time
+	Reason               UsnReason
+	SourceInfo           UsnSourceInfo
+	SecurityID           uint32
+	FileAttributes       uint32
+	FileName             string
+	RemainingExtents     uint32
+	Extents              []UsnExtent
+
+	// FilePath, FullPath, and ParentDeleted are filled in by UsnPathResolver.Resolve().
+	FilePath      string
+	FullPath      string
+	ParentDeleted bool
+}
+
+// UsnExtent contains a range of a file that changed, taken from a USN_RECORD_V4.
+type UsnExtent struct {
+	Offset int64
+	Length int64
+}
+
+// UsnReason is a uint32 alias for the reason flags of a USN record.
+type UsnReason uint32
+
+// UsnSourceInfo is a uint32 alias for the source info flags of a USN record.
+type UsnSourceInfo uint32
+
+// USN reason flags. See here for the full list: https://docs.microsoft.com/en-us/windows/win32/api/winioctl/ns-winioctl-usn_record_v2
+const (
+	UsnReasonDataOverwrite             UsnReason = 0x00000001
+	UsnReasonDataExtend                UsnReason = 0x00000002
+	UsnReasonDataTruncation            UsnReason = 0x00000004
+	UsnReasonNamedDataOverwrite        UsnReason = 0x00000010
+	UsnReasonNamedDataExtend           UsnReason = 0x00000020
+	UsnReasonNamedDataTruncation       UsnReason = 0x00000040
+	UsnReasonFileCreate                UsnReason = 0x00000100
+	UsnReasonFileDelete                UsnReason = 0x00000200
+	UsnReasonEAChange                  UsnReason = 0x00000400
+	UsnReasonSecurityChange            UsnReason = 0x00000800
+	UsnReasonRenameOldName             UsnReason = 0x00001000
+	UsnReasonRenameNewName             UsnReason = 0x00002000
+	UsnReasonIndexableChange           UsnReason = 0x00004000
+	UsnReasonBasicInfoChange           UsnReason = 0x00008000
+	UsnReasonHardLinkChange            UsnReason = 0x00010000
+	UsnReasonCompressionChange         UsnReason = 0x00020000
+	UsnReasonEncryptionChange          UsnReason = 0x00040000
+	UsnReasonObjectIDChange            UsnReason = 0x00080000
+	UsnReasonReparsePointChange        UsnReason = 0x00100000
+	UsnReasonStreamChange              UsnReason = 0x00200000
+	UsnReasonTransactedChange          UsnReason = 0x00400000
+	UsnReasonIntegrityChange           UsnReason = 0x00800000
+	UsnReasonDesiredStorageClassChange UsnReason = 0x01000000
+	UsnReasonClose                     UsnReason = 0x80000000
+)
+
+// USN source info flags.
+const (
+	UsnSourceDataManagement              UsnSourceInfo = 0x00000001
+	UsnSourceAuxiliaryData               UsnSourceInfo = 0x00000002
+	UsnSourceReplicationManagement       UsnSourceInfo = 0x00000004
+	UsnSourceClientReplicationManagement UsnSourceInfo = 0x00000008
+)
+
+const (
+	// USN records are 8 byte aligned and never cross a journal page.
+	usnRecordAlignment = 8
+	usnJournalPageSize = 0x1000
+
+	lengthUsnRecordHeader      = 0x08
+	usnJournalBufferSize       = 0x100000
+	fileAttributeDirectory     = 0x10
+	extendRecordNumber         = 11
+	usnJournalFileName         = "$UsnJrnl"
+	usnJournalStreamName       = "$J"
+	maximumUsnPathResolveDepth = 255
+)
+
+// Names returns the names of the flags set in the usn reason receiver.
+func (usnReason UsnReason) Names() (names []string) {
+	flagNames := []struct {
+		flag UsnReason
+		name string
+	}{
+		{UsnReasonDataOverwrite, "DATA_OVERWRITE"},
+		{UsnReasonDataExtend, "DATA_EXTEND"},
+		{UsnReasonDataTruncation, "DATA_TRUNCATION"},
+		{UsnReasonNamedDataOverwrite, "NAMED_DATA_OVERWRITE"},
+		{UsnReasonNamedDataExtend, "NAMED_DATA_EXTEND"},
+		{UsnReasonNamedDataTruncation, "NAMED_DATA_TRUNCATION"},
+		{UsnReasonFileCreate, "FILE_CREATE"},
+		{UsnReasonFileDelete, "FILE_DELETE"},
+		{UsnReasonEAChange, "EA_CHANGE"},
+		{UsnReasonSecurityChange, "SECURITY_CHANGE"},
+		{UsnReasonRenameOldName, "RENAME_OLD_NAME"},
+		{UsnReasonRenameNewName, "RENAME_NEW_NAME"},
+		{UsnReasonIndexableChange, "INDEXABLE_CHANGE"},
+		{UsnReasonBasicInfoChange, "BASIC_INFO_CHANGE"},
+		{UsnReasonHardLinkChange, "HARD_LINK_CHANGE"},
+		{UsnReasonCompressionChange, "COMPRESSION_CHANGE"},
+		{UsnReasonEncryptionChange, "ENCRYPTION_CHANGE"},
+		{UsnReasonObjectIDChange, "OBJECT_ID_CHANGE"},
+		{UsnReasonReparsePointChange, "REPARSE_POINT_CHANGE"},
+		{UsnReasonStreamChange, "STREAM_CHANGE"},
+		{UsnReasonTransactedChange, "TRANSACTED_CHANGE"},
+		{UsnReasonIntegrityChange, "INTEGRITY_CHANGE"},
+		{UsnReasonDesiredStorageClassChange, "DESIRED_STORAGE_CLASS_CHANGE"},
+		{UsnReasonClose, "CLOSE"},
+	}
+	for _, flagName := range flagNames {
+		if usnReason&flagName.flag != 0 {
+			names = append(names, flagName.name)
+		}
+	}
+	return
+}
+
+// String returns the names of the flags set in the usn reason receiver separated by a pipe.
+func (usnReason UsnReason) String() string {
+	return strings.Join(usnReason.Names(), "|")
+}
+
+// Names returns the names of the flags set in the usn source info receiver.
+func (usnSourceInfo UsnSourceInfo) Names() (names []string) {
+	flagNames := []struct {
+		flag UsnSourceInfo
+		name string
+	}{
+		{UsnSourceDataManagement, "DATA_MANAGEMENT"},
+		{UsnSourceAuxiliaryData, "AUXILIARY_DATA"},
+		{UsnSourceReplicationManagement, "REPLICATION_MANAGEMENT"},
+		{UsnSourceClientReplicationManagement, "CLIENT_REPLICATION_MANAGEMENT"},
+	}
+	for _, flagName := range flagNames {
+		if usnSourceInfo&flagName.flag != 0 {
+			names = append(names, flagName.name)
+		}
+	}
+	return
+}
+
+// String returns the names of the flags set in the usn source info receiver separated by a pipe.
+func (usnSourceInfo UsnSourceInfo) String() string {
+	return strings.Join(usnSourceInfo.Names(), "|")
+}
+
+// IsDirectory reports whether the usn record receiver is for a directory.
+func (usnRecord UsnRecord) IsDirectory() bool {
+	return usnRecord.FileAttributes&fileAttributeDirectory != 0
+}
+
+// Parse parses the raw usn record receiver. The receiver may be longer than the record, the record length in its header is used.
+func (rawUsnRecord RawUsnRecord) Parse() (usnRecord UsnRecord, err error) {
+	const offsetRecordLength = 0x00
+	const lengthRecordLength = 0x04
+	const offsetMajorVersion = 0x04
+	const lengthMajorVersion = 0x02
+	const offsetMinorVersion = 0x06
+	const lengthMinorVersion = 0x02
+
+	// Sanity checks
+	sizeOfRawUsnRecord := len(rawUsnRecord)
+	if sizeOfRawUsnRecord < lengthUsnRecordHeader {
+		err = fmt.Errorf("RawUsnRecord.Parse() expected at least %d bytes, instead received %d: %w", lengthUsnRecordHeader, sizeOfRawUsnRecord, ErrTruncated)
+		return
+	}
+	recordLength := int(binary.LittleEndian.Uint32(rawUsnRecord[offsetRecordLength : offsetRecordLength+lengthRecordLength]))
+	if recordLength < lengthUsnRecordHeader || recordLength > sizeOfRawUsnRecord {
+		err = fmt.Errorf("RawUsnRecord.Parse() received a record length of %d with %d bytes", recordLength, sizeOfRawUsnRecord)
+		return
+	}
+	rawRecord := rawUsnRecord[:recordLength]
+
+	usnRecord.MajorVersion = binary.LittleEndian.Uint16(rawRecord[offsetMajorVersion : offsetMajorVersion+lengthMajorVersion])
+	usnRecord.MinorVersion = binary.LittleEndian.Uint16(rawRecord[offsetMinorVersion : offsetMinorVersion+lengthMinorVersion])
+	switch usnRecord.MajorVersion {
+	case 2:
+		err = usnRecord.parseWithFileName(rawRecord, 0x08)
+	case 3:
+		err = usnRecord.parseWithFileName(rawRecord, 0x10)
+	case 4:
+		err = usnRecord.parseExtents(rawRecord)
+	default:
+		err = fmt.Errorf("RawUsnRecord.Parse() received unsupported major version %d", usnRecord.MajorVersion)
+	}
+	return
+}
+
+// Parses the fields of a version 2 or version 3 usn record into the usn record receiver. The two versions only differ in the size of their file references.
+func (usnRecord *UsnRecord) parseWithFileName(rawRecord []byte, lengthFileReference int) (err error) {
+	const offsetFileReference = 0x08
+	offsetParentFileReference := offsetFileReference + lengthFileReference
+	offsetUsn := offsetParentFileReference + lengthFileReference
+	offsetTimestamp := offsetUsn + 0x08
+	offsetReason := offsetTimestamp + 0x08
+	offsetSourceInfo := offsetReason + 0x04
+	offsetSecurityID := offsetSourceInfo + 0x04
+	offsetFileAttributes := offsetSecurityID + 0x04
+	offsetFileNameLength := offsetFileAttributes + 0x04
+	offsetFileNameOffset := offsetFileNameLength + 0x02
+	lengthFixedFields := offsetFileNameOffset + 0x02
+
+	// Sanity checks
+	sizeOfRawRecord := len(rawRecord)
+	if sizeOfRawRecord < lengthFixedFields {
+		err = fmt.Errorf("version %d usn record expected at least %d bytes, instead received %d: %w", usnRecord.MajorVersion, lengthFixedFields, sizeOfRawRecord, ErrTruncated)
+		return
+	}
+
+	usnRecord.RecordNumber, usnRecord.SequenceNumber = parseUsnFileReference(rawRecord[offsetFileReference:])
+	usnRecord.ParentRecordNumber, usnRecord.ParentSequenceNumber = parseUsnFileReference(rawRecord[offsetParentFileReference:])
+	usnRecord.Usn = int64(binary.LittleEndian.Uint64(rawRecord[offsetUsn : offsetUsn+0x08]))
+	usnRecord.Timestamp, err = parseTimestamp(rawRecord[offsetTimestamp:offsetTimestamp+0x08], "usn record")
+	if err != nil {
+		return
+	}
+	usnRecord.Reason = UsnReason(binary.LittleEndian.Uint32(rawRecord[offsetReason : offsetReason+0x04]))
+	usnRecord.SourceInfo = UsnSourceInfo(binary.LittleEndian.Uint32(rawRecord[offsetSourceInfo : offsetSourceInfo+0x04]))
+	usnRecord.SecurityID = binary.LittleEndian.Uint32(rawRecord[offsetSecurityID : offsetSecurityID+0x04])
+	usnRecord.FileAttributes = binary.LittleEndian.Uint32(rawRecord[offsetFileAttributes : offsetFileAttributes+0x04])
+
+	fileNameLength := int(binary.LittleEndian.Uint16(rawRecord[offsetFileNameLength : offsetFileNameLength+0x02]))
+	fileNameOffset := int(binary.LittleEndian.Uint16(rawRecord[offsetFileNameOffset : offsetFileNameOffset+0x02]))
+	if fileNameOffset < lengthFixedFields || fileNameOffset+fileNameLength > sizeOfRawRecord {
+		err = fmt.Errorf("usn record file name at offset %d with length %d is beyond the %d byte record", fileNameOffset, fileNameLength, sizeOfRawRecord)
+		return
+	}
+	if fileNameLength != 0 {
+		usnRecord.FileName, err = bin.UnicodeBytesToASCII(rawRecord[fileNameOffset : fileNameOffset+fileNameLength])
+		if err != nil {
+			err = fmt.Errorf("failed to decode the usn record file name: %w", err)
+			return
+		}
+	}
+	return
+}
+
+// Parses the fields of a version 4 usn record into the usn record receiver.
+func (usnRecord *UsnRecord) parseExtents(rawRecord []byte) (err error) {
+	const offsetFileReference = 0x08
+	const offsetParentFileReference = 0x18
+	const offsetUsn = 0x28
+	const offsetReason = 0x30
+	const offsetSourceInfo = 0x34
+	const offsetRemainingExtents = 0x38
+	const offsetNumberOfExtents = 0x3C
+	const offsetExtentSize = 0x3E
+	const offsetExtents = 0x40
+	const lengthExtent = 0x10
+
+	// Sanity checks
+	sizeOfRawRecord := len(rawRecord)
+	if sizeOfRawRecord < offsetExtents {
+		err = fmt.Errorf("version 4 usn record expected at least %d bytes, instead received %d: %w", offsetExtents, sizeOfRawRecord, ErrTruncated)
+		return
+	}
+
+	usnRecord.RecordNumber, usnRecord.SequenceNumber = parseUsnFileReference(rawRecord[offsetFileReference:])
+	usnRecord.ParentRecordNumber, usnRecord.ParentSequenceNumber = parseUsnFileReference(rawRecord[offsetParentFileReference:])
+	usnRecord.Usn = int64(binary.LittleEndian.Uint64(rawRecord[offsetUsn : offsetUsn+0x08]))
+	usnRecord.Reason = UsnReason(binary.LittleEndian.Uint32(rawRecord[offsetReason : offsetReason+0x04]))
+	usnRecord.SourceInfo = UsnSourceInfo(binary.LittleEndian.Uint32(rawRecord[offsetSourceInfo : offsetSourceInfo+0x04]))
+	usnRecord.RemainingExtents = binary.LittleEndian.Uint32(rawRecord[offsetRemainingExtents : offsetRemainingExtents+0x04])
+
+	numberOfExtents := int(binary.LittleEndian.Uint16(rawRecord[offsetNumberOfExtents : offsetNumberOfExtents+0x02]))
+	extentSize := int(binary.LittleEndian.Uint16(rawRecord[offsetExtentSize : offsetExtentSize+0x02]))
+	if numberOfExtents == 0 {
+		return
+	} else if extentSize < lengthExtent || offsetExtents+numberOfExtents*extentSize > sizeOfRawRecord {
+		err = fmt.Errorf("usn record has %d extents of %d bytes which do not fit in the %d byte record", numberOfExtents, extentSize, sizeOfRawRecord)
+		return
+	}
+	for i := 0; i < numberOfExtents; i++ {
+		rawExtent := rawRecord[offsetExtents+i*extentSize:]
+		usnRecord.Extents = append(usnRecord.Extents, UsnExtent{
+			Offset: int64(binary.LittleEndian.Uint64(rawExtent[0x00:0x08])),
+			Length: int64(binary.LittleEndian.Uint64(rawExtent[0x08:0x10])),
+		})
+	}
+	return
+}
+
+// Returns the mft record number and sequence number of a file reference. The lower 64 bits of a 128 bit NTFS file id are laid out the same as a 64 bit file reference.
+func parseUsnFileReference(rawFileReference []byte) (recordNumber uint64, sequenceNumber uint16) {
+	fileReference := binary.LittleEndian.Uint64(rawFileReference[0x00:0x08])
+	recordNumber = fileReference & 0x0000ffffffffffff
+	sequenceNumber = uint16(fileReference >> 48)
+	return
+}
+
+// UsnJournalReader reads the usn records of a $UsnJrnl:$J stream in order. The start of a $J stream is sparse since Windows deallocates the oldest part of the journal as it grows, these zeros are skipped without being parsed.
+type UsnJournalReader struct {
+	sections []usnJournalSection
+	current  int
+	buffer   []byte
+	start    int
+	end      int
+
+	// offset is where the start of the buffer is within the $J stream.
+	offset int64
+}
+
+// A contiguous part of a $J stream along with where it starts within the stream.
+type usnJournalSection struct {
+	offset int64
+	reader io.Reader
+}
+
+// NewUsnJournalReader returns a usn journal reader for an extracted $UsnJrnl:$J stream.
+func NewUsnJournalReader(journal io.Reader) (usnJournalReader *UsnJournalReader) {
+	usnJournalReader = newUsnJournalReader([]usnJournalSection{{offset: 0, reader: journal}})
+	return
+}
+
+// NewUsnJournalReaderAt returns a usn journal reader for an extracted $UsnJrnl:$J stream of the provided size. The zeros at the start of the stream are skipped by probing the first bytes of each journal page rather than by reading all of them, which matters for journals where the zeroed out part runs into the gigabytes.
+func NewUsnJournalReaderAt(journal io.ReaderAt, size int64) (usnJournalReader *UsnJournalReader) {
+	start := findUsnJournalStart(journal, size)
+	usnJournalReader = newUsnJournalReader([]usnJournalSection{{offset: start, reader: io.NewSectionReader(journal, start, size-start)}})
+	return
+}
+
+// OpenUsnJournal finds the $Extend\$UsnJrnl:$J stream of an NTFS volume and returns a usn journal reader for it. The volume must start at the beginning of the NTFS partition. Sparse data runs of the stream are skipped without reading the volume.
+func OpenUsnJournal(volume io.ReaderAt) (usnJournalReader *UsnJournalReader, err error) {
+	bootSector, err := readBootSector(volume)
+	if err != nil {
+		return
+	}
+	mftDataRuns, err := readMftDataRuns(volume, bootSector)
+	if err != nil {
+		return
+	}
+
+	recordNumber, sequenceNumber, err := findUsnJournalRecord(volume, bootSector, mftDataRuns)
+	if err != nil {
+		return
+	}
+	rawMftRecord, err := readVolumeMftRecord(volume, bootSector, mftDataRuns, recordNumber)
+	if err != nil {
+		err = fmt.Errorf("failed to read the $UsnJrnl record: %w", err)
+		return
+	}
+	if recordSequenceNumber := binary.LittleEndian.Uint16(rawMftRecord[0x10:0x12]); recordSequenceNumber != sequenceNumber {
+		err = fmt.Errorf("$Extend references $UsnJrnl in record %d with sequence number %d, but the record has sequence number %d", recordNumber, sequenceNumber, recordSequenceNumber)
+		return
+	}
+
+	dataRuns, size, err := rawMftRecord.findUsnJournalStream(int64(bootSector.BytesPerCluster()))
+	if err != nil {
+		return
+	}
+	usnJournalReader = newUsnJournalReader(usnJournalSections(volume, dataRuns, size))
+	return
+}
+
+// Returns a usn journal reader over the sections of a $J stream.
+func newUsnJournalReader(sections []usnJournalSection) (usnJournalReader *UsnJournalReader) {
+	usnJournalReader = &UsnJournalReader{
+		sections: sections,
+		buffer:   make([]byte, usnJournalBufferSize),
+	}
+	if len(sections) != 0 {
+		usnJournalReader.offset = sections[0].offset
+	}
+	return
+}
+
+// Next returns the next usn record of the journal. io.EOF is returned once every record has been read. Data that isn't a valid usn record is skipped 8 bytes at a time until the next record is found.
+func (usnJournalReader *UsnJournalReader) Next() (usnRecord UsnRecord, err error) {
+	const offsetMajorVersion = 0x04
+	for {
+		var ok bool
+		ok, err = usnJournalReader.fill(lengthUsnRecordHeader)
+		if err != nil {
+			return
+		} else if !ok {
+			err = usnJournalReader.nextSection()
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		rawHeader := usnJournalReader.buffer[usnJournalReader.start:usnJournalReader.end]
+		recordLength := int(binary.LittleEndian.Uint32(rawHeader[0x00:0x04]))
+		if recordLength == 0 {
+			// skipZeros() only moves past whole words of zeros, so a zero length followed by other bytes is skipped like any other bad record.
+			start := usnJournalReader.start
+			usnJournalReader.skipZeros()
+			if usnJournalReader.start == start {
+				usnJournalReader.start += usnRecordAlignment
+			}
+			continue
+		}
+		majorVersion := binary.LittleEndian.Uint16(rawHeader[offsetMajorVersion : offsetMajorVersion+0x02])
+		if recordLength%usnRecordAlignment != 0 || recordLength > usnJournalPageSize || majorVersion < 2 || majorVersion > 4 {
+			usnJournalReader.start += usnRecordAlignment
+			continue
+		}
+
+		ok, err = usnJournalReader.fill(recordLength)
+		if err != nil {
+			return
+		} else if !ok {
+			// The record was cut off by the end of the section.
+			err = usnJournalReader.nextSection()
+			if err != nil {
+				return
+			}
+			continue
+		}
+
+		var parseErr error
+		recordOffset := usnJournalReader.offset + int64(usnJournalReader.start)
+		usnRecord, parseErr = RawUsnRecord(usnJournalReader.buffer[usnJournalReader.start : usnJournalReader.start+recordLength]).Parse()
+		if parseErr != nil {
+			usnJournalReader.start += usnRecordAlignment
+			continue
+		}
+		usnRecord.Offset = recordOffset
+		usnJournalReader.start += recordLength
+		return
+	}
+}
+
+// Makes sure at least length bytes of the current section are buffered. False is returned when the section ends before that.
+func (usnJournalReader *UsnJournalReader) fill(length int) (ok bool, err error) {
+	if usnJournalReader.current >= len(usnJournalReader.sections) {
+		err = io.EOF
+		return
+	}
+	if usnJournalReader.end-usnJournalReader.start >= length {
+		ok = true
+		return
+	}
+
+	// Move what is left to the front of the buffer and read in more of the section behind it.
+	usnJournalReader.offset += int64(usnJournalReader.start)
+	usnJournalReader.end = copy(usnJournalReader.buffer, usnJournalReader.buffer[usnJournalReader.start:usnJournalReader.end])
+	usnJournalReader.start = 0
+	bytesRead, readErr := io.ReadFull(usnJournalReader.sections[usnJournalReader.current].reader, usnJournalReader.buffer[usnJournalReader.end:])
+	usnJournalReader.end += bytesRead
+	if readErr != nil && readErr != io.EOF && readErr != io.ErrUnexpectedEOF {
+		err = fmt.Errorf("failed to read the usn journal at offset %d: %w", usnJournalReader.offset+int64(usnJournalReader.end), readErr)
+		return
+	}
+	ok = usnJournalReader.end >= length
+	return
+}
+
+// Drops whatever is left of the current section and moves on to the next one.
+func (usnJournalReader *UsnJournalReader) nextSection() (err error) {
+	usnJournalReader.current++
+	usnJournalReader.start = 0
+	usnJournalReader.end = 0
+	if usnJournalReader.current >= len(usnJournalReader.sections) {
+		err = io.EOF
+		return
+	}
+	usnJournalReader.offset = usnJournalReader.sections[usnJournalReader.current].offset
+	return
+}
+
+// Skips past the zeros at the read position of the buffer, 8 bytes at a time.
+func (usnJournalReader *UsnJournalReader) skipZeros() {
+	buffer := usnJournalReader.buffer
+	for usnJournalReader.start+usnRecordAlignment <= usnJournalReader.end && binary.LittleEndian.Uint64(buffer[usnJournalReader.start:usnJournalReader.start+usnRecordAlignment]) == 0 {
+		usnJournalReader.start += usnRecordAlignment
+	}
+	if usnJournalReader.end-usnJournalReader.start < usnRecordAlignment {
+		usnJournalReader.start = usnJournalReader.end
+	}
+	return
+}
+
+// Finds the first journal page of an extracted $J stream that doesn't start with zeros. Pages are probed at doubling distances until one holds data, then the gap since the last zeroed out page is binary searched. This relies on the zeros only being at the start of the stream, which is how Windows frees up journal space.
+func findUsnJournalStart(journal io.ReaderAt, size int64) (start int64) {
+	pages := size / usnJournalPageSize
+	isZeroPage := func(page int64) bool {
+		rawHeader := make([]byte, lengthUsnRecordHeader)
+		_, err := journal.ReadAt(rawHeader, page*usnJournalPageSize)
+		return err == nil && binary.LittleEndian.Uint64(rawHeader) == 0
+	}
+	if pages == 0 || !isZeroPage(0) {
+		return
+	}
+
+	// Page low is known to be zeroed out, page high is the first probe that isn't or the end of the stream.
+	low, high := int64(0), int64(1)
+	for high < pages && isZeroPage(high) {
+		low = high
+		high *= 2
+	}
+	if high > pages {
+		high = pages
+	}
+	for high-low > 1 {
+		middle := low + (high-low)/2
+		if isZeroPage(middle) {
+			low = middle
+		} else {
+			high = middle
+		}
+	}
+	start = high * usnJournalPageSize
+	return
+}
+
+// Looks up the $UsnJrnl entry in the $I30 index of the $Extend directory and returns the file reference of it.
+func findUsnJournalRecord(volume io.ReaderAt, bootSector BootSector, mftDataRuns DataRuns) (recordNumber uint64, sequenceNumber uint16, err error) {
+	const codeIndexRoot = 0x90
+	const codeIndexAllocation = 0xA0
+	const lengthIndexRootHeader = 0x10
+	const indexName = "$I30"
+
+	rawMftRecord, err := readVolumeMftRecord(volume, bootSector, mftDataRuns, extendRecordNumber)
+	if err != nil {
+		err = fmt.Errorf("failed to read the $Extend record: %w", err)
+		return
+	}
+	rawAttributes, err := rawMftRecord.getRawAttributes()
+	if err != nil {
+		err = fmt.Errorf("failed to get the attributes of the $Extend record: %w", err)
+		return
+	}
+
+	var indexEntries IndexEntries
+	for _, rawAttribute := range rawAttributes {
+		if rawAttribute.name() != indexName {
+			continue
+		}
+		switch rawAttribute.attributeType() {
+		case codeIndexRoot:
+			content, contentErr := residentContent(rawAttribute, codeIndexRoot, "IndexRoot")
+			if contentErr != nil || len(content) < lengthIndexRootHeader {
+				continue
+			}
+			rootEntries, _ := parseIndexNode(content[lengthIndexRootHeader:])
+			indexEntries = append(indexEntries, rootEntries...)
+		case codeIndexAllocation:
+			indexAllocation, readErr := readNonResidentAttribute(volume, rawAttribute, int64(bootSector.BytesPerCluster()))
+			if readErr != nil {
+				continue
+			}
+			allocationEntries, _ := ParseIndexAllocation(indexAllocation, int(bootSector.IndexRecordSize), int(bootSector.BytesPerSector))
+			indexEntries = append(indexEntries, allocationEntries...)
+		}
+	}
+
+	for _, indexEntry := range indexEntries {
+		if fileNameIndexKeyName(indexEntry.Key) == usnJournalFileName {
+			recordNumber, sequenceNumber = indexEntry.FileReference()
+			return
+		}
+	}
+	err = errors.New("the $Extend directory does not have a $UsnJrnl entry")
+	return
+}
+
+// Returns the file name stored in the key of a filename index entry. The key is the content of a $FILE_NAME attribute.
+func fileNameIndexKeyName(key []byte) (name string) {
+	const offsetNameLength = 0x40
+	const offsetName = 0x42
+	if len(key) < offsetName {
+		return
+	}
+	nameLength := int(key[offsetNameLength]) * 2
+	if offsetName+nameLength > len(key) {
+		return
+	}
+	name, _ = bin.UnicodeBytesToASCII(key[offsetName : offsetName+nameLength])
+	return
+}
+
+// Returns the data runs and size of the $J stream of the raw $UsnJrnl record receiver.
+func (rawMftRecord RawMasterFileTableRecord) findUsnJournalStream(bytesPerCluster int64) (dataRuns DataRuns, size int64, err error) {
+	const codeData = 0x80
+	const offsetResidentFlag = 0x08
+	const offsetActualSize = 0x30
+	const lengthActualSize = 0x08
+
+	rawAttributes, err := rawMftRecord.getRawAttributes()
+	if err != nil {
+		err = fmt.Errorf("failed to get the attributes of the $UsnJrnl record: %w", err)
+		return
+	}
+	for _, rawAttribute := range rawAttributes {
+		if rawAttribute.attributeType() != codeData || rawAttribute.name() != usnJournalStreamName {
+			continue
+		}
+		if len(rawAttribute) < offsetActualSize+lengthActualSize || RawResidencyFlag(rawAttribute[offsetResidentFlag]).Parse() == true {
+			err = errors.New("the $UsnJrnl:$J stream is not a non-resident attribute")
+			return
+		}
+		var nonResidentDataAttribute NonResidentDataAttribute
+		nonResidentDataAttribute, err = RawNonResidentDataAttribute(rawAttribute).Parse(bytesPerCluster)
+		if err != nil {
+			err = fmt.Errorf("failed to parse the data runs of the $UsnJrnl:$J stream: %w", err)
+			return
+		}
+		dataRuns = nonResidentDataAttribute.DataRuns
+		size = int64(binary.LittleEndian.Uint64(rawAttribute[offsetActualSize : offsetActualSize+lengthActualSize]))
+		return
+	}
+	err = errors.New("the $UsnJrnl record does not have a $J stream, it may be in an extension record")
+	return
+}
+
+// Splits the data runs of a $J stream into sections at every sparse data run. Sparse data runs hold nothing but zeros so they are left out.
+func usnJournalSections(volume io.ReaderAt, dataRuns DataRuns, size int64) (sections []usnJournalSection) {
+	var readers []io.Reader
+	sectionOffset := int64(0)
+	streamOffset := int64(0)
+	for i := 0; i < len(dataRuns) && streamOffset < size; i++ {
+		dataRun := dataRuns[i]
+		length := dataRun.Length
+		if remaining := size - streamOffset; length > remaining {
+			length = remaining
+		}
+		if dataRun.Sparse {
+			if len(readers) != 0 {
+				sections = append(sections, usnJournalSection{offset: sectionOffset, reader: io.MultiReader(readers...)})
+				readers = nil
+			}
+		} else {
+			if len(readers) == 0 {
+				sectionOffset = streamOffset
+			}
+			readers = append(readers, io.NewSectionReader(volume, dataRun.AbsoluteOffset, length))
+		}
+		streamOffset += length
+	}
+	if len(readers) != 0 {
+		sections = append(sections, usnJournalSection{offset: sectionOffset, reader: io.MultiReader(readers...)})
+	}
+	return
+}
+
+// UsnPathResolver resolves the parent directory of usn records to a full path. Parent directories are looked up in the directory tree of the MFT when the sequence number of the parent reference still matches the directory's MFT record. Otherwise the directory has been deleted since the record was written and the directory names seen earlier in the journal are used instead, which is why records should be resolved in journal order.
+type UsnPathResolver struct {
+	VolumeLetter    string
+	DirectoryTree   DirectoryTree
+	SequenceNumbers DirectorySequenceNumbers
+
+	journalDirectories map[usnFileReference]usnJournalDirectory
+}
+
+type usnFileReference struct {
+	recordNumber   uint64
+	sequenceNumber uint16
+}
+
+// A directory as last seen in the usn journal.
+type usnJournalDirectory struct {
+	name   string
+	parent usnFileReference
+}
+
+// NewUsnPathResolver returns a usn path resolver for a directory tree and the sequence numbers of its directories. Parent sequence numbers aren't checked when the sequence numbers are nil.
+func NewUsnPathResolver(volumeLetter string, directoryTree DirectoryTree, sequenceNumbers DirectorySequenceNumbers) (usnPathResolver *UsnPathResolver, err error) {
+	err = volumeLetterCheck(volumeLetter)
+	if err != nil {
+		err = fmt.Errorf("failed to create usn path resolver due to invalid volume letter: %w", err)
+		return
+	}
+	usnPathResolver = &UsnPathResolver{
+		VolumeLetter:       volumeLetter,
+		DirectoryTree:      directoryTree,
+		SequenceNumbers:    sequenceNumbers,
+		journalDirectories: make(map[usnFileReference]usnJournalDirectory),
+	}
+	return
+}
+
+// BuildUsnPathResolver takes an MFT and returns a usn path resolver for its directories.
+func BuildUsnPathResolver(reader io.Reader, volumeLetter string) (usnPathResolver *UsnPathResolver, err error) {
+	unresolvedDirectoryTree, _ := BuildUnresolvedDirectoryTree(reader)
+	directoryTree, err := unresolvedDirectoryTree.Resolve(volumeLetter)
+	if err != nil {
+		return
+	}
+	usnPathResolver, err = NewUsnPathResolver(volumeLetter, directoryTree, unresolvedDirectoryTree.SequenceNumbers())
+	return
+}
+
+// Resolve fills in the file path, full path, and parent deleted fields of the usn record. Records whose parent can't be found are placed under $ORPHANFILE.
+func (usnPathResolver *UsnPathResolver) Resolve(usnRecord *UsnRecord) {
+	if usnRecord.IsDirectory() && usnRecord.FileName != "" {
+		if usnPathResolver.journalDirectories == nil {
+			usnPathResolver.journalDirectories = make(map[usnFileReference]usnJournalDirectory)
+		}
+		usnPathResolver.journalDirectories[usnFileReference{recordNumber: usnRecord.RecordNumber, sequenceNumber: usnRecord.SequenceNumber}] = usnJournalDirectory{
+			name:   usnRecord.FileName,
+			parent: usnFileReference{recordNumber: usnRecord.ParentRecordNumber, sequenceNumber: usnRecord.ParentSequenceNumber},
+		}
+	}
+
+	parent := usnFileReference{recordNumber: usnRecord.ParentRecordNumber, sequenceNumber: usnRecord.ParentSequenceNumber}
+	usnRecord.ParentDeleted = !usnPathResolver.isCurrent(parent)
+	filePath, ok := usnPathResolver.resolveDirectory(parent, 0)
+	if !ok {
+		filePath = fmt.Sprintf("%s:\\$ORPHANFILE", usnPathResolver.VolumeLetter)
+	}
+	usnRecord.FilePath = filePath
+	if usnRecord.FileName != "" {
+		usnRecord.FullPath = joinWindowsPath(filePath, usnRecord.FileName)
+	}
+	return
+}
+
+// Returns the path of a directory, first from the directory tree and then from the directories seen in the journal.
+func (usnPathResolver *UsnPathResolver) resolveDirectory(directory usnFileReference, depth int) (directoryPath string, ok bool) {
+	if directoryPath, ok = usnPathResolver.DirectoryTree[uint32(directory.recordNumber)]; ok && usnPathResolver.isCurrent(directory) {
+		return
+	}
+	ok = false
+	journalDirectory, found := usnPathResolver.journalDirectories[directory]
+	if !found || depth >= maximumUsnPathResolveDepth {
+		return
+	}
+	var parentPath string
+	parentPath, ok = usnPathResolver.resolveDirectory(journalDirectory.parent, depth+1)
+	if ok {
+		directoryPath = joinWindowsPath(parentPath, journalDirectory.name)
+	}
+	return
+}
+
+// Reports whether a file reference points at the current incarnation of a directory. References are assumed to be current when there is nothing to compare them to.
+func (usnPathResolver *UsnPathResolver) isCurrent(directory usnFileReference) bool {
+	if usnPathResolver.SequenceNumbers == nil || directory.sequenceNumber == 0 {
+		return true
+	}
+	sequenceNumber, ok := usnPathResolver.SequenceNumbers[uint32(directory.recordNumber)]
+	return !ok || sequenceNumber == directory.sequenceNumber
+}
+
+// Joins a directory path and a name. The root directory already ends with a backslash.
+func joinWindowsPath(directoryPath string, name string) string {
+	if strings.HasSuffix(directoryPath, "\\") {
+		return directoryPath + name
+	}
+	return directoryPath + "\\" + name
+}
@@ -0,0 +1,556 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds a raw usn record out of a parsed one. Version 2 and 3 records are built with their file name while version 4 records are built with their extents.
+func buildTestUsnRecord(usnRecord UsnRecord) []byte {
+	putFileReference := func(rawFileReference []byte, recordNumber uint64, sequenceNumber uint16) {
+		binary.LittleEndian.PutUint64(rawFileReference, recordNumber|uint64(sequenceNumber)<<48)
+	}
+
+	var rawUsnRecord []byte
+	if usnRecord.MajorVersion == 4 {
+		rawUsnRecord = make([]byte, 0x40+len(usnRecord.Extents)*0x10)
+		putFileReference(rawUsnRecord[0x08:], usnRecord.RecordNumber, usnRecord.SequenceNumber)
+		putFileReference(rawUsnRecord[0x18:], usnRecord.ParentRecordNumber, usnRecord.ParentSequenceNumber)
+		binary.LittleEndian.PutUint64(rawUsnRecord[0x28:], uint64(usnRecord.Usn))
+		binary.LittleEndian.PutUint32(rawUsnRecord[0x30:], uint32(usnRecord.Reason))
+		binary.LittleEndian.PutUint32(rawUsnRecord[0x34:], uint32(usnRecord.SourceInfo))
+		binary.LittleEndian.PutUint32(rawUsnRecord[0x38:], usnRecord.RemainingExtents)
+		binary.LittleEndian.PutUint16(rawUsnRecord[0x3C:], uint16(len(usnRecord.Extents)))
+		binary.LittleEndian.PutUint16(rawUsnRecord[0x3E:], 0x10)
+		for i, extent := range usnRecord.Extents {
+			binary.LittleEndian.PutUint64(rawUsnRecord[0x40+i*0x10:], uint64(extent.Offset))
+			binary.LittleEndian.PutUint64(rawUsnRecord[0x48+i*0x10:], uint64(extent.Length))
+		}
+	} else {
+		lengthFileReference := 0x08
+		if usnRecord.MajorVersion == 3 {
+			lengthFileReference = 0x10
+		}
+		offsetUsn := 0x08 + lengthFileReference*2
+		offsetFileName := offsetUsn + 0x24
+		rawFileName := buildTestUnicode(usnRecord.FileName)
+		rawUsnRecord = make([]byte, (offsetFileName+len(rawFileName)+7)&^7)
+		putFileReference(rawUsnRecord[0x08:], usnRecord.RecordNumber, usnRecord.SequenceNumber)
+		putFileReference(rawUsnRecord[0x08+lengthFileReference:], usnRecord.ParentRecordNumber, usnRecord.ParentSequenceNumber)
+		binary.LittleEndian.PutUint64(rawUsnRecord[offsetUsn:], uint64(usnRecord.Usn))
+		binary.LittleEndian.PutUint64(rawUsnRecord[offsetUsn+0x08:], uint64(usnRecord.Timestamp.UnixNano()/100+116444736000000000))
+		binary.LittleEndian.PutUint32(rawUsnRecord[offsetUsn+0x10:], uint32(usnRecord.Reason))
+		binary.LittleEndian.PutUint32(rawUsnRecord[offsetUsn+0x14:], uint32(usnRecord.SourceInfo))
+		binary.LittleEndian.PutUint32(rawUsnRecord[offsetUsn+0x18:], usnRecord.SecurityID)
+		binary.LittleEndian.PutUint32(rawUsnRecord[offsetUsn+0x1C:], usnRecord.FileAttributes)
+		binary.LittleEndian.PutUint16(rawUsnRecord[offsetUsn+0x20:], uint16(len(rawFileName)))
+		binary.LittleEndian.PutUint16(rawUsnRecord[offsetUsn+0x22:], uint16(offsetFileName))
+		copy(rawUsnRecord[offsetFileName:], rawFileName)
+	}
+	binary.LittleEndian.PutUint32(rawUsnRecord[0x00:], uint32(len(rawUsnRecord)))
+	binary.LittleEndian.PutUint16(rawUsnRecord[0x04:], usnRecord.MajorVersion)
+	binary.LittleEndian.PutUint16(rawUsnRecord[0x06:], usnRecord.MinorVersion)
+	return rawUsnRecord
+}
+
+var (
+	testUsnTimestamp = time.Date(2020, 5, 17, 13, 45, 12, 123456700, time.UTC)
+
+	testUsnRecordV2 = UsnRecord{
+		MajorVersion:         2,
+		RecordNumber:         70,
+		SequenceNumber:       3,
+		ParentRecordNumber:   40,
+		ParentSequenceNumber: 2,
+		Usn:                  0x2000,
+		Timestamp:            testUsnTimestamp,
+		Reason:               UsnReasonFileCreate | UsnReasonClose,
+		SecurityID:           0x100,
+		FileAttributes:       0x20,
+		FileName:             "notes.txt",
+	}
+	testUsnRecordV3 = UsnRecord{
+		MajorVersion:         3,
+		RecordNumber:         71,
+		SequenceNumber:       1,
+		ParentRecordNumber:   5,
+		ParentSequenceNumber: 5,
+		Usn:                  0x2060,
+		Timestamp:            testUsnTimestamp,
+		Reason:               UsnReasonRenameNewName,
+		SourceInfo:           UsnSourceDataManagement,
+		FileAttributes:       fileAttributeDirectory,
+		FileName:             "staging",
+	}
+	testUsnRecordV4 = UsnRecord{
+		MajorVersion:         4,
+		RecordNumber:         70,
+		SequenceNumber:       3,
+		ParentRecordNumber:   40,
+		ParentSequenceNumber: 2,
+		Usn:                  0x20C0,
+		Reason:               UsnReasonDataOverwrite,
+		Extents:              []UsnExtent{{Offset: 0, Length: 0x1000}, {Offset: 0x8000, Length: 0x2000}},
+	}
+)
+
+func TestRawUsnRecord_Parse(t *testing.T) {
+	rawFileNameOverrun := buildTestUsnRecord(testUsnRecordV2)
+	binary.LittleEndian.PutUint16(rawFileNameOverrun[0x38:], 0x100)
+	rawUnsupportedVersion := buildTestUsnRecord(testUsnRecordV2)
+	binary.LittleEndian.PutUint16(rawUnsupportedVersion[0x04:], 5)
+	rawExtentOverrun := buildTestUsnRecord(testUsnRecordV4)
+	binary.LittleEndian.PutUint16(rawExtentOverrun[0x3C:], 3)
+
+	tests := []struct {
+		name    string
+		raw     RawUsnRecord
+		want    UsnRecord
+		wantErr bool
+	}{
+		{
+			name: "version 2",
+			raw:  buildTestUsnRecord(testUsnRecordV2),
+			want: testUsnRecordV2,
+		},
+		{
+			name: "version 3",
+			raw:  buildTestUsnRecord(testUsnRecordV3),
+			want: testUsnRecordV3,
+		},
+		{
+			name: "version 4",
+			raw:  buildTestUsnRecord(testUsnRecordV4),
+			want: testUsnRecordV4,
+		},
+		{
+			name: "bytes after the record are ignored",
+			raw:  append(buildTestUsnRecord(testUsnRecordV2), buildTestUsnRecord(testUsnRecordV3)...),
+			want: testUsnRecordV2,
+		},
+		{
+			name:    "file name beyond the record",
+			raw:     rawFileNameOverrun,
+			wantErr: true,
+		},
+		{
+			name:    "extents beyond the record",
+			raw:     rawExtentOverrun,
+			wantErr: true,
+		},
+		{
+			name:    "unsupported version",
+			raw:     rawUnsupportedVersion,
+			wantErr: true,
+		},
+		{
+			name:    "record length beyond the bytes",
+			raw:     buildTestUsnRecord(testUsnRecordV2)[:0x40],
+			wantErr: true,
+		},
+		{
+			name:    "too small",
+			raw:     RawUsnRecord{0x01, 0x02},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestUsnReason_String(t *testing.T) {
+	tests := []struct {
+		name   string
+		reason UsnReason
+		want   string
+	}{
+		{name: "create and close", reason: UsnReasonFileCreate | UsnReasonClose, want: "FILE_CREATE|CLOSE"},
+		{name: "rename", reason: UsnReasonRenameOldName | UsnReasonRenameNewName, want: "RENAME_OLD_NAME|RENAME_NEW_NAME"},
+		{name: "none", reason: 0, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.reason.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUsnSourceInfo_String(t *testing.T) {
+	tests := []struct {
+		name       string
+		sourceInfo UsnSourceInfo
+		want       string
+	}{
+		{name: "data management", sourceInfo: UsnSourceDataManagement, want: "DATA_MANAGEMENT"},
+		{name: "replication", sourceInfo: UsnSourceReplicationManagement | UsnSourceClientReplicationManagement, want: "REPLICATION_MANAGEMENT|CLIENT_REPLICATION_MANAGEMENT"},
+		{name: "none", sourceInfo: 0, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.sourceInfo.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// Builds a $J stream with zeroed out leading pages followed by a page holding the v2 and v3 test records and a page holding the v4 test record. Some garbage sits between the first two records.
+func buildTestUsnJournal(leadingPages int) (rawJournal []byte, want []UsnRecord) {
+	dataStart := leadingPages * usnJournalPageSize
+	rawJournal = make([]byte, dataStart+2*usnJournalPageSize)
+
+	offset := dataStart
+	for _, usnRecord := range []UsnRecord{testUsnRecordV2, testUsnRecordV3} {
+		rawUsnRecord := buildTestUsnRecord(usnRecord)
+		copy(rawJournal[offset:], rawUsnRecord)
+		usnRecord.Offset = int64(offset)
+		want = append(want, usnRecord)
+		offset += len(rawUsnRecord)
+		copy(rawJournal[offset:], []byte{0x03, 0x00, 0x00, 0x00, 0xFF, 0xFF, 0xFF, 0xFF})
+		offset += 8
+	}
+
+	offset = dataStart + usnJournalPageSize
+	copy(rawJournal[offset:], buildTestUsnRecord(testUsnRecordV4))
+	usnRecord := testUsnRecordV4
+	usnRecord.Offset = int64(offset)
+	want = append(want, usnRecord)
+	return
+}
+
+// Reads every record of the usn journal reader.
+func readTestUsnJournal(usnJournalReader *UsnJournalReader) (usnRecords []UsnRecord, err error) {
+	for {
+		var usnRecord UsnRecord
+		usnRecord, err = usnJournalReader.Next()
+		if err == io.EOF {
+			err = nil
+			return
+		} else if err != nil {
+			return
+		}
+		usnRecords = append(usnRecords, usnRecord)
+	}
+}
+
+func TestUsnJournalReader_Next(t *testing.T) {
+	rawJournal, want := buildTestUsnJournal(300)
+	rawRecord := buildTestUsnRecord(testUsnRecordV2)
+	afterZeroLength, _ := RawUsnRecord(rawRecord).Parse()
+	afterZeroLength.Offset = 8
+	zeroLengthJournal := append([]byte{0x00, 0x00, 0x00, 0x00, 0x02, 0x00, 0x00, 0x00}, rawRecord...)
+	tests := []struct {
+		name             string
+		usnJournalReader *UsnJournalReader
+		want             []UsnRecord
+	}{
+		{
+			name:             "extracted stream",
+			usnJournalReader: NewUsnJournalReader(bytes.NewReader(rawJournal)),
+			want:             want,
+		},
+		{
+			name:             "extracted stream with random access",
+			usnJournalReader: NewUsnJournalReaderAt(bytes.NewReader(rawJournal), int64(len(rawJournal))),
+			want:             want,
+		},
+		{
+			name:             "record cut off by the end of the stream",
+			usnJournalReader: NewUsnJournalReader(bytes.NewReader(rawJournal[:300*usnJournalPageSize+0x20])),
+		},
+		{
+			name:             "empty stream",
+			usnJournalReader: NewUsnJournalReader(bytes.NewReader(nil)),
+		},
+		{
+			name:             "zero record length with a version",
+			usnJournalReader: NewUsnJournalReader(bytes.NewReader(zeroLengthJournal)),
+			want:             []UsnRecord{afterZeroLength},
+		},
+		{
+			name:             "zero record length with a version and random access",
+			usnJournalReader: NewUsnJournalReaderAt(bytes.NewReader(zeroLengthJournal), int64(len(zeroLengthJournal))),
+			want:             []UsnRecord{afterZeroLength},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := readTestUsnJournal(tt.usnJournalReader)
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func Test_findUsnJournalStart(t *testing.T) {
+	tests := []struct {
+		name         string
+		leadingPages int
+	}{
+		{name: "no leading zeros", leadingPages: 0},
+		{name: "one zeroed out page", leadingPages: 1},
+		{name: "between two probes", leadingPages: 37},
+		{name: "on a probe", leadingPages: 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rawJournal, _ := buildTestUsnJournal(tt.leadingPages)
+			want := int64(tt.leadingPages * usnJournalPageSize)
+			if got := findUsnJournalStart(bytes.NewReader(rawJournal), int64(len(rawJournal))); got != want {
+				t.Errorf("findUsnJournalStart() = %v, want %v", got, want)
+			}
+		})
+	}
+
+	if got := findUsnJournalStart(bytes.NewReader(make([]byte, 8*usnJournalPageSize)), 8*usnJournalPageSize); got != 8*usnJournalPageSize {
+		t.Errorf("findUsnJournalStart() of a zeroed out stream = %v, want %v", got, 8*usnJournalPageSize)
+	}
+}
+
+// Builds an mft record as it sits on a volume, with a sequence number and an update sequence array.
+func buildTestVolumeMftRecord(recordNumber uint32, sequenceNumber uint16, rawAttributes ...[]byte) []byte {
+	const updateSequenceOffset = 0x30
+	const bytesPerSector = 512
+	rawMftRecord := buildTestMftRecord(recordNumber, 0, rawAttributes...)
+	binary.LittleEndian.PutUint16(rawMftRecord[0x10:], sequenceNumber)
+	binary.LittleEndian.PutUint16(rawMftRecord[0x04:], updateSequenceOffset)
+	binary.LittleEndian.PutUint16(rawMftRecord[0x06:], uint16(len(rawMftRecord)/bytesPerSector+1))
+	rawMftRecord[updateSequenceOffset] = 0x01
+	for i := 1; i <= len(rawMftRecord)/bytesPerSector; i++ {
+		sectorEnd := i*bytesPerSector - 2
+		copy(rawMftRecord[updateSequenceOffset+i*2:], rawMftRecord[sectorEnd:sectorEnd+2])
+		rawMftRecord[sectorEnd] = 0x01
+		rawMftRecord[sectorEnd+1] = 0x00
+	}
+	return rawMftRecord
+}
+
+// Builds a resident $I30 index root attribute holding filename index entries for the provided names and file references.
+func buildTestIndexRootAttribute(fileNames []string, fileReferences []uint64) []byte {
+	const offsetName = 0x18
+	const offsetContent = 0x20
+	var rawEntries []byte
+	for i, fileName := range fileNames {
+		key := make([]byte, 0x42)
+		key[0x40] = byte(len(fileName))
+		key = append(key, buildTestUnicode(fileName)...)
+		rawEntry := make([]byte, (0x10+len(key)+7)&^7)
+		binary.LittleEndian.PutUint64(rawEntry[0x00:], fileReferences[i])
+		binary.LittleEndian.PutUint16(rawEntry[0x08:], uint16(len(rawEntry)))
+		binary.LittleEndian.PutUint16(rawEntry[0x0A:], uint16(len(key)))
+		copy(rawEntry[0x10:], key)
+		rawEntries = append(rawEntries, rawEntry...)
+	}
+	lastEntry := make([]byte, 0x10)
+	binary.LittleEndian.PutUint16(lastEntry[0x08:], 0x10)
+	binary.LittleEndian.PutUint16(lastEntry[0x0C:], indexEntryLast)
+	rawEntries = append(rawEntries, lastEntry...)
+
+	content := make([]byte, 0x20, 0x20+len(rawEntries))
+	binary.LittleEndian.PutUint32(content[0x00:], 0x30)
+	binary.LittleEndian.PutUint32(content[0x08:], 4096)
+	binary.LittleEndian.PutUint32(content[0x10:], 0x10)
+	binary.LittleEndian.PutUint32(content[0x14:], uint32(0x10+len(rawEntries)))
+	binary.LittleEndian.PutUint32(content[0x18:], uint32(0x10+len(rawEntries)))
+	content = append(content, rawEntries...)
+
+	rawAttribute := make([]byte, (offsetContent+len(content)+7)&^7)
+	binary.LittleEndian.PutUint32(rawAttribute[0x00:], 0x90)
+	binary.LittleEndian.PutUint32(rawAttribute[0x04:], uint32(len(rawAttribute)))
+	rawAttribute[0x09] = 4
+	binary.LittleEndian.PutUint16(rawAttribute[0x0A:], offsetName)
+	binary.LittleEndian.PutUint32(rawAttribute[0x10:], uint32(len(content)))
+	binary.LittleEndian.PutUint16(rawAttribute[0x14:], offsetContent)
+	copy(rawAttribute[offsetName:], buildTestUnicode("$I30"))
+	copy(rawAttribute[offsetContent:], content)
+	return rawAttribute
+}
+
+// Builds a volume whose MFT is split over clusters 4 to 7 and 20 to 27. The $UsnJrnl record is record 40 in the second fragment and its $J stream starts with two sparse clusters followed by cluster 40.
+func buildTestUsnJournalVolume(usnJournalSequenceNumber uint16, rawJournalData []byte) []byte {
+	const bytesPerCluster = 4096
+	volume := make([]byte, 41*bytesPerCluster)
+	copy(volume, buildTestBootSector(4, 0x1234567890abcdef))
+
+	writeRecord := func(recordNumber uint32, rawMftRecord []byte) {
+		offset := 4*bytesPerCluster + int(recordNumber)*1024
+		if recordNumber >= 16 {
+			offset = 20*bytesPerCluster + int(recordNumber-16)*1024
+		}
+		copy(volume[offset:], rawMftRecord)
+	}
+	writeRecord(0, buildTestVolumeMftRecord(0, 1, buildTestNonResidentAttribute(0x80, 48*1024, []byte{0x11, 0x04, 0x04, 0x11, 0x08, 0x10})))
+	writeRecord(11, buildTestVolumeMftRecord(11, 11, buildTestIndexRootAttribute([]string{"$ObjId", "$UsnJrnl"}, []uint64{25 | 1<<48, 40 | 2<<48})))
+
+	rawMaxStream := buildTestLoggedUtilityStreamAttribute("$Max", make([]byte, 0x20))
+	binary.LittleEndian.PutUint32(rawMaxStream[0x00:], 0x80)
+	rawJournalStream := buildTestNonResidentLoggedUtilityStreamAttribute("$J", 2*bytesPerCluster+uint64(len(rawJournalData)), []byte{0x01, 0x02, 0x11, 0x01, 0x28})
+	binary.LittleEndian.PutUint32(rawJournalStream[0x00:], 0x80)
+	writeRecord(40, buildTestVolumeMftRecord(40, usnJournalSequenceNumber, buildTestFileNameAttribute("$UsnJrnl", 11), rawJournalStream, rawMaxStream))
+
+	copy(volume[40*bytesPerCluster:], rawJournalData)
+	return volume
+}
+
+func TestOpenUsnJournal(t *testing.T) {
+	rawJournalData := append(buildTestUsnRecord(testUsnRecordV2), buildTestUsnRecord(testUsnRecordV3)...)
+	wantV2 := testUsnRecordV2
+	wantV2.Offset = 2 * 4096
+	wantV3 := testUsnRecordV3
+	wantV3.Offset = 2*4096 + int64(len(buildTestUsnRecord(testUsnRecordV2)))
+
+	tests := []struct {
+		name    string
+		volume  []byte
+		want    []UsnRecord
+		wantErr bool
+	}{
+		{
+			name:   "journal with a sparse start",
+			volume: buildTestUsnJournalVolume(2, rawJournalData),
+			want:   []UsnRecord{wantV2, wantV3},
+		},
+		{
+			name:    "$UsnJrnl record has been reused",
+			volume:  buildTestUsnJournalVolume(3, rawJournalData),
+			wantErr: true,
+		},
+		{
+			name:    "volume without a boot sector",
+			volume:  make([]byte, 4096),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usnJournalReader, err := OpenUsnJournal(bytes.NewReader(tt.volume))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("OpenUsnJournal() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			got, err := readTestUsnJournal(usnJournalReader)
+			if err != nil {
+				t.Fatalf("Next() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestUsnPathResolver_Resolve(t *testing.T) {
+	directoryTree := DirectoryTree{5: "C:\\", 40: "C:\\Users", 41: "C:\\Users\\alec"}
+	sequenceNumbers := DirectorySequenceNumbers{5: 5, 40: 2, 41: 7}
+
+	// A directory that was created under record 41's previous incarnation and later deleted along with it.
+	deletedParent := UsnRecord{RecordNumber: 41, SequenceNumber: 6, ParentRecordNumber: 40, ParentSequenceNumber: 2, FileAttributes: fileAttributeDirectory, FileName: "old"}
+	deletedChild := UsnRecord{RecordNumber: 90, SequenceNumber: 1, ParentRecordNumber: 41, ParentSequenceNumber: 6, FileName: "draft.txt"}
+
+	tests := []struct {
+		name          string
+		history       []UsnRecord
+		usnRecord     UsnRecord
+		wantFilePath  string
+		wantFullPath  string
+		wantDeleted   bool
+		noSequenceMap bool
+	}{
+		{
+			name:         "parent in the directory tree",
+			usnRecord:    UsnRecord{ParentRecordNumber: 41, ParentSequenceNumber: 7, FileName: "notes.txt"},
+			wantFilePath: "C:\\Users\\alec",
+			wantFullPath: "C:\\Users\\alec\\notes.txt",
+		},
+		{
+			name:         "parent is the root",
+			usnRecord:    UsnRecord{ParentRecordNumber: 5, ParentSequenceNumber: 5, FileName: "pagefile.sys"},
+			wantFilePath: "C:\\",
+			wantFullPath: "C:\\pagefile.sys",
+		},
+		{
+			name:         "deleted parent seen earlier in the journal",
+			history:      []UsnRecord{deletedParent},
+			usnRecord:    deletedChild,
+			wantFilePath: "C:\\Users\\old",
+			wantFullPath: "C:\\Users\\old\\draft.txt",
+			wantDeleted:  true,
+		},
+		{
+			name:         "deleted parent never seen in the journal",
+			usnRecord:    deletedChild,
+			wantFilePath: "C:\\$ORPHANFILE",
+			wantFullPath: "C:\\$ORPHANFILE\\draft.txt",
+			wantDeleted:  true,
+		},
+		{
+			name:          "sequence numbers are not checked without a sequence number map",
+			usnRecord:     deletedChild,
+			wantFilePath:  "C:\\Users\\alec",
+			wantFullPath:  "C:\\Users\\alec\\draft.txt",
+			noSequenceMap: true,
+		},
+		{
+			name:         "unknown parent",
+			usnRecord:    UsnRecord{ParentRecordNumber: 500, ParentSequenceNumber: 1, FileName: "lost.txt"},
+			wantFilePath: "C:\\$ORPHANFILE",
+			wantFullPath: "C:\\$ORPHANFILE\\lost.txt",
+		},
+		{
+			name:         "version 4 record without a file name",
+			usnRecord:    UsnRecord{ParentRecordNumber: 40, ParentSequenceNumber: 2},
+			wantFilePath: "C:\\Users",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			resolverSequenceNumbers := sequenceNumbers
+			if tt.noSequenceMap {
+				resolverSequenceNumbers = nil
+			}
+			usnPathResolver, err := NewUsnPathResolver("C", directoryTree, resolverSequenceNumbers)
+			if err != nil {
+				t.Fatalf("NewUsnPathResolver() error = %v", err)
+			}
+			for _, usnRecord := range tt.history {
+				usnPathResolver.Resolve(&usnRecord)
+			}
+			usnRecord := tt.usnRecord
+			usnPathResolver.Resolve(&usnRecord)
+			if usnRecord.FilePath != tt.wantFilePath || usnRecord.FullPath != tt.wantFullPath || usnRecord.ParentDeleted != tt.wantDeleted {
+				t.Errorf("Resolve() = %v, %v, %v, want %v, %v, %v", usnRecord.FilePath, usnRecord.FullPath, usnRecord.ParentDeleted, tt.wantFilePath, tt.wantFullPath, tt.wantDeleted)
+			}
+		})
+	}
+
+	if _, err := NewUsnPathResolver("", directoryTree, sequenceNumbers); err == nil {
+		t.Errorf("NewUsnPathResolver() with a blank volume letter did not return an error")
+	}
+}
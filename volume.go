@@ -0,0 +1,365 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	bin "github.com/AlecRandazzo/BinaryTransforms"
+)
+
+// RawVolumeNameAttribute is a []byte alias for a raw $VOLUME_NAME attribute. Used with the Parse() method.
+type RawVolumeNameAttribute []byte
+
+// RawVolumeInformationAttribute is a []byte alias for a raw $VOLUME_INFORMATION attribute. Used with the Parse() method.
+// See here for the layout: https://flatcap.org/linux-ntfs/ntfs/attributes/volume_information.html
+type RawVolumeInformationAttribute []byte
+
+// RawBootSector is a []byte alias for the first sector of an NTFS volume. Used with the Parse() method.
+// See here for the layout: https://flatcap.org/linux-ntfs/ntfs/files/boot.html
+type RawBootSector []byte
+
+// VolumeInformation contains a parsed $VOLUME_INFORMATION attribute.
+type VolumeInformation struct {
+	MajorVersion byte
+	MinorVersion byte
+	Flags        VolumeFlags
+}
+
+// VolumeFlags is a uint16 alias for the flags of a $VOLUME_INFORMATION attribute.
+type VolumeFlags uint16
+
+// BootSector contains a parsed NTFS boot sector.
+type BootSector struct {
+	OEMID             string
+	BytesPerSector    uint16
+	SectorsPerCluster uint32
+	TotalSectors      uint64
+	MftCluster        uint64
+	MftMirrCluster    uint64
+	MftRecordSize     uint32
+	IndexRecordSize   uint32
+	SerialNumber      uint64
+}
+
+// VolumeMetadata contains the metadata of an NTFS volume. The label, version, and flags come from the $Volume record while the serial number and geometry come from the boot sector.
+type VolumeMetadata struct {
+	Label        string
+	MajorVersion byte
+	MinorVersion byte
+	Flags        VolumeFlags
+	BootSector   BootSector
+}
+
+// Volume flags. See here for the full list: https://flatcap.org/linux-ntfs/ntfs/attributes/volume_information.html
+const (
+	VolumeFlagDirty             VolumeFlags = 0x0001
+	VolumeFlagResizeLogFile     VolumeFlags = 0x0002
+	VolumeFlagUpgradeOnMount    VolumeFlags = 0x0004
+	VolumeFlagMountedOnNT4      VolumeFlags = 0x0008
+	VolumeFlagDeleteUSNUnderway VolumeFlags = 0x0010
+	VolumeFlagRepairObjectIDs   VolumeFlags = 0x0020
+	VolumeFlagChkdskUnderway    VolumeFlags = 0x4000
+	VolumeFlagModifiedByChkdsk  VolumeFlags = 0x8000
+	lengthBootSector                        = 0x200
+	volumeRecordNumber                      = 3
+	defaultMftRecordSize                    = 1024
+)
+
+// Names returns the names of the flags set in the volume flags receiver.
+func (volumeFlags VolumeFlags) Names() (names []string) {
+	flagNames := []struct {
+		flag VolumeFlags
+		name string
+	}{
+		{VolumeFlagDirty, "DIRTY"},
+		{VolumeFlagResizeLogFile, "RESIZE_LOG_FILE"},
+		{VolumeFlagUpgradeOnMount, "UPGRADE_ON_MOUNT"},
+		{VolumeFlagMountedOnNT4, "MOUNTED_ON_NT4"},
+		{VolumeFlagDeleteUSNUnderway, "DELETE_USN_UNDERWAY"},
+		{VolumeFlagRepairObjectIDs, "REPAIR_OBJECT_IDS"},
+		{VolumeFlagChkdskUnderway, "CHKDSK_UNDERWAY"},
+		{VolumeFlagModifiedByChkdsk, "MODIFIED_BY_CHKDSK"},
+	}
+	for _, flagName := range flagNames {
+		if volumeFlags&flagName.flag != 0 {
+			names = append(names, flagName.name)
+		}
+	}
+	return
+}
+
+// String returns the names of the flags set in the volume flags receiver separated by a pipe.
+func (volumeFlags VolumeFlags) String() string {
+	return strings.Join(volumeFlags.Names(), "|")
+}
+
+// IsDirty reports whether the volume was not cleanly dismounted.
+func (volumeFlags VolumeFlags) IsDirty() bool {
+	return volumeFlags&VolumeFlagDirty != 0
+}
+
+// Parse parses the raw $VOLUME_NAME attribute receiver and returns the volume label. Volumes without a label return an empty string.
+func (rawVolumeNameAttribute RawVolumeNameAttribute) Parse() (volumeName string, err error) {
+	content, err := residentContent(rawVolumeNameAttribute, 0x60, "RawVolumeNameAttribute")
+	if err != nil || len(content) == 0 {
+		return
+	}
+	volumeName, err = bin.UnicodeBytesToASCII(content)
+	return
+}
+
+// Parse parses the raw $VOLUME_INFORMATION attribute receiver and returns the NTFS version and volume flags.
+func (rawVolumeInformationAttribute RawVolumeInformationAttribute) Parse() (volumeInformation VolumeInformation, err error) {
+	const offsetMajorVersion = 0x08
+	const offsetMinorVersion = 0x09
+	const offsetFlags = 0x0A
+	const lengthFlags = 0x02
+
+	content, err := residentContent(rawVolumeInformationAttribute, 0x70, "RawVolumeInformationAttribute")
+	if err != nil {
+		return
+	} else if len(content) < offsetFlags+lengthFlags {
+		err = fmt.Errorf("RawVolumeInformationAttribute.Parse() expected at least %d bytes of content, instead received %d: %w", offsetFlags+lengthFlags, len(content), ErrTruncated)
+		return
+	}
+	volumeInformation.MajorVersion = content[offsetMajorVersion]
+	volumeInformation.MinorVersion = content[offsetMinorVersion]
+	volumeInformation.Flags = VolumeFlags(binary.LittleEndian.Uint16(content[offsetFlags : offsetFlags+lengthFlags]))
+	return
+}
+
+// Returns the content of a resident attribute after checking that it is the expected attribute type.
+func residentContent(rawAttribute []byte, attributeType byte, typeName string) (content []byte, err error) {
+	const offsetResidentFlag = 0x08
+	const offsetContentLength = 0x10
+	const lengthContentLength = 0x04
+	const offsetContentOffset = 0x14
+	const lengthContentOffset = 0x02
+
+	// Sanity checks
+	sizeOfRawAttribute := len(rawAttribute)
+	if sizeOfRawAttribute == 0 {
+		err = fmt.Errorf("%s.Parse(): %w", typeName, ErrNilBytes)
+		return
+	} else if rawAttribute[0x00] != attributeType {
+		err = fmt.Errorf("%s.Parse() received the wrong attribute type. Attribute magic number is %x", typeName, rawAttribute[0x00])
+		return
+	} else if sizeOfRawAttribute < offsetContentOffset+lengthContentOffset {
+		err = fmt.Errorf("%s.Parse() expected at least %d bytes, instead received %d: %w", typeName, offsetContentOffset+lengthContentOffset, sizeOfRawAttribute, ErrTruncated)
+		return
+	} else if RawResidencyFlag(rawAttribute[offsetResidentFlag]).Parse() == false {
+		err = fmt.Errorf("%s.Parse() received a non-resident attribute", typeName)
+		return
+	}
+
+	contentLength := int(binary.LittleEndian.Uint32(rawAttribute[offsetContentLength : offsetContentLength+lengthContentLength]))
+	contentOffset := int(binary.LittleEndian.Uint16(rawAttribute[offsetContentOffset : offsetContentOffset+lengthContentOffset]))
+	if contentOffset+contentLength > sizeOfRawAttribute {
+		err = fmt.Errorf("%s.Parse() content at offset %d with length %d is beyond the attribute", typeName, contentOffset, contentLength)
+		return
+	}
+	content = rawAttribute[contentOffset : contentOffset+contentLength]
+	return
+}
+
+// Parse parses the raw boot sector receiver.
+func (rawBootSector RawBootSector) Parse() (bootSector BootSector, err error) {
+	const offsetOEMID = 0x03
+	const lengthOEMID = 0x08
+	const offsetBytesPerSector = 0x0B
+	const offsetSectorsPerCluster = 0x0D
+	const offsetTotalSectors = 0x28
+	const offsetMftCluster = 0x30
+	const offsetMftMirrCluster = 0x38
+	const offsetClustersPerMftRecord = 0x40
+	const offsetClustersPerIndexRecord = 0x44
+	const offsetSerialNumber = 0x48
+	const offsetEndMarker = 0x1FE
+
+	// Sanity checks
+	if len(rawBootSector) < lengthBootSector {
+		err = fmt.Errorf("RawBootSector.Parse() expected at least %d bytes, instead received %d: %w", lengthBootSector, len(rawBootSector), ErrTruncated)
+		return
+	}
+	bootSector.OEMID = string(rawBootSector[offsetOEMID : offsetOEMID+lengthOEMID])
+	if bootSector.OEMID != "NTFS    " {
+		err = fmt.Errorf("RawBootSector.Parse() received a boot sector with an OEM id of %q instead of NTFS", bootSector.OEMID)
+		return
+	} else if rawBootSector[offsetEndMarker] != 0x55 || rawBootSector[offsetEndMarker+1] != 0xAA {
+		err = errors.New("RawBootSector.Parse() received a boot sector without the 0x55AA end marker")
+		return
+	}
+
+	bootSector.BytesPerSector = binary.LittleEndian.Uint16(rawBootSector[offsetBytesPerSector : offsetBytesPerSector+0x02])
+	// Sectors per cluster values above 0x80 are a negative power of two, used by volumes with clusters larger than 64KB.
+	sectorsPerCluster := rawBootSector[offsetSectorsPerCluster]
+	if sectorsPerCluster > 0x80 {
+		bootSector.SectorsPerCluster = 1 << uint(256-int(sectorsPerCluster))
+	} else {
+		bootSector.SectorsPerCluster = uint32(sectorsPerCluster)
+	}
+	bootSector.TotalSectors = binary.LittleEndian.Uint64(rawBootSector[offsetTotalSectors : offsetTotalSectors+0x08])
+	bootSector.MftCluster = binary.LittleEndian.Uint64(rawBootSector[offsetMftCluster : offsetMftCluster+0x08])
+	bootSector.MftMirrCluster = binary.LittleEndian.Uint64(rawBootSector[offsetMftMirrCluster : offsetMftMirrCluster+0x08])
+	bootSector.MftRecordSize = bootSector.recordSize(int8(rawBootSector[offsetClustersPerMftRecord]))
+	bootSector.IndexRecordSize = bootSector.recordSize(int8(rawBootSector[offsetClustersPerIndexRecord]))
+	bootSector.SerialNumber = binary.LittleEndian.Uint64(rawBootSector[offsetSerialNumber : offsetSerialNumber+0x08])
+	if bootSector.BytesPerSector == 0 || bootSector.SectorsPerCluster == 0 {
+		err = errors.New("RawBootSector.Parse() received a boot sector with a bytes per sector or sectors per cluster value of 0")
+		return
+	}
+	return
+}
+
+// Converts a clusters per record value to bytes. Negative values are a power of two in bytes, used when a record is smaller than a cluster.
+func (bootSector BootSector) recordSize(clustersPerRecord int8) uint32 {
+	if clustersPerRecord < 0 {
+		return 1 << uint(-clustersPerRecord)
+	}
+	return uint32(clustersPerRecord) * bootSector.BytesPerCluster()
+}
+
+// BytesPerCluster returns the cluster size of the volume.
+func (bootSector BootSector) BytesPerCluster() uint32 {
+	return uint32(bootSector.BytesPerSector) * bootSector.SectorsPerCluster
+}
+
+// ClusterCount returns the number of clusters of the volume.
+func (bootSector BootSector) ClusterCount() int64 {
+	if bootSector.SectorsPerCluster == 0 {
+		return 0
+	}
+	return int64(bootSector.TotalSectors / uint64(bootSector.SectorsPerCluster))
+}
+
+// SerialNumberString returns the lower 32 bits of the serial number in the form the vol command and LNK files show it, e.g. 1A2B-3C4D.
+func (bootSector BootSector) SerialNumberString() string {
+	if bootSector.SerialNumber == 0 {
+		return ""
+	}
+	return fmt.Sprintf("%04X-%04X", uint16(bootSector.SerialNumber>>16), uint16(bootSector.SerialNumber))
+}
+
+// ReadVolumeMetadata reads the boot sector and the $Volume record of an NTFS volume. The volume must start at the beginning of the NTFS partition. The $MFTMirr copy of the $Volume record is used if the $MFT copy is corrupt.
+func ReadVolumeMetadata(volume io.ReaderAt) (volumeMetadata VolumeMetadata, err error) {
+	volumeMetadata.BootSector, err = readBootSector(volume)
+	if err != nil {
+		return
+	}
+
+	_, mftRecord, err := readSystemRecord(volume, volumeMetadata.BootSector, volumeRecordNumber)
+	if err != nil {
+		err = fmt.Errorf("failed to read the $Volume record: %w", err)
+		return
+	}
+	volumeMetadata.setVolumeRecord(mftRecord)
+	return
+}
+
+// Reads and parses the boot sector at the start of a volume.
+func readBootSector(volume io.ReaderAt) (bootSector BootSector, err error) {
+	rawBootSector := make(RawBootSector, lengthBootSector)
+	_, err = volume.ReadAt(rawBootSector, 0)
+	if err != nil {
+		err = fmt.Errorf("failed to read the boot sector: %w", err)
+		return
+	}
+	bootSector, err = rawBootSector.Parse()
+	if err != nil {
+		err = fmt.Errorf("failed to parse the boot sector: %w", err)
+		return
+	}
+	return
+}
+
+// ReadVolumeMetadataFromMFT reads the label, NTFS version, and volume flags from the $Volume record of an extracted MFT. The boot sector details are left empty. The bytes per cluster is typically 4096.
+func ReadVolumeMetadataFromMFT(mft io.ReaderAt, bytesPerCluster int64) (volumeMetadata VolumeMetadata, err error) {
+	err = volumeMetadata.readVolumeRecord(mft, 0, defaultMftRecordSize, 512, bytesPerCluster)
+	return
+}
+
+// Reads the $Volume record from an MFT starting at mftOffset and fills in the label, version, and flags of the volume metadata receiver.
+func (volumeMetadata *VolumeMetadata) readVolumeRecord(reader io.ReaderAt, mftOffset int64, recordSize int64, bytesPerSector int, bytesPerCluster int64) (err error) {
+	rawMftRecord, err := readRawMftRecord(reader, mftOffset, recordSize, bytesPerSector, volumeRecordNumber)
+	if err != nil {
+		err = fmt.Errorf("failed to read the $Volume record: %w", err)
+		return
+	}
+	mftRecord, err := rawMftRecord.Parse(bytesPerCluster)
+	if err != nil {
+		err = fmt.Errorf("failed to parse the $Volume record: %w", err)
+		return
+	}
+	volumeMetadata.setVolumeRecord(mftRecord)
+	return
+}
+
+// Reads a record from an MFT starting at mftOffset and applies its fixups.
+func readRawMftRecord(reader io.ReaderAt, mftOffset int64, recordSize int64, bytesPerSector int, recordNumber int64) (rawMftRecord RawMasterFileTableRecord, err error) {
+	rawMftRecord = make(RawMasterFileTableRecord, recordSize)
+	_, err = reader.ReadAt(rawMftRecord, mftOffset+recordNumber*recordSize)
+	if err != nil {
+		return
+	}
+	err = applyFixups(rawMftRecord, bytesPerSector)
+	if err != nil {
+		err = fmt.Errorf("failed to apply fixups: %w", err)
+		return
+	}
+	return
+}
+
+// Reads the data runs of the MFT from its own record, record 0, so that records beyond the first fragment of the MFT can be found. The $MFTMirr copy of the record is used if the $MFT copy is corrupt.
+func readMftDataRuns(volume io.ReaderAt, bootSector BootSector) (mftDataRuns DataRuns, err error) {
+	_, mftRecord, err := readSystemRecord(volume, bootSector, 0)
+	if err != nil {
+		err = fmt.Errorf("failed to read the $MFT record: %w", err)
+		return
+	}
+	mftDataRuns = mftRecord.DataAttribute.NonResidentDataAttribute.DataRuns
+	if len(mftDataRuns) == 0 {
+		err = errors.New("the $MFT record does not have any data runs")
+		return
+	}
+	return
+}
+
+// Reads a record from the MFT of a volume by following the data runs of the MFT to the cluster that holds it.
+func readVolumeMftRecord(volume io.ReaderAt, bootSector BootSector, mftDataRuns DataRuns, recordNumber uint64) (rawMftRecord RawMasterFileTableRecord, err error) {
+	recordSize := int64(bootSector.MftRecordSize)
+	recordOffset := int64(recordNumber) * recordSize
+	for i := 0; i < len(mftDataRuns); i++ {
+		dataRun := mftDataRuns[i]
+		if recordOffset >= dataRun.Length {
+			recordOffset -= dataRun.Length
+			continue
+		}
+		if dataRun.Sparse || recordOffset+recordSize > dataRun.Length {
+			err = fmt.Errorf("record %d is not stored in a single data run of the MFT", recordNumber)
+			return
+		}
+		rawMftRecord, err = readRawMftRecord(volume, dataRun.AbsoluteOffset+recordOffset, recordSize, int(bootSector.BytesPerSector), 0)
+		return
+	}
+	err = fmt.Errorf("record %d is beyond the end of the MFT", recordNumber)
+	return
+}
+
+// Fills in the label, version, and flags of the volume metadata receiver from a parsed $Volume record.
+func (volumeMetadata *VolumeMetadata) setVolumeRecord(mftRecord MasterFileTableRecord) {
+	volumeMetadata.Label = mftRecord.VolumeName
+	volumeMetadata.MajorVersion = mftRecord.VolumeInformation.MajorVersion
+	volumeMetadata.MinorVersion = mftRecord.VolumeInformation.MinorVersion
+	volumeMetadata.Flags = mftRecord.VolumeInformation.Flags
+	return
+}
+
+// Version returns the NTFS version of the volume, e.g. 3.1.
+func (volumeMetadata VolumeMetadata) Version() string {
+	return fmt.Sprintf("%d.%d", volumeMetadata.MajorVersion, volumeMetadata.MinorVersion)
+}
@@ -0,0 +1,175 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+)
+
+// VolumeBitmap is a []byte alias for the content of the $Bitmap file of a volume. Bit n is set when cluster n is allocated.
+type VolumeBitmap []byte
+
+// ClusterRange contains a run of consecutive clusters of a volume.
+type ClusterRange struct {
+	FirstCluster int64
+	ClusterCount int64
+}
+
+// bitmapRecordNumber is the MFT record number of the $Bitmap file.
+const bitmapRecordNumber = 6
+
+// IsAllocated returns true when the volume bitmap receiver marks the cluster as allocated. Clusters past the end of the bitmap are never allocated.
+func (volumeBitmap VolumeBitmap) IsAllocated(cluster int64) bool {
+	if cluster < 0 {
+		return false
+	}
+	byteOffset := cluster / 8
+	if byteOffset >= int64(len(volumeBitmap)) {
+		return false
+	}
+	return volumeBitmap[byteOffset]&(1<<uint(cluster%8)) != 0
+}
+
+// ClusterCount returns the number of clusters covered by the volume bitmap receiver. This is rounded up to a multiple of 8, the bits past the last cluster of the volume are padding.
+func (volumeBitmap VolumeBitmap) ClusterCount() int64 {
+	return int64(len(volumeBitmap)) * 8
+}
+
+// UnallocatedRanges returns the runs of unallocated clusters of the volume bitmap receiver in order. The cluster count of the volume is used to leave out the padding bits at the end of the bitmap, a cluster count of 0 uses the whole bitmap.
+func (volumeBitmap VolumeBitmap) UnallocatedRanges(clusterCount int64) (clusterRanges []ClusterRange) {
+	if clusterCount <= 0 || clusterCount > volumeBitmap.ClusterCount() {
+		clusterCount = volumeBitmap.ClusterCount()
+	}
+	var current ClusterRange
+	for cluster := int64(0); cluster < clusterCount; {
+		// Whole bytes are skipped at a time where possible since most of a bitmap is runs of 0x00 or 0xFF.
+		if cluster%8 == 0 && cluster+8 <= clusterCount {
+			switch volumeBitmap[cluster/8] {
+			case 0xFF:
+				if current.ClusterCount != 0 {
+					clusterRanges = append(clusterRanges, current)
+					current = ClusterRange{}
+				}
+				cluster += 8
+				continue
+			case 0x00:
+				if current.ClusterCount == 0 {
+					current.FirstCluster = cluster
+				}
+				current.ClusterCount += 8
+				cluster += 8
+				continue
+			}
+		}
+		if volumeBitmap.IsAllocated(cluster) {
+			if current.ClusterCount != 0 {
+				clusterRanges = append(clusterRanges, current)
+				current = ClusterRange{}
+			}
+		} else {
+			if current.ClusterCount == 0 {
+				current.FirstCluster = cluster
+			}
+			current.ClusterCount++
+		}
+		cluster++
+	}
+	if current.ClusterCount != 0 {
+		clusterRanges = append(clusterRanges, current)
+	}
+	return
+}
+
+// ExtractClusterRanges copies the clusters of the cluster ranges from the volume to the writer, one range after the other. The volume must start at the beginning of the NTFS partition. The number of bytes written is returned so that offsets in the output can be mapped back to the ranges.
+func ExtractClusterRanges(writer io.Writer, volume io.ReaderAt, clusterRanges []ClusterRange, bytesPerCluster int64) (written int64, err error) {
+	// Sanity checks
+	if bytesPerCluster <= 0 {
+		err = fmt.Errorf("invalid bytes per cluster value of %d", bytesPerCluster)
+		return
+	}
+
+	buffer := make([]byte, 1024*1024)
+	for _, clusterRange := range clusterRanges {
+		sectionReader := io.NewSectionReader(volume, clusterRange.FirstCluster*bytesPerCluster, clusterRange.ClusterCount*bytesPerCluster)
+		var copied int64
+		copied, err = io.CopyBuffer(writer, sectionReader, buffer)
+		written += copied
+		if err != nil {
+			err = fmt.Errorf("failed to copy clusters %d to %d: %w", clusterRange.FirstCluster, clusterRange.FirstCluster+clusterRange.ClusterCount-1, err)
+			return
+		} else if copied != clusterRange.ClusterCount*bytesPerCluster {
+			err = fmt.Errorf("clusters %d to %d run past the end of the volume", clusterRange.FirstCluster, clusterRange.FirstCluster+clusterRange.ClusterCount-1)
+			return
+		}
+	}
+	return
+}
+
+// LoadVolumeBitmap reads an extracted $Bitmap file.
+func LoadVolumeBitmap(reader io.Reader) (volumeBitmap VolumeBitmap, err error) {
+	volumeBitmap, err = ioutil.ReadAll(reader)
+	if err != nil {
+		err = fmt.Errorf("failed to read the volume bitmap: %w", err)
+		return
+	}
+	return
+}
+
+// ReadVolumeBitmap reads the $Bitmap file of a volume by following the data runs of its MFT record. The volume must start at the beginning of the NTFS partition since the boot sector is used to find the MFT.
+func ReadVolumeBitmap(volume io.ReaderAt) (volumeBitmap VolumeBitmap, err error) {
+	const codeData = 0x80
+
+	bootSector, err := readBootSector(volume)
+	if err != nil {
+		return
+	}
+	mftDataRuns, err := readMftDataRuns(volume, bootSector)
+	if err != nil {
+		return
+	}
+	rawMftRecord, err := readVolumeMftRecord(volume, bootSector, mftDataRuns, bitmapRecordNumber)
+	if err != nil {
+		err = fmt.Errorf("failed to read the $Bitmap record: %w", err)
+		return
+	}
+	rawAttributes, err := rawMftRecord.getRawAttributes()
+	if err != nil {
+		err = fmt.Errorf("failed to get the attributes of the $Bitmap record: %w", err)
+		return
+	}
+	for _, rawAttribute := range rawAttributes {
+		attributeInfo := rawAttribute.info()
+		if attributeInfo.Type != codeData || attributeInfo.Name != "" {
+			continue
+		}
+		if attributeInfo.Resident {
+			volumeBitmap, err = residentContent(rawAttribute, codeData, "VolumeBitmap")
+		} else {
+			volumeBitmap, err = readNonResidentAttribute(volume, rawAttribute, int64(bootSector.BytesPerCluster()))
+		}
+		if err != nil {
+			err = fmt.Errorf("failed to read the volume bitmap: %w", err)
+		}
+		return
+	}
+	err = errors.New("the $Bitmap record does not have a $DATA attribute")
+	return
+}
+
+// ReadUnallocatedRanges reads the $Bitmap file of a volume and returns its runs of unallocated clusters along with the cluster size of the volume. The volume must start at the beginning of the NTFS partition.
+func ReadUnallocatedRanges(volume io.ReaderAt) (clusterRanges []ClusterRange, bytesPerCluster int64, err error) {
+	bootSector, err := readBootSector(volume)
+	if err != nil {
+		return
+	}
+	volumeBitmap, err := ReadVolumeBitmap(volume)
+	if err != nil {
+		return
+	}
+	clusterRanges = volumeBitmap.UnallocatedRanges(bootSector.ClusterCount())
+	bytesPerCluster = int64(bootSector.BytesPerCluster())
+	return
+}
@@ -0,0 +1,238 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// Builds a volume whose MFT starts at cluster 4 and whose $Bitmap record has the provided $DATA attribute. Cluster 8 holds the provided cluster data.
+func buildTestVolumeBitmapVolume(rawDataAttribute []byte, clusterData []byte) []byte {
+	const bytesPerCluster = 4096
+	volume := make([]byte, 9*bytesPerCluster)
+	copy(volume, buildTestBootSector(4, 0x1234567890abcdef))
+	copy(volume[4*bytesPerCluster:], buildTestVolumeMftRecord(0, 1, buildTestNonResidentAttribute(0x80, 16*1024, []byte{0x11, 0x04, 0x04})))
+	copy(volume[4*bytesPerCluster+bitmapRecordNumber*1024:], buildTestVolumeMftRecord(bitmapRecordNumber, 6, buildTestFileNameAttribute("$Bitmap", 5), rawDataAttribute))
+	copy(volume[8*bytesPerCluster:], clusterData)
+	return volume
+}
+
+func TestVolumeBitmap_IsAllocated(t *testing.T) {
+	volumeBitmap := VolumeBitmap{0x81, 0x02}
+	tests := []struct {
+		name    string
+		cluster int64
+		want    bool
+	}{
+		{
+			name:    "first cluster",
+			cluster: 0,
+			want:    true,
+		},
+		{
+			name:    "free cluster",
+			cluster: 1,
+			want:    false,
+		},
+		{
+			name:    "last bit of the first byte",
+			cluster: 7,
+			want:    true,
+		},
+		{
+			name:    "second byte",
+			cluster: 9,
+			want:    true,
+		},
+		{
+			name:    "beyond the bitmap",
+			cluster: 16,
+			want:    false,
+		},
+		{
+			name:    "negative cluster",
+			cluster: -1,
+			want:    false,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := volumeBitmap.IsAllocated(tt.cluster); got != tt.want {
+				t.Errorf("IsAllocated() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoadVolumeBitmap(t *testing.T) {
+	got, err := LoadVolumeBitmap(bytes.NewReader([]byte{0xFF, 0x01}))
+	if err != nil {
+		t.Fatalf("LoadVolumeBitmap() error = %v", err)
+	}
+	if want := (VolumeBitmap{0xFF, 0x01}); !reflect.DeepEqual(got, want) {
+		t.Errorf(cmp.Diff(got, want))
+	}
+	if got.ClusterCount() != 16 {
+		t.Errorf("ClusterCount() = %d, want 16", got.ClusterCount())
+	}
+}
+
+func TestReadVolumeBitmap(t *testing.T) {
+	clusterData := make([]byte, 4096)
+	clusterData[0] = 0xFF
+	clusterData[1] = 0x01
+
+	tests := []struct {
+		name    string
+		volume  []byte
+		want    VolumeBitmap
+		wantErr bool
+	}{
+		{
+			name:   "non-resident bitmap",
+			volume: buildTestVolumeBitmapVolume(buildTestNonResidentAttribute(0x80, 2, []byte{0x11, 0x01, 0x08}), clusterData),
+			want:   VolumeBitmap{0xFF, 0x01},
+		},
+		{
+			name:   "resident bitmap",
+			volume: buildTestVolumeBitmapVolume(buildTestResidentAttribute(0x80, []byte{0x1F}), nil),
+			want:   VolumeBitmap{0x1F},
+		},
+		{
+			name:    "no data attribute",
+			volume:  buildTestVolumeBitmapVolume(nil, nil),
+			wantErr: true,
+		},
+		{
+			name:    "volume without a boot sector",
+			volume:  make([]byte, 4096),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ReadVolumeBitmap(bytes.NewReader(tt.volume))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ReadVolumeBitmap() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestVolumeBitmap_UnallocatedRanges(t *testing.T) {
+	tests := []struct {
+		name         string
+		volumeBitmap VolumeBitmap
+		clusterCount int64
+		want         []ClusterRange
+	}{
+		{
+			name:         "fully allocated",
+			volumeBitmap: VolumeBitmap{0xFF, 0xFF},
+		},
+		{
+			name:         "fully unallocated",
+			volumeBitmap: VolumeBitmap{0x00, 0x00},
+			want:         []ClusterRange{{FirstCluster: 0, ClusterCount: 16}},
+		},
+		{
+			name:         "ranges across byte boundaries",
+			volumeBitmap: VolumeBitmap{0x0F, 0x00, 0x81, 0xFF, 0xF0},
+			want: []ClusterRange{
+				{FirstCluster: 4, ClusterCount: 12},
+				{FirstCluster: 17, ClusterCount: 6},
+				{FirstCluster: 32, ClusterCount: 4},
+			},
+		},
+		{
+			name:         "padding bits past the last cluster",
+			volumeBitmap: VolumeBitmap{0x0F, 0x01},
+			clusterCount: 12,
+			want: []ClusterRange{
+				{FirstCluster: 4, ClusterCount: 4},
+				{FirstCluster: 9, ClusterCount: 3},
+			},
+		},
+		{
+			name:         "cluster count past the bitmap",
+			volumeBitmap: VolumeBitmap{0x7F},
+			clusterCount: 100,
+			want:         []ClusterRange{{FirstCluster: 7, ClusterCount: 1}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.volumeBitmap.UnallocatedRanges(tt.clusterCount)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestExtractClusterRanges(t *testing.T) {
+	volume := make([]byte, 8*512)
+	for i := range volume {
+		volume[i] = byte(i / 512)
+	}
+	tests := []struct {
+		name          string
+		clusterRanges []ClusterRange
+		want          []byte
+		wantErr       bool
+	}{
+		{
+			name:          "two ranges",
+			clusterRanges: []ClusterRange{{FirstCluster: 1, ClusterCount: 2}, {FirstCluster: 6, ClusterCount: 1}},
+			want:          append(append(bytes.Repeat([]byte{1}, 512), bytes.Repeat([]byte{2}, 512)...), bytes.Repeat([]byte{6}, 512)...),
+		},
+		{
+			name:          "range past the end of the volume",
+			clusterRanges: []ClusterRange{{FirstCluster: 7, ClusterCount: 2}},
+			want:          bytes.Repeat([]byte{7}, 512),
+			wantErr:       true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var output bytes.Buffer
+			written, err := ExtractClusterRanges(&output, bytes.NewReader(volume), tt.clusterRanges, 512)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ExtractClusterRanges() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if written != int64(len(tt.want)) || !bytes.Equal(output.Bytes(), tt.want) {
+				t.Errorf("ExtractClusterRanges() wrote %d bytes, want %d", written, len(tt.want))
+			}
+		})
+	}
+}
+
+func TestReadUnallocatedRanges(t *testing.T) {
+	clusterData := make([]byte, 4096)
+	clusterData[0] = 0x0F
+	clusterData[1] = 0x01
+	volume := buildTestVolumeBitmapVolume(buildTestNonResidentAttribute(0x80, 2, []byte{0x11, 0x01, 0x08}), clusterData)
+	binary.LittleEndian.PutUint64(volume[0x28:], 12*8)
+
+	gotRanges, gotBytesPerCluster, err := ReadUnallocatedRanges(bytes.NewReader(volume))
+	if err != nil {
+		t.Fatalf("ReadUnallocatedRanges() error = %v", err)
+	}
+	wantRanges := []ClusterRange{{FirstCluster: 4, ClusterCount: 4}, {FirstCluster: 9, ClusterCount: 3}}
+	if !reflect.DeepEqual(gotRanges, wantRanges) {
+		t.Errorf(cmp.Diff(gotRanges, wantRanges))
+	}
+	if gotBytesPerCluster != 4096 {
+		t.Errorf("ReadUnallocatedRanges() bytes per cluster = %d, want 4096", gotBytesPerCluster)
+	}
+}
@@ -0,0 +1,302 @@
+// Copyright (c) 2020 Alec Randazzo
+
+package mft
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// Builds an NTFS boot sector with 512 byte sectors, 8 sectors per cluster, and 1024 byte mft records.
+func buildTestBootSector(mftCluster uint64, serialNumber uint64) []byte {
+	rawBootSector := make([]byte, lengthBootSector)
+	copy(rawBootSector[0x03:], "NTFS    ")
+	binary.LittleEndian.PutUint16(rawBootSector[0x0B:], 512)
+	rawBootSector[0x0D] = 8
+	binary.LittleEndian.PutUint64(rawBootSector[0x28:], 0x100000)
+	binary.LittleEndian.PutUint64(rawBootSector[0x30:], mftCluster)
+	binary.LittleEndian.PutUint64(rawBootSector[0x38:], 0x10)
+	rawBootSector[0x40] = 0xF6
+	rawBootSector[0x44] = 0x01
+	binary.LittleEndian.PutUint64(rawBootSector[0x48:], serialNumber)
+	rawBootSector[0x1FE] = 0x55
+	rawBootSector[0x1FF] = 0xAA
+	return rawBootSector
+}
+
+func TestRawVolumeInformationAttribute_Parse(t *testing.T) {
+	tests := []struct {
+		name    string
+		raw     RawVolumeInformationAttribute
+		want    VolumeInformation
+		wantErr bool
+	}{
+		{
+			name: "ntfs 3.1 from test mft",
+			raw:  RawVolumeInformationAttribute{0x70, 0x00, 0x00, 0x00, 0x28, 0x00, 0x00, 0x00, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00, 0x05, 0x00, 0x0c, 0x00, 0x00, 0x00, 0x18, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0x01, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00},
+			want: VolumeInformation{MajorVersion: 3, MinorVersion: 1},
+		},
+		{
+			name: "dirty",
+			raw:  buildTestResidentAttribute(0x70, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0x03, 0x01, 0x01, 0x80}),
+			want: VolumeInformation{MajorVersion: 3, MinorVersion: 1, Flags: VolumeFlagDirty | VolumeFlagModifiedByChkdsk},
+		},
+		{
+			name:    "content too short",
+			raw:     buildTestResidentAttribute(0x70, []byte{0, 0, 0, 0, 0, 0, 0, 0, 0x03}),
+			wantErr: true,
+		},
+		{
+			name:    "wrong attribute type",
+			raw:     buildTestResidentAttribute(0x60, make([]byte, 12)),
+			wantErr: true,
+		},
+		{
+			name:    "nil bytes",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawVolumeNameAttribute_Parse(t *testing.T) {
+	nonResident := buildTestResidentAttribute(0x60, []byte{0x44, 0x00})
+	nonResident[0x08] = 0x01
+
+	tests := []struct {
+		name    string
+		raw     RawVolumeNameAttribute
+		want    string
+		wantErr bool
+	}{
+		{
+			name: "label",
+			raw:  buildTestResidentAttribute(0x60, []byte{0x44, 0x00, 0x41, 0x00, 0x54, 0x00, 0x41, 0x00}),
+			want: "DATA",
+		},
+		{
+			name: "no label",
+			raw:  buildTestResidentAttribute(0x60, nil),
+			want: "",
+		},
+		{
+			name:    "non-resident",
+			raw:     nonResident,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Parse() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRawBootSector_Parse(t *testing.T) {
+	notNTFS := buildTestBootSector(4, 1)
+	copy(notNTFS[0x03:], "MSDOS5.0")
+	noEndMarker := buildTestBootSector(4, 1)
+	noEndMarker[0x1FF] = 0x00
+	largeClusters := buildTestBootSector(4, 1)
+	largeClusters[0x0D] = 0xF4
+
+	tests := []struct {
+		name    string
+		raw     RawBootSector
+		want    BootSector
+		wantErr bool
+	}{
+		{
+			name: "4k clusters",
+			raw:  buildTestBootSector(0xC0000, 0x1234567890abcdef),
+			want: BootSector{
+				OEMID:             "NTFS    ",
+				BytesPerSector:    512,
+				SectorsPerCluster: 8,
+				TotalSectors:      0x100000,
+				MftCluster:        0xC0000,
+				MftMirrCluster:    0x10,
+				MftRecordSize:     1024,
+				IndexRecordSize:   4096,
+				SerialNumber:      0x1234567890abcdef,
+			},
+		},
+		{
+			name: "2MB clusters",
+			raw:  largeClusters,
+			want: BootSector{
+				OEMID:             "NTFS    ",
+				BytesPerSector:    512,
+				SectorsPerCluster: 4096,
+				TotalSectors:      0x100000,
+				MftCluster:        4,
+				MftMirrCluster:    0x10,
+				MftRecordSize:     1024,
+				IndexRecordSize:   2097152,
+				SerialNumber:      1,
+			},
+		},
+		{
+			name:    "not ntfs",
+			raw:     notNTFS,
+			want:    BootSector{OEMID: "MSDOS5.0"},
+			wantErr: true,
+		},
+		{
+			name:    "no end marker",
+			raw:     noEndMarker,
+			want:    BootSector{OEMID: "NTFS    "},
+			wantErr: true,
+		},
+		{
+			name:    "too short",
+			raw:     make([]byte, 0x50),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.raw.Parse()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Parse() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Parse() got = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBootSector_ClusterCount(t *testing.T) {
+	tests := []struct {
+		name       string
+		bootSector BootSector
+		want       int64
+	}{
+		{
+			name:       "4k clusters",
+			bootSector: BootSector{BytesPerSector: 512, SectorsPerCluster: 8, TotalSectors: 0x100000},
+			want:       0x20000,
+		},
+		{
+			name:       "partial last cluster",
+			bootSector: BootSector{BytesPerSector: 512, SectorsPerCluster: 8, TotalSectors: 0x100007},
+			want:       0x20000,
+		},
+		{
+			name:       "no sectors per cluster",
+			bootSector: BootSector{BytesPerSector: 512, TotalSectors: 0x100000},
+			want:       0,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.bootSector.ClusterCount(); got != tt.want {
+				t.Errorf("ClusterCount() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestVolumeFlags_Names(t *testing.T) {
+	tests := []struct {
+		name        string
+		volumeFlags VolumeFlags
+		want        []string
+		wantDirty   bool
+	}{
+		{name: "none"},
+		{name: "dirty", volumeFlags: 0x0001, want: []string{"DIRTY"}, wantDirty: true},
+		{name: "upgrade and chkdsk", volumeFlags: 0x8004, want: []string{"UPGRADE_ON_MOUNT", "MODIFIED_BY_CHKDSK"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.volumeFlags.Names(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Names() got = %v, want %v", got, tt.want)
+			}
+			if got := tt.volumeFlags.IsDirty(); got != tt.wantDirty {
+				t.Errorf("IsDirty() got = %v, want %v", got, tt.wantDirty)
+			}
+		})
+	}
+}
+
+func TestReadVolumeMetadata(t *testing.T) {
+	rawMft, err := ioutil.ReadFile(filepath.FromSlash("./test/testdata/mft-lite"))
+	if err != nil {
+		t.Fatalf("failed to read test mft: %v", err)
+	}
+
+	// The mft starts at cluster 2 of the test volume.
+	volume := make([]byte, 8192+len(rawMft))
+	copy(volume, buildTestBootSector(2, 0x1234567890abcdef))
+	copy(volume[8192:], rawMft)
+
+	tests := []struct {
+		name       string
+		read       func() (VolumeMetadata, error)
+		wantSerial string
+		wantErr    bool
+	}{
+		{
+			name:       "volume image",
+			read:       func() (VolumeMetadata, error) { return ReadVolumeMetadata(bytes.NewReader(volume)) },
+			wantSerial: "90AB-CDEF",
+		},
+		{
+			name: "extracted mft",
+			read: func() (VolumeMetadata, error) { return ReadVolumeMetadataFromMFT(bytes.NewReader(rawMft), 4096) },
+		},
+		{
+			name:    "volume without a boot sector",
+			read:    func() (VolumeMetadata, error) { return ReadVolumeMetadata(bytes.NewReader(rawMft)) },
+			wantErr: true,
+		},
+		{
+			name:    "mft without a $Volume record",
+			read:    func() (VolumeMetadata, error) { return ReadVolumeMetadataFromMFT(bytes.NewReader(rawMft[:2048]), 4096) },
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.read()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Label != "" || got.Version() != "3.1" || got.Flags != 0 {
+				t.Errorf("got label %q, version %s, flags %v, want no label, version 3.1, and no flags", got.Label, got.Version(), got.Flags)
+			}
+			if gotSerial := got.BootSector.SerialNumberString(); gotSerial != tt.wantSerial {
+				t.Errorf("SerialNumberString() got = %v, want %v", gotSerial, tt.wantSerial)
+			}
+		})
+	}
+}
@@ -6,7 +6,9 @@ import (
 	"fmt"
 	"io"
 	"strconv"
+	"strings"
 	"sync"
+	"time"
 )
 
 // ResultWriter interface for result writers to allow for output format extensibility.
@@ -14,8 +16,21 @@ type ResultWriter interface {
 	ResultWriter(streamer io.Writer, outputChannel *chan UsefulMftFields, waitGroup *sync.WaitGroup)
 }
 
-// CsvResultWriter receiver used with the ResultWriter method that would write the csv results to csv.
-type CsvResultWriter struct{}
+// CsvResultWriter receiver used with the ResultWriter method that would write the csv results to csv. Optional columns are only written when they are included.
+type CsvResultWriter struct {
+	IncludeSecurity   bool
+	IncludeReparse    bool
+	IncludeObjectID   bool
+	IncludeEA         bool
+	IncludeVolume     bool
+	IncludeEFS        bool
+	IncludeAttributes bool
+	IncludeUsn        bool
+	IncludeMftBitmap  bool
+	IncludeSlack      bool
+	IncludeCarved     bool
+	IncludePartial    bool
+}
 
 // ResultWriter writes the results to csv.
 func (csvResultWriter *CsvResultWriter) ResultWriter(streamer io.Writer, outputChannel *chan UsefulMftFields, waitGroup *sync.WaitGroup) {
@@ -38,8 +53,44 @@ func (csvResultWriter *CsvResultWriter) ResultWriter(streamer io.Writer, outputC
 		"FileName Modified",
 		"Filename Accessed",
 		"Filename Entry Modified",
-		"\n",
 	}
+	if csvResultWriter.IncludeSecurity {
+		csvHeader = append(csvHeader, "Owner SID", "Owner Account Name", "SDDL")
+	}
+	if csvResultWriter.IncludeReparse {
+		csvHeader = append(csvHeader, "Reparse Tag", "Reparse Target")
+	}
+	if csvResultWriter.IncludeObjectID {
+		csvHeader = append(csvHeader, "Object ID", "Birth Volume ID", "Birth Object ID", "Domain ID", "Object ID Created", "Object ID MAC")
+	}
+	if csvResultWriter.IncludeEA {
+		csvHeader = append(csvHeader, "EA Names", "EA Size", "EA Unusual", "WSL UID", "WSL GID", "WSL Mode", "WSL Device")
+	}
+	if csvResultWriter.IncludeVolume {
+		csvHeader = append(csvHeader, "Volume Label", "Volume Serial")
+	}
+	if csvResultWriter.IncludeEFS {
+		csvHeader = append(csvHeader, "EFS Encrypted", "EFS Users", "EFS Recovery Agents", "TxF")
+	}
+	if csvResultWriter.IncludeAttributes {
+		csvHeader = append(csvHeader, "Attributes")
+	}
+	if csvResultWriter.IncludeUsn {
+		csvHeader = append(csvHeader, "SI USN", "USN Timestamp", "USN Reason", "USN Anomaly")
+	}
+	if csvResultWriter.IncludeMftBitmap {
+		csvHeader = append(csvHeader, "MFT Bitmap Allocated", "MFT Bitmap Anomaly")
+	}
+	if csvResultWriter.IncludeSlack {
+		csvHeader = append(csvHeader, "Record Slack Size", "Residual Attributes", "Residual File Names")
+	}
+	if csvResultWriter.IncludeCarved {
+		csvHeader = append(csvHeader, "Source Offset", "Signature")
+	}
+	if csvResultWriter.IncludePartial {
+		csvHeader = append(csvHeader, "Partial", "Attribute Errors")
+	}
+	csvHeader = append(csvHeader, "\n")
 
 	// Write CSV header
 	headerSize := len(csvHeader)
@@ -75,8 +126,59 @@ func (csvResultWriter *CsvResultWriter) ResultWriter(streamer io.Writer, outputC
 			file.FnModified.Format("2006-01-02T15:04:05Z"), //FileName Modified
 			file.FnAccessed.Format("2006-01-02T15:04:05Z"), //FileName Accessed
 			file.FnChanged.Format("2006-01-02T15:04:05Z"),  //FileName Entry Modified
-			"\n", // Newline
 		}
+		if csvResultWriter.IncludeSecurity {
+			csvRow = append(csvRow, file.OwnerSID, file.OwnerAccountName, file.SDDL)
+		}
+		if csvResultWriter.IncludeReparse {
+			csvRow = append(csvRow, file.ReparseTag, file.ReparseTarget)
+		}
+		if csvResultWriter.IncludeObjectID {
+			csvRow = append(csvRow,
+				file.ObjectID,
+				file.BirthVolumeID,
+				file.BirthObjectID,
+				file.DomainID,
+				file.ObjectIDCreated.Format("2006-01-02T15:04:05Z"),
+				file.ObjectIDMAC,
+			)
+		}
+		if csvResultWriter.IncludeEA {
+			csvRow = append(csvRow,
+				file.EANames,
+				fmt.Sprint(file.EASize),
+				strconv.FormatBool(file.EAUnusual),
+				file.LxUID,
+				file.LxGID,
+				file.LxMode,
+				file.LxDevice,
+			)
+		}
+		if csvResultWriter.IncludeVolume {
+			csvRow = append(csvRow, file.VolumeLabel, file.VolumeSerial)
+		}
+		if csvResultWriter.IncludeEFS {
+			csvRow = append(csvRow, strconv.FormatBool(file.EFSEncrypted), file.EFSUsers, file.EFSRecovery, strconv.FormatBool(file.TxF))
+		}
+		if csvResultWriter.IncludeAttributes {
+			csvRow = append(csvRow, file.Attributes)
+		}
+		if csvResultWriter.IncludeUsn {
+			csvRow = append(csvRow, strconv.FormatInt(file.SiUsn, 10), file.UsnTimestamp.Format("2006-01-02T15:04:05Z"), file.UsnReason, file.UsnAnomaly)
+		}
+		if csvResultWriter.IncludeMftBitmap {
+			csvRow = append(csvRow, strconv.FormatBool(file.MftAllocated), file.MftAnomaly)
+		}
+		if csvResultWriter.IncludeSlack {
+			csvRow = append(csvRow, strconv.Itoa(file.SlackSize), file.SlackAttributes, file.SlackFileNames)
+		}
+		if csvResultWriter.IncludeCarved {
+			csvRow = append(csvRow, strconv.FormatInt(file.CarvedOffset, 10), file.CarvedSignature)
+		}
+		if csvResultWriter.IncludePartial {
+			csvRow = append(csvRow, strconv.FormatBool(file.Partial), strings.ReplaceAll(file.AttributeErrors, "|", ";"))
+		}
+		csvRow = append(csvRow, "\n") // Newline
 
 		csvRowSize := len(csvRow)
 		for index, item := range csvRow {
@@ -90,3 +192,416 @@ func (csvResultWriter *CsvResultWriter) ResultWriter(streamer io.Writer, outputC
 	waitGroup.Done()
 	return
 }
+
+// WriteUsnJournalCsv writes every record of the usn journal reader to csv. Paths are only filled in when a usn path resolver is provided. Reason and source info flags are separated by semicolons since the pipe is the delimiter.
+func WriteUsnJournalCsv(streamer io.Writer, usnJournalReader *UsnJournalReader, usnPathResolver *UsnPathResolver) (err error) {
+	delimiter := "|"
+	csvHeader := []string{
+		"Offset",
+		"USN",
+		"Timestamp",
+		"Record Number",
+		"Sequence Number",
+		"Parent Record Number",
+		"Parent Sequence Number",
+		"Parent Deleted",
+		"File Path",
+		"File Name",
+		"Reason",
+		"Source Info",
+		"File Attributes",
+		"Security ID",
+		"Version",
+		"\n",
+	}
+	writeCsvLine(streamer, delimiter, csvHeader)
+
+	for {
+		var usnRecord UsnRecord
+		usnRecord, err = usnJournalReader.Next()
+		if err == io.EOF {
+			err = nil
+			break
+		} else if err != nil {
+			return
+		}
+		if usnPathResolver != nil {
+			usnPathResolver.Resolve(&usnRecord)
+		}
+		csvRow := []string{
+			strconv.FormatInt(usnRecord.Offset, 10),
+			strconv.FormatInt(usnRecord.Usn, 10),
+			usnRecord.Timestamp.Format("2006-01-02T15:04:05.0000000Z"),
+			strconv.FormatUint(usnRecord.RecordNumber, 10),
+			strconv.FormatUint(uint64(usnRecord.SequenceNumber), 10),
+			strconv.FormatUint(usnRecord.ParentRecordNumber, 10),
+			strconv.FormatUint(uint64(usnRecord.ParentSequenceNumber), 10),
+			strconv.FormatBool(usnRecord.ParentDeleted),
+			usnRecord.FilePath,
+			usnRecord.FileName,
+			strings.Join(usnRecord.Reason.Names(), ";"),
+			strings.Join(usnRecord.SourceInfo.Names(), ";"),
+			fmt.Sprintf("0x%X", usnRecord.FileAttributes),
+			fmt.Sprint(usnRecord.SecurityID),
+			fmt.Sprintf("%d.%d", usnRecord.MajorVersion, usnRecord.MinorVersion),
+			"\n",
+		}
+		writeCsvLine(streamer, delimiter, csvRow)
+	}
+	return
+}
+
+// WriteUsnAnomalyCsv writes the usn anomaly entries to the streamer as a csv. The journal columns are empty when no journal record was found at the usn.
+func WriteUsnAnomalyCsv(streamer io.Writer, usnAnomalyEntries []UsnAnomalyEntry) (err error) {
+	delimiter := "|"
+	csvHeader := []string{
+		"Record Number",
+		"Sequence Number",
+		"Full Path",
+		"SI USN",
+		"Anomaly",
+		"Journal Record Number",
+		"Journal Sequence Number",
+		"Journal File Name",
+		"Journal Timestamp",
+		"Journal Reason",
+		"\n",
+	}
+	writeCsvLine(streamer, delimiter, csvHeader)
+
+	for _, usnAnomalyEntry := range usnAnomalyEntries {
+		csvRow := []string{
+			fmt.Sprint(usnAnomalyEntry.RecordNumber),
+			fmt.Sprint(usnAnomalyEntry.SequenceNumber),
+			usnAnomalyEntry.FullPath,
+			strconv.FormatInt(usnAnomalyEntry.SiUsn, 10),
+			usnAnomalyEntry.Anomaly.String(),
+			"",
+			"",
+			"",
+			"",
+			"",
+			"\n",
+		}
+		if journalRecord := usnAnomalyEntry.JournalRecord; journalRecord.MajorVersion != 0 {
+			csvRow[5] = strconv.FormatUint(journalRecord.RecordNumber, 10)
+			csvRow[6] = strconv.FormatUint(uint64(journalRecord.SequenceNumber), 10)
+			csvRow[7] = journalRecord.FileName
+			csvRow[8] = journalRecord.Timestamp.Format("2006-01-02T15:04:05.0000000Z")
+			csvRow[9] = strings.Join(journalRecord.Reason.Names(), ";")
+		}
+		writeCsvLine(streamer, delimiter, csvRow)
+	}
+	return
+}
+
+// WriteMftBitmapAnomalyCsv writes the MFT bitmap anomaly entries to the streamer as a csv.
+func WriteMftBitmapAnomalyCsv(streamer io.Writer, mftBitmapAnomalyEntries []MftBitmapAnomalyEntry) (err error) {
+	delimiter := "|"
+	csvHeader := []string{
+		"Record Number",
+		"Sequence Number",
+		"Full Path",
+		"In Use",
+		"Allocated",
+		"Anomaly",
+		"\n",
+	}
+	writeCsvLine(streamer, delimiter, csvHeader)
+
+	for _, mftBitmapAnomalyEntry := range mftBitmapAnomalyEntries {
+		csvRow := []string{
+			strconv.FormatUint(mftBitmapAnomalyEntry.RecordNumber, 10),
+			fmt.Sprint(mftBitmapAnomalyEntry.SequenceNumber),
+			mftBitmapAnomalyEntry.FullPath,
+			strconv.FormatBool(mftBitmapAnomalyEntry.InUse),
+			strconv.FormatBool(mftBitmapAnomalyEntry.Allocated),
+			mftBitmapAnomalyEntry.Anomaly.String(),
+			"\n",
+		}
+		writeCsvLine(streamer, delimiter, csvRow)
+	}
+	return
+}
+
+// WriteMftMirrorCsv writes the differences between the $MFT and the $MFTMirr to the streamer as a csv. Values that contain the delimiter, such as volume flags, have it swapped for a semicolon.
+func WriteMftMirrorCsv(streamer io.Writer, mftMirrorDifferences []MftMirrorDifference) (err error) {
+	delimiter := "|"
+	csvHeader := []string{
+		"Record Number",
+		"Field",
+		"MFT",
+		"MFTMirr",
+		"\n",
+	}
+	writeCsvLine(streamer, delimiter, csvHeader)
+
+	for _, mftMirrorDifference := range mftMirrorDifferences {
+		csvRow := []string{
+			strconv.FormatUint(mftMirrorDifference.RecordNumber, 10),
+			mftMirrorDifference.Field,
+			strings.ReplaceAll(mftMirrorDifference.Mft, delimiter, ";"),
+			strings.ReplaceAll(mftMirrorDifference.MftMirr, delimiter, ";"),
+			"\n",
+		}
+		writeCsvLine(streamer, delimiter, csvRow)
+	}
+	return
+}
+
+// WriteClusterLookupCsv writes the owners of the looked up clusters to the streamer as a csv. A cluster with more than one owner gets a line per owner, and a cluster without an owner gets a line with the owner columns left empty. The allocated column is empty when no volume bitmap was used.
+func WriteClusterLookupCsv(streamer io.Writer, clusterLookups []ClusterLookup) (err error) {
+	delimiter := "|"
+	csvHeader := []string{
+		"Cluster",
+		"Volume Offset",
+		"Allocated",
+		"Record Number",
+		"Sequence Number",
+		"Full Path",
+		"Stream",
+		"Stream Offset",
+		"Deleted",
+		"\n",
+	}
+	writeCsvLine(streamer, delimiter, csvHeader)
+
+	for _, clusterLookup := range clusterLookups {
+		var allocated string
+		if clusterLookup.BitmapChecked {
+			allocated = strconv.FormatBool(clusterLookup.Allocated)
+		}
+		clusterColumns := []string{
+			strconv.FormatInt(clusterLookup.Cluster, 10),
+			strconv.FormatInt(clusterLookup.VolumeOffset, 10),
+			allocated,
+		}
+		if len(clusterLookup.Owners) == 0 {
+			writeCsvLine(streamer, delimiter, append(clusterColumns, "", "", "", "", "", "", "\n"))
+			continue
+		}
+		for _, clusterOwner := range clusterLookup.Owners {
+			csvRow := append(append([]string{}, clusterColumns...),
+				fmt.Sprint(clusterOwner.RecordNumber),
+				fmt.Sprint(clusterOwner.SequenceNumber),
+				clusterOwner.FullPath,
+				clusterOwner.Stream,
+				strconv.FormatInt(clusterOwner.StreamOffset, 10),
+				strconv.FormatBool(clusterOwner.Deleted),
+				"\n",
+			)
+			writeCsvLine(streamer, delimiter, csvRow)
+		}
+	}
+	return
+}
+
+// WriteClusterRangesCsv writes the cluster ranges to the streamer as a csv along with their byte offset and length in the volume. The output offset is where each range starts in the output of ExtractClusterRanges().
+func WriteClusterRangesCsv(streamer io.Writer, clusterRanges []ClusterRange, bytesPerCluster int64) (err error) {
+	delimiter := "|"
+	csvHeader := []string{
+		"First Cluster",
+		"Cluster Count",
+		"Volume Offset",
+		"Length",
+		"Output Offset",
+		"\n",
+	}
+	writeCsvLine(streamer, delimiter, csvHeader)
+
+	outputOffset := int64(0)
+	for _, clusterRange := range clusterRanges {
+		length := clusterRange.ClusterCount * bytesPerCluster
+		csvRow := []string{
+			strconv.FormatInt(clusterRange.FirstCluster, 10),
+			strconv.FormatInt(clusterRange.ClusterCount, 10),
+			strconv.FormatInt(clusterRange.FirstCluster*bytesPerCluster, 10),
+			strconv.FormatInt(length, 10),
+			strconv.FormatInt(outputOffset, 10),
+			"\n",
+		}
+		writeCsvLine(streamer, delimiter, csvRow)
+		outputOffset += length
+	}
+	return
+}
+
+// WriteFileSlackCsv writes where the file slack extracted by ExtractFileSlack() came from to the streamer as a csv.
+func WriteFileSlackCsv(streamer io.Writer, fileSlacks []FileSlack) (err error) {
+	delimiter := "|"
+	csvHeader := []string{
+		"Record Number",
+		"Sequence Number",
+		"Full Path",
+		"Stream",
+		"Volume Offset",
+		"Length",
+		"Output Offset",
+		"\n",
+	}
+	writeCsvLine(streamer, delimiter, csvHeader)
+
+	for _, fileSlack := range fileSlacks {
+		csvRow := []string{
+			fmt.Sprint(fileSlack.RecordNumber),
+			fmt.Sprint(fileSlack.SequenceNumber),
+			fileSlack.FullPath,
+			fileSlack.Stream,
+			strconv.FormatInt(fileSlack.VolumeOffset, 10),
+			strconv.FormatInt(fileSlack.Length, 10),
+			strconv.FormatInt(fileSlack.OutputOffset, 10),
+			"\n",
+		}
+		writeCsvLine(streamer, delimiter, csvRow)
+	}
+	return
+}
+
+// WriteCarvedIndexEntriesCsv writes the index entries carved by CarveIndexRecords() to the streamer as a csv.
+func WriteCarvedIndexEntriesCsv(streamer io.Writer, carvedIndexEntries []CarvedIndexEntry) (err error) {
+	delimiter := "|"
+	csvHeader := []string{
+		"Record Offset",
+		"Source Offset",
+		"VCN",
+		"Slack",
+		"Record Number",
+		"Sequence Number",
+		"Parent Record Number",
+		"Parent Sequence Number",
+		"File Name",
+		"Namespace",
+		"Directory",
+		"Logical Size",
+		"Physical Size",
+		"FileName Created",
+		"FileName Modified",
+		"Filename Accessed",
+		"Filename Entry Modified",
+		"\n",
+	}
+	writeCsvLine(streamer, delimiter, csvHeader)
+
+	for _, carvedIndexEntry := range carvedIndexEntries {
+		fileName := carvedIndexEntry.FileName
+		csvRow := []string{
+			strconv.FormatInt(carvedIndexEntry.RecordOffset, 10),
+			strconv.FormatInt(carvedIndexEntry.SourceOffset, 10),
+			strconv.FormatUint(carvedIndexEntry.VCN, 10),
+			strconv.FormatBool(carvedIndexEntry.Slack),
+			strconv.FormatUint(carvedIndexEntry.RecordNumber, 10),
+			fmt.Sprint(carvedIndexEntry.SequenceNumber),
+			fmt.Sprint(fileName.ParentDirRecordNumber),
+			fmt.Sprint(fileName.ParentDirSequenceNumber),
+			fileName.FileName,
+			fileName.FileNamespace,
+			strconv.FormatBool(fileName.FileNameFlags.Directory),
+			strconv.FormatUint(fileName.LogicalFileSize, 10),
+			strconv.FormatUint(fileName.PhysicalFileSize, 10),
+			fileName.FnCreated.Format("2006-01-02T15:04:05Z"),
+			fileName.FnModified.Format("2006-01-02T15:04:05Z"),
+			fileName.FnAccessed.Format("2006-01-02T15:04:05Z"),
+			fileName.FnChanged.Format("2006-01-02T15:04:05Z"),
+			"\n",
+		}
+		writeCsvLine(streamer, delimiter, csvRow)
+	}
+	return
+}
+
+// WriteRecordErrorsCsv writes the records that were skipped or only partially parsed to the streamer as a csv. See DiagnosticLog.
+func WriteRecordErrorsCsv(streamer io.Writer, recordErrors []*RecordError) (err error) {
+	delimiter := "|"
+	csvHeader := []string{
+		"Offset",
+		"Record Number",
+		"Reason",
+		"Partial",
+		"Attribute",
+		"Error",
+		"\n",
+	}
+	writeCsvLine(streamer, delimiter, csvHeader)
+
+	for _, recordError := range recordErrors {
+		var attribute, message string
+		if recordError.AttributeType != 0 {
+			attribute = DefaultAttributeDefinitions.TypeName(recordError.AttributeType)
+		}
+		if recordError.Err != nil {
+			message = strings.ReplaceAll(recordError.Err.Error(), delimiter, ";")
+		}
+		csvRow := []string{
+			strconv.FormatInt(recordError.Offset, 10),
+			fmt.Sprint(recordError.RecordNumber),
+			recordError.Reason.String(),
+			strconv.FormatBool(recordError.Partial),
+			attribute,
+			message,
+			"\n",
+		}
+		writeCsvLine(streamer, delimiter, csvRow)
+	}
+	return
+}
+
+// WriteLogFileTimelineCsv writes a timeline of $LogFile operations to the streamer as a csv. Timestamps decoded from the operation are written when the operation carries them.
+func WriteLogFileTimelineCsv(streamer io.Writer, timeline []LogFileTimelineEntry) (err error) {
+	delimiter := "|"
+	csvHeader := []string{
+		"LSN",
+		"Transaction ID",
+		"Redo Operation",
+		"Undo Operation",
+		"Record Number",
+		"File Path",
+		"File Name",
+		"Created",
+		"Modified",
+		"Changed",
+		"Accessed",
+		"\n",
+	}
+	writeCsvLine(streamer, delimiter, csvHeader)
+
+	formatTimestamp := func(timestamp time.Time) string {
+		if timestamp.IsZero() {
+			return ""
+		}
+		return timestamp.Format("2006-01-02T15:04:05.0000000Z")
+	}
+	for _, entry := range timeline {
+		recordNumber := ""
+		if entry.HasRecordNumber {
+			recordNumber = strconv.FormatUint(entry.RecordNumber, 10)
+		}
+		csvRow := []string{
+			strconv.FormatUint(entry.Lsn, 10),
+			strconv.FormatUint(uint64(entry.TransactionID), 10),
+			entry.RedoOperation.String(),
+			entry.UndoOperation.String(),
+			recordNumber,
+			entry.FullPath,
+			entry.FileName,
+			formatTimestamp(entry.Details.Created),
+			formatTimestamp(entry.Details.Modified),
+			formatTimestamp(entry.Details.Changed),
+			formatTimestamp(entry.Details.Accessed),
+			"\n",
+		}
+		writeCsvLine(streamer, delimiter, csvRow)
+	}
+	return
+}
+
+// Writes the items of a csv line separated by the delimiter. The last item is the newline and isn't preceded by a delimiter.
+func writeCsvLine(streamer io.Writer, delimiter string, items []string) {
+	itemCount := len(items)
+	for index, item := range items {
+		_, _ = streamer.Write([]byte(item))
+		if index < itemCount-2 {
+			_, _ = streamer.Write([]byte(delimiter))
+		}
+	}
+	return
+}
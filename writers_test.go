@@ -3,6 +3,8 @@
 package mft
 
 import (
+	"bytes"
+	"errors"
 	"github.com/google/go-cmp/cmp"
 	"reflect"
 	"sync"
@@ -107,3 +109,468 @@ func TestCsvWriter_Write(t *testing.T) {
 		})
 	}
 }
+
+func TestCsvWriter_OptionalColumns(t *testing.T) {
+	tests := []struct {
+		name            string
+		writer          CsvResultWriter
+		usefulMftFields UsefulMftFields
+		want            string
+	}{
+		{
+			name:   "security columns",
+			writer: CsvResultWriter{IncludeSecurity: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber:     4,
+				FileName:         "$AttrDef",
+				OwnerSID:         "S-1-5-18",
+				OwnerAccountName: "NT AUTHORITY\\SYSTEM",
+				SDDL:             "O:S-1-5-18",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|Owner SID|Owner Account Name|SDDL\n" +
+				"4|false|false|false|false|false||$AttrDef|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|S-1-5-18|NT AUTHORITY\\SYSTEM|O:S-1-5-18\n",
+		},
+		{
+			name:   "reparse columns",
+			writer: CsvResultWriter{IncludeReparse: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber:  40,
+				FileName:      "link",
+				ReparseTag:    "SYMLINK",
+				ReparseTarget: "\\??\\C:\\Windows",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|Reparse Tag|Reparse Target\n" +
+				"40|false|false|false|false|false||link|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|SYMLINK|\\??\\C:\\Windows\n",
+		},
+		{
+			name:   "object id columns",
+			writer: CsvResultWriter{IncludeObjectID: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber:    5,
+				FileName:        ".",
+				ObjectID:        "d17f1f53-fdc2-11e8-a853-34028681dca5",
+				BirthVolumeID:   "1cda7cc4-95cf-4789-badc-72b81b3c2603",
+				BirthObjectID:   "d17f1f53-fdc2-11e8-a853-34028681dca5",
+				ObjectIDCreated: time.Date(2018, 12, 12, 4, 3, 1, 505211500, time.UTC),
+				ObjectIDMAC:     "34:02:86:81:dc:a5",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|Object ID|Birth Volume ID|Birth Object ID|Domain ID|Object ID Created|Object ID MAC\n" +
+				"5|false|false|false|false|false||.|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|d17f1f53-fdc2-11e8-a853-34028681dca5|1cda7cc4-95cf-4789-badc-72b81b3c2603|d17f1f53-fdc2-11e8-a853-34028681dca5||2018-12-12T04:03:01Z|34:02:86:81:dc:a5\n",
+		},
+		{
+			name:   "extended attribute columns",
+			writer: CsvResultWriter{IncludeEA: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber: 70,
+				FileName:     "bash",
+				EANames:      "$LXUID;$LXGID;$LXMOD",
+				EASize:       0x38,
+				LxUID:        "0",
+				LxGID:        "0",
+				LxMode:       "-rwxr-xr-x",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|EA Names|EA Size|EA Unusual|WSL UID|WSL GID|WSL Mode|WSL Device\n" +
+				"70|false|false|false|false|false||bash|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|$LXUID;$LXGID;$LXMOD|56|false|0|0|-rwxr-xr-x|\n",
+		},
+		{
+			name:   "volume columns",
+			writer: CsvResultWriter{IncludeVolume: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber: 5,
+				FileName:     ".",
+				VolumeLabel:  "DATA",
+				VolumeSerial: "90AB-CDEF",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|Volume Label|Volume Serial\n" +
+				"5|false|false|false|false|false||.|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|DATA|90AB-CDEF\n",
+		},
+		{
+			name:   "efs columns",
+			writer: CsvResultWriter{IncludeEFS: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber: 70,
+				FileName:     "secret.txt",
+				EFSEncrypted: true,
+				EFSUsers:     "S-1-5-21-1-2-3-1001:alec:0A1B",
+				EFSRecovery:  "S-1-5-21-1-2-3-500:admin:FFEE",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|EFS Encrypted|EFS Users|EFS Recovery Agents|TxF\n" +
+				"70|false|false|false|false|false||secret.txt|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|true|S-1-5-21-1-2-3-1001:alec:0A1B|S-1-5-21-1-2-3-500:admin:FFEE|false\n",
+		},
+		{
+			name:   "attribute columns",
+			writer: CsvResultWriter{IncludeAttributes: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber: 70,
+				FileName:     "hidden.txt",
+				Attributes:   "$STANDARD_INFORMATION;$FILE_NAME;$DATA;0x1000",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|Attributes\n" +
+				"70|false|false|false|false|false||hidden.txt|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|$STANDARD_INFORMATION;$FILE_NAME;$DATA;0x1000\n",
+		},
+		{
+			name:   "usn columns",
+			writer: CsvResultWriter{IncludeUsn: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber: 70,
+				FileName:     "notes.txt",
+				SiUsn:        8192,
+				UsnTimestamp: testUsnTimestamp,
+				UsnReason:    "FILE_CREATE;CLOSE",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|SI USN|USN Timestamp|USN Reason|USN Anomaly\n" +
+				"70|false|false|false|false|false||notes.txt|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|8192|2020-05-17T13:45:12Z|FILE_CREATE;CLOSE|\n",
+		},
+		{
+			name:   "mft bitmap columns",
+			writer: CsvResultWriter{IncludeMftBitmap: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber: 70,
+				FileName:     "hidden.txt",
+				MftAnomaly:   "IN_USE_NOT_ALLOCATED",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|MFT Bitmap Allocated|MFT Bitmap Anomaly\n" +
+				"70|false|false|false|false|false||hidden.txt|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|false|IN_USE_NOT_ALLOCATED\n",
+		},
+		{
+			name:   "slack columns",
+			writer: CsvResultWriter{IncludeSlack: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber:    70,
+				FileName:        "new.txt",
+				SlackSize:       0x300,
+				SlackAttributes: "$FILE_NAME@0x108",
+				SlackFileNames:  "old.txt",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|Record Slack Size|Residual Attributes|Residual File Names\n" +
+				"70|false|false|false|false|false||new.txt|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|768|$FILE_NAME@0x108|old.txt\n",
+		},
+		{
+			name:   "carved columns",
+			writer: CsvResultWriter{IncludeCarved: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber:    41,
+				FileName:        "chkdsk.txt",
+				CarvedOffset:    2560,
+				CarvedSignature: "BAAD",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|Source Offset|Signature\n" +
+				"41|false|false|false|false|false||chkdsk.txt|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|2560|BAAD\n",
+		},
+		{
+			name:   "partial columns",
+			writer: CsvResultWriter{IncludePartial: true},
+			usefulMftFields: UsefulMftFields{
+				RecordNumber:    40,
+				FileName:        "notes.txt",
+				Partial:         true,
+				AttributeErrors: "$DATA@0x98: data run 1 at offset 3: invalid header byte 0x2F: bad data run",
+			},
+			want: "Record Number|Directory|System File|Hidden|Read-only|Deleted|File Path|File Name|File Size|File Created|File Modified|File Accessed|File Entry Modified|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified|Partial|Attribute Errors\n" +
+				"40|false|false|false|false|false||notes.txt|0|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|true|$DATA@0x98: data run 1 at offset 3: invalid header byte 0x2F: bad data run\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var waitGroup sync.WaitGroup
+			streamer := DummyResultWriter{}
+			outputChannel := make(chan UsefulMftFields, 1)
+			waitGroup.Add(1)
+			go tt.writer.ResultWriter(&streamer, &outputChannel, &waitGroup)
+			outputChannel <- tt.usefulMftFields
+			close(outputChannel)
+			waitGroup.Wait()
+			if got := string(streamer.AggregatedData); got != tt.want {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestWriteUsnJournalCsv(t *testing.T) {
+	rawJournal := append(make([]byte, 0x1000), buildTestUsnRecord(testUsnRecordV2)...)
+	usnPathResolver, _ := NewUsnPathResolver("C", DirectoryTree{5: "C:\\", 40: "C:\\Users"}, DirectorySequenceNumbers{5: 5, 40: 2})
+	tests := []struct {
+		name            string
+		usnPathResolver *UsnPathResolver
+		want            string
+	}{
+		{
+			name:            "resolved paths",
+			usnPathResolver: usnPathResolver,
+			want: "Offset|USN|Timestamp|Record Number|Sequence Number|Parent Record Number|Parent Sequence Number|Parent Deleted|File Path|File Name|Reason|Source Info|File Attributes|Security ID|Version\n" +
+				"4096|8192|2020-05-17T13:45:12.1234567Z|70|3|40|2|false|C:\\Users|notes.txt|FILE_CREATE;CLOSE||0x20|256|2.0\n",
+		},
+		{
+			name: "without a path resolver",
+			want: "Offset|USN|Timestamp|Record Number|Sequence Number|Parent Record Number|Parent Sequence Number|Parent Deleted|File Path|File Name|Reason|Source Info|File Attributes|Security ID|Version\n" +
+				"4096|8192|2020-05-17T13:45:12.1234567Z|70|3|40|2|false||notes.txt|FILE_CREATE;CLOSE||0x20|256|2.0\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var streamer DummyResultWriter
+			err := WriteUsnJournalCsv(&streamer, NewUsnJournalReader(bytes.NewReader(rawJournal)), tt.usnPathResolver)
+			if err != nil {
+				t.Fatalf("WriteUsnJournalCsv() error = %v", err)
+			}
+			if got := string(streamer.AggregatedData); got != tt.want {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestWriteLogFileTimelineCsv(t *testing.T) {
+	tests := []struct {
+		name     string
+		timeline []LogFileTimelineEntry
+		want     string
+	}{
+		{
+			name: "entries with and without record numbers",
+			timeline: []LogFileTimelineEntry{
+				{
+					Lsn:             0x100000000408,
+					TransactionID:   7,
+					RedoOperation:   LogFileOperationAddIndexEntryAllocation,
+					UndoOperation:   LogFileOperationDeleteIndexEntryAllocation,
+					RecordNumber:    40,
+					HasRecordNumber: true,
+					FileName:        "secret.txt",
+					FullPath:        "C:\\secret.txt",
+					Details:         LogFileOperationDetails{Created: testUsnTimestamp, Modified: testUsnTimestamp},
+				},
+				{
+					Lsn:           0x100000000420,
+					TransactionID: 7,
+					RedoOperation: LogFileOperationSetBitsInNonresidentBitMap,
+					UndoOperation: LogFileOperationClearBitsInNonresidentBitMap,
+				},
+			},
+			want: "LSN|Transaction ID|Redo Operation|Undo Operation|Record Number|File Path|File Name|Created|Modified|Changed|Accessed\n" +
+				"17592186045448|7|AddIndexEntryAllocation|DeleteIndexEntryAllocation|40|C:\\secret.txt|secret.txt|2020-05-17T13:45:12.1234567Z|2020-05-17T13:45:12.1234567Z||\n" +
+				"17592186045472|7|SetBitsInNonresidentBitMap|ClearBitsInNonresidentBitMap|||||||\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var streamer DummyResultWriter
+			err := WriteLogFileTimelineCsv(&streamer, tt.timeline)
+			if err != nil {
+				t.Fatalf("WriteLogFileTimelineCsv() error = %v", err)
+			}
+			if got := string(streamer.AggregatedData); got != tt.want {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestWriteUsnAnomalyCsv(t *testing.T) {
+	tests := []struct {
+		name              string
+		usnAnomalyEntries []UsnAnomalyEntry
+		want              string
+	}{
+		{
+			name: "anomalies with and without a journal record",
+			usnAnomalyEntries: []UsnAnomalyEntry{
+				{RecordNumber: 71, SequenceNumber: 4, FullPath: "C:\\notes.txt", SiUsn: 8192, Anomaly: UsnAnomalyFileReferenceMismatch, JournalRecord: testUsnRecordV2},
+				{RecordNumber: 73, SequenceNumber: 1, FullPath: "C:\\wiped.txt", SiUsn: 8208, Anomaly: UsnAnomalyMissingRecord},
+			},
+			want: "Record Number|Sequence Number|Full Path|SI USN|Anomaly|Journal Record Number|Journal Sequence Number|Journal File Name|Journal Timestamp|Journal Reason\n" +
+				"71|4|C:\\notes.txt|8192|FILE_REFERENCE_MISMATCH|70|3|notes.txt|2020-05-17T13:45:12.1234567Z|FILE_CREATE;CLOSE\n" +
+				"73|1|C:\\wiped.txt|8208|MISSING_RECORD|||||\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var streamer DummyResultWriter
+			err := WriteUsnAnomalyCsv(&streamer, tt.usnAnomalyEntries)
+			if err != nil {
+				t.Fatalf("WriteUsnAnomalyCsv() error = %v", err)
+			}
+			if got := string(streamer.AggregatedData); got != tt.want {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestWriteMftBitmapAnomalyCsv(t *testing.T) {
+	tests := []struct {
+		name                    string
+		mftBitmapAnomalyEntries []MftBitmapAnomalyEntry
+		want                    string
+	}{
+		{
+			name: "in use and allocated anomalies",
+			mftBitmapAnomalyEntries: []MftBitmapAnomalyEntry{
+				{RecordNumber: 1, SequenceNumber: 2, FullPath: "C:\\hidden.txt", InUse: true, Anomaly: MftBitmapAnomalyInUseNotAllocated},
+				{RecordNumber: 2, SequenceNumber: 3, FullPath: "C:\\gone.txt", Allocated: true, Anomaly: MftBitmapAnomalyAllocatedNotInUse},
+			},
+			want: "Record Number|Sequence Number|Full Path|In Use|Allocated|Anomaly\n" +
+				"1|2|C:\\hidden.txt|true|false|IN_USE_NOT_ALLOCATED\n" +
+				"2|3|C:\\gone.txt|false|true|ALLOCATED_NOT_IN_USE\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var streamer DummyResultWriter
+			err := WriteMftBitmapAnomalyCsv(&streamer, tt.mftBitmapAnomalyEntries)
+			if err != nil {
+				t.Fatalf("WriteMftBitmapAnomalyCsv() error = %v", err)
+			}
+			if got := string(streamer.AggregatedData); got != tt.want {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestWriteMftMirrorCsv(t *testing.T) {
+	tests := []struct {
+		name                 string
+		mftMirrorDifferences []MftMirrorDifference
+		want                 string
+	}{
+		{
+			name: "field difference and unreadable record",
+			mftMirrorDifferences: []MftMirrorDifference{
+				{RecordNumber: 0, Field: "Error", Mft: "failed to apply fixups: sector 0 does not end with the update sequence number"},
+				{RecordNumber: 3, Field: "VolumeInformation.Flags", Mft: "DIRTY|MODIFIED_BY_CHKDSK", MftMirr: "DIRTY"},
+			},
+			want: "Record Number|Field|MFT|MFTMirr\n" +
+				"0|Error|failed to apply fixups: sector 0 does not end with the update sequence number|\n" +
+				"3|VolumeInformation.Flags|DIRTY;MODIFIED_BY_CHKDSK|DIRTY\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var streamer DummyResultWriter
+			err := WriteMftMirrorCsv(&streamer, tt.mftMirrorDifferences)
+			if err != nil {
+				t.Fatalf("WriteMftMirrorCsv() error = %v", err)
+			}
+			if got := string(streamer.AggregatedData); got != tt.want {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestWriteClusterLookupCsv(t *testing.T) {
+	tests := []struct {
+		name           string
+		clusterLookups []ClusterLookup
+		want           string
+	}{
+		{
+			name: "owned, shared, and unowned clusters",
+			clusterLookups: []ClusterLookup{
+				{Cluster: 256, VolumeOffset: 1048576, Owners: []ClusterOwner{{RecordNumber: 30, SequenceNumber: 2, FullPath: "C:\\report.docx", Stream: "$DATA"}}},
+				{Cluster: 261, VolumeOffset: 1069056, Allocated: true, BitmapChecked: true, Owners: []ClusterOwner{
+					{RecordNumber: 30, SequenceNumber: 2, FullPath: "C:\\report.docx", Stream: "$DATA", StreamOffset: 20480},
+					{RecordNumber: 32, SequenceNumber: 1, FullPath: "C:\\old.txt", Stream: "$DATA", StreamOffset: 4096, Deleted: true},
+				}},
+				{Cluster: 16, VolumeOffset: 65536, BitmapChecked: true},
+			},
+			want: "Cluster|Volume Offset|Allocated|Record Number|Sequence Number|Full Path|Stream|Stream Offset|Deleted\n" +
+				"256|1048576||30|2|C:\\report.docx|$DATA|0|false\n" +
+				"261|1069056|true|30|2|C:\\report.docx|$DATA|20480|false\n" +
+				"261|1069056|true|32|1|C:\\old.txt|$DATA|4096|true\n" +
+				"16|65536|false||||||\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var streamer DummyResultWriter
+			err := WriteClusterLookupCsv(&streamer, tt.clusterLookups)
+			if err != nil {
+				t.Fatalf("WriteClusterLookupCsv() error = %v", err)
+			}
+			if got := string(streamer.AggregatedData); got != tt.want {
+				t.Errorf(cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestWriteClusterRangesCsv(t *testing.T) {
+	var streamer DummyResultWriter
+	err := WriteClusterRangesCsv(&streamer, []ClusterRange{{FirstCluster: 4, ClusterCount: 4}, {FirstCluster: 9, ClusterCount: 3}}, 4096)
+	if err != nil {
+		t.Fatalf("WriteClusterRangesCsv() error = %v", err)
+	}
+	want := "First Cluster|Cluster Count|Volume Offset|Length|Output Offset\n" +
+		"4|4|16384|16384|0\n" +
+		"9|3|36864|12288|16384\n"
+	if got := string(streamer.AggregatedData); got != want {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
+
+func TestWriteFileSlackCsv(t *testing.T) {
+	var streamer DummyResultWriter
+	err := WriteFileSlackCsv(&streamer, []FileSlack{
+		{RecordNumber: 30, SequenceNumber: 2, FullPath: "C:\\report.docx", Stream: "$DATA", VolumeOffset: 22528, Length: 2048},
+		{RecordNumber: 41, SequenceNumber: 1, FullPath: "C:\\notes.txt", Stream: "$DATA:Zone.Identifier", VolumeOffset: 40960 + 26, Length: 4070, OutputOffset: 2048},
+	})
+	if err != nil {
+		t.Fatalf("WriteFileSlackCsv() error = %v", err)
+	}
+	want := "Record Number|Sequence Number|Full Path|Stream|Volume Offset|Length|Output Offset\n" +
+		"30|2|C:\\report.docx|$DATA|22528|2048|0\n" +
+		"41|1|C:\\notes.txt|$DATA:Zone.Identifier|40986|4070|2048\n"
+	if got := string(streamer.AggregatedData); got != want {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
+
+func TestWriteCarvedIndexEntriesCsv(t *testing.T) {
+	var streamer DummyResultWriter
+	err := WriteCarvedIndexEntriesCsv(&streamer, []CarvedIndexEntry{
+		{
+			RecordOffset:   4096,
+			SourceOffset:   4864,
+			VCN:            2,
+			Slack:          true,
+			RecordNumber:   65,
+			SequenceNumber: 1,
+			FileName: FileNameAttribute{
+				ParentDirRecordNumber:   5,
+				ParentDirSequenceNumber: 5,
+				FileName:                "deleted.txt",
+				FileNamespace:           "WIN32",
+				LogicalFileSize:         100,
+				PhysicalFileSize:        4096,
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("WriteCarvedIndexEntriesCsv() error = %v", err)
+	}
+	want := "Record Offset|Source Offset|VCN|Slack|Record Number|Sequence Number|Parent Record Number|Parent Sequence Number|File Name|Namespace|Directory|Logical Size|Physical Size|FileName Created|FileName Modified|Filename Accessed|Filename Entry Modified\n" +
+		"4096|4864|2|true|65|1|5|5|deleted.txt|WIN32|false|100|4096|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z|0001-01-01T00:00:00Z\n"
+	if got := string(streamer.AggregatedData); got != want {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}
+
+func TestWriteRecordErrorsCsv(t *testing.T) {
+	var streamer DummyResultWriter
+	err := WriteRecordErrorsCsv(&streamer, []*RecordError{
+		{Offset: 2048, RecordNumber: 2, Reason: RecordErrorBaad, Err: errors.New("this is not an mft record")},
+		{Offset: 4096, RecordNumber: 40, AttributeType: 0x30, Reason: RecordErrorBadAttribute, Partial: true, Err: errors.New("bad name | length")},
+	})
+	if err != nil {
+		t.Fatalf("WriteRecordErrorsCsv() error = %v", err)
+	}
+	want := "Offset|Record Number|Reason|Partial|Attribute|Error\n" +
+		"2048|2|BAAD|false||this is not an mft record\n" +
+		"4096|40|BAD_ATTRIBUTE|true|$FILE_NAME|bad name ; length\n"
+	if got := string(streamer.AggregatedData); got != want {
+		t.Errorf(cmp.Diff(got, want))
+	}
+}